@@ -10,6 +10,7 @@ import (
 
 	"github.com/moonr-app/crypto-signal-trading-bot/internal/persistence"
 	"github.com/moonr-app/crypto-signal-trading-bot/internal/shared/logging"
+	"github.com/moonr-app/crypto-signal-trading-bot/internal/trader"
 )
 
 func main() {
@@ -19,12 +20,12 @@ func main() {
 
 	d := persistence.NewDynamo(dynamoID, dynamoSecret, dynamoRegion)
 	ctx := context.Background()
-	err := d.StoreCoinPurchased(ctx, "MAT2", decimal.NewFromInt(300), decimal.NewFromInt(200), time.Now().Add(time.Hour))
+	err := d.StoreCoinPurchased(ctx, "MAT2", trader.Fill{Price: decimal.NewFromInt(300), Amount: decimal.NewFromInt(200)}, time.Now().Add(time.Hour))
 	if err != nil {
 		logging.Fatal(ctx, "could not store purchased coin", zap.String("coin", "MAT2"), zap.Error(err))
 	}
 
-	err = d.StoreCoinPurchased(ctx, "MAT", decimal.NewFromInt(300), decimal.NewFromInt(200), time.Now().Add(time.Hour))
+	err = d.StoreCoinPurchased(ctx, "MAT", trader.Fill{Price: decimal.NewFromInt(300), Amount: decimal.NewFromInt(200)}, time.Now().Add(time.Hour))
 	if err != nil {
 		logging.Fatal(ctx, "could not store purchased coin", zap.String("coin", "MAT"), zap.Error(err))
 	}
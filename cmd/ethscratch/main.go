@@ -0,0 +1,39 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/moonr-app/crypto-signal-trading-bot/internal/scraper"
+	"github.com/moonr-app/crypto-signal-trading-bot/internal/shared/logging"
+)
+
+func main() {
+	ctx := context.Background()
+	e := scraper.NewEthereum(http.DefaultClient, os.Getenv("ETH_RPC_URL"))
+
+	ticker := time.NewTicker(12 * time.Second)
+
+	for {
+		select {
+		case <-ticker.C:
+			coin, err := e.Scrape(ctx)
+			if err != nil {
+				switch {
+				case errors.Is(err, scraper.ErrNoCoin):
+					logging.Info(ctx, "no new pool")
+					continue
+				default:
+					logging.Error(ctx, "unexpected scraping error", zap.Error(err))
+					return
+				}
+			}
+			logging.Info(ctx, "new pool", zap.String("coin", coin))
+		}
+	}
+}
@@ -4,7 +4,10 @@ import (
 	"context"
 	"net/http"
 	"os"
+	ossignal "os/signal"
 	"strconv"
+	"strings"
+	"syscall"
 	"time"
 
 	"github.com/gateio/gateapi-go/v6"
@@ -16,6 +19,7 @@ import (
 	"github.com/moonr-app/crypto-signal-trading-bot/internal/persistence"
 	"github.com/moonr-app/crypto-signal-trading-bot/internal/scraper"
 	"github.com/moonr-app/crypto-signal-trading-bot/internal/shared/logging"
+	"github.com/moonr-app/crypto-signal-trading-bot/internal/signal"
 	"github.com/moonr-app/crypto-signal-trading-bot/internal/trader"
 )
 
@@ -28,6 +32,11 @@ func main() {
 	gateapiKey := os.Getenv("GATE_API_KEY")
 	gateapiSecret := os.Getenv("GATE_API_SECRET")
 
+	kucoinKey := os.Getenv("KUCOIN_API_KEY")
+	kucoinSecret := os.Getenv("KUCOIN_API_SECRET")
+	kucoinPassphrase := os.Getenv("KUCOIN_API_PASSPHRASE")
+	exchangePriority := os.Getenv("EXCHANGE_PRIORITY")
+
 	disableTelegram := os.Getenv("DISABLE_TELEGRAM")
 	enableTestMode := os.Getenv("ENABLE_TEST_MODE")
 
@@ -40,6 +49,32 @@ func main() {
 	tickerCacheIntervalInSeconds := os.Getenv("TICKER_CACHE_INTERVAL_SECONDS")
 	sellThresholdPercentage := os.Getenv("SELL_THRESHOLD_PERCENTAGE")
 	toSpend := os.Getenv("USDT_TO_SPEND")
+	ethRPCURL := os.Getenv("ETH_RPC_URL")
+	signalThreshold := os.Getenv("SIGNAL_THRESHOLD")
+	signalWindowInSeconds := os.Getenv("SIGNAL_WINDOW_SECONDS")
+	signalWeights := os.Getenv("SIGNAL_WEIGHTS")
+	signalDedupInSeconds := os.Getenv("SIGNAL_DEDUP_SECONDS")
+	symbolAliases := os.Getenv("SYMBOL_ALIASES")
+	orderTimeoutInSeconds := os.Getenv("ORDER_TIMEOUT_SECONDS")
+	buySlippageLadder := os.Getenv("BUY_SLIPPAGE_LADDER")
+	sellRequoteInSeconds := os.Getenv("SELL_REQUOTE_SECONDS")
+	stopLossPercentage := os.Getenv("STOP_LOSS_PERCENTAGE")
+	allowSymbols := os.Getenv("FILTER_ALLOW_SYMBOLS")
+	denySymbols := os.Getenv("FILTER_DENY_SYMBOLS")
+	allowKeywords := os.Getenv("FILTER_ALLOW_KEYWORDS")
+	denyKeywords := os.Getenv("FILTER_DENY_KEYWORDS")
+	disablePriceStream := os.Getenv("DISABLE_PRICE_STREAM")
+	quoteCurrencies := os.Getenv("QUOTE_CURRENCIES")
+	profitSweepTo := os.Getenv("PROFIT_SWEEP_TO")
+	profitSweepFloor := os.Getenv("PROFIT_SWEEP_FLOOR_USDT")
+	lowBalanceFloor := os.Getenv("LOW_BALANCE_FLOOR_USDT")
+	lowBalanceCheckInSeconds := os.Getenv("LOW_BALANCE_CHECK_SECONDS")
+	enableDelistingShorts := os.Getenv("ENABLE_DELISTING_SHORTS")
+	shortMarginUSDT := os.Getenv("SHORT_MARGIN_USDT")
+	shortBudgetUSDT := os.Getenv("SHORT_BUDGET_USDT")
+	shortLeverage := os.Getenv("SHORT_LEVERAGE")
+	shortStopLossPercentage := os.Getenv("SHORT_STOP_LOSS_PERCENTAGE")
+	shortTakeProfitPercentage := os.Getenv("SHORT_TAKE_PROFIT_PERCENTAGE")
 
 	spendableUSDT, err := decimal.NewFromString(toSpend)
 	if err != nil {
@@ -76,14 +111,185 @@ func main() {
 		logging.Fatal(ctx, "failed to parse failed to parse sellConsiderInterval", zap.Error(err))
 	}
 
+	threshold := 1.0
+	if signalThreshold != "" {
+		threshold, err = strconv.ParseFloat(signalThreshold, 64)
+		if err != nil {
+			logging.Fatal(ctx, "failed to parse signalThreshold", zap.Error(err))
+		}
+	}
+
+	signalWindow := 300.0
+	if signalWindowInSeconds != "" {
+		signalWindow, err = strconv.ParseFloat(signalWindowInSeconds, 64)
+		if err != nil {
+			logging.Fatal(ctx, "failed to parse signalWindow", zap.Error(err))
+		}
+	}
+
+	signalDedup := 60.0
+	if signalDedupInSeconds != "" {
+		signalDedup, err = strconv.ParseFloat(signalDedupInSeconds, 64)
+		if err != nil {
+			logging.Fatal(ctx, "failed to parse signalDedup", zap.Error(err))
+		}
+	}
+
+	weights, err := signal.ParseWeights(signalWeights)
+	if err != nil {
+		logging.Fatal(ctx, "failed to parse signalWeights", zap.Error(err))
+	}
+
+	orderTimeout := 30.0
+	if orderTimeoutInSeconds != "" {
+		orderTimeout, err = strconv.ParseFloat(orderTimeoutInSeconds, 64)
+		if err != nil {
+			logging.Fatal(ctx, "failed to parse orderTimeout", zap.Error(err))
+		}
+	}
+
+	sellRequote := 5.0
+	if sellRequoteInSeconds != "" {
+		sellRequote, err = strconv.ParseFloat(sellRequoteInSeconds, 64)
+		if err != nil {
+			logging.Fatal(ctx, "failed to parse sellRequote", zap.Error(err))
+		}
+	}
+
+	stopLoss := decimal.Zero
+	if stopLossPercentage != "" {
+		stopLoss, err = decimal.NewFromString(stopLossPercentage)
+		if err != nil {
+			logging.Fatal(ctx, "failed to parse stopLossPercentage", zap.Error(err))
+		}
+	}
+
+	streamDisabled := false
+	if disablePriceStream != "" {
+		streamDisabled, err = strconv.ParseBool(disablePriceStream)
+		if err != nil {
+			logging.Fatal(ctx, "failed to parse disablePriceStream", zap.Error(err))
+		}
+	}
+	slippageLadder, err := exchange.ParseSlippageLadder(buySlippageLadder)
+	if err != nil {
+		logging.Fatal(ctx, "failed to parse buySlippageLadder", zap.Error(err))
+	}
+
+	aliases, err := exchange.ParseAliases(symbolAliases)
+	if err != nil {
+		logging.Fatal(ctx, "failed to parse symbolAliases", zap.Error(err))
+	}
+
+	quotes, err := exchange.ParseQuoteCurrencies(quoteCurrencies)
+	if err != nil {
+		logging.Fatal(ctx, "failed to parse quoteCurrencies", zap.Error(err))
+	}
+
+	profitTo, err := exchange.ParseProfitDestination(profitSweepTo)
+	if err != nil {
+		logging.Fatal(ctx, "failed to parse profitSweepTo", zap.Error(err))
+	}
+
+	sweepFloor := decimal.Zero
+	if profitSweepFloor != "" {
+		sweepFloor, err = decimal.NewFromString(profitSweepFloor)
+		if err != nil {
+			logging.Fatal(ctx, "failed to parse profitSweepFloor", zap.Error(err))
+		}
+	}
+
+	var balanceFloor decimal.Decimal
+	if lowBalanceFloor != "" {
+		balanceFloor, err = decimal.NewFromString(lowBalanceFloor)
+		if err != nil {
+			logging.Fatal(ctx, "failed to parse lowBalanceFloor", zap.Error(err))
+		}
+	}
+
+	lowBalanceCheck := 300.0
+	if lowBalanceCheckInSeconds != "" {
+		lowBalanceCheck, err = strconv.ParseFloat(lowBalanceCheckInSeconds, 64)
+		if err != nil {
+			logging.Fatal(ctx, "failed to parse lowBalanceCheck", zap.Error(err))
+		}
+	}
+
+	shortsEnabled := false
+	if enableDelistingShorts != "" {
+		shortsEnabled, err = strconv.ParseBool(enableDelistingShorts)
+		if err != nil {
+			logging.Fatal(ctx, "failed to parse enableDelistingShorts", zap.Error(err))
+		}
+	}
+
+	var (
+		shortBudget     trader.ShortBudget
+		shortLev        int64 = 2
+		shortStopLoss         = decimal.NewFromInt(15)
+		shortTakeProfit       = decimal.NewFromInt(30)
+	)
+	if shortsEnabled {
+		shortBudget.PerShort, err = decimal.NewFromString(shortMarginUSDT)
+		if err != nil || !shortBudget.PerShort.IsPositive() {
+			logging.Fatal(ctx, "failed to parse SHORT_MARGIN_USDT", zap.String("value_passed", shortMarginUSDT))
+		}
+
+		// one short at a time unless told otherwise.
+		shortBudget.Total = shortBudget.PerShort
+		if shortBudgetUSDT != "" {
+			shortBudget.Total, err = decimal.NewFromString(shortBudgetUSDT)
+			if err != nil {
+				logging.Fatal(ctx, "failed to parse shortBudgetUSDT", zap.Error(err))
+			}
+		}
+
+		if shortLeverage != "" {
+			shortLev, err = strconv.ParseInt(shortLeverage, 10, 64)
+			if err != nil {
+				logging.Fatal(ctx, "failed to parse shortLeverage", zap.Error(err))
+			}
+		}
+
+		if shortStopLossPercentage != "" {
+			shortStopLoss, err = decimal.NewFromString(shortStopLossPercentage)
+			if err != nil {
+				logging.Fatal(ctx, "failed to parse shortStopLossPercentage", zap.Error(err))
+			}
+		}
+
+		if shortTakeProfitPercentage != "" {
+			shortTakeProfit, err = decimal.NewFromString(shortTakeProfitPercentage)
+			if err != nil {
+				logging.Fatal(ctx, "failed to parse shortTakeProfitPercentage", zap.Error(err))
+			}
+		}
+	}
+
+	filter, err := trader.NewFilter(
+		strings.Split(allowSymbols, ","),
+		strings.Split(denySymbols, ","),
+		strings.Split(allowKeywords, ","),
+		strings.Split(denyKeywords, ","),
+	)
+	if err != nil {
+		logging.Fatal(ctx, "failed to create filter", zap.Error(err))
+	}
+
 	var (
 		buyConsiderIntervalSecs  = time.Duration(float64(time.Second) * buyConsiderInterval)
 		sellConsiderIntervalSecs = time.Duration(float64(time.Second) * sellConsiderInterval)
 		tickerCacheIntervalSecs  = time.Duration(float64(time.Second) * tickerCacheInterval)
+		signalWindowSecs         = time.Duration(float64(time.Second) * signalWindow)
+		signalDedupSecs          = time.Duration(float64(time.Second) * signalDedup)
+		orderTimeoutSecs         = time.Duration(float64(time.Second) * orderTimeout)
+		sellRequoteSecs          = time.Duration(float64(time.Second) * sellRequote)
+		lowBalanceCheckSecs      = time.Duration(float64(time.Second) * lowBalanceCheck)
 		doer                     = http.DefaultClient
 		db                       = persistence.NewDynamo(dynamoID, dynamoSecret, dynamoRegion)
 		binance                  = scraper.NewBinance(doer)
 		binanceCZ                = scraper.NewBinanceCZ(doer)
+		aggregator               = signal.NewAggregator(threshold, signalWindowSecs, weights)
 	)
 
 	logging.Info(ctx, "running with threshold", zap.Int64("treshold", sellThreshAsFloat))
@@ -93,6 +299,15 @@ func main() {
 		logging.Fatal(ctx, "failed to init coinbase", zap.Error(err))
 	}
 
+	scrapers := []trader.Scraper{
+		aggregator.Wrap(binance),
+		aggregator.Wrap(coinbase),
+		aggregator.Wrap(binanceCZ),
+	}
+	if ethRPCURL != "" {
+		scrapers = append(scrapers, aggregator.Wrap(scraper.NewEthereum(doer, ethRPCURL)))
+	}
+
 	telegram := notifier.NewTelegram(doer, botOwner, disableTeleBool)
 
 	ctx = context.WithValue(ctx, gateapi.ContextGateAPIV4, gateapi.GateAPIV4{
@@ -100,18 +315,126 @@ func main() {
 		Secret: gateapiSecret,
 	})
 
-	gate, err := exchange.NewGateIO(ctx, testmode, spendableUSDT, tickerCacheIntervalSecs)
+	gateOpts := []exchange.Option{
+		exchange.WithAliases(aliases),
+		exchange.WithOrderTimeout(orderTimeoutSecs),
+		exchange.WithSweepBuy(slippageLadder...),
+		exchange.WithSellRequoteInterval(sellRequoteSecs),
+		exchange.WithBracket(decimal.NewFromInt(sellThreshAsFloat), stopLoss),
+		exchange.WithPositions(db),
+	}
+	if !streamDisabled {
+		gateOpts = append(gateOpts, exchange.WithPriceStream(exchange.DefaultStreamURL))
+	}
+	if len(quotes) > 0 {
+		gateOpts = append(gateOpts, exchange.WithQuoteCurrencies(quotes...))
+	}
+	if !profitTo.IsZero() {
+		gateOpts = append(gateOpts, exchange.WithProfitDestination(profitTo))
+	}
+	if shortsEnabled {
+		gateOpts = append(gateOpts, exchange.WithShorts(shortLev, shortTakeProfit, shortStopLoss))
+	}
+
+	gate, err := exchange.NewGateIO(
+		ctx,
+		testmode,
+		spendableUSDT,
+		tickerCacheIntervalSecs,
+		gateOpts...,
+	)
 	if err != nil {
 		logging.Fatal(ctx, "failed to create gate.io client", zap.Error(err))
 	}
 
+	exchanges := map[string]exchange.VenueExchange{exchange.VenueGateIO: gate}
+	if kucoinKey != "" {
+		kucoin, err := exchange.NewKuCoin(
+			testmode,
+			spendableUSDT,
+			exchange.KuCoinCredentials{Key: kucoinKey, Secret: kucoinSecret, Passphrase: kucoinPassphrase},
+			exchange.WithOrderTimeout(orderTimeoutSecs),
+		)
+		if err != nil {
+			logging.Fatal(ctx, "failed to create kucoin client", zap.Error(err))
+		}
+		exchanges[exchange.VenueKuCoin] = kucoin
+	}
+
+	if exchangePriority == "" {
+		exchangePriority = exchange.VenueGateIO + "," + exchange.VenueKuCoin
+	}
+	var venues []exchange.Venue
+	for _, name := range strings.Split(exchangePriority, ",") {
+		name = strings.TrimSpace(name)
+		if ex, ok := exchanges[name]; ok {
+			venues = append(venues, exchange.Venue{Name: name, Exchange: ex})
+		}
+	}
+
+	router, err := exchange.NewRouter(db, venues...)
+	if err != nil {
+		logging.Fatal(ctx, "failed to create exchange router", zap.Error(err))
+	}
+
 	var (
-		buyer  = trader.NewBuyer(db, telegram, gate)
-		seller = trader.NewSeller(telegram, db, gate, sellThreshAsFloat)
-		t      = trader.NewTrader(buyConsiderIntervalSecs, sellConsiderIntervalSecs, buyer, seller, binance, coinbase, binanceCZ)
+		bus         = signal.NewBus(len(scrapers))
+		buyer       = trader.NewBuyer(db, telegram, router, filter)
+		coordinator = trader.NewCoordinator(buyer, bus, signalDedupSecs)
+		seller      = trader.NewSeller(telegram, db, router, sellThreshAsFloat)
+		t           = trader.NewTrader(buyConsiderIntervalSecs, sellConsiderIntervalSecs, coordinator, bus, seller, scrapers...)
 	)
 
+	if !profitTo.IsZero() {
+		seller.SetProfitSweeper(trader.NewProfitSweeper(gate, db, telegram, sweepFloor, exchange.VenueGateIO))
+	}
+
+	// delistings aren't buy signals, so they're kept off the bus and go straight to the shorter.
+	if shortsEnabled {
+		shorter := trader.NewShorter(db, telegram, gate, shortBudget, buyConsiderIntervalSecs, scraper.NewBinanceDelisting(doer))
+		go shorter.Run(ctx)
+	}
+
+	coordinator.Subscribe(trader.LogHook)
+	coordinator.Subscribe(trader.NotifyHook(telegram))
+
+	// there's nothing on the exchange to reconcile with in test mode.
+	if !testmode {
+		if err := seller.Reconcile(ctx); err != nil {
+			logging.Error(ctx, "failed to reconcile positions", zap.Error(err))
+			telegram.NotifyError(ctx, err)
+		}
+		go reconcileOnSignal(ctx, seller, telegram)
+
+		if balanceFloor.IsPositive() {
+			for _, v := range venues {
+				go trader.NewBalanceWatcher(v.Name, v.Exchange, telegram, balanceFloor, lowBalanceCheckSecs).Run(ctx)
+			}
+		}
+	}
+
 	if err := t.Trade(ctx); err != nil {
 		logging.Fatal(ctx, "unexpected trading error", zap.Error(err))
 	}
 }
+
+// reconcileOnSignal reconciles positions with the exchange whenever the process gets SIGUSR1, e.g. after trading
+// by hand on an exchange.
+func reconcileOnSignal(ctx context.Context, seller *trader.Seller, n trader.Notifier) {
+	sigs := make(chan os.Signal, 1)
+	ossignal.Notify(sigs, syscall.SIGUSR1)
+	defer ossignal.Stop(sigs)
+
+	for {
+		select {
+		case <-sigs:
+			logging.Info(ctx, "reconciling positions on request")
+			if err := seller.Reconcile(ctx); err != nil {
+				logging.Error(ctx, "failed to reconcile positions", zap.Error(err))
+				n.NotifyError(ctx, err)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
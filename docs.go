@@ -4,3 +4,7 @@ package docs
 //go:generate mockgen -package mocks -destination internal/mocks/buyer.go  -source internal/trader/buyer.go Scraper,PurchaseDB,ExchangePurchaser
 //go:generate mockgen -package mocks -destination internal/mocks/seller.go  -source internal/trader/seller.go SellingDB,SellingExchange
 //go:generate mockgen -package mocks -destination internal/mocks/trader.go  -source internal/trader/trader.go Notifier
+//go:generate mockgen -package mocks -destination internal/mocks/coordinator.go  -source internal/trader/coordinator.go SignalBuyer
+//go:generate mockgen -package mocks -destination internal/mocks/sweeper.go  -source internal/trader/sweeper.go ProfitVault,SweepDB
+//go:generate mockgen -package mocks -destination internal/mocks/balance.go  -source internal/trader/balance.go BalanceSource
+//go:generate mockgen -package mocks -destination internal/mocks/short.go  -source internal/trader/short.go ShortExchange,ShortDB
@@ -0,0 +1,140 @@
+package exchange
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/antihax/optional"
+	"github.com/gateio/gateapi-go/v6"
+
+	"github.com/moonr-app/crypto-signal-trading-bot/internal/trader"
+)
+
+// maxTradesListed is as many trades as gate.io lists in one go.
+const maxTradesListed = 1000
+
+// Holdings returns every currency in the spot account, available and locked, keyed by upper case currency. They are
+// always listed afresh, and cached for the balance check before a buy.
+func (g *GateIO) Holdings(ctx context.Context) (map[string]trader.Holding, error) {
+	var accounts []gateapi.SpotAccount
+	err := g.retry.do(ctx, func() (res *http.Response, err error) {
+		accounts, res, err = g.api.SpotApi.ListSpotAccounts(ctx, nil)
+		return res, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list spot accounts: %w", err)
+	}
+
+	holdings := make(map[string]trader.Holding, len(accounts))
+	for _, a := range accounts {
+		available, err := parseDecimal(a.Available)
+		if err != nil {
+			return nil, fmt.Errorf("invalid available balance for %s: %w", a.Currency, err)
+		}
+		locked, err := parseDecimal(a.Locked)
+		if err != nil {
+			return nil, fmt.Errorf("invalid locked balance for %s: %w", a.Currency, err)
+		}
+		holdings[strings.ToUpper(a.Currency)] = trader.Holding{Available: available, Locked: locked}
+	}
+
+	g.balances.set(holdings)
+	return holdings, nil
+}
+
+// OpenOrders returns every open spot order, across all currency pairs. Orders with one of the bot's client IDs are
+// marked as its own.
+func (g *GateIO) OpenOrders(ctx context.Context) ([]trader.OpenOrder, error) {
+	var pairs []gateapi.OpenOrders
+	err := g.retry.do(ctx, func() (res *http.Response, err error) {
+		pairs, res, err = g.api.SpotApi.ListAllOpenOrders(ctx, &gateapi.ListAllOpenOrdersOpts{Account: optional.NewString(accountType)})
+		return res, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list open orders: %w", err)
+	}
+
+	var orders []trader.OpenOrder
+	for _, p := range pairs {
+		for _, o := range p.Orders {
+			left, err := parseDecimal(o.Left)
+			if err != nil {
+				return nil, fmt.Errorf("invalid order left: %w", err)
+			}
+			orders = append(orders, trader.OpenOrder{
+				Coin:    baseCurrency(p.CurrencyPair),
+				OrderID: o.Id,
+				Side:    o.Side,
+				Left:    left,
+				Ours:    strings.HasPrefix(o.Text, clientIDPrefix),
+			})
+		}
+	}
+	return orders, nil
+}
+
+// Trades returns the account's trades in coin since since, across every pair coin has in the quote currencies. Prices
+// are left in the quote currency of the pair each trade was in.
+func (g *GateIO) Trades(ctx context.Context, coin string, since time.Time) ([]trader.Trade, error) {
+	pairs, err := g.catalogue.pairsFor(ctx, coin)
+	if err != nil {
+		return nil, err
+	}
+
+	var trades []trader.Trade
+	for _, currencyPair := range pairs {
+		listed, err := g.pairTrades(ctx, currencyPair, since)
+		if err != nil {
+			return nil, err
+		}
+		trades = append(trades, listed...)
+	}
+	return trades, nil
+}
+
+func (g *GateIO) pairTrades(ctx context.Context, currencyPair string, since time.Time) ([]trader.Trade, error) {
+	var listed []gateapi.Trade
+	err := g.retry.do(ctx, func() (res *http.Response, err error) {
+		listed, res, err = g.api.SpotApi.ListMyTrades(ctx, currencyPair, &gateapi.ListMyTradesOpts{
+			Account: optional.NewString(accountType),
+			From:    optional.NewInt64(since.Unix()),
+			Limit:   optional.NewInt32(maxTradesListed),
+		})
+		return res, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list trades: %w", err)
+	}
+
+	trades := make([]trader.Trade, 0, len(listed))
+	for _, t := range listed {
+		amount, err := parseDecimal(t.Amount)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trade amount: %w", err)
+		}
+		price, err := parseDecimal(t.Price)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trade price: %w", err)
+		}
+		at, err := strconv.ParseInt(t.CreateTime, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trade time: %w", err)
+		}
+		trades = append(trades, trader.Trade{
+			OrderID: t.OrderId,
+			Side:    t.Side,
+			Amount:  amount,
+			Price:   price,
+			At:      time.Unix(at, 0),
+		})
+	}
+	return trades, nil
+}
+
+func baseCurrency(currencyPair string) string {
+	return strings.SplitN(currencyPair, "_", 2)[0]
+}
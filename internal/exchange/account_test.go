@@ -0,0 +1,70 @@
+package exchange_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gateio/gateapi-go/v6"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGateIO_Holdings(t *testing.T) {
+	stub := newGateStub(t)
+	stub.accounts = []gateapi.SpotAccount{
+		{Currency: "rare", Available: "20", Locked: "5.5"},
+		{Currency: "USDT", Available: "100"},
+	}
+
+	holdings, err := stub.gate(t).Holdings(context.Background())
+	require.NoError(t, err)
+
+	require.Len(t, holdings, 2)
+	assert.Equal(t, "25.5", holdings["RARE"].Total().String())
+	assert.Equal(t, "100", holdings["USDT"].Available.String())
+}
+
+func TestGateIO_OpenOrders(t *testing.T) {
+	stub := newGateStub(t)
+	stub.handle("/spot/open_orders", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, []gateapi.OpenOrders{{
+			CurrencyPair: "RARE_USDT",
+			Orders: []gateapi.Order{
+				{Id: "1", Text: "t-RARE.abc-1.s-1", Side: "sell", Left: "5"},
+				{Id: "2", Text: "web", Side: "buy", Left: "7"},
+			},
+		}})
+	})
+
+	orders, err := stub.gate(t).OpenOrders(context.Background())
+	require.NoError(t, err)
+
+	require.Len(t, orders, 2)
+	assert.Equal(t, "RARE", orders[0].Coin)
+	assert.Equal(t, "5", orders[0].Left.String())
+	assert.True(t, orders[0].Ours)
+	assert.False(t, orders[1].Ours)
+}
+
+func TestGateIO_Trades(t *testing.T) {
+	since := time.Unix(1700000000, 0)
+
+	stub := newGateStub(t)
+	stub.handle("/spot/my_trades", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "RARE_USDT", r.URL.Query().Get("currency_pair"))
+		assert.Equal(t, "1700000000", r.URL.Query().Get("from"))
+		writeJSON(w, []gateapi.Trade{
+			{OrderId: "1", Side: "sell", Amount: "10", Price: "0.5", CreateTime: "1700000100"},
+		})
+	})
+
+	trades, err := stub.gate(t).Trades(context.Background(), "RARE", since)
+	require.NoError(t, err)
+
+	require.Len(t, trades, 1)
+	assert.Equal(t, "sell", trades[0].Side)
+	assert.Equal(t, "10", trades[0].Amount.String())
+	assert.Equal(t, since.Add(100*time.Second), trades[0].At)
+}
@@ -0,0 +1,130 @@
+package exchange
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+
+	"github.com/moonr-app/crypto-signal-trading-bot/internal/shared/logging"
+	"github.com/moonr-app/crypto-signal-trading-bot/internal/trader"
+)
+
+// buyBalanceMaxAge is how old the balance a buy is checked against can be. What the bot's own buys spend is taken off
+// it, and anything else the bot does to the balance clears it, so it only goes stale through trading by hand or
+// deposits.
+const buyBalanceMaxAge = time.Minute
+
+// balanceCache holds the spot account's holdings as last listed.
+type balanceCache struct {
+	lock     sync.Mutex
+	holdings map[string]trader.Holding
+	at       time.Time
+}
+
+// get returns the holdings if they were listed within maxAge.
+func (c *balanceCache) get(maxAge time.Duration) (map[string]trader.Holding, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.holdings == nil || time.Since(c.at) > maxAge {
+		return nil, false
+	}
+	return c.holdings, true
+}
+
+func (c *balanceCache) set(holdings map[string]trader.Holding) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.holdings, c.at = holdings, time.Now()
+}
+
+// spend takes amount off what's available of currency, after a buy has paid for its coin with it. The holdings are
+// copied rather than changed, as callers of get may still be reading them.
+func (c *balanceCache) spend(currency string, amount decimal.Decimal) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.holdings == nil {
+		return
+	}
+
+	holdings := make(map[string]trader.Holding, len(c.holdings))
+	for cur, h := range c.holdings {
+		holdings[cur] = h
+	}
+	h := holdings[currency]
+	h.Available = decimal.Max(decimal.Zero, h.Available.Sub(amount))
+	holdings[currency] = h
+	c.holdings = holdings
+}
+
+// clear drops the holdings, after an order or a transfer has changed them.
+func (c *balanceCache) clear() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.holdings = nil
+}
+
+// refreshBalances lists the spot account's holdings straight away and then every interval until ctx is done.
+func (g *GateIO) refreshBalances(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		// the next tick is the retry.
+		if _, err := g.Holdings(background(ctx)); err != nil && ctx.Err() == nil {
+			logging.Error(ctx, "failed to refresh balances", zap.Error(err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// cachedHoldings returns the holdings listed within maxAge, or lists them again.
+func (g *GateIO) cachedHoldings(ctx context.Context, maxAge time.Duration) (map[string]trader.Holding, error) {
+	if holdings, ok := g.balances.get(maxAge); ok {
+		return holdings, nil
+	}
+	return g.Holdings(ctx)
+}
+
+// budget is how much, in USDT, a buy through r can spend: the configured amount, or less if that's more than the
+// account has available of r's quote currency. It returns trader.ErrInsufficientBalance if what's available is under
+// the pair's minimum order.
+func (g *GateIO) budget(ctx context.Context, r route) (decimal.Decimal, error) {
+	holdings, err := g.cachedHoldings(ctx, buyBalanceMaxAge)
+	if err != nil {
+		return decimal.Zero, err
+	}
+
+	available := holdings[r.quote].Available
+	if r.report(available).GreaterThanOrEqual(g.toSpend) {
+		return g.toSpend, nil
+	}
+
+	pair, err := g.catalogue.get(ctx, r.pair)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	if !available.IsPositive() || available.LessThan(pair.minQuoteAmount) {
+		return decimal.Zero, fmt.Errorf("%w: only %s %s available to buy on %s", trader.ErrInsufficientBalance, available, r.quote, r.pair)
+	}
+
+	spend := r.report(available)
+	logging.Warn(
+		ctx,
+		"not enough available to spend the full amount, buying less",
+		zap.String("currency_pair", r.pair),
+		zap.String("available", available.String()),
+		zap.String("spend", spend.String()),
+	)
+	return spend, nil
+}
@@ -0,0 +1,86 @@
+package exchange_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gateio/gateapi-go/v6"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/moonr-app/crypto-signal-trading-bot/internal/exchange"
+	"github.com/moonr-app/crypto-signal-trading-bot/internal/trader"
+)
+
+func TestGateIO_PurchaseCoin_Balance(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("buys with what there is given the account is short", func(t *testing.T) {
+		stub := newGateStub(t)
+		stub.accounts = []gateapi.SpotAccount{{Currency: "USDT", Available: "4"}}
+		orders := stub.iocHandler(t, func(n int, o gateapi.Order) (string, string) {
+			return "0", "4"
+		})
+
+		fill, err := stub.gate(t).PurchaseCoin(ctx, "RARE", decimal.NewFromFloat(0.5), "")
+		require.NoError(t, err)
+
+		require.Len(t, *orders, 1)
+		assert.Equal(t, "8", (*orders)[0].Amount)
+		assert.Equal(t, "4", fill.FilledTotal.String())
+		assert.Equal(t, "6", fill.Shortfall.String())
+	})
+
+	t.Run("ErrInsufficientBalance given less than the pair's minimum order", func(t *testing.T) {
+		stub := newGateStub(t)
+		stub.pairs[0].MinQuoteAmount = "5"
+		stub.accounts = []gateapi.SpotAccount{{Currency: "USDT", Available: "3"}}
+
+		_, err := stub.gate(t).PurchaseCoin(ctx, "RARE", decimal.NewFromFloat(0.5), "")
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, trader.ErrInsufficientBalance))
+		assert.Equal(t, 0, stub.count("POST /spot/orders"))
+	})
+
+	t.Run("checks against the cached balance, less what was bought", func(t *testing.T) {
+		stub := newGateStub(t)
+		stub.accounts = []gateapi.SpotAccount{{Currency: "USDT", Available: "15"}}
+		stub.iocHandler(t, func(n int, o gateapi.Order) (string, string) {
+			return "0", "10"
+		})
+		g := stub.gate(t, exchange.WithSweepBuy(decimal.NewFromInt(1)))
+		stub.orderBook([][]string{{"0.5", "100"}})
+
+		_, err := g.Holdings(ctx)
+		require.NoError(t, err)
+
+		fill, err := g.PurchaseCoin(ctx, "RARE", decimal.NewFromFloat(0.5), "")
+		require.NoError(t, err)
+		assert.True(t, fill.Shortfall.IsZero())
+
+		fill, err = g.PurchaseCoin(ctx, "RARE", decimal.NewFromFloat(0.5), "")
+		require.NoError(t, err)
+		assert.Equal(t, "5", fill.Shortfall.String())
+		assert.Equal(t, 1, stub.count("GET /spot/accounts"))
+	})
+
+	t.Run("lists the balance again given a buy failed", func(t *testing.T) {
+		stub := newGateStub(t)
+		stub.handle("/spot/orders", gateError(http.StatusInternalServerError, "SERVER_ERROR"))
+		g := stub.gate(t, exchange.WithRetryPolicy(1, time.Millisecond))
+
+		_, err := g.Holdings(ctx)
+		require.NoError(t, err)
+
+		_, err = g.PurchaseCoin(ctx, "RARE", decimal.NewFromFloat(0.5), "")
+		require.Error(t, err)
+
+		_, err = g.PurchaseCoin(ctx, "RARE", decimal.NewFromFloat(0.5), "")
+		require.Error(t, err)
+		assert.Equal(t, 2, stub.count("GET /spot/accounts"))
+	})
+}
@@ -0,0 +1,277 @@
+package exchange
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/antihax/optional"
+	"github.com/gateio/gateapi-go/v6"
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+
+	"github.com/moonr-app/crypto-signal-trading-bot/internal/shared/logging"
+	"github.com/moonr-app/crypto-signal-trading-bot/internal/trader"
+)
+
+const (
+	triggerRuleAtOrAbove = ">="
+	triggerRuleAtOrBelow = "<="
+
+	// price orders name the spot account differently to plain orders.
+	priceOrderAccount        = "normal"
+	priceOrderStatusOpen     = "open"
+	priceOrderStatusFinished = "finish"
+
+	// bracketExpiration is the longest gate.io keeps a price order waiting to trigger. CheckBracket asks for a
+	// bracket to be placed again once it is within bracketRenewal of it.
+	bracketExpiration = 7 * 24 * time.Hour
+	bracketRenewal    = 24 * time.Hour
+	priceOrderExpired = "expired"
+
+	// stopLimitBufferPercentage is how far under its trigger the stop is priced, so it still fills in a falling market.
+	stopLimitBufferPercentage = 2
+)
+
+// PlaceBracket leaves a take-profit and a stop on gate.io for what fill bought, priced from the fill price.
+//
+// Both legs are price triggered orders rather than the take-profit being a plain limit sell. A resting limit would
+// lock the balance, and the stop would then fail for lack of funds when it fired.
+func (g *GateIO) PlaceBracket(ctx context.Context, coin string, fill trader.Fill) (trader.Bracket, error) {
+	if !g.stopLossPercentage.IsPositive() || !g.takeProfitPercentage.IsPositive() {
+		return trader.Bracket{}, nil
+	}
+
+	if g.testMode {
+		logging.Info(ctx, "test mode, not placing bracket")
+		return trader.Bracket{}, nil
+	}
+
+	r, err := g.fillRoute(ctx, coin, fill)
+	if err != nil {
+		return trader.Bracket{}, err
+	}
+	currencyPair := r.pair
+
+	pair, err := g.catalogue.get(ctx, currencyPair)
+	if err != nil {
+		return trader.Bracket{}, err
+	}
+
+	var (
+		hundred    = decimal.NewFromInt(100)
+		price      = r.toQuote(fill.Price)
+		amount     = pair.amount(fill.Received(coin))
+		takeProfit = pair.price(price.Mul(hundred.Add(g.takeProfitPercentage)).Div(hundred), sideTypeSell)
+		rawStop    = price.Mul(hundred.Sub(g.stopLossPercentage)).Div(hundred)
+		stop       = pair.price(rawStop, sideTypeSell)
+		stopLimit  = pair.price(rawStop.Mul(hundred.Sub(decimal.NewFromInt(stopLimitBufferPercentage))).Div(hundred), sideTypeSell)
+	)
+
+	// the stop is the leg that fills lowest, so if it's big enough the take-profit is too.
+	if err := pair.check(currencyPair, amount, stopLimit); err != nil {
+		return trader.Bracket{}, err
+	}
+
+	takeProfitID, err := g.placeTriggered(ctx, currencyPair, takeProfit, triggerRuleAtOrAbove, gateapi.SpotPricePutOrder{
+		Side:        sideTypeSell,
+		Price:       takeProfit.String(),
+		Amount:      amount.String(),
+		Account:     priceOrderAccount,
+		TimeInForce: timeInForceGoodToClose,
+	})
+	if err != nil {
+		return trader.Bracket{}, fmt.Errorf("failed to place take profit: %w", err)
+	}
+
+	stopID, err := g.placeTriggered(ctx, currencyPair, stop, triggerRuleAtOrBelow, gateapi.SpotPricePutOrder{
+		Side:        sideTypeSell,
+		Price:       stopLimit.String(),
+		Amount:      amount.String(),
+		Account:     priceOrderAccount,
+		TimeInForce: timeInForceImmediateOrCancel,
+	})
+	if err != nil {
+		cerr := g.retry.do(ctx, func() (res *http.Response, err error) {
+			_, res, err = g.api.SpotApi.CancelSpotPriceTriggeredOrder(ctx, takeProfitID)
+			return res, err
+		})
+		if cerr != nil {
+			logging.Error(ctx, "failed to cancel take profit after stop failed", zap.String("order_id", takeProfitID), zap.Error(cerr))
+		}
+		return trader.Bracket{}, fmt.Errorf("failed to place stop: %w", err)
+	}
+
+	logging.Info(
+		ctx,
+		"placed bracket",
+		zap.String("currency_pair", currencyPair),
+		zap.String("take_profit", takeProfit.String()),
+		zap.String("stop", stop.String()),
+		zap.String("amount", amount.String()),
+	)
+
+	return trader.Bracket{TakeProfitOrderID: takeProfitID, StopOrderID: stopID}, nil
+}
+
+func (g *GateIO) placeTriggered(ctx context.Context, currencyPair string, trigger decimal.Decimal, rule string, put gateapi.SpotPricePutOrder) (string, error) {
+	var created gateapi.TriggerOrderResponse
+	err := g.retry.doIf(ctx, isRateLimited, func() (res *http.Response, err error) {
+		created, res, err = g.api.SpotApi.CreateSpotPriceTriggeredOrder(ctx, gateapi.SpotPriceTriggeredOrder{
+			Market: currencyPair,
+			Trigger: gateapi.SpotPriceTrigger{
+				Price:      trigger.String(),
+				Rule:       rule,
+				Expiration: int32(bracketExpiration / time.Second),
+			},
+			Put: put,
+		})
+		return res, err
+	})
+	if err != nil {
+		return "", err
+	}
+	return strconv.FormatInt(created.Id, 10), nil
+}
+
+// CheckBracket looks up both legs of bracket. Once one has executed the other is cancelled, and the fill of the
+// order it fired is returned as soon as that order is done with. If neither has, but either has expired or is
+// about to, it returns trader.ErrBracketExpiring.
+func (g *GateIO) CheckBracket(ctx context.Context, coin string, bracket trader.Bracket) (trader.Fill, bool, error) {
+	var (
+		ids  = []string{bracket.TakeProfitOrderID, bracket.StopOrderID}
+		legs = make([]gateapi.SpotPriceTriggeredOrder, len(ids))
+	)
+
+	for i, id := range ids {
+		if id == "" {
+			continue
+		}
+		leg, err := g.getTriggered(ctx, id)
+		if err != nil {
+			return trader.Fill{}, false, fmt.Errorf("failed to get price order %s: %w", id, err)
+		}
+		legs[i] = leg
+	}
+
+	for i, leg := range legs {
+		if leg.Status != priceOrderStatusFinished {
+			continue
+		}
+
+		// the legs were placed on whichever pair the coin was bought through, which may not be the one it would be
+		// routed through now.
+		r, err := g.legRoute(ctx, coin, leg)
+		if err != nil {
+			return trader.Fill{}, false, err
+		}
+		currencyPair := r.pair
+
+		other := ids[len(ids)-1-i]
+		if err := g.cancelTriggeredIfOpen(ctx, other); err != nil {
+			return trader.Fill{}, false, err
+		}
+
+		var fired gateapi.Order
+		err = g.retry.do(ctx, func() (res *http.Response, err error) {
+			fired, res, err = g.api.SpotApi.GetOrder(ctx, strconv.FormatInt(leg.FiredOrderId, 10), currencyPair, &gateapi.GetOrderOpts{Account: optional.NewString(accountType)})
+			return res, err
+		})
+		if err != nil {
+			return trader.Fill{}, false, fmt.Errorf("failed to get order fired by price order %s: %w", ids[i], err)
+		}
+
+		// the fired order is still working, look again on the next pass.
+		if fired.Status == orderStatusOpen {
+			return trader.Fill{}, false, nil
+		}
+
+		fill, err := fillFromOrder(fired)
+		if err != nil {
+			return trader.Fill{}, false, err
+		}
+
+		logging.Info(ctx, "bracket executed", zap.String("currency_pair", currencyPair), zap.String("price_order_id", ids[i]))
+		return g.valueFees(ctx, r.reportFill(fill), r), true, nil
+	}
+
+	for i, leg := range legs {
+		if expiring(leg) {
+			return trader.Fill{}, false, fmt.Errorf("%w: price order %s", trader.ErrBracketExpiring, ids[i])
+		}
+	}
+
+	return trader.Fill{}, false, nil
+}
+
+// expiring reports whether leg has expired or will within bracketRenewal.
+func expiring(leg gateapi.SpotPriceTriggeredOrder) bool {
+	if leg.Status == priceOrderExpired {
+		return true
+	}
+	if leg.Status != priceOrderStatusOpen || leg.Ctime == 0 {
+		return false
+	}
+	return time.Since(time.Unix(leg.Ctime, 0)) > bracketExpiration-bracketRenewal
+}
+
+// legRoute is the route leg was placed through. Given leg doesn't say which market it's on, it's taken to be the
+// pair coin is held through.
+func (g *GateIO) legRoute(ctx context.Context, coin string, leg gateapi.SpotPriceTriggeredOrder) (route, error) {
+	if leg.Market == "" {
+		return g.heldRoute(ctx, coin)
+	}
+	return g.routeFor(ctx, leg.Market)
+}
+
+// fillRoute is the route fill was traded through, or the pair coin is held through if it doesn't say.
+func (g *GateIO) fillRoute(ctx context.Context, coin string, fill trader.Fill) (route, error) {
+	if fill.Pair == "" {
+		return g.heldRoute(ctx, coin)
+	}
+	return g.routeFor(ctx, fill.Pair)
+}
+
+// CancelBracket cancels whichever legs of bracket are still waiting to trigger.
+func (g *GateIO) CancelBracket(ctx context.Context, coin string, bracket trader.Bracket) error {
+	for _, id := range []string{bracket.TakeProfitOrderID, bracket.StopOrderID} {
+		if err := g.cancelTriggeredIfOpen(ctx, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (g *GateIO) cancelTriggeredIfOpen(ctx context.Context, id string) error {
+	if id == "" {
+		return nil
+	}
+
+	o, err := g.getTriggered(ctx, id)
+	if err != nil {
+		return fmt.Errorf("failed to get price order %s: %w", id, err)
+	}
+	if o.Status != priceOrderStatusOpen {
+		return nil
+	}
+
+	err = g.retry.do(ctx, func() (res *http.Response, err error) {
+		_, res, err = g.api.SpotApi.CancelSpotPriceTriggeredOrder(ctx, id)
+		return res, err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to cancel price order %s: %w", id, err)
+	}
+	return nil
+}
+
+func (g *GateIO) getTriggered(ctx context.Context, id string) (gateapi.SpotPriceTriggeredOrder, error) {
+	var o gateapi.SpotPriceTriggeredOrder
+	err := g.retry.do(ctx, func() (res *http.Response, err error) {
+		o, res, err = g.api.SpotApi.GetSpotPriceTriggeredOrder(ctx, id)
+		return res, err
+	})
+	return o, err
+}
@@ -0,0 +1,203 @@
+package exchange_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gateio/gateapi-go/v6"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/moonr-app/crypto-signal-trading-bot/internal/exchange"
+	"github.com/moonr-app/crypto-signal-trading-bot/internal/trader"
+)
+
+// priceOrderStub serves gate.io price triggered orders, keyed by ID.
+type priceOrderStub struct {
+	*gateStub
+	lock    sync.Mutex
+	created []gateapi.SpotPriceTriggeredOrder
+	orders  map[string]gateapi.SpotPriceTriggeredOrder
+}
+
+func newPriceOrderStub(t *testing.T) *priceOrderStub {
+	s := &priceOrderStub{gateStub: newGateStub(t), orders: make(map[string]gateapi.SpotPriceTriggeredOrder)}
+
+	s.handle("/spot/price_orders", func(w http.ResponseWriter, r *http.Request) {
+		var o gateapi.SpotPriceTriggeredOrder
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&o))
+
+		s.lock.Lock()
+		s.created = append(s.created, o)
+		id := int64(len(s.created))
+		s.lock.Unlock()
+
+		if o.Trigger.Rule == "<=" && strings.HasPrefix(o.Market, "FAIL") {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"label":"INVALID_PARAM_VALUE","message":"bad stop"}`))
+			return
+		}
+		writeJSON(w, gateapi.TriggerOrderResponse{Id: id})
+	})
+	s.handle("/spot/price_orders/", func(w http.ResponseWriter, r *http.Request) {
+		id := strings.TrimPrefix(r.URL.Path, "/spot/price_orders/")
+
+		s.lock.Lock()
+		defer s.lock.Unlock()
+		o := s.orders[id]
+		if r.Method == http.MethodDelete {
+			o.Status = "cancelled"
+			s.orders[id] = o
+		}
+		writeJSON(w, o)
+	})
+	return s
+}
+
+func (s *priceOrderStub) set(id string, o gateapi.SpotPriceTriggeredOrder) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.orders[id] = o
+}
+
+func (s *priceOrderStub) status(id string) string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.orders[id].Status
+}
+
+func TestGateIO_PlaceBracket(t *testing.T) {
+	var (
+		ctx     = context.Background()
+		bracket = exchange.WithBracket(decimal.NewFromInt(50), decimal.NewFromInt(10))
+		fill    = trader.Fill{Price: decimal.NewFromInt(2), Amount: decimal.NewFromInt(100), Fee: decimal.NewFromInt(1), FeeCurrency: "RARE"}
+	)
+
+	t.Run("places a take profit and a stop for what is held", func(t *testing.T) {
+		stub := newPriceOrderStub(t)
+
+		b, err := stub.gate(t, bracket).PlaceBracket(ctx, "RARE", fill)
+		require.NoError(t, err)
+		assert.Equal(t, trader.Bracket{TakeProfitOrderID: "1", StopOrderID: "2"}, b)
+
+		require.Len(t, stub.created, 2)
+		takeProfit, stop := stub.created[0], stub.created[1]
+
+		assert.Equal(t, "RARE_USDT", takeProfit.Market)
+		assert.Equal(t, ">=", takeProfit.Trigger.Rule)
+		assert.Equal(t, "3", takeProfit.Trigger.Price)
+		assert.Equal(t, "3", takeProfit.Put.Price)
+		assert.Equal(t, "99", takeProfit.Put.Amount)
+		assert.Equal(t, "sell", takeProfit.Put.Side)
+
+		assert.Equal(t, "<=", stop.Trigger.Rule)
+		assert.Equal(t, "1.8", stop.Trigger.Price)
+		assert.Equal(t, "1.764", stop.Put.Price)
+		assert.Equal(t, "99", stop.Put.Amount)
+		assert.Equal(t, "ioc", stop.Put.TimeInForce)
+	})
+
+	t.Run("no bracket given it isn't configured", func(t *testing.T) {
+		stub := newPriceOrderStub(t)
+
+		b, err := stub.gate(t).PlaceBracket(ctx, "RARE", fill)
+		require.NoError(t, err)
+		assert.True(t, b.IsZero())
+		assert.Equal(t, 0, stub.count("POST /spot/price_orders"))
+	})
+
+	t.Run("cancels the take profit given the stop cannot be placed", func(t *testing.T) {
+		stub := newPriceOrderStub(t)
+		stub.set("1", gateapi.SpotPriceTriggeredOrder{Id: 1, Status: "open"})
+
+		_, err := stub.gate(t, bracket).PlaceBracket(ctx, "FAIL", fill)
+		require.Error(t, err)
+		assert.Equal(t, 1, stub.count("DELETE /spot/price_orders/1"))
+	})
+}
+
+func TestGateIO_CheckBracket(t *testing.T) {
+	var (
+		ctx     = context.Background()
+		bracket = trader.Bracket{TakeProfitOrderID: "1", StopOrderID: "2"}
+	)
+
+	t.Run("not executed given both legs are open", func(t *testing.T) {
+		stub := newPriceOrderStub(t)
+		stub.set("1", gateapi.SpotPriceTriggeredOrder{Id: 1, Status: "open"})
+		stub.set("2", gateapi.SpotPriceTriggeredOrder{Id: 2, Status: "open"})
+
+		_, executed, err := stub.gate(t).CheckBracket(ctx, "RARE", bracket)
+		require.NoError(t, err)
+		assert.False(t, executed)
+		assert.Equal(t, 0, stub.count("DELETE /spot/price_orders/1"))
+		assert.Equal(t, 0, stub.count("DELETE /spot/price_orders/2"))
+	})
+
+	t.Run("cancels the take profit and reports the fill given the stop fired", func(t *testing.T) {
+		stub := newPriceOrderStub(t)
+		stub.set("1", gateapi.SpotPriceTriggeredOrder{Id: 1, Status: "open"})
+		stub.set("2", gateapi.SpotPriceTriggeredOrder{Id: 2, Status: "finish", FiredOrderId: 77})
+		stub.handle("/spot/orders/", func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/spot/orders/77", r.URL.Path)
+			writeJSON(w, gateapi.Order{Id: "77", Status: "closed", Amount: "99", Left: "0", FilledTotal: "178.2"})
+		})
+
+		fill, executed, err := stub.gate(t).CheckBracket(ctx, "RARE", bracket)
+		require.NoError(t, err)
+		assert.True(t, executed)
+		assert.Equal(t, "99", fill.Amount.String())
+		assert.Equal(t, "1.8", fill.Price.String())
+		assert.Equal(t, "cancelled", stub.status("1"))
+	})
+
+	t.Run("not executed yet given the fired order is still open", func(t *testing.T) {
+		stub := newPriceOrderStub(t)
+		stub.set("1", gateapi.SpotPriceTriggeredOrder{Id: 1, Status: "finish", FiredOrderId: 77})
+		stub.set("2", gateapi.SpotPriceTriggeredOrder{Id: 2, Status: "open"})
+		stub.handle("/spot/orders/", func(w http.ResponseWriter, r *http.Request) {
+			writeJSON(w, gateapi.Order{Id: "77", Status: "open", Amount: "99", Left: "50"})
+		})
+
+		_, executed, err := stub.gate(t).CheckBracket(ctx, "RARE", bracket)
+		require.NoError(t, err)
+		assert.False(t, executed)
+		assert.Equal(t, "cancelled", stub.status("2"))
+	})
+
+	t.Run("ErrBracketExpiring given a leg has expired or is about to", func(t *testing.T) {
+		for name, leg := range map[string]gateapi.SpotPriceTriggeredOrder{
+			"expired":  {Id: 2, Status: "expired"},
+			"about to": {Id: 2, Status: "open", Ctime: time.Now().Add(-6*24*time.Hour - time.Minute).Unix()},
+		} {
+			stub := newPriceOrderStub(t)
+			stub.set("1", gateapi.SpotPriceTriggeredOrder{Id: 1, Status: "open", Ctime: time.Now().Unix()})
+			stub.set("2", leg)
+
+			_, executed, err := stub.gate(t).CheckBracket(ctx, "RARE", bracket)
+			assert.True(t, errors.Is(err, trader.ErrBracketExpiring), name)
+			assert.False(t, executed, name)
+		}
+	})
+}
+
+func TestGateIO_CancelBracket(t *testing.T) {
+	t.Run("cancels only the legs still open", func(t *testing.T) {
+		stub := newPriceOrderStub(t)
+		stub.set("1", gateapi.SpotPriceTriggeredOrder{Id: 1, Status: "open"})
+		stub.set("2", gateapi.SpotPriceTriggeredOrder{Id: 2, Status: "expired"})
+
+		err := stub.gate(t).CancelBracket(context.Background(), "RARE", trader.Bracket{TakeProfitOrderID: "1", StopOrderID: "2"})
+		require.NoError(t, err)
+		assert.Equal(t, 1, stub.count("DELETE /spot/price_orders/1"))
+		assert.Equal(t, 0, stub.count("DELETE /spot/price_orders/2"))
+	})
+}
@@ -0,0 +1,261 @@
+package exchange
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gateio/gateapi-go/v6"
+	"go.uber.org/zap"
+
+	"github.com/moonr-app/crypto-signal-trading-bot/internal/shared/logging"
+	"github.com/moonr-app/crypto-signal-trading-bot/internal/trader"
+)
+
+const (
+	// defaultCatalogueRefresh is how often the catalogue is refreshed in the background. It's well inside
+	// pairRefreshInterval, so a buy never has to wait for a refresh.
+	defaultCatalogueRefresh = 30 * time.Second
+	// pairRefreshInterval is how old the pairs can get before they are fetched again when next needed.
+	pairRefreshInterval = 5 * time.Minute
+)
+
+// catalogue is everything about gate.io's currencies and pairs a buy needs to know before placing its order: whether
+// the currency trades, the pair's precision and minimums, and a recent price. Kept up to date in the background by
+// run, it means a fresh signal is checked, priced and rounded without waiting on gate.io. Anything listed since the
+// last refresh is looked up one at a time. With streamed set prices are left to the price stream, which only watches
+// the pairs asked about, rather than listing every ticker each refresh.
+type catalogue struct {
+	api      *gateapi.APIClient
+	retry    retryPolicy
+	resolver *Resolver
+	prices   *priceCache
+	quotes   []string
+	streamed bool
+
+	lock sync.Mutex
+	// currencies is keyed by the upper case currency.
+	currencies map[string]gateapi.Currency
+	pairs      map[string]pairMeta
+	// byBase is the ID of every pair each upper case currency is the base of.
+	byBase      map[string][]string
+	refreshedAt time.Time
+}
+
+func newCatalogue(api *gateapi.APIClient, retry retryPolicy, resolver *Resolver, prices *priceCache, quotes []string) *catalogue {
+	return &catalogue{
+		api:        api,
+		retry:      retry,
+		resolver:   resolver,
+		prices:     prices,
+		quotes:     quotes,
+		currencies: make(map[string]gateapi.Currency),
+		pairs:      make(map[string]pairMeta),
+		byBase:     make(map[string][]string),
+	}
+}
+
+// run refreshes the catalogue straight away and then every interval until ctx is done.
+func (c *catalogue) run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		// the next tick is the retry.
+		bg := background(ctx)
+		if err := c.refreshPairs(bg); err != nil && ctx.Err() == nil {
+			logging.Error(ctx, "failed to refresh currency pairs", zap.Error(err))
+		}
+		if err := c.refreshCurrencies(bg); err != nil && ctx.Err() == nil {
+			logging.Error(ctx, "failed to refresh currencies", zap.Error(err))
+		}
+		if !c.streamed {
+			if err := c.refreshPrices(bg); err != nil && ctx.Err() == nil {
+				logging.Error(ctx, "failed to refresh prices", zap.Error(err))
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (c *catalogue) refreshPairs(ctx context.Context) error {
+	pairs, res, err := c.api.SpotApi.ListCurrencyPairs(ctx)
+	if err := classify(res, err); err != nil {
+		return fmt.Errorf("failed to list currency pairs: %w", err)
+	}
+
+	c.setPairs(pairs)
+	return nil
+}
+
+func (c *catalogue) setPairs(pairs []gateapi.CurrencyPair) {
+	var (
+		metas  = make(map[string]pairMeta, len(pairs))
+		byBase = make(map[string][]string, len(pairs))
+	)
+	for _, p := range pairs {
+		if m, err := newPairMeta(p); err == nil {
+			metas[p.Id] = m
+			byBase[strings.ToUpper(p.Base)] = append(byBase[strings.ToUpper(p.Base)], p.Id)
+		}
+	}
+
+	c.lock.Lock()
+	c.pairs = metas
+	c.byBase = byBase
+	c.refreshedAt = time.Now()
+	c.lock.Unlock()
+
+	c.resolver.update(pairs)
+}
+
+func (c *catalogue) refreshCurrencies(ctx context.Context) error {
+	currencies, res, err := c.api.SpotApi.ListCurrencies(ctx)
+	if err := classify(res, err); err != nil {
+		return fmt.Errorf("failed to list currencies: %w", err)
+	}
+
+	byName := make(map[string]gateapi.Currency, len(currencies))
+	for _, cur := range currencies {
+		byName[strings.ToUpper(cur.Currency)] = cur
+	}
+
+	c.lock.Lock()
+	c.currencies = byName
+	c.lock.Unlock()
+	return nil
+}
+
+func (c *catalogue) refreshPrices(ctx context.Context) error {
+	tickers, res, err := c.api.SpotApi.ListTickers(ctx, &gateapi.ListTickersOpts{})
+	if err := classify(res, err); err != nil {
+		return fmt.Errorf("failed to list tickers: %w", err)
+	}
+
+	storeTickers(ctx, c.prices, c.quotes, tickers, time.Now())
+	return nil
+}
+
+// currency returns coin from the catalogue, or from gate.io if it has been listed since the last refresh.
+func (c *catalogue) currency(ctx context.Context, coin string) (gateapi.Currency, error) {
+	c.lock.Lock()
+	cur, ok := c.currencies[strings.ToUpper(coin)]
+	c.lock.Unlock()
+	if ok {
+		return cur, nil
+	}
+
+	err := c.retry.do(ctx, func() (res *http.Response, err error) {
+		cur, res, err = c.api.SpotApi.GetCurrency(ctx, coin)
+		return res, err
+	})
+	if err != nil {
+		return gateapi.Currency{}, err
+	}
+
+	c.lock.Lock()
+	c.currencies[strings.ToUpper(coin)] = cur
+	c.lock.Unlock()
+	return cur, nil
+}
+
+// ensurePairs fetches the pairs if the background refresh isn't running or keeps failing, and they are older than
+// pairRefreshInterval.
+func (c *catalogue) ensurePairs(ctx context.Context) error {
+	c.lock.Lock()
+	stale := time.Since(c.refreshedAt) > pairRefreshInterval
+	c.lock.Unlock()
+	if !stale {
+		return nil
+	}
+
+	var pairs []gateapi.CurrencyPair
+	err := c.retry.do(ctx, func() (res *http.Response, err error) {
+		pairs, res, err = c.api.SpotApi.ListCurrencyPairs(ctx)
+		return res, err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list currency pairs: %w", err)
+	}
+	c.setPairs(pairs)
+	return nil
+}
+
+// pairsFor returns coin's pairs in each of the quote currencies, in the quote currencies' order. A coin listed since
+// the last refresh has its pairs looked up one at a time, and the first one found is all that is returned.
+func (c *catalogue) pairsFor(ctx context.Context, coin string) ([]string, error) {
+	if err := c.ensurePairs(ctx); err != nil {
+		return nil, err
+	}
+
+	c.lock.Lock()
+	listed := c.byBase[strings.ToUpper(coin)]
+	c.lock.Unlock()
+
+	var pairs []string
+	for _, q := range c.quotes {
+		for _, id := range listed {
+			if quoteOf(id) == q {
+				pairs = append(pairs, id)
+			}
+		}
+	}
+	if len(pairs) > 0 {
+		return pairs, nil
+	}
+
+	for _, q := range c.quotes {
+		id := pairID(coin, q)
+		_, err := c.get(ctx, id)
+		if errors.Is(err, trader.ErrInvalidPair) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return []string{id}, nil
+	}
+	return nil, nil
+}
+
+// get returns currencyPair's precision and minimums, looking it up if it has been listed since the last refresh.
+func (c *catalogue) get(ctx context.Context, currencyPair string) (pairMeta, error) {
+	if err := c.ensurePairs(ctx); err != nil {
+		return pairMeta{}, err
+	}
+
+	c.lock.Lock()
+	m, ok := c.pairs[currencyPair]
+	c.lock.Unlock()
+	if ok {
+		return m, nil
+	}
+
+	var p gateapi.CurrencyPair
+	err := c.retry.do(ctx, func() (res *http.Response, err error) {
+		p, res, err = c.api.SpotApi.GetCurrencyPair(ctx, currencyPair)
+		return res, err
+	})
+	if err != nil {
+		return pairMeta{}, fmt.Errorf("failed to get currency pair %s: %w", currencyPair, err)
+	}
+	m, err = newPairMeta(p)
+	if err != nil {
+		return pairMeta{}, err
+	}
+
+	c.lock.Lock()
+	c.pairs[currencyPair] = m
+	c.byBase[strings.ToUpper(p.Base)] = append(c.byBase[strings.ToUpper(p.Base)], currencyPair)
+	c.lock.Unlock()
+	return m, nil
+}
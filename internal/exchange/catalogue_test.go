@@ -0,0 +1,210 @@
+package exchange_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gateio/gateapi-go/v6"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/moonr-app/crypto-signal-trading-bot/internal/exchange"
+)
+
+// catalogueStub serves every coin in coins from the bulk endpoints, and counts lookups of a single currency or ticker.
+type catalogueStub struct {
+	*gateStub
+	lock    sync.Mutex
+	lookups int
+}
+
+func newCatalogueStub(t testing.TB, coins ...string) *catalogueStub {
+	s := &catalogueStub{gateStub: newGateStub(t)}
+
+	var (
+		currencies []gateapi.Currency
+		tickers    []gateapi.Ticker
+	)
+	s.pairs = nil
+	for _, c := range coins {
+		s.pairs = append(s.pairs, gateapi.CurrencyPair{Id: c + "_USDT", Base: c, Quote: "USDT", AmountPrecision: 8, Precision: 8, TradeStatus: "tradable"})
+		currencies = append(currencies, gateapi.Currency{Currency: c})
+		tickers = append(tickers, gateapi.Ticker{CurrencyPair: c + "_USDT", Last: "0.5", LowestAsk: "0.5", HighestBid: "0.49"})
+	}
+
+	s.handle("/spot/currencies", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, currencies)
+	})
+	s.handle("/spot/currencies/", func(w http.ResponseWriter, r *http.Request) {
+		s.lookup()
+		writeJSON(w, gateapi.Currency{Currency: strings.TrimPrefix(r.URL.Path, "/spot/currencies/")})
+	})
+	s.handle("/spot/tickers", func(w http.ResponseWriter, r *http.Request) {
+		pair := r.URL.Query().Get("currency_pair")
+		if pair == "" {
+			writeJSON(w, tickers)
+			return
+		}
+		s.lookup()
+		writeJSON(w, []gateapi.Ticker{{CurrencyPair: pair, Last: "0.5", LowestAsk: "0.5", HighestBid: "0.49"}})
+	})
+	s.iocHandler(t, func(n int, o gateapi.Order) (string, string) {
+		return "0", "10"
+	})
+	return s
+}
+
+func (s *catalogueStub) lookup() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.lookups++
+}
+
+func (s *catalogueStub) singleLookups() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.lookups
+}
+
+// loaded waits for the catalogue to be refreshed twice, so the first refresh is over whatever the second is doing.
+func (s *catalogueStub) loaded(t testing.TB) {
+	require.Eventually(t, func() bool {
+		return s.count("GET /spot/tickers") > 1
+	}, time.Second, time.Millisecond)
+}
+
+// buy is everything the buyer asks of gate.io for a fresh signal.
+func buy(ctx context.Context, g *exchange.GateIO, coin string) error {
+	resolved, err := g.ResolveSymbol(ctx, coin, "", "")
+	if err != nil {
+		return err
+	}
+	if _, err := g.CheckSupport(ctx, resolved); err != nil {
+		return err
+	}
+	price, err := g.GetPrice(ctx, resolved, time.Minute)
+	if err != nil {
+		return err
+	}
+	_, err = g.PurchaseCoin(ctx, resolved, price.BuyPrice(), "")
+	return err
+}
+
+func TestGateIO_Catalogue(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("a fresh signal is bought with nothing but the order", func(t *testing.T) {
+		stub := newCatalogueStub(t, "RARE", "VAI")
+		g := stub.gate(t, exchange.WithCatalogueRefresh(time.Hour))
+		require.Eventually(t, func() bool {
+			for _, listed := range []string{"currencies", "currency_pairs", "tickers", "accounts"} {
+				if stub.count("GET /spot/"+listed) == 0 {
+					return false
+				}
+			}
+			return true
+		}, time.Second, time.Millisecond)
+
+		// the balance a buy spends from is kept up to date by the buy, so the one after needn't list it either.
+		for i, coin := range []string{"RARE", "VAI"} {
+			before := stub.total()
+			require.NoError(t, buy(ctx, g, coin))
+			assert.Equal(t, 1, stub.total()-before, coin)
+			assert.Equal(t, i+1, stub.count("POST /spot/orders"))
+		}
+		assert.Equal(t, 0, stub.singleLookups())
+	})
+
+	t.Run("leaves prices to the stream given it is on", func(t *testing.T) {
+		var (
+			stream = newStreamStub(t)
+			stub   = newCatalogueStub(t, "RARE")
+		)
+		stub.gate(t, exchange.WithCatalogueRefresh(5*time.Millisecond), exchange.WithPriceStream(stream.url()))
+		require.Eventually(t, func() bool {
+			return stub.count("GET /spot/currency_pairs") > 1
+		}, time.Second, time.Millisecond)
+
+		assert.Equal(t, 0, stub.count("GET /spot/tickers"))
+	})
+
+	t.Run("looks up a currency listed since the last refresh", func(t *testing.T) {
+		stub := newCatalogueStub(t, "RARE")
+		g := stub.gate(t, exchange.WithCatalogueRefresh(time.Hour))
+		require.Eventually(t, func() bool {
+			return stub.count("GET /spot/tickers") > 0
+		}, time.Second, time.Millisecond)
+
+		supported, err := g.CheckSupport(ctx, "NEW")
+		require.NoError(t, err)
+		assert.True(t, supported)
+		assert.Equal(t, 1, stub.count("GET /spot/currencies/NEW"))
+
+		// and remembers it.
+		_, err = g.CheckSupport(ctx, "NEW")
+		require.NoError(t, err)
+		assert.Equal(t, 1, stub.count("GET /spot/currencies/NEW"))
+	})
+
+	t.Run("everything is looked up one at a time given the refresh is off", func(t *testing.T) {
+		stub := newCatalogueStub(t, "RARE")
+		g := stub.gate(t)
+
+		require.NoError(t, buy(ctx, g, "RARE"))
+		assert.Equal(t, 2, stub.singleLookups())
+		assert.Equal(t, 0, stub.count("GET /spot/currencies"))
+	})
+}
+
+// BenchmarkGateIO_Buy is the time from a fresh signal to its order being filled, with every request to gate.io
+// taking a millisecond.
+func BenchmarkGateIO_Buy(b *testing.B) {
+	var (
+		ctx   = context.Background()
+		coins = make([]string, 1000)
+		fast  = exchange.RateLimit{Requests: 1000000, Per: time.Second}
+	)
+	for i := range coins {
+		coins[i] = fmt.Sprintf("C%d", i)
+	}
+
+	for _, bc := range []struct {
+		name    string
+		refresh time.Duration
+	}{
+		{name: "looked up per signal", refresh: 0},
+		{name: "from the catalogue", refresh: time.Hour},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			stub := newCatalogueStub(b, coins...)
+			g := stub.gate(b, exchange.WithCatalogueRefresh(bc.refresh), exchange.WithRateLimits(fast, fast, fast))
+
+			if bc.refresh > 0 {
+				require.Eventually(b, func() bool {
+					return stub.count("GET /spot/tickers") > 0
+				}, time.Second, time.Millisecond)
+				// gives the refresh time to store what it fetched.
+				time.Sleep(50 * time.Millisecond)
+			}
+			stub.gateStub.lock.Lock()
+			stub.latency = time.Millisecond
+			stub.gateStub.lock.Unlock()
+
+			before := stub.total()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if err := buy(ctx, g, coins[i%len(coins)]); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.StopTimer()
+
+			b.ReportMetric(float64(stub.total()-before)/float64(b.N), "requests/op")
+		})
+	}
+}
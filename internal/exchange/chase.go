@@ -0,0 +1,177 @@
+package exchange
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/antihax/optional"
+	"github.com/gateio/gateapi-go/v6"
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+
+	"github.com/moonr-app/crypto-signal-trading-bot/internal/shared/logging"
+	"github.com/moonr-app/crypto-signal-trading-bot/internal/trader"
+)
+
+const defaultRequoteInterval = 5 * time.Second
+
+var errEmptyBids = errors.New("order book has no bids")
+
+// chaseSell sells amount by resting a limit at the best bid and re-quoting it every requote interval, so the order
+// follows the market down rather than sitting above it. Once the order timeout has passed, whatever is left is sold
+// into the bids with an immediate-or-cancel order. If ctx is done mid-chase the order resting at the time is cancelled,
+// and what sold up to then is returned with the error.
+func (g *GateIO) chaseSell(ctx context.Context, currencyPair string, amount decimal.Decimal, ids *clientIDs) (trader.Fill, error) {
+	var (
+		deadline  = time.Now().Add(g.orders.timeout)
+		total     trader.Fill
+		remaining = amount
+	)
+
+	for remaining.IsPositive() && time.Now().Before(deadline) {
+		bids, err := g.bids(ctx, currencyPair, 1)
+		if err != nil {
+			return total, err
+		}
+
+		wait := g.requoteInterval
+		if untilDeadline := time.Until(deadline); untilDeadline < wait {
+			wait = untilDeadline
+		}
+
+		logging.Info(
+			ctx,
+			"quoting sell at best bid",
+			zap.String("currency_pair", currencyPair),
+			zap.String("price", bids[0][0]),
+			zap.String("amount", remaining.String()),
+		)
+
+		fill, err := g.orders.placeFor(ctx, gateapi.Order{
+			CurrencyPair: currencyPair,
+			Account:      accountType,
+			Side:         sideTypeSell,
+			TimeInForce:  timeInForceGoodToClose,
+			Price:        bids[0][0],
+			Amount:       remaining.String(),
+			Text:         ids.next(),
+		}, wait)
+		if errors.Is(err, trader.ErrOrderTooSmall) {
+			// what's left is dust gate.io won't take an order for, there's no point carrying on.
+			return g.finishChase(total, amount, err)
+		}
+		if err != nil && !errors.Is(err, trader.ErrOrderNotFilled) {
+			return mergeFills(total, fill), err
+		}
+
+		total = mergeFills(total, fill)
+		remaining = amount.Sub(total.Amount)
+	}
+
+	if remaining.IsPositive() {
+		fill, err := g.dumpIntoBids(ctx, currencyPair, remaining, ids)
+		if errors.Is(err, trader.ErrOrderTooSmall) {
+			return g.finishChase(total, amount, err)
+		}
+		if err != nil && !errors.Is(err, trader.ErrOrderNotFilled) {
+			return mergeFills(total, fill), err
+		}
+
+		total = mergeFills(total, fill)
+	}
+
+	return g.finishChase(total, amount, fmt.Errorf("%w: %s", trader.ErrOrderNotFilled, currencyPair))
+}
+
+// finishChase works out what was left of amount, and returns unsold as the error if nothing sold at all.
+func (g *GateIO) finishChase(total trader.Fill, amount decimal.Decimal, unsold error) (trader.Fill, error) {
+	total.Left = amount.Sub(total.Amount)
+	if total.Amount.IsZero() {
+		return total, unsold
+	}
+	return total, nil
+}
+
+// dumpIntoBids sells amount with an immediate-or-cancel order priced as deep into the bids as it takes to fill.
+func (g *GateIO) dumpIntoBids(ctx context.Context, currencyPair string, amount decimal.Decimal, ids *clientIDs) (trader.Fill, error) {
+	bids, err := g.bids(ctx, currencyPair, orderBookDepth)
+	if err != nil {
+		return trader.Fill{}, err
+	}
+
+	price, err := sweepBids(bids, amount)
+	if err != nil {
+		return trader.Fill{}, err
+	}
+
+	logging.Info(
+		ctx,
+		"sell not filled in time, selling into the bids",
+		zap.String("currency_pair", currencyPair),
+		zap.String("price", price.String()),
+		zap.String("amount", amount.String()),
+	)
+
+	return g.orders.place(ctx, gateapi.Order{
+		CurrencyPair: currencyPair,
+		Account:      accountType,
+		Side:         sideTypeSell,
+		TimeInForce:  timeInForceImmediateOrCancel,
+		Price:        price.String(),
+		Amount:       amount.String(),
+		Text:         ids.next(),
+	})
+}
+
+func (g *GateIO) bids(ctx context.Context, currencyPair string, depth int32) ([][]string, error) {
+	var book gateapi.OrderBook
+	err := g.retry.do(ctx, func() (res *http.Response, err error) {
+		book, res, err = g.api.SpotApi.ListOrderBook(ctx, currencyPair, &gateapi.ListOrderBookOpts{Limit: optional.NewInt32(depth)})
+		return res, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get order book: %w", err)
+	}
+	if len(book.Bids) == 0 || len(book.Bids[0]) < 2 {
+		return nil, errEmptyBids
+	}
+	return book.Bids, nil
+}
+
+// sweepBids returns the price of the deepest bid needed to sell amount. If the book is thinner than amount, it is
+// the price of the last bid seen.
+func sweepBids(bids [][]string, amount decimal.Decimal) (decimal.Decimal, error) {
+	var (
+		price  decimal.Decimal
+		filled = decimal.Zero
+	)
+
+	for _, level := range bids {
+		if len(level) < 2 {
+			break
+		}
+
+		p, err := decimal.NewFromString(level[0])
+		if err != nil {
+			return decimal.Zero, fmt.Errorf("invalid bid price: %w", err)
+		}
+		size, err := decimal.NewFromString(level[1])
+		if err != nil {
+			return decimal.Zero, fmt.Errorf("invalid bid size: %w", err)
+		}
+
+		price = p
+		filled = filled.Add(size)
+		if filled.GreaterThanOrEqual(amount) {
+			break
+		}
+	}
+
+	if price.IsZero() {
+		return decimal.Zero, errEmptyBids
+	}
+	return price, nil
+}
@@ -0,0 +1,260 @@
+package exchange_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gateio/gateapi-go/v6"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/moonr-app/crypto-signal-trading-bot/internal/exchange"
+	"github.com/moonr-app/crypto-signal-trading-bot/internal/trader"
+)
+
+// sellStub serves a sell being chased. Each order is numbered from 1 in the order it is posted; resting orders are
+// answered by polled and cancelled, immediate-or-cancel ones by ioc.
+type sellStub struct {
+	*gateStub
+	lock      sync.Mutex
+	orders    []gateapi.Order
+	polled    func(o gateapi.Order) gateapi.Order
+	cancelled func(o gateapi.Order) gateapi.Order
+	ioc       func(o gateapi.Order) gateapi.Order
+}
+
+func newSellStub(t *testing.T, balance string, bids ...[][]string) *sellStub {
+	s := &sellStub{gateStub: newGateStub(t)}
+
+	var books int
+	s.handle("/spot/order_book", func(w http.ResponseWriter, r *http.Request) {
+		s.lock.Lock()
+		book := bids[books]
+		if books < len(bids)-1 {
+			books++
+		}
+		s.lock.Unlock()
+		writeJSON(w, gateapi.OrderBook{Bids: book})
+	})
+	s.accounts = []gateapi.SpotAccount{{Currency: "RARE", Available: balance}}
+	s.handle("/spot/orders", func(w http.ResponseWriter, r *http.Request) {
+		var o gateapi.Order
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&o))
+
+		s.lock.Lock()
+		o.Id = strconv.Itoa(len(s.orders) + 1)
+		s.orders = append(s.orders, o)
+		s.lock.Unlock()
+
+		if o.TimeInForce == "ioc" {
+			writeJSON(w, s.ioc(o))
+			return
+		}
+		o.Status = "open"
+		o.Left = o.Amount
+		writeJSON(w, o)
+	})
+	s.handle("/spot/orders/", func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/spot/orders/"))
+		require.NoError(t, err)
+
+		s.lock.Lock()
+		o := s.orders[id-1]
+		s.lock.Unlock()
+
+		switch r.Method {
+		case http.MethodGet:
+			writeJSON(w, s.polled(o))
+		case http.MethodDelete:
+			writeJSON(w, s.cancelled(o))
+		}
+	})
+	return s
+}
+
+func (s *sellStub) posted() []gateapi.Order {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return append([]gateapi.Order(nil), s.orders...)
+}
+
+func withStatus(o gateapi.Order, status, left, filledTotal string) gateapi.Order {
+	o.Status, o.Left, o.FilledTotal = status, left, filledTotal
+	return o
+}
+
+func TestGateIO_Sell(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("re-quotes at the new best bid until everything is sold", func(t *testing.T) {
+		stub := newSellStub(t, "20", [][]string{{"0.5", "100"}}, [][]string{{"0.49", "100"}})
+		stub.polled = func(o gateapi.Order) gateapi.Order {
+			if o.Id == "2" {
+				return withStatus(o, "closed", "0", "4.9")
+			}
+			return withStatus(o, "open", o.Amount, "")
+		}
+		stub.cancelled = func(o gateapi.Order) gateapi.Order {
+			return withStatus(o, "cancelled", "10", "5")
+		}
+
+		g := stub.gate(t, exchange.WithOrderTimeout(time.Second), exchange.WithSellRequoteInterval(5*time.Millisecond))
+
+		fill, err := g.Sell(ctx, "RARE", decimal.NewFromInt(20), decimal.NewFromFloat(0.6), "t-RARE.abc-1")
+		require.NoError(t, err)
+
+		orders := stub.posted()
+		require.Len(t, orders, 2)
+		assert.Equal(t, "t-RARE.abc-1.s-1", orders[0].Text)
+		assert.Equal(t, "t-RARE.abc-1.s-2", orders[1].Text)
+		assert.Equal(t, "0.5", orders[0].Price)
+		assert.Equal(t, "20", orders[0].Amount)
+		assert.Equal(t, "0.49", orders[1].Price)
+		assert.Equal(t, "10", orders[1].Amount)
+		assert.Equal(t, "gtc", orders[1].TimeInForce)
+
+		assert.Equal(t, "20", fill.Amount.String())
+		assert.Equal(t, "9.9", fill.FilledTotal.String())
+		assert.Equal(t, "0.495", fill.Price.String())
+		assert.True(t, fill.Left.IsZero())
+	})
+
+	t.Run("sells what is left into the bids after the timeout", func(t *testing.T) {
+		stub := newSellStub(t, "20", [][]string{{"0.5", "5"}, {"0.48", "15"}, {"0.4", "100"}})
+		stub.polled = func(o gateapi.Order) gateapi.Order {
+			return withStatus(o, "open", o.Amount, "")
+		}
+		stub.cancelled = func(o gateapi.Order) gateapi.Order {
+			return withStatus(o, "cancelled", o.Amount, "")
+		}
+		stub.ioc = func(o gateapi.Order) gateapi.Order {
+			return withStatus(o, "closed", "0", "9.8")
+		}
+
+		g := stub.gate(t, exchange.WithOrderTimeout(20*time.Millisecond), exchange.WithSellRequoteInterval(5*time.Millisecond))
+
+		fill, err := g.Sell(ctx, "RARE", decimal.NewFromInt(20), decimal.NewFromFloat(0.6), "")
+		require.NoError(t, err)
+
+		orders := stub.posted()
+		last := orders[len(orders)-1]
+		assert.Equal(t, "ioc", last.TimeInForce)
+		assert.Equal(t, "0.48", last.Price)
+		assert.Equal(t, "20", last.Amount)
+
+		assert.Equal(t, "20", fill.Amount.String())
+		assert.Equal(t, "0.49", fill.Price.String())
+	})
+
+	t.Run("cancels the resting order and returns what sold given ctx is cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		stub := newSellStub(t, "20", [][]string{{"0.5", "100"}})
+		stub.polled = func(o gateapi.Order) gateapi.Order {
+			cancel()
+			return withStatus(o, "open", "15", "2.5")
+		}
+		stub.cancelled = func(o gateapi.Order) gateapi.Order {
+			return withStatus(o, "cancelled", "15", "2.5")
+		}
+
+		g := stub.gate(t, exchange.WithOrderTimeout(time.Second), exchange.WithSellRequoteInterval(time.Second))
+
+		fill, err := g.Sell(ctx, "RARE", decimal.NewFromInt(20), decimal.NewFromFloat(0.6), "")
+		assert.True(t, errors.Is(err, context.Canceled))
+		assert.Equal(t, "5", fill.Amount.String())
+		assert.Equal(t, 1, stub.count("DELETE /spot/orders/1"))
+	})
+
+	t.Run("ErrOrderNotFilled given nothing sells", func(t *testing.T) {
+		stub := newSellStub(t, "20", [][]string{{"0.5", "5"}})
+		stub.polled = func(o gateapi.Order) gateapi.Order {
+			return withStatus(o, "open", o.Amount, "")
+		}
+		stub.cancelled = func(o gateapi.Order) gateapi.Order {
+			return withStatus(o, "cancelled", o.Amount, "")
+		}
+		stub.ioc = func(o gateapi.Order) gateapi.Order {
+			return withStatus(o, "cancelled", o.Amount, "")
+		}
+
+		g := stub.gate(t, exchange.WithOrderTimeout(10*time.Millisecond), exchange.WithSellRequoteInterval(5*time.Millisecond))
+
+		fill, err := g.Sell(ctx, "RARE", decimal.NewFromInt(20), decimal.NewFromFloat(0.6), "")
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, trader.ErrOrderNotFilled))
+		assert.Equal(t, "20", fill.Left.String())
+	})
+
+	t.Run("stops chasing given what is left is under the pair minimum", func(t *testing.T) {
+		stub := newSellStub(t, "20", [][]string{{"0.5", "100"}})
+		stub.pairs[0].MinBaseAmount = "1"
+		stub.polled = func(o gateapi.Order) gateapi.Order {
+			return withStatus(o, "open", o.Amount, "")
+		}
+		stub.cancelled = func(o gateapi.Order) gateapi.Order {
+			return withStatus(o, "cancelled", "0.5", "9.75")
+		}
+
+		g := stub.gate(t, exchange.WithOrderTimeout(time.Second), exchange.WithSellRequoteInterval(5*time.Millisecond))
+
+		fill, err := g.Sell(ctx, "RARE", decimal.NewFromInt(20), decimal.NewFromFloat(0.6), "")
+		require.NoError(t, err)
+		assert.Len(t, stub.posted(), 1)
+		assert.Equal(t, "19.5", fill.Amount.String())
+		assert.Equal(t, "0.5", fill.Left.String())
+	})
+
+	t.Run("sells only what the bot owns given the account holds more", func(t *testing.T) {
+		stub := newSellStub(t, "50", [][]string{{"0.5", "100"}})
+		stub.polled = func(o gateapi.Order) gateapi.Order {
+			return withStatus(o, "closed", "0", "10")
+		}
+
+		fill, err := stub.gate(t).Sell(ctx, "RARE", decimal.NewFromInt(20), decimal.NewFromFloat(0.6), "")
+		require.NoError(t, err)
+		assert.Equal(t, "20", stub.posted()[0].Amount)
+		assert.Equal(t, "20", fill.Amount.String())
+		assert.True(t, fill.Shortfall.IsZero())
+	})
+
+	t.Run("reports a shortfall given the account holds less than the bot owns", func(t *testing.T) {
+		stub := newSellStub(t, "12", [][]string{{"0.5", "100"}})
+		stub.polled = func(o gateapi.Order) gateapi.Order {
+			return withStatus(o, "closed", "0", "6")
+		}
+
+		fill, err := stub.gate(t).Sell(ctx, "RARE", decimal.NewFromInt(20), decimal.NewFromFloat(0.6), "")
+		require.NoError(t, err)
+		assert.Equal(t, "12", stub.posted()[0].Amount)
+		assert.Equal(t, "12", fill.Amount.String())
+		assert.Equal(t, "8", fill.Shortfall.String())
+	})
+
+	t.Run("sells nothing given the account holds none of the coin", func(t *testing.T) {
+		stub := newSellStub(t, "0", [][]string{{"0.5", "100"}})
+
+		fill, err := stub.gate(t).Sell(ctx, "RARE", decimal.NewFromInt(20), decimal.NewFromFloat(0.6), "")
+		require.NoError(t, err)
+		assert.True(t, fill.Amount.IsZero())
+		assert.Equal(t, "20", fill.Shortfall.String())
+		assert.Equal(t, 0, stub.count("POST /spot/orders"))
+	})
+
+	t.Run("err given the book has no bids", func(t *testing.T) {
+		stub := newSellStub(t, "20", nil)
+
+		_, err := stub.gate(t).Sell(ctx, "RARE", decimal.NewFromInt(20), decimal.NewFromFloat(0.6), "")
+		require.Error(t, err)
+		assert.Equal(t, 0, stub.count("POST /spot/orders"))
+	})
+}
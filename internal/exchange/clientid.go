@@ -0,0 +1,63 @@
+package exchange
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	clientIDPrefix = "t-"
+	// maxClientIDLength is as much as gate.io takes after t-.
+	maxClientIDLength = 28
+	// maxBuyRefLength leaves room for the attempt, and for a sell's suffix when the buy's ID is used for its orders.
+	maxBuyRefLength = 20
+	sellRefSuffix   = ".s"
+)
+
+// clientIDs hands out the client order IDs for one trade, t-<ref>-<attempt>, which go in each order's text. An order
+// can be looked up by its ID if placing it fails in a way that leaves it unclear whether it went through.
+type clientIDs struct {
+	ref     string
+	attempt int
+}
+
+// buyClientIDs numbers the orders of a buy after the signal that prompted it, or the coin if there isn't one.
+func buyClientIDs(coin, signalID string) *clientIDs {
+	ref := signalID
+	if ref == "" {
+		ref = coin
+	}
+	return &clientIDs{ref: clientRef(ref, maxBuyRefLength)}
+}
+
+// sellClientIDs numbers the orders of a sell after the order that bought what is being sold. Positions from before
+// client IDs were used are numbered after the coin.
+func sellClientIDs(coin, boughtWith string) *clientIDs {
+	ref := strings.TrimPrefix(boughtWith, clientIDPrefix)
+	if ref == "" {
+		ref = coin
+	}
+	// three for the attempt, -NN.
+	return &clientIDs{ref: clientRef(ref, maxClientIDLength-len(sellRefSuffix)-3) + sellRefSuffix}
+}
+
+func (c *clientIDs) next() string {
+	c.attempt++
+	return fmt.Sprintf("%s%s-%d", clientIDPrefix, c.ref, c.attempt)
+}
+
+// clientRef drops anything gate.io won't take in an order's text and cuts it down to size.
+func clientRef(s string, max int) string {
+	ref := strings.Map(func(r rune) rune {
+		switch {
+		case r >= '0' && r <= '9', r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r == '_', r == '.', r == '-':
+			return r
+		}
+		return -1
+	}, s)
+
+	if len(ref) > max {
+		ref = ref[:max]
+	}
+	return ref
+}
@@ -0,0 +1,117 @@
+package exchange
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+
+	"github.com/gateio/gateapi-go/v6"
+
+	"github.com/moonr-app/crypto-signal-trading-bot/internal/trader"
+)
+
+// labelKinds maps the labels gate.io puts in error bodies onto the trader error they mean.
+var labelKinds = map[string]error{
+	"BALANCE_NOT_ENOUGH":        trader.ErrInsufficientBalance,
+	"MARGIN_BALANCE_NOT_ENOUGH": trader.ErrInsufficientBalance,
+	"INVALID_CURRENCY_PAIR":     trader.ErrInvalidPair,
+	"INVALID_CURRENCY":          trader.ErrInvalidPair,
+	"CURRENCY_PAIR_NOT_FOUND":   trader.ErrInvalidPair,
+	"CONTRACT_NOT_FOUND":        trader.ErrInvalidPair,
+	"INSUFFICIENT_AVAILABLE":    trader.ErrInsufficientBalance,
+	"TOO_MANY_REQUESTS":         trader.ErrRateLimited,
+	"AMOUNT_TOO_LITTLE":         trader.ErrOrderTooSmall,
+	"INVALID_KEY":               trader.ErrExchangeAuth,
+	"INVALID_SIGNATURE":         trader.ErrExchangeAuth,
+	"MISSING_REQUIRED_HEADER":   trader.ErrExchangeAuth,
+	"REQUEST_EXPIRED":           trader.ErrExchangeAuth,
+	"INVALID_CREDENTIALS":       trader.ErrExchangeAuth,
+	"FORBIDDEN":                 trader.ErrExchangeAuth,
+	"IP_FORBIDDEN":              trader.ErrExchangeAuth,
+	"READ_ONLY":                 trader.ErrExchangeAuth,
+	"ACCOUNT_LOCKED":            trader.ErrExchangeAuth,
+	"SERVER_ERROR":              trader.ErrExchangeUnavailable,
+	"TOO_BUSY":                  trader.ErrExchangeUnavailable,
+}
+
+// APIError is a gate.io failure decoded into one of the trader errors, e.g. errors.Is(err, trader.ErrRateLimited).
+// The original error from the client is still reachable with errors.As.
+type APIError struct {
+	StatusCode int
+	Label      string
+	Message    string
+	Kind       error
+	err        error
+}
+
+func (e *APIError) Error() string {
+	if e.Label == "" {
+		return fmt.Sprintf("gate.io: %s: %v", e.Kind, e.err)
+	}
+	return fmt.Sprintf("gate.io: %s: %s %s", e.Kind, e.Label, e.Message)
+}
+
+func (e *APIError) Is(target error) bool {
+	return target == e.Kind
+}
+
+func (e *APIError) Unwrap() error {
+	return e.err
+}
+
+// classify turns what the generated client returned into an APIError where it can tell what went wrong. Anything
+// it can't place is returned as it was.
+func classify(res *http.Response, err error) error {
+	if err == nil {
+		return nil
+	}
+	// giving up on a call isn't gate.io being unavailable, and retrying it won't help.
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+	// KuCoin's errors are placed as they are made.
+	var kucoin *KuCoinError
+	if errors.As(err, &kucoin) {
+		return err
+	}
+
+	var (
+		apiErr = &APIError{err: err}
+		gate   gateapi.GateAPIError
+		open   gateapi.GenericOpenAPIError
+		netErr net.Error
+	)
+
+	switch {
+	case errors.As(err, &gate):
+		apiErr.Label, apiErr.Message = gate.Label, gate.GetMessage()
+	case errors.As(err, &open):
+		// bodies the client couldn't decode into a GateAPIError, usually for want of a content type.
+		_ = json.Unmarshal(open.Body(), &gate)
+		apiErr.Label, apiErr.Message = gate.Label, gate.GetMessage()
+	}
+
+	if res != nil {
+		apiErr.StatusCode = res.StatusCode
+	}
+
+	apiErr.Kind = labelKinds[apiErr.Label]
+	if apiErr.Kind == nil {
+		switch {
+		case apiErr.StatusCode == http.StatusTooManyRequests:
+			apiErr.Kind = trader.ErrRateLimited
+		case apiErr.StatusCode == http.StatusUnauthorized || apiErr.StatusCode == http.StatusForbidden:
+			apiErr.Kind = trader.ErrExchangeAuth
+		case apiErr.StatusCode >= http.StatusInternalServerError:
+			apiErr.Kind = trader.ErrExchangeUnavailable
+		case res == nil && errors.As(err, &netErr):
+			apiErr.Kind = trader.ErrExchangeUnavailable
+		default:
+			return err
+		}
+	}
+	return apiErr
+}
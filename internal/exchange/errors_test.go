@@ -0,0 +1,141 @@
+package exchange_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/gateio/gateapi-go/v6"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/moonr-app/crypto-signal-trading-bot/internal/exchange"
+	"github.com/moonr-app/crypto-signal-trading-bot/internal/trader"
+)
+
+func gateError(status int, label string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(`{"label":"` + label + `","message":"something went wrong"}`))
+	}
+}
+
+func TestGateIO_Errors(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("retries a rate limited call until it succeeds", func(t *testing.T) {
+		stub := newGateStub(t)
+		stub.handle("/spot/currencies/RARE", func(w http.ResponseWriter, r *http.Request) {
+			if stub.count("GET /spot/currencies/RARE") < 3 {
+				gateError(http.StatusTooManyRequests, "TOO_MANY_REQUESTS")(w, r)
+				return
+			}
+			writeJSON(w, map[string]interface{}{"currency": "RARE", "trade_disabled": false})
+		})
+
+		supported, err := stub.gate(t).CheckSupport(ctx, "RARE")
+		require.NoError(t, err)
+
+		assert.True(t, supported)
+		assert.Equal(t, 3, stub.count("GET /spot/currencies/RARE"))
+	})
+
+	t.Run("gives up after the configured attempts", func(t *testing.T) {
+		stub := newGateStub(t)
+		stub.handle("/spot/currencies/RARE", gateError(http.StatusBadGateway, "SERVER_ERROR"))
+
+		_, err := stub.gate(t, exchange.WithRetryPolicy(2, 0)).CheckSupport(ctx, "RARE")
+		require.Error(t, err)
+
+		assert.True(t, errors.Is(err, trader.ErrExchangeUnavailable))
+		assert.True(t, trader.IsTemporary(err))
+		assert.Equal(t, 2, stub.count("GET /spot/currencies/RARE"))
+	})
+
+	t.Run("does not retry an error that will keep happening", func(t *testing.T) {
+		stub := newGateStub(t)
+		stub.handle("/spot/currencies/NOPE", gateError(http.StatusBadRequest, "INVALID_CURRENCY"))
+
+		_, err := stub.gate(t).CheckSupport(ctx, "NOPE")
+		require.Error(t, err)
+
+		assert.True(t, errors.Is(err, trader.ErrInvalidPair))
+		assert.Equal(t, 1, stub.count("GET /spot/currencies/NOPE"))
+
+		var apiErr *exchange.APIError
+		require.True(t, errors.As(err, &apiErr))
+		assert.Equal(t, http.StatusBadRequest, apiErr.StatusCode)
+		assert.Equal(t, "INVALID_CURRENCY", apiErr.Label)
+	})
+
+	t.Run("ErrInsufficientBalance given order is rejected for balance", func(t *testing.T) {
+		stub := newGateStub(t)
+		stub.handle("/spot/orders", gateError(http.StatusBadRequest, "BALANCE_NOT_ENOUGH"))
+
+		_, err := stub.gate(t).PurchaseCoin(ctx, "RARE", decimal.NewFromFloat(0.5), "")
+		require.Error(t, err)
+
+		assert.True(t, errors.Is(err, trader.ErrInsufficientBalance))
+		assert.Equal(t, 1, stub.count("POST /spot/orders"))
+	})
+
+	t.Run("reads the label given the body has no content type", func(t *testing.T) {
+		stub := newGateStub(t)
+		stub.handle("/spot/orders", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"label":"BALANCE_NOT_ENOUGH","message":"Not enough balance"}`))
+		})
+
+		_, err := stub.gate(t).PurchaseCoin(ctx, "RARE", decimal.NewFromFloat(0.5), "")
+		require.Error(t, err)
+
+		assert.True(t, errors.Is(err, trader.ErrInsufficientBalance))
+	})
+
+	t.Run("ErrExchangeAuth given keys are rejected", func(t *testing.T) {
+		stub := newGateStub(t)
+		stub.handle("/spot/orders", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusUnauthorized)
+		})
+
+		_, err := stub.gate(t).PurchaseCoin(ctx, "RARE", decimal.NewFromFloat(0.5), "")
+		require.Error(t, err)
+
+		assert.True(t, errors.Is(err, trader.ErrExchangeAuth))
+	})
+
+	t.Run("does not retry placing an order after a server error", func(t *testing.T) {
+		stub := newGateStub(t)
+		stub.handle("/spot/orders", gateError(http.StatusInternalServerError, "SERVER_ERROR"))
+
+		_, err := stub.gate(t).PurchaseCoin(ctx, "RARE", decimal.NewFromFloat(0.5), "")
+		require.Error(t, err)
+
+		assert.True(t, errors.Is(err, trader.ErrExchangeUnavailable))
+		assert.Equal(t, 1, stub.count("POST /spot/orders"))
+	})
+
+	t.Run("retries placing an order after a rate limit", func(t *testing.T) {
+		stub := newGateStub(t)
+		stub.handle("/spot/orders", func(w http.ResponseWriter, r *http.Request) {
+			if stub.count("POST /spot/orders") == 1 {
+				gateError(http.StatusTooManyRequests, "TOO_MANY_REQUESTS")(w, r)
+				return
+			}
+			var o gateapi.Order
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&o))
+			o.Id, o.Status, o.Left, o.FilledTotal = "123", "closed", "0", "10"
+			writeJSON(w, o)
+		})
+
+		fill, err := stub.gate(t).PurchaseCoin(ctx, "RARE", decimal.NewFromFloat(0.5), "")
+		require.NoError(t, err)
+
+		assert.Equal(t, "20", fill.Amount.String())
+		assert.Equal(t, 2, stub.count("POST /spot/orders"))
+	})
+}
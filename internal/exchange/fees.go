@@ -0,0 +1,142 @@
+package exchange
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/antihax/optional"
+	"github.com/gateio/gateapi-go/v6"
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+
+	"github.com/moonr-app/crypto-signal-trading-bot/internal/shared/logging"
+	"github.com/moonr-app/crypto-signal-trading-bot/internal/trader"
+)
+
+const (
+	gtCurrency             = "GT"
+	feeRateRefreshInterval = time.Hour
+	// gtPriceMaxAge is how old GT's price can be when valuing fees, which are small enough not to need it exact.
+	gtPriceMaxAge = time.Minute
+)
+
+// defaultTakerFeeRate is gate.io's standard spot taker fee, used when the account's own rate can't be fetched.
+var defaultTakerFeeRate = decimal.NewFromFloat(0.002)
+
+type feeRate struct {
+	rate      decimal.Decimal
+	fetchedAt time.Time
+}
+
+// feeRates caches the account's taker fee rate per currency pair. Rates only change with the account's VIP level,
+// so there is no need to look them up on every sell.
+type feeRates struct {
+	api   *gateapi.APIClient
+	retry retryPolicy
+
+	lock  sync.Mutex
+	rates map[string]feeRate
+}
+
+func newFeeRates(api *gateapi.APIClient, retry retryPolicy) *feeRates {
+	return &feeRates{api: api, retry: retry, rates: make(map[string]feeRate)}
+}
+
+// taker returns the taker fee rate for currencyPair from the spot fee endpoint, falling back to the wallet one.
+func (f *feeRates) taker(ctx context.Context, currencyPair string) (decimal.Decimal, error) {
+	f.lock.Lock()
+	cached, ok := f.rates[currencyPair]
+	f.lock.Unlock()
+	if ok && time.Since(cached.fetchedAt) < feeRateRefreshInterval {
+		return cached.rate, nil
+	}
+
+	var fee gateapi.TradeFee
+	err := f.retry.do(ctx, func() (res *http.Response, err error) {
+		fee, res, err = f.api.SpotApi.GetFee(ctx, &gateapi.GetFeeOpts{CurrencyPair: optional.NewString(currencyPair)})
+		return res, err
+	})
+	if err != nil {
+		logging.Warn(ctx, "failed to get spot fee, trying wallet fee", zap.String("currency_pair", currencyPair), zap.Error(err))
+
+		err = f.retry.do(ctx, func() (res *http.Response, err error) {
+			fee, res, err = f.api.WalletApi.GetTradeFee(ctx, &gateapi.GetTradeFeeOpts{CurrencyPair: optional.NewString(currencyPair)})
+			return res, err
+		})
+		if err != nil {
+			return decimal.Zero, fmt.Errorf("failed to get trade fee: %w", err)
+		}
+	}
+
+	rate, err := takerRate(fee)
+	if err != nil {
+		return decimal.Zero, err
+	}
+
+	f.lock.Lock()
+	f.rates[currencyPair] = feeRate{rate: rate, fetchedAt: time.Now()}
+	f.lock.Unlock()
+	return rate, nil
+}
+
+func takerRate(fee gateapi.TradeFee) (decimal.Decimal, error) {
+	raw := fee.TakerFee
+	if fee.GtDiscount && fee.GtTakerFee != "" {
+		raw = fee.GtTakerFee
+	}
+
+	rate, err := decimal.NewFromString(raw)
+	if err != nil {
+		return decimal.Zero, fmt.Errorf("invalid taker fee %q: %w", raw, err)
+	}
+	return rate, nil
+}
+
+// SellFeeRate is the fraction of a sell gate.io will keep as its fee. If the account's rate can't be fetched the
+// standard rate is assumed, so the seller can still work out a break-even price.
+func (g *GateIO) SellFeeRate(ctx context.Context, coin string) (decimal.Decimal, error) {
+	if g.testMode {
+		return defaultTakerFeeRate, nil
+	}
+
+	r, err := g.heldRoute(ctx, coin)
+	if err == nil {
+		var rate decimal.Decimal
+		if rate, err = g.fees.taker(ctx, r.pair); err == nil {
+			return rate, nil
+		}
+	}
+	logging.Warn(ctx, "failed to get fee rate, assuming the standard rate", zap.String("coin", coin), zap.Error(err))
+	return defaultTakerFeeRate, nil
+}
+
+// valueFees works out fill's QuoteFee in USDT: the fee if it was paid in the quote currency of the pair r traded
+// through, plus anything paid in GT at GT's last price. Fees taken from the coin itself are left out,
+// trader.Fill.Received accounts for those.
+func (g *GateIO) valueFees(ctx context.Context, fill trader.Fill, r route) trader.Fill {
+	quoteFee := decimal.Zero
+	if strings.EqualFold(fill.FeeCurrency, r.quote) {
+		quoteFee = r.report(fill.Fee)
+	}
+
+	inGT := fill.GtFee
+	if strings.EqualFold(fill.FeeCurrency, gtCurrency) {
+		inGT = fill.Fee
+	}
+
+	if inGT.IsPositive() {
+		gtPrice, err := g.pairPrice(ctx, pairID(gtCurrency, quoteCurrency), gtPriceMaxAge)
+		if err != nil {
+			logging.Warn(ctx, "failed to price GT fee, leaving it out", zap.String("gt_fee", inGT.String()), zap.Error(err))
+		} else {
+			quoteFee = quoteFee.Add(inGT.Mul(gtPrice.Last))
+		}
+	}
+
+	fill.QuoteFee = quoteFee
+	return fill
+}
@@ -0,0 +1,88 @@
+package exchange_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/gateio/gateapi-go/v6"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGateIO_SellFeeRate(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("uses the GT rate given GT deduction is on, and caches it", func(t *testing.T) {
+		stub := newGateStub(t)
+		stub.handle("/spot/fee", func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "RARE_USDT", r.URL.Query().Get("currency_pair"))
+			writeJSON(w, gateapi.TradeFee{TakerFee: "0.002", GtDiscount: true, GtTakerFee: "0.0015"})
+		})
+		g := stub.gate(t)
+
+		for i := 0; i < 2; i++ {
+			rate, err := g.SellFeeRate(ctx, "RARE")
+			require.NoError(t, err)
+			assert.Equal(t, "0.0015", rate.String())
+		}
+		assert.Equal(t, 1, stub.count("GET /spot/fee"))
+	})
+
+	t.Run("falls back to the wallet fee given the spot fee fails", func(t *testing.T) {
+		stub := newGateStub(t)
+		stub.handle("/spot/fee", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		})
+		stub.handle("/wallet/fee", func(w http.ResponseWriter, r *http.Request) {
+			writeJSON(w, gateapi.TradeFee{TakerFee: "0.001"})
+		})
+
+		rate, err := stub.gate(t).SellFeeRate(ctx, "RARE")
+		require.NoError(t, err)
+		assert.Equal(t, "0.001", rate.String())
+	})
+
+	t.Run("assumes the standard rate given no fee can be fetched", func(t *testing.T) {
+		stub := newGateStub(t)
+
+		rate, err := stub.gate(t).SellFeeRate(ctx, "RARE")
+		require.NoError(t, err)
+		assert.Equal(t, "0.002", rate.String())
+	})
+}
+
+func TestGateIO_PurchaseCoin_Fees(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("values a fee paid in the quote currency", func(t *testing.T) {
+		stub := newGateStub(t)
+		stub.orderHandler(t, func(n int) gateapi.Order {
+			return gateapi.Order{Id: "123", Status: "closed", Amount: "20", Left: "0", FilledTotal: "10", Fee: "0.02", FeeCurrency: "USDT"}
+		}, nil)
+
+		fill, err := stub.gate(t).PurchaseCoin(ctx, "RARE", decimal.NewFromFloat(0.5), "")
+		require.NoError(t, err)
+		assert.Equal(t, "0.02", fill.QuoteFee.String())
+		assert.Equal(t, "20", fill.Received("RARE").String())
+		assert.Equal(t, "0.501", fill.CostPrice("RARE").String())
+	})
+
+	t.Run("values a fee paid in GT at GT's last price", func(t *testing.T) {
+		stub := newGateStub(t)
+		stub.orderHandler(t, func(n int) gateapi.Order {
+			return gateapi.Order{Id: "123", Status: "closed", Amount: "20", Left: "0", FilledTotal: "10", Fee: "0.004", FeeCurrency: "GT", GtFee: "0.004"}
+		}, nil)
+		stub.pairs = append(stub.pairs, gateapi.CurrencyPair{Id: "GT_USDT", Base: "GT", Quote: "USDT"})
+		stub.handle("/spot/tickers", func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "GT_USDT", r.URL.Query().Get("currency_pair"))
+			writeJSON(w, []gateapi.Ticker{{CurrencyPair: "GT_USDT", Last: "5"}})
+		})
+
+		fill, err := stub.gate(t).PurchaseCoin(ctx, "RARE", decimal.NewFromFloat(0.5), "")
+		require.NoError(t, err)
+		assert.Equal(t, "0.004", fill.GtFee.String())
+		assert.Equal(t, "0.02", fill.QuoteFee.String())
+	})
+}
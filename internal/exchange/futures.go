@@ -0,0 +1,410 @@
+package exchange
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/antihax/optional"
+	"github.com/gateio/gateapi-go/v6"
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+
+	"github.com/moonr-app/crypto-signal-trading-bot/internal/shared/logging"
+	"github.com/moonr-app/crypto-signal-trading-bot/internal/trader"
+)
+
+const (
+	// futuresSettle is the settle currency of the perpetuals the bot shorts.
+	futuresSettle = "usdt"
+
+	// futures price orders number their rules and price types, unlike spot ones.
+	futuresTriggerAtOrAbove int32 = 1
+	futuresTriggerAtOrBelow int32 = 2
+	futuresTriggerMarkPrice int32 = 1
+
+	// futuresMarketPrice with immediate-or-cancel makes a futures order a market order.
+	futuresMarketPrice = "0"
+)
+
+// WithShorts sets the leverage OpenShort opens shorts at, and how far under and over the entry price, as percentages,
+// the take-profit and the stop that close them are. Shorts can't be opened until it's set.
+func WithShorts(leverage int64, takeProfitPercentage, stopLossPercentage decimal.Decimal) Option {
+	return func(o *options) {
+		o.shortLeverage = leverage
+		o.shortTakeProfit = takeProfitPercentage
+		o.shortStopLoss = stopLossPercentage
+	}
+}
+
+// ShortContract is the gate.io USDT perpetual for coin. Like Resolver.Resolve, an alias always wins, and otherwise the
+// coin and its 1000x denomination are both looked for, e.g. 1000SATS_USDT for SATS. It's false if gate.io has no such
+// perpetual, or is delisting it and so won't open new positions on it. Given a perpetual for more than one of them,
+// it returns trader.ErrCoinAmbiguous rather than guess.
+func (g *GateIO) ShortContract(ctx context.Context, coin string) (string, bool, error) {
+	var (
+		names     []string
+		contracts []gateapi.Contract
+	)
+	for _, candidate := range g.resolver.candidates(coin) {
+		name := candidate + "_" + quoteCurrency
+
+		c, err := g.futuresContract(ctx, name)
+		if errors.Is(err, trader.ErrInvalidPair) {
+			continue
+		}
+		if err != nil {
+			return "", false, err
+		}
+		names, contracts = append(names, name), append(contracts, c)
+	}
+
+	switch len(contracts) {
+	case 0:
+		return "", false, nil
+	case 1:
+		return names[0], !contracts[0].InDelisting, nil
+	default:
+		return "", false, fmt.Errorf("%w: %s could be any of %s", trader.ErrCoinAmbiguous, coin, strings.Join(names, ", "))
+	}
+}
+
+// OpenShort sells as many contracts short as margin covers at the configured leverage, with a market order, then
+// leaves a stop over the entry price and a take-profit under it on gate.io to buy them back. Both trigger on the mark
+// price and don't expire, so the position is protected until one of them closes it, whether the bot is running or
+// not. If the stop can't be placed the short is closed straight away rather than left unprotected.
+func (g *GateIO) OpenShort(ctx context.Context, coin string, contract string, margin decimal.Decimal) (trader.ShortPosition, error) {
+	if g.shortLeverage <= 0 || !g.shortStopLossPercentage.IsPositive() || !g.shortTakeProfitPercentage.IsPositive() {
+		return trader.ShortPosition{}, errors.New("shorts aren't configured")
+	}
+
+	c, err := g.futuresContract(ctx, contract)
+	if err != nil {
+		return trader.ShortPosition{}, err
+	}
+
+	var (
+		markPrice, _  = decimal.NewFromString(c.MarkPrice)
+		multiplier, _ = decimal.NewFromString(c.QuantoMultiplier)
+		leverage      = decimal.NewFromInt(g.shortLeverage)
+	)
+	if !markPrice.IsPositive() || !multiplier.IsPositive() {
+		return trader.ShortPosition{}, fmt.Errorf("%s has no mark price or multiplier", contract)
+	}
+
+	size := margin.Mul(leverage).Div(markPrice.Mul(multiplier)).Floor().IntPart()
+	if c.OrderSizeMax > 0 && size > c.OrderSizeMax {
+		size = c.OrderSizeMax
+	}
+	if size < c.OrderSizeMin || size < 1 {
+		return trader.ShortPosition{}, fmt.Errorf("%w: %s USDT at %dx buys under %d contracts of %s", trader.ErrOrderTooSmall, margin, g.shortLeverage, c.OrderSizeMin, contract)
+	}
+
+	if g.testMode {
+		logging.Info(ctx, "test mode, not really shorting")
+		return g.shortPosition(coin, contract, c, size, markPrice, margin), nil
+	}
+
+	err = g.retry.do(ctx, func() (res *http.Response, err error) {
+		_, res, err = g.api.FuturesApi.UpdatePositionLeverage(ctx, futuresSettle, contract, strconv.FormatInt(g.shortLeverage, 10), nil)
+		return res, err
+	})
+	if err != nil {
+		return trader.ShortPosition{}, fmt.Errorf("failed to set leverage of %s: %w", contract, err)
+	}
+
+	order, err := g.createFuturesOrder(ctx, gateapi.FuturesOrder{
+		Contract: contract,
+		Size:     -size,
+		Price:    futuresMarketPrice,
+		Tif:      timeInForceImmediateOrCancel,
+		Text:     buyClientIDs(contract, "").next(),
+	})
+	if err != nil {
+		return trader.ShortPosition{}, fmt.Errorf("failed to short %s: %w", contract, err)
+	}
+
+	// both are negative for a short.
+	sold := order.Left - order.Size
+	entryPrice, _ := decimal.NewFromString(order.FillPrice)
+	if sold <= 0 || !entryPrice.IsPositive() {
+		return trader.ShortPosition{}, trader.ErrOrderNotFilled
+	}
+
+	position := g.shortPosition(coin, contract, c, sold, entryPrice, margin)
+
+	stopID, err := g.placeFuturesTriggered(ctx, contract, sold, position.StopPrice, futuresTriggerAtOrAbove)
+	if err != nil {
+		g.closeShort(ctx, contract, sold)
+		return trader.ShortPosition{}, fmt.Errorf("failed to place stop on %s, closed the short: %w", contract, err)
+	}
+
+	takeProfitID, err := g.placeFuturesTriggered(ctx, contract, sold, position.TakeProfitPrice, futuresTriggerAtOrBelow)
+	if err != nil {
+		// the stop still protects the position, it just won't take profit on its own.
+		logging.Error(ctx, "failed to place take profit on short", zap.String("contract", contract), zap.Error(err))
+	}
+	position.Bracket = trader.Bracket{TakeProfitOrderID: takeProfitID, StopOrderID: stopID}
+
+	logging.Info(
+		ctx,
+		"shorted",
+		zap.String("contract", contract),
+		zap.Int64("contracts", sold),
+		zap.String("entry_price", entryPrice.String()),
+		zap.String("stop", position.StopPrice.String()),
+		zap.String("take_profit", position.TakeProfitPrice.String()),
+	)
+	return position, nil
+}
+
+// shortPosition is a short of size contracts of contract, described by c, at entryPrice, with its stop and take-profit
+// priced from it.
+func (g *GateIO) shortPosition(coin, contract string, c gateapi.Contract, size int64, entryPrice, margin decimal.Decimal) trader.ShortPosition {
+	var (
+		hundred       = decimal.NewFromInt(100)
+		multiplier, _ = decimal.NewFromString(c.QuantoMultiplier)
+		tick, _       = decimal.NewFromString(c.OrderPriceRound)
+	)
+
+	return trader.ShortPosition{
+		Coin:            coin,
+		Contract:        contract,
+		Contracts:       size,
+		Amount:          multiplier.Mul(decimal.NewFromInt(size)),
+		EntryPrice:      entryPrice,
+		Margin:          margin,
+		Leverage:        g.shortLeverage,
+		StopPrice:       roundToTick(entryPrice.Mul(hundred.Add(g.shortStopLossPercentage)).Div(hundred), tick),
+		TakeProfitPrice: roundToTick(entryPrice.Mul(hundred.Sub(g.shortTakeProfitPercentage)).Div(hundred), tick),
+		OpenedAt:        time.Now(),
+	}
+}
+
+// placeFuturesTriggered leaves an order on gate.io to buy back size contracts at the market once the mark price
+// crosses trigger. It's reduce only, so it can never open a long.
+func (g *GateIO) placeFuturesTriggered(ctx context.Context, contract string, size int64, trigger decimal.Decimal, rule int32) (string, error) {
+	var created gateapi.TriggerOrderResponse
+	err := g.retry.doIf(ctx, isRateLimited, func() (res *http.Response, err error) {
+		created, res, err = g.api.FuturesApi.CreatePriceTriggeredOrder(ctx, futuresSettle, gateapi.FuturesPriceTriggeredOrder{
+			Initial: gateapi.FuturesInitialOrder{
+				Contract:   contract,
+				Size:       size,
+				Price:      futuresMarketPrice,
+				Tif:        timeInForceImmediateOrCancel,
+				ReduceOnly: true,
+			},
+			Trigger: gateapi.FuturesPriceTrigger{
+				PriceType: futuresTriggerMarkPrice,
+				Price:     trigger.String(),
+				Rule:      rule,
+			},
+		})
+		return res, err
+	})
+	if err != nil {
+		return "", err
+	}
+	return strconv.FormatInt(created.Id, 10), nil
+}
+
+// createFuturesOrder places order, which must have a client ID. As with orderManager.create, placing it is only
+// retried straight away when it was rate limited. After any other failure the order is looked for by its client ID
+// first, and only placed again if it isn't there, so a contract is never shorted twice.
+func (g *GateIO) createFuturesOrder(ctx context.Context, order gateapi.FuturesOrder) (gateapi.FuturesOrder, error) {
+	since := time.Now()
+
+	for attempt := 1; ; attempt++ {
+		var created gateapi.FuturesOrder
+		err := g.retry.doIf(ctx, isRateLimited, func() (res *http.Response, err error) {
+			created, res, err = g.api.FuturesApi.CreateFuturesOrder(ctx, futuresSettle, order)
+			return res, err
+		})
+		if err == nil || !errors.Is(err, trader.ErrExchangeUnavailable) {
+			return created, err
+		}
+
+		found, ok, ferr := g.findFuturesOrder(ctx, order, since)
+		if ferr != nil {
+			return gateapi.FuturesOrder{}, fmt.Errorf("%w, and couldn't check whether it was placed: %v", err, ferr)
+		}
+		if ok {
+			logging.Info(ctx, "futures order was placed after all", zap.String("client_order_id", order.Text), zap.Int64("order_id", found.Id))
+			return found, nil
+		}
+		if attempt >= g.retry.attempts {
+			return gateapi.FuturesOrder{}, err
+		}
+
+		logging.Warn(ctx, "futures order was not placed, placing it again", zap.String("client_order_id", order.Text), zap.Error(err))
+	}
+}
+
+// findFuturesOrder looks for an order by its client ID, first among the open orders and then among those on its
+// contract finished since.
+func (g *GateIO) findFuturesOrder(ctx context.Context, order gateapi.FuturesOrder, since time.Time) (gateapi.FuturesOrder, bool, error) {
+	var open gateapi.FuturesOrder
+	err := g.retry.do(ctx, func() (res *http.Response, err error) {
+		open, res, err = g.api.FuturesApi.GetFuturesOrder(ctx, futuresSettle, order.Text)
+		return res, err
+	})
+	if err == nil {
+		return open, true, nil
+	}
+	// gate.io only finds orders by client ID while they are open, anything else is looked for below.
+	if trader.IsTemporary(err) {
+		return gateapi.FuturesOrder{}, false, err
+	}
+
+	var finished []gateapi.FuturesOrder
+	err = g.retry.do(ctx, func() (res *http.Response, err error) {
+		finished, res, err = g.api.FuturesApi.ListFuturesOrders(ctx, futuresSettle, order.Contract, orderStatusFinished, nil)
+		return res, err
+	})
+	if err != nil {
+		return gateapi.FuturesOrder{}, false, err
+	}
+
+	for _, o := range finished {
+		if o.Text == order.Text && o.CreateTime >= float64(since.Unix()) {
+			return o, true, nil
+		}
+	}
+	return gateapi.FuturesOrder{}, false, nil
+}
+
+// closeShort buys back size contracts at the market. Failing to is only logged, and sent on by the caller's error.
+func (g *GateIO) closeShort(ctx context.Context, contract string, size int64) {
+	err := g.retry.doIf(ctx, isRateLimited, func() (res *http.Response, err error) {
+		_, res, err = g.api.FuturesApi.CreateFuturesOrder(ctx, futuresSettle, gateapi.FuturesOrder{
+			Contract:   contract,
+			Size:       size,
+			Price:      futuresMarketPrice,
+			Tif:        timeInForceImmediateOrCancel,
+			ReduceOnly: true,
+		})
+		return res, err
+	})
+	if err != nil {
+		logging.Error(ctx, "failed to close unprotected short, close it by hand", zap.String("contract", contract), zap.Error(err))
+	}
+}
+
+// CheckShort reports whether gate.io has closed position, and if so cancels whichever of its stop and take-profit
+// is still waiting and returns the PnL of the close. In test mode it's closed once the mark price crosses either.
+func (g *GateIO) CheckShort(ctx context.Context, position trader.ShortPosition) (decimal.Decimal, bool, error) {
+	if g.testMode {
+		return g.checkTestShort(ctx, position)
+	}
+
+	var p gateapi.Position
+	err := g.retry.do(ctx, func() (res *http.Response, err error) {
+		p, res, err = g.api.FuturesApi.GetPosition(background(ctx), futuresSettle, position.Contract)
+		return res, err
+	})
+	if err != nil {
+		return decimal.Zero, false, fmt.Errorf("failed to get position on %s: %w", position.Contract, err)
+	}
+	if p.Size != 0 {
+		return decimal.Zero, false, nil
+	}
+
+	for _, id := range []string{position.Bracket.TakeProfitOrderID, position.Bracket.StopOrderID} {
+		if err := g.cancelFuturesTriggeredIfOpen(ctx, id); err != nil {
+			return decimal.Zero, false, err
+		}
+	}
+
+	var closes []gateapi.PositionClose
+	err = g.retry.do(ctx, func() (res *http.Response, err error) {
+		closes, res, err = g.api.FuturesApi.ListPositionClose(background(ctx), futuresSettle, &gateapi.ListPositionCloseOpts{
+			Contract: optional.NewString(position.Contract),
+			Limit:    optional.NewInt32(1),
+		})
+		return res, err
+	})
+	if err != nil {
+		return decimal.Zero, false, fmt.Errorf("failed to get close of %s: %w", position.Contract, err)
+	}
+
+	// a position closed by hand in pieces has no single close, only what's in the account book.
+	if len(closes) == 0 || float64(position.OpenedAt.Unix()) > closes[0].Time {
+		logging.Warn(ctx, "short closed without a close record", zap.String("contract", position.Contract))
+		return decimal.Zero, true, nil
+	}
+
+	pnl, err := decimal.NewFromString(closes[0].Pnl)
+	if err != nil {
+		return decimal.Zero, false, fmt.Errorf("failed to parse pnl %q: %w", closes[0].Pnl, err)
+	}
+	return pnl, true, nil
+}
+
+func (g *GateIO) checkTestShort(ctx context.Context, position trader.ShortPosition) (decimal.Decimal, bool, error) {
+	c, err := g.futuresContract(ctx, position.Contract)
+	if err != nil {
+		return decimal.Zero, false, err
+	}
+
+	markPrice, err := decimal.NewFromString(c.MarkPrice)
+	if err != nil {
+		return decimal.Zero, false, fmt.Errorf("failed to parse mark price %q: %w", c.MarkPrice, err)
+	}
+	if markPrice.LessThan(position.StopPrice) && markPrice.GreaterThan(position.TakeProfitPrice) {
+		return decimal.Zero, false, nil
+	}
+
+	logging.Info(ctx, "test mode, short closed", zap.String("contract", position.Contract), zap.String("mark_price", markPrice.String()))
+	return position.EntryPrice.Sub(markPrice).Mul(position.Amount), true, nil
+}
+
+func (g *GateIO) cancelFuturesTriggeredIfOpen(ctx context.Context, id string) error {
+	if id == "" {
+		return nil
+	}
+
+	var o gateapi.FuturesPriceTriggeredOrder
+	err := g.retry.do(ctx, func() (res *http.Response, err error) {
+		o, res, err = g.api.FuturesApi.GetPriceTriggeredOrder(ctx, futuresSettle, id)
+		return res, err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to get futures price order %s: %w", id, err)
+	}
+	if o.Status != priceOrderStatusOpen {
+		return nil
+	}
+
+	err = g.retry.do(ctx, func() (res *http.Response, err error) {
+		_, res, err = g.api.FuturesApi.CancelPriceTriggeredOrder(ctx, futuresSettle, id)
+		return res, err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to cancel futures price order %s: %w", id, err)
+	}
+	return nil
+}
+
+func (g *GateIO) futuresContract(ctx context.Context, contract string) (gateapi.Contract, error) {
+	var c gateapi.Contract
+	err := g.retry.do(ctx, func() (res *http.Response, err error) {
+		c, res, err = g.api.FuturesApi.GetFuturesContract(ctx, futuresSettle, contract)
+		return res, err
+	})
+	if err != nil {
+		return gateapi.Contract{}, fmt.Errorf("failed to get futures contract %s: %w", contract, err)
+	}
+	return c, nil
+}
+
+// roundToTick rounds price to the nearest multiple of tick, or leaves it as it is if there's no tick.
+func roundToTick(price, tick decimal.Decimal) decimal.Decimal {
+	if !tick.IsPositive() {
+		return price
+	}
+	return price.Div(tick).Round(0).Mul(tick)
+}
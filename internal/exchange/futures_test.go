@@ -0,0 +1,243 @@
+package exchange_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gateio/gateapi-go/v6"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/moonr-app/crypto-signal-trading-bot/internal/exchange"
+	"github.com/moonr-app/crypto-signal-trading-bot/internal/trader"
+)
+
+// futuresStub serves ANT_USDT, a perpetual at 4 USDT a contract, and records the orders placed on it.
+func futuresStub(t *testing.T) (*gateStub, *[]gateapi.FuturesOrder) {
+	stub := newGateStub(t)
+
+	var orders []gateapi.FuturesOrder
+
+	stub.handle("/futures/usdt/contracts/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/futures/usdt/contracts/ANT_USDT" {
+			gateError(http.StatusNotFound, "CONTRACT_NOT_FOUND")(w, r)
+			return
+		}
+		writeJSON(w, gateapi.Contract{
+			Name:             "ANT_USDT",
+			QuantoMultiplier: "1",
+			MarkPrice:        "4",
+			OrderPriceRound:  "0.001",
+			OrderSizeMin:     1,
+			OrderSizeMax:     1000000,
+		})
+	})
+	stub.handle("/futures/usdt/positions/ANT_USDT/leverage", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "3", r.URL.Query().Get("leverage"))
+		writeJSON(w, gateapi.Position{Contract: "ANT_USDT", Leverage: "3"})
+	})
+	stub.handle("/futures/usdt/orders", func(w http.ResponseWriter, r *http.Request) {
+		var o gateapi.FuturesOrder
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&o))
+		orders = append(orders, o)
+
+		o.Id, o.Status, o.FillPrice = int64(len(orders)), "finished", "4"
+		writeJSON(w, o)
+	})
+
+	return stub, &orders
+}
+
+// priceOrderHandler records the futures price orders placed, numbering them from 1.
+func (s *gateStub) priceOrderHandler(t *testing.T) *[]gateapi.FuturesPriceTriggeredOrder {
+	var triggered []gateapi.FuturesPriceTriggeredOrder
+	s.handle("/futures/usdt/price_orders", func(w http.ResponseWriter, r *http.Request) {
+		var o gateapi.FuturesPriceTriggeredOrder
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&o))
+		triggered = append(triggered, o)
+		writeJSON(w, gateapi.TriggerOrderResponse{Id: int64(len(triggered))})
+	})
+	return &triggered
+}
+
+func shortsOption() exchange.Option {
+	return exchange.WithShorts(3, decimal.NewFromInt(20), decimal.NewFromInt(10))
+}
+
+func TestGateIO_ShortContract(t *testing.T) {
+	ctx := context.Background()
+
+	// listing serves the perpetuals named, and no others.
+	listing := func(t *testing.T, names ...string) *exchange.GateIO {
+		stub := newGateStub(t)
+		stub.handle("/futures/usdt/contracts/", func(w http.ResponseWriter, r *http.Request) {
+			for _, name := range names {
+				if r.URL.Path == "/futures/usdt/contracts/"+name {
+					writeJSON(w, gateapi.Contract{Name: name})
+					return
+				}
+			}
+			gateError(http.StatusNotFound, "CONTRACT_NOT_FOUND")(w, r)
+		})
+		return stub.gate(t)
+	}
+
+	t.Run("finds the perpetual for the coin", func(t *testing.T) {
+		stub, _ := futuresStub(t)
+		g := stub.gate(t)
+
+		contract, ok, err := g.ShortContract(ctx, "ant")
+		require.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, "ANT_USDT", contract)
+
+		_, ok, err = g.ShortContract(ctx, "vai")
+		require.NoError(t, err)
+		assert.False(t, ok)
+	})
+
+	t.Run("finds the perpetual for the coin's 1000x denomination", func(t *testing.T) {
+		contract, ok, err := listing(t, "1000SATS_USDT").ShortContract(ctx, "sats")
+		require.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, "1000SATS_USDT", contract)
+	})
+
+	t.Run("ErrCoinAmbiguous given a perpetual for both denominations", func(t *testing.T) {
+		_, _, err := listing(t, "SATS_USDT", "1000SATS_USDT").ShortContract(ctx, "sats")
+		assert.True(t, errors.Is(err, trader.ErrCoinAmbiguous))
+	})
+}
+
+func TestGateIO_OpenShort(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("sells short at the leverage and leaves a stop and a take-profit", func(t *testing.T) {
+		stub, orders := futuresStub(t)
+		triggered := stub.priceOrderHandler(t)
+
+		p, err := stub.gate(t, shortsOption()).OpenShort(ctx, "ant", "ANT_USDT", decimal.NewFromInt(20))
+		require.NoError(t, err)
+
+		require.Len(t, *orders, 1)
+		assert.Equal(t, int64(-15), (*orders)[0].Size)
+		assert.Equal(t, "0", (*orders)[0].Price)
+		assert.Equal(t, "ioc", (*orders)[0].Tif)
+		assert.Equal(t, 1, stub.count("POST /futures/usdt/positions/ANT_USDT/leverage"))
+
+		require.Len(t, *triggered, 2)
+		stop, takeProfit := (*triggered)[0], (*triggered)[1]
+		assert.Equal(t, "4.4", stop.Trigger.Price)
+		assert.Equal(t, int32(1), stop.Trigger.Rule)
+		assert.Equal(t, "3.2", takeProfit.Trigger.Price)
+		assert.Equal(t, int32(2), takeProfit.Trigger.Rule)
+		for _, o := range *triggered {
+			assert.Equal(t, int64(15), o.Initial.Size)
+			assert.True(t, o.Initial.ReduceOnly)
+		}
+
+		assert.Equal(t, "ANT_USDT", p.Contract)
+		assert.Equal(t, int64(15), p.Contracts)
+		assert.Equal(t, "15", p.Amount.String())
+		assert.Equal(t, "4", p.EntryPrice.String())
+		assert.Equal(t, "20", p.Margin.String())
+		assert.Equal(t, trader.Bracket{StopOrderID: "1", TakeProfitOrderID: "2"}, p.Bracket)
+	})
+
+	t.Run("closes the short given the stop can't be placed", func(t *testing.T) {
+		stub, orders := futuresStub(t)
+		stub.handle("/futures/usdt/price_orders", gateError(http.StatusBadRequest, "INVALID_PARAM_VALUE"))
+
+		_, err := stub.gate(t, shortsOption()).OpenShort(ctx, "ant", "ANT_USDT", decimal.NewFromInt(20))
+		require.Error(t, err)
+
+		require.Len(t, *orders, 2)
+		assert.Equal(t, int64(15), (*orders)[1].Size)
+		assert.True(t, (*orders)[1].ReduceOnly)
+	})
+
+	t.Run("ErrOrderTooSmall given the margin buys less than a contract", func(t *testing.T) {
+		stub, orders := futuresStub(t)
+
+		_, err := stub.gate(t, shortsOption()).OpenShort(ctx, "ant", "ANT_USDT", decimal.NewFromInt(1))
+		assert.True(t, errors.Is(err, trader.ErrOrderTooSmall))
+		assert.Empty(t, *orders)
+	})
+
+	t.Run("finds the short by its client ID given placing it failed", func(t *testing.T) {
+		stub, orders := futuresStub(t)
+		stub.priceOrderHandler(t)
+		placed := stub.mux
+		stub.mux = http.NewServeMux()
+		stub.handle("/futures/usdt/orders", func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodPost {
+				// gate.io took the order, but the answer never arrived.
+				placed.ServeHTTP(httptest.NewRecorder(), r)
+				gateError(http.StatusInternalServerError, "SERVER_ERROR")(w, r)
+				return
+			}
+			assert.Equal(t, "ANT_USDT", r.URL.Query().Get("contract"))
+			assert.Equal(t, "finished", r.URL.Query().Get("status"))
+			o := (*orders)[0]
+			o.Id, o.Status, o.FillPrice, o.CreateTime = 1, "finished", "4", float64(time.Now().Unix())
+			writeJSON(w, []gateapi.FuturesOrder{o})
+		})
+		stub.handle("/futures/usdt/orders/", gateError(http.StatusNotFound, "ORDER_NOT_FOUND"))
+		stub.handle("/", placed.ServeHTTP)
+
+		p, err := stub.gate(t, shortsOption()).OpenShort(ctx, "ant", "ANT_USDT", decimal.NewFromInt(20))
+		require.NoError(t, err)
+		assert.Len(t, *orders, 1)
+		assert.Equal(t, int64(15), p.Contracts)
+		assert.Equal(t, 1, stub.count("GET /futures/usdt/orders"))
+	})
+}
+
+func TestGateIO_CheckShort(t *testing.T) {
+	var (
+		ctx      = context.Background()
+		position = trader.ShortPosition{Contract: "ANT_USDT", Bracket: trader.Bracket{StopOrderID: "1", TakeProfitOrderID: "2"}}
+	)
+
+	t.Run("not closed given the position is still open", func(t *testing.T) {
+		stub, _ := futuresStub(t)
+		stub.handle("/futures/usdt/positions/ANT_USDT", func(w http.ResponseWriter, r *http.Request) {
+			writeJSON(w, gateapi.Position{Contract: "ANT_USDT", Size: -15})
+		})
+
+		_, closed, err := stub.gate(t).CheckShort(ctx, position)
+		require.NoError(t, err)
+		assert.False(t, closed)
+	})
+
+	t.Run("cancels the leg left and returns the pnl given the position is closed", func(t *testing.T) {
+		stub, _ := futuresStub(t)
+		stub.handle("/futures/usdt/positions/ANT_USDT", func(w http.ResponseWriter, r *http.Request) {
+			writeJSON(w, gateapi.Position{Contract: "ANT_USDT"})
+		})
+		stub.handle("/futures/usdt/price_orders/", func(w http.ResponseWriter, r *http.Request) {
+			status := "finished"
+			if r.URL.Path == "/futures/usdt/price_orders/1" {
+				status = "open"
+			}
+			writeJSON(w, gateapi.FuturesPriceTriggeredOrder{Status: status})
+		})
+		stub.handle("/futures/usdt/position_close", func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "ANT_USDT", r.URL.Query().Get("contract"))
+			writeJSON(w, []gateapi.PositionClose{{Contract: "ANT_USDT", Pnl: "11.7", Time: 1}})
+		})
+
+		pnl, closed, err := stub.gate(t).CheckShort(ctx, position)
+		require.NoError(t, err)
+		assert.True(t, closed)
+		assert.Equal(t, "11.7", pnl.String())
+		assert.Equal(t, 1, stub.count("DELETE /futures/usdt/price_orders/1"))
+		assert.Equal(t, 0, stub.count("DELETE /futures/usdt/price_orders/2"))
+	})
+}
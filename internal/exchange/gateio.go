@@ -6,20 +6,20 @@ import (
 	"fmt"
 	"net/http"
 	"strings"
-	"sync"
 	"time"
 
-	"github.com/antihax/optional"
 	"github.com/gateio/gateapi-go/v6"
 	"github.com/shopspring/decimal"
 	"go.uber.org/zap"
 
 	"github.com/moonr-app/crypto-signal-trading-bot/internal/shared/logging"
+	"github.com/moonr-app/crypto-signal-trading-bot/internal/trader"
 )
 
-var (
-	currencyTradingPairFmtString = "%s_USDT"
+// quoteCurrency is what the bot spends and reports in. Coins may be bought with other quote currencies, see route.
+const quoteCurrency = "USDT"
 
+var (
 	accountType  = "spot"
 	sideTypeBuy  = "buy"
 	sideTypeSell = "sell"
@@ -30,63 +30,269 @@ var (
 )
 
 type GateIO struct {
-	api         *gateapi.APIClient
-	testMode    bool
-	toSpend     decimal.Decimal
-	pricesCache map[string]decimal.Decimal
-	lock        *sync.Mutex
+	api       *gateapi.APIClient
+	resolver  *Resolver
+	catalogue *catalogue
+	fees      *feeRates
+	orders    *orderManager
+	retry     retryPolicy
+	// slippageLadder is the max slippage percentage for each sweep attempt. If empty, buys are plain limit orders.
+	slippageLadder []decimal.Decimal
+	// requoteInterval is how long a sell rests at the best bid before it is cancelled and posted again.
+	requoteInterval time.Duration
+	// takeProfitPercentage and stopLossPercentage price the bracket left after each buy. No bracket is placed unless both are set.
+	takeProfitPercentage decimal.Decimal
+	stopLossPercentage   decimal.Decimal
+	testMode             bool
+	toSpend              decimal.Decimal
+	prices               *priceCache
+	// stream, if set, keeps prices up to date instead of polling every ticker.
+	stream *priceStream
+	// quotes are the currencies coins can be bought with, in order of preference.
+	quotes []string
+	// held is the pair each coin was bought through, which it is sold back through.
+	held *heldPairs
+	// profitTo is where TransferProfit moves profit to.
+	profitTo ProfitDestination
+	balances *balanceCache
+	// shortLeverage, shortTakeProfitPercentage and shortStopLossPercentage are how shorts are opened and closed.
+	shortLeverage             int64
+	shortTakeProfitPercentage decimal.Decimal
+	shortStopLossPercentage   decimal.Decimal
+}
+
+type options struct {
+	basePath          string
+	aliases           map[string]string
+	orderTimeout      time.Duration
+	orderPollInterval time.Duration
+	slippageLadder    []decimal.Decimal
+	requoteInterval   time.Duration
+	takeProfit        decimal.Decimal
+	stopLoss          decimal.Decimal
+	retry             retryPolicy
+	publicLimit       RateLimit
+	privateLimit      RateLimit
+	orderLimit        RateLimit
+	streamURL         string
+	catalogueRefresh  time.Duration
+	quotes            []string
+	positions         PositionStore
+	profitTo          ProfitDestination
+	shortLeverage     int64
+	shortTakeProfit   decimal.Decimal
+	shortStopLoss     decimal.Decimal
+}
+
+// Option customises how NewGateIO or NewKuCoin builds the client.
+type Option func(*options)
+
+// WithBasePath points the client at a different API root, mostly useful for tests.
+func WithBasePath(basePath string) Option {
+	return func(o *options) {
+		o.basePath = basePath
+	}
+}
+
+// WithAliases maps announced symbols onto the gate.io currency to trade, e.g. 1000SATS -> SATS.
+func WithAliases(aliases map[string]string) Option {
+	return func(o *options) {
+		o.aliases = aliases
+	}
+}
+
+// WithOrderTimeout sets how long an order is given to fill before whatever is left of it is cancelled.
+func WithOrderTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.orderTimeout = timeout
+	}
+}
+
+// WithOrderPollInterval sets how often an open order is checked on.
+func WithOrderPollInterval(interval time.Duration) Option {
+	return func(o *options) {
+		o.orderPollInterval = interval
+	}
+}
+
+// WithSweepBuy makes buys take liquidity from the order book with immediate-or-cancel orders instead of resting a
+// limit at the last price. Each entry is the max slippage percentage allowed for one attempt, tried in order until
+// something fills.
+func WithSweepBuy(slippageLadder ...decimal.Decimal) Option {
+	return func(o *options) {
+		o.slippageLadder = slippageLadder
+	}
+}
+
+// WithSellRequoteInterval sets how long a sell rests at the best bid before it is re-quoted.
+func WithSellRequoteInterval(d time.Duration) Option {
+	return func(o *options) {
+		o.requoteInterval = d
+	}
+}
+
+// WithBracket leaves a take-profit and a stop on the exchange after every buy, as percentages of the fill price,
+// so positions stay protected while the bot isn't running.
+func WithBracket(takeProfitPercentage, stopLossPercentage decimal.Decimal) Option {
+	return func(o *options) {
+		o.takeProfit = takeProfitPercentage
+		o.stopLoss = stopLossPercentage
+	}
+}
+
+// WithRetryPolicy sets how many times a call that failed for a temporary reason, such as a rate limit or a gate.io
+// outage, is tried in all, and how long to wait before the first retry. The wait doubles after each retry.
+func WithRetryPolicy(attempts int, backoff time.Duration) Option {
+	return func(o *options) {
+		o.retry = retryPolicy{attempts: attempts, backoff: backoff}
+	}
+}
+
+// WithRateLimits sets how fast each group of gate.io endpoints is called: public market data, private account and
+// order lookups, and placing or cancelling orders. A zero RateLimit keeps the exchange's own limit for that group.
+func WithRateLimits(public, private, orders RateLimit) Option {
+	return func(o *options) {
+		setLimit(&o.publicLimit, public)
+		setLimit(&o.privateLimit, private)
+		setLimit(&o.orderLimit, orders)
+	}
+}
+
+// WithPriceStream streams prices from gate.io's websocket at url, usually DefaultStreamURL, instead of listing every
+// ticker each cache interval. Only coins whose price is asked for are subscribed to, and prices the stream hasn't
+// got, e.g. while it reconnects, come from REST.
+func WithPriceStream(url string) Option {
+	return func(o *options) {
+		o.streamURL = url
+	}
+}
+
+// WithCatalogueRefresh sets how often gate.io's currencies, pairs and every pair's price (unless they are streamed,
+// see WithPriceStream), and the account's balances, are fetched in the background, so buying a fresh signal doesn't
+// have to wait for them. Zero stops the background refresh, and they are fetched as they are needed instead.
+func WithCatalogueRefresh(interval time.Duration) Option {
+	return func(o *options) {
+		o.catalogueRefresh = interval
+	}
+}
+
+// WithQuoteCurrencies sets which currencies coins can be bought with, in order of preference, e.g. from
+// ParseQuoteCurrencies. Each coin is bought with whichever of them it trades most in. Only USDT is used if it's empty.
+func WithQuoteCurrencies(quotes ...string) Option {
+	return func(o *options) {
+		o.quotes = quotes
+	}
+}
+
+// WithPositions is where the pair each open position was bought through is found, so positions bought before a
+// restart are still sold back into the currency that paid for them.
+func WithPositions(positions PositionStore) Option {
+	return func(o *options) {
+		o.positions = positions
+	}
+}
+
+func setLimit(dst *RateLimit, l RateLimit) {
+	if l.Requests > 0 && l.Per > 0 {
+		*dst = l
+	}
 }
 
-func NewGateIO(ctx context.Context, testMode bool, toSpend decimal.Decimal, cacheInterval time.Duration) (*GateIO, error) {
+func NewGateIO(ctx context.Context, testMode bool, toSpend decimal.Decimal, cacheInterval time.Duration, opts ...Option) (*GateIO, error) {
 	if toSpend.LessThanOrEqual(decimal.NewFromInt(0)) {
 		return nil, errors.New("cannot have a 0 or less value for toSpend ")
 	}
+
+	o := options{
+		orderTimeout:      defaultOrderTimeout,
+		orderPollInterval: defaultOrderPollInterval,
+		requoteInterval:   defaultRequoteInterval,
+		retry:             retryPolicy{attempts: defaultRetryAttempts, backoff: defaultRetryBackoff},
+		publicLimit:       defaultPublicLimit,
+		privateLimit:      defaultPrivateLimit,
+		orderLimit:        defaultOrderLimit,
+		catalogueRefresh:  defaultCatalogueRefresh,
+		quotes:            defaultQuoteCurrencies,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if len(o.quotes) == 0 {
+		o.quotes = []string{quoteCurrency}
+	}
+	if o.shortLeverage > 0 && o.profitTo.SubAccount == "" && o.profitTo.Account == futuresAccount {
+		// profit swept there would be margin for the next short rather than kept.
+		return nil, errors.New("profit can't be swept to the futures account while shorts are enabled")
+	}
+
+	// every call shares one limiter, so the ticker cache, the seller and order placement can't add up to a 429.
+	httpClient := &http.Client{
+		Transport: newRateLimiter(http.DefaultTransport, gateEndpoints, o.publicLimit, o.privateLimit, o.orderLimit),
+	}
+
 	cfg := gateapi.NewConfiguration()
+	cfg.HTTPClient = httpClient
+	if o.basePath != "" {
+		cfg.BasePath = o.basePath
+	}
 
-	client := gateapi.NewAPIClient(cfg)
+	var (
+		client    = gateapi.NewAPIClient(cfg)
+		resolver  = NewResolver(client, httpClient, o.aliases)
+		prices    = newPriceCache()
+		catalogue = newCatalogue(client, o.retry, resolver, prices, o.quotes)
+	)
+	resolver.quotes = o.quotes
 
 	g := &GateIO{
-		api:         client,
-		testMode:    testMode,
-		toSpend:     toSpend,
-		lock:        &sync.Mutex{},
-		pricesCache: make(map[string]decimal.Decimal),
-	}
-
-	go func() {
-		ticker := time.NewTicker(cacheInterval)
-		for {
-			select {
-			case <-ticker.C:
-				tickers, _, err := client.SpotApi.ListTickers(ctx, &gateapi.ListTickersOpts{})
-				if err != nil {
-					logging.Error(ctx, "failed to list tickers", zap.Error(err))
-				}
-
-				g.lock.Lock()
-				for _, t := range tickers {
-					if strings.Contains(t.CurrencyPair, "USDT") && t.LowestAsk != "" {
-						i, err := decimal.NewFromString(t.LowestAsk)
-						if err != nil {
-							logging.Error(ctx, "invalid ticker price",
-								zap.String("ticker", t.CurrencyPair),
-								zap.String("lowest_ask", t.LowestAsk),
-							)
-							continue
-						}
-						g.pricesCache[t.CurrencyPair] = i
-					}
-				}
-				g.lock.Unlock()
-			}
+		api:                  client,
+		resolver:             resolver,
+		catalogue:            catalogue,
+		fees:                 newFeeRates(client, o.retry),
+		orders:               &orderManager{api: client, catalogue: catalogue, retry: o.retry, pollInterval: o.orderPollInterval, timeout: o.orderTimeout},
+		retry:                o.retry,
+		slippageLadder:       o.slippageLadder,
+		requoteInterval:      o.requoteInterval,
+		takeProfitPercentage: o.takeProfit,
+		stopLossPercentage:   o.stopLoss,
+		testMode:             testMode,
+		toSpend:              toSpend,
+		prices:               prices,
+		quotes:               o.quotes,
+		held:                 newHeldPairs(o.positions),
+		profitTo:             o.profitTo,
+		balances:             &balanceCache{},
+
+		shortLeverage:             o.shortLeverage,
+		shortTakeProfitPercentage: o.shortTakeProfit,
+		shortStopLossPercentage:   o.shortStopLoss,
+	}
+
+	if o.catalogueRefresh > 0 {
+		catalogue.streamed = o.streamURL != ""
+		go catalogue.run(ctx, o.catalogueRefresh)
+		// there's no account to check the balance of in test mode.
+		if !testMode {
+			go g.refreshBalances(ctx, o.catalogueRefresh)
 		}
-	}()
+	}
+	if o.streamURL != "" {
+		g.stream = newPriceStream(o.streamURL, g.prices)
+		go g.stream.run(ctx)
+	} else {
+		go g.pollPrices(ctx, cacheInterval)
+	}
 
 	return g, nil
 }
 
+// ResolveSymbol returns the gate.io currency to trade for a signalled symbol. See Resolver.Resolve.
+func (g *GateIO) ResolveSymbol(ctx context.Context, coin, chain, contract string) (string, error) {
+	return g.resolver.Resolve(ctx, coin, chain, contract)
+}
+
 func (g *GateIO) CheckSupport(ctx context.Context, coin string) (bool, error) {
-	cur, _, err := g.api.SpotApi.GetCurrency(ctx, coin)
+	cur, err := g.catalogue.currency(ctx, coin)
 	if err != nil {
 		return false, fmt.Errorf("failed to check support for currency: %w", err)
 	}
@@ -94,44 +300,86 @@ func (g *GateIO) CheckSupport(ctx context.Context, coin string) (bool, error) {
 	return !cur.TradeDisabled, nil
 }
 
-func (g *GateIO) PurchaseCoin(ctx context.Context, coin string, lastPrice decimal.Decimal) (pricePurchased decimal.Decimal, amountPurchased decimal.Decimal, err error) {
-	var (
-		currencyPair = fmt.Sprintf(currencyTradingPairFmtString, coin)
-		volume       = g.toSpend.Div(lastPrice)
-	)
+// PurchaseCoin places a limit buy at lastPrice and waits for it to fill, cancelling anything left after the order timeout.
+// If sweep buys are configured, it sweeps the order book instead. The returned fill is what was really bought.
+// Each order's client ID is made from signalID.
+//
+// The coin is bought through the most liquid pair the account can pay for, see buyRoute, which is recorded on the
+// fill as its Pair. lastPrice and the fill are in USDT, and for any other quote currency the price and the budget are
+// converted at its current rate.
+//
+// If the account has less of the quote currency available than the buy would spend, it spends what there is and
+// reports the difference as the fill's shortfall. It returns trader.ErrInsufficientBalance if there isn't enough for
+// the pair's minimum order.
+func (g *GateIO) PurchaseCoin(ctx context.Context, coin string, lastPrice decimal.Decimal, signalID string) (trader.Fill, error) {
+	ids := buyClientIDs(coin, signalID)
 
 	if g.testMode {
 		logging.Info(ctx, "test mode, not really trading")
-		return lastPrice, volume, nil
+		return trader.Fill{ClientOrderID: ids.next(), Price: lastPrice, Amount: g.toSpend.Div(lastPrice), FilledTotal: g.toSpend}, nil
 	}
 
-	order, _, err := g.api.SpotApi.CreateOrder(ctx, gateapi.Order{
-		CurrencyPair: currencyPair,
-		Account:      accountType,
-		Side:         sideTypeBuy,
-		TimeInForce:  timeInForceGoodToClose,
-		Price:        lastPrice.String(),
-		Amount:       volume.String(),
-	})
+	r, err := g.buyRoute(ctx, coin)
+	if err != nil {
+		return trader.Fill{}, err
+	}
+	g.held.set(coin, r.pair)
 
+	spend, err := g.budget(ctx, r)
 	if err != nil {
-		return nilReturnCurr, nilReturnCurr, err
+		return trader.Fill{}, err
 	}
 
-	pricePurchasedAt, err := decimal.NewFromString(order.Price)
+	var fill trader.Fill
+	if len(g.slippageLadder) > 0 {
+		fill, err = g.sweepBuy(ctx, r.pair, r.toQuote(spend), ids)
+	} else {
+		fill, err = g.orders.place(ctx, gateapi.Order{
+			CurrencyPair: r.pair,
+			Account:      accountType,
+			Side:         sideTypeBuy,
+			TimeInForce:  timeInForceGoodToClose,
+			Price:        r.toQuote(lastPrice).String(),
+			Amount:       spend.Div(lastPrice).String(),
+			Text:         ids.next(),
+		})
+	}
+	g.spent(r, fill, err)
+	fill = r.reportFill(fill)
+	fill.Pair = r.pair
+	fill.Shortfall = g.toSpend.Sub(spend)
 	if err != nil {
-		return nilReturnCurr, nilReturnCurr, err
+		return fill, err
 	}
 
-	amtPurchased, err := decimal.NewFromString(order.Amount)
+	return g.valueFees(ctx, fill, r), nil
+}
+
+// spent takes what a buy through r paid, in its quote currency, off the cached balance, so the next buy needn't list
+// the balance again. A buy that failed may have spent anything, so the balance is listed again instead.
+func (g *GateIO) spent(r route, fill trader.Fill, err error) {
 	if err != nil {
-		return nilReturnCurr, nilReturnCurr, err
+		g.balances.clear()
+		return
+	}
+
+	paid := fill.FilledTotal
+	if strings.EqualFold(fill.FeeCurrency, r.quote) {
+		paid = paid.Add(fill.Fee)
 	}
-	return pricePurchasedAt, amtPurchased, nil
+	g.balances.spend(r.quote, paid)
 }
 
 func (g *GateIO) GetBalanceForCoin(ctx context.Context, coin string) (decimal.Decimal, error) {
-	bals, resp, err := g.api.SpotApi.ListSpotAccounts(ctx, nil)
+	var (
+		bals []gateapi.SpotAccount
+		resp *http.Response
+	)
+	err := g.retry.do(ctx, func() (res *http.Response, err error) {
+		bals, res, err = g.api.SpotApi.ListSpotAccounts(ctx, nil)
+		resp = res
+		return res, err
+	})
 	if err != nil {
 		return nilReturnCurr, fmt.Errorf("failed to get-account balance: %w", err)
 	}
@@ -152,70 +400,60 @@ func (g *GateIO) GetBalanceForCoin(ctx context.Context, coin string) (decimal.De
 	return nilReturnCurr, fmt.Errorf("didn't find coin %s in balances", coin)
 }
 
-func (g *GateIO) GetLastPrice(ctx context.Context, coin string) (decimal.Decimal, error) {
-	currencyPair := fmt.Sprintf("%s_USDT", coin)
+// Sell chases the best bid until the order timeout, then sells anything left into the bids. lastPrice is only used
+// in test mode. Only amount is ever sold, never anything else held of coin, and if the account holds less than
+// amount the difference is reported as the fill's shortfall. The returned fill is everything that sold across each
+// re-quote. Each order's client ID is made from the client ID of the order that bought the coin. The coin is sold
+// through the pair it was bought through, see heldRoute.
+func (g *GateIO) Sell(ctx context.Context, coin string, amount decimal.Decimal, lastPrice decimal.Decimal, buyClientOrderID string) (trader.Fill, error) {
+	ids := sellClientIDs(coin, buyClientOrderID)
 
-	g.lock.Lock()
-	price, ok := g.pricesCache[currencyPair]
-	g.lock.Unlock()
-	if ok {
-		return price, nil
-	}
-
-	cp, _, err := g.api.SpotApi.GetCurrencyPair(ctx, currencyPair)
-	if err != nil {
-		return decimal.NewFromInt(0), err
+	if g.testMode {
+		logging.Info(ctx, "test mode, not really trading")
+		return trader.Fill{ClientOrderID: ids.next(), Price: lastPrice, Amount: amount, FilledTotal: amount.Mul(lastPrice)}, nil
 	}
 
-	ticks, _, err := g.api.SpotApi.ListTickers(ctx, &gateapi.ListTickersOpts{CurrencyPair: optional.NewString(cp.Id)})
+	bal, err := g.GetBalanceForCoin(ctx, coin)
 	if err != nil {
-		return decimal.NewFromInt(0), err
+		return trader.Fill{}, fmt.Errorf("failed to get coin balance:%w", err)
 	}
 
-	if len(ticks) < 1 {
-		return decimal.Zero, fmt.Errorf("expected at least one ticker, got none")
-	}
-
-	logging.Info(
-		ctx,
-		"got tickers",
-		zap.String("last", ticks[0].Last),
-		zap.String("lowest_ask", ticks[0].LowestAsk),
-		zap.String("highest_bid", ticks[0].HighestBid),
+	var (
+		toSell    = decimal.Min(amount, bal)
+		shortfall = amount.Sub(toSell)
 	)
 
-	i, _ := decimal.NewFromString(ticks[0].Last)
-	return i, nil
-}
-
-func (g *GateIO) Sell(ctx context.Context, coin string, amount decimal.Decimal, lastPrice decimal.Decimal) (decimal.Decimal, error) {
-
-	currencyPair := fmt.Sprintf("%s_USDT", coin)
+	if shortfall.IsPositive() {
+		logging.Warn(
+			ctx,
+			"holding less than the bot bought",
+			zap.String("coin", coin),
+			zap.String("owned", amount.String()),
+			zap.String("available", bal.String()),
+		)
+	}
 
-	if g.testMode {
-		logging.Info(ctx, "test mode, not really trading")
-		return amount, nil
+	if !toSell.IsPositive() {
+		return trader.Fill{Shortfall: shortfall}, nil
 	}
 
-	bal, err := g.GetBalanceForCoin(ctx, coin)
+	r, err := g.heldRoute(ctx, coin)
 	if err != nil {
-		return nilReturnCurr, fmt.Errorf("failed to get coin balance:%w", err)
+		return trader.Fill{Shortfall: shortfall}, err
 	}
 
-	logging.Info(ctx, "about to try and sell", zap.String("amount", amount.String()))
+	logging.Info(ctx, "about to try and sell", zap.String("amount", toSell.String()), zap.String("currency_pair", r.pair))
 
-	_, _, err = g.api.SpotApi.CreateOrder(ctx, gateapi.Order{
-		CurrencyPair: currencyPair,
-		Account:      accountType,
-		Side:         sideTypeSell,
-		TimeInForce:  timeInForceGoodToClose,
-		Price:        lastPrice.String(),
-		Amount:       bal.String(),
-	})
+	fill, err := g.chaseSell(ctx, r.pair, toSell, ids)
+	g.balances.clear()
+	fill = r.reportFill(fill)
+	fill.Pair = r.pair
+	fill.Shortfall = shortfall
 	if err != nil {
-		return decimal.NewFromInt(0), err
+		return fill, err
 	}
+	fill = g.valueFees(ctx, fill, r)
 
-	logging.Info(ctx, "and sold!")
-	return amount, nil
+	logging.Info(ctx, "and sold!", zap.String("amount", fill.Amount.String()), zap.String("left", fill.Left.String()))
+	return fill, nil
 }
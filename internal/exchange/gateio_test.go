@@ -0,0 +1,401 @@
+package exchange_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gateio/gateapi-go/v6"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/moonr-app/crypto-signal-trading-bot/internal/exchange"
+	"github.com/moonr-app/crypto-signal-trading-bot/internal/trader"
+)
+
+// gateStub is a stand-in for the parts of the gate.io API a test needs. Handlers are registered per path.
+// Currency pairs are always served, from pairs, and so are the spot account's balances, from accounts.
+type gateStub struct {
+	mux      *http.ServeMux
+	srv      *httptest.Server
+	lock     sync.Mutex
+	hits     map[string]int
+	pairs    []gateapi.CurrencyPair
+	accounts []gateapi.SpotAccount
+	// latency is added to every response, to stand in for the round trip to gate.io.
+	latency time.Duration
+}
+
+func newGateStub(t testing.TB) *gateStub {
+	t.Helper()
+
+	s := &gateStub{mux: http.NewServeMux(), hits: make(map[string]int)}
+	s.srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.lock.Lock()
+		s.hits[r.Method+" "+r.URL.Path]++
+		latency := s.latency
+		s.lock.Unlock()
+		time.Sleep(latency)
+		s.mux.ServeHTTP(w, r)
+	}))
+	t.Cleanup(s.srv.Close)
+
+	s.pairs = []gateapi.CurrencyPair{
+		{Id: "RARE_USDT", Base: "RARE", Quote: "USDT", AmountPrecision: 8, Precision: 8, TradeStatus: "tradable"},
+		{Id: "FAIL_USDT", Base: "FAIL", Quote: "USDT", AmountPrecision: 8, Precision: 8, TradeStatus: "tradable"},
+	}
+	s.handle("/spot/currency_pairs", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, s.pairs)
+	})
+	s.handle("/spot/currency_pairs/", func(w http.ResponseWriter, r *http.Request) {
+		for _, p := range s.pairs {
+			if r.URL.Path == "/spot/currency_pairs/"+p.Id {
+				writeJSON(w, p)
+				return
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"label":"INVALID_CURRENCY_PAIR","message":"Invalid currency pair"}`))
+	})
+
+	// plenty to buy with, whatever the quote currency.
+	s.accounts = []gateapi.SpotAccount{{Currency: "USDT", Available: "1000"}, {Currency: "BTC", Available: "1"}}
+	s.handle("/spot/accounts", func(w http.ResponseWriter, r *http.Request) {
+		s.lock.Lock()
+		accounts := s.accounts
+		s.lock.Unlock()
+		writeJSON(w, accounts)
+	})
+	return s
+}
+
+func (s *gateStub) handle(pattern string, fn http.HandlerFunc) {
+	s.mux.HandleFunc(pattern, fn)
+}
+
+func (s *gateStub) count(methodAndPath string) int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.hits[methodAndPath]
+}
+
+// total is how many requests have been made to every path.
+func (s *gateStub) total() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	var n int
+	for _, hits := range s.hits {
+		n += hits
+	}
+	return n
+}
+
+func (s *gateStub) gate(t testing.TB, opts ...exchange.Option) *exchange.GateIO {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	opts = append([]exchange.Option{
+		exchange.WithBasePath(s.srv.URL),
+		exchange.WithOrderPollInterval(time.Millisecond),
+		exchange.WithRetryPolicy(3, time.Millisecond),
+		// so tests see every call they make.
+		exchange.WithCatalogueRefresh(0),
+	}, opts...)
+
+	g, err := exchange.NewGateIO(ctx, false, decimal.NewFromInt(10), time.Hour, opts...)
+	require.NoError(t, err)
+	return g
+}
+
+// orderHandler serves POST /spot/orders by echoing the order back as open, then GET and DELETE on
+// /spot/orders/{id} from the polled and cancelled functions.
+func (s *gateStub) orderHandler(t *testing.T, polled func(n int) gateapi.Order, cancelled func() gateapi.Order) {
+	var polls int
+
+	s.handle("/spot/orders", func(w http.ResponseWriter, r *http.Request) {
+		var o gateapi.Order
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&o))
+		o.Id = "123"
+		o.Status = "open"
+		o.Left = o.Amount
+		writeJSON(w, o)
+	})
+	s.handle("/spot/orders/", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/spot/orders/123", r.URL.Path)
+		switch r.Method {
+		case http.MethodGet:
+			polls++
+			writeJSON(w, polled(polls))
+		case http.MethodDelete:
+			writeJSON(w, cancelled())
+		}
+	})
+}
+
+func TestGateIO_PurchaseCoin(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("waits for the order to fill and reports the real fill", func(t *testing.T) {
+		stub := newGateStub(t)
+		stub.orderHandler(t, func(n int) gateapi.Order {
+			if n < 3 {
+				return gateapi.Order{Id: "123", CurrencyPair: "RARE_USDT", Status: "open", Amount: "20", Left: "20"}
+			}
+			return gateapi.Order{
+				Id: "123", CurrencyPair: "RARE_USDT", Status: "closed",
+				Amount: "20", Left: "0", FilledTotal: "9.8", Fee: "0.04", FeeCurrency: "RARE",
+			}
+		}, nil)
+
+		fill, err := stub.gate(t).PurchaseCoin(ctx, "RARE", decimal.NewFromFloat(0.5), "")
+		require.NoError(t, err)
+
+		assert.Equal(t, "123", fill.OrderID)
+		assert.Equal(t, "20", fill.Amount.String())
+		assert.Equal(t, "0.49", fill.Price.String())
+		assert.Equal(t, "9.8", fill.FilledTotal.String())
+		assert.Equal(t, "0.04", fill.Fee.String())
+		assert.Equal(t, "RARE", fill.FeeCurrency)
+		assert.True(t, fill.Left.IsZero())
+		assert.Equal(t, 0, stub.count("DELETE /spot/orders/123"))
+	})
+
+	t.Run("cancels whatever is left after the timeout", func(t *testing.T) {
+		stub := newGateStub(t)
+		stub.orderHandler(t, func(n int) gateapi.Order {
+			return gateapi.Order{Id: "123", CurrencyPair: "RARE_USDT", Status: "open", Amount: "20", Left: "8", FilledTotal: "6"}
+		}, func() gateapi.Order {
+			return gateapi.Order{Id: "123", CurrencyPair: "RARE_USDT", Status: "cancelled", Amount: "20", Left: "8", FilledTotal: "6"}
+		})
+
+		fill, err := stub.gate(t, exchange.WithOrderTimeout(10*time.Millisecond)).PurchaseCoin(ctx, "RARE", decimal.NewFromFloat(0.5), "")
+		require.NoError(t, err)
+
+		assert.Equal(t, "12", fill.Amount.String())
+		assert.Equal(t, "8", fill.Left.String())
+		assert.Equal(t, "0.5", fill.Price.String())
+		assert.Equal(t, 1, stub.count("DELETE /spot/orders/123"))
+	})
+
+	t.Run("cancels the order and returns what filled given ctx is cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(ctx)
+
+		stub := newGateStub(t)
+		stub.orderHandler(t, func(n int) gateapi.Order {
+			cancel()
+			return gateapi.Order{Id: "123", CurrencyPair: "RARE_USDT", Status: "open", Amount: "20", Left: "8", FilledTotal: "6"}
+		}, func() gateapi.Order {
+			return gateapi.Order{Id: "123", CurrencyPair: "RARE_USDT", Status: "cancelled", Amount: "20", Left: "8", FilledTotal: "6"}
+		})
+
+		fill, err := stub.gate(t).PurchaseCoin(ctx, "RARE", decimal.NewFromFloat(0.5), "")
+		assert.True(t, errors.Is(err, context.Canceled))
+		assert.Equal(t, "12", fill.Amount.String())
+		assert.Equal(t, 1, stub.count("DELETE /spot/orders/123"))
+	})
+
+	t.Run("returns what filled given the order finished before it could be cancelled", func(t *testing.T) {
+		var polls int
+
+		stub := newGateStub(t)
+		stub.handle("/spot/orders", func(w http.ResponseWriter, r *http.Request) {
+			writeJSON(w, gateapi.Order{Id: "123", CurrencyPair: "RARE_USDT", Status: "open", Amount: "20", Left: "20"})
+		})
+		stub.handle("/spot/orders/123", func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodDelete {
+				gateError(http.StatusBadRequest, "ORDER_NOT_FOUND")(w, r)
+				return
+			}
+			if polls++; polls == 1 {
+				writeJSON(w, gateapi.Order{Id: "123", CurrencyPair: "RARE_USDT", Status: "open", Amount: "20", Left: "8", FilledTotal: "6"})
+				return
+			}
+			writeJSON(w, gateapi.Order{Id: "123", CurrencyPair: "RARE_USDT", Status: "closed", Amount: "20", Left: "0", FilledTotal: "10"})
+		})
+
+		fill, err := stub.gate(t, exchange.WithOrderTimeout(time.Millisecond)).PurchaseCoin(ctx, "RARE", decimal.NewFromFloat(0.5), "")
+		require.NoError(t, err)
+		assert.Equal(t, "20", fill.Amount.String())
+		assert.Equal(t, 1, stub.count("DELETE /spot/orders/123"))
+	})
+
+	t.Run("ErrOrderNotFilled given nothing filled", func(t *testing.T) {
+		stub := newGateStub(t)
+		stub.orderHandler(t, func(n int) gateapi.Order {
+			return gateapi.Order{Id: "123", CurrencyPair: "RARE_USDT", Status: "open", Amount: "20", Left: "20"}
+		}, func() gateapi.Order {
+			return gateapi.Order{Id: "123", CurrencyPair: "RARE_USDT", Status: "cancelled", Amount: "20", Left: "20"}
+		})
+
+		_, err := stub.gate(t, exchange.WithOrderTimeout(time.Millisecond)).PurchaseCoin(ctx, "RARE", decimal.NewFromFloat(0.5), "")
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, trader.ErrOrderNotFilled))
+	})
+
+	t.Run("err given order cannot be created", func(t *testing.T) {
+		stub := newGateStub(t)
+		stub.handle("/spot/orders", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"label":"BALANCE_NOT_ENOUGH","message":"Not enough balance"}`))
+		})
+
+		_, err := stub.gate(t).PurchaseCoin(ctx, "RARE", decimal.NewFromFloat(0.5), "")
+		require.Error(t, err)
+		assert.True(t, strings.Contains(err.Error(), "failed to create order"))
+	})
+	t.Run("gives each order a client ID made from the signal", func(t *testing.T) {
+		stub := newGateStub(t)
+		orders := stub.iocHandler(t, func(n int, o gateapi.Order) (string, string) {
+			return "0", "10"
+		})
+
+		fill, err := stub.gate(t).PurchaseCoin(ctx, "RARE", decimal.NewFromFloat(0.5), "RARE.abc")
+		require.NoError(t, err)
+
+		require.Len(t, *orders, 1)
+		assert.Equal(t, "t-RARE.abc-1", (*orders)[0].Text)
+		assert.Equal(t, "t-RARE.abc-1", fill.ClientOrderID)
+	})
+
+	t.Run("does not place the order again given it went through despite an error", func(t *testing.T) {
+		stub := newGateStub(t)
+		stub.handle("/spot/orders", func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodPost {
+				gateError(http.StatusBadGateway, "SERVER_ERROR")(w, r)
+				return
+			}
+			assert.Equal(t, "finished", r.URL.Query().Get("status"))
+			writeJSON(w, []gateapi.Order{
+				{Id: "99", Text: "t-RARE.old-1", CurrencyPair: "RARE_USDT", Status: "closed", Amount: "5", Left: "0", FilledTotal: "2"},
+				{Id: "123", Text: "t-RARE.abc-1", CurrencyPair: "RARE_USDT", Status: "closed", Amount: "20", Left: "0", FilledTotal: "10"},
+			})
+		})
+		stub.handle("/spot/orders/", gateError(http.StatusNotFound, "ORDER_NOT_FOUND"))
+
+		fill, err := stub.gate(t).PurchaseCoin(ctx, "RARE", decimal.NewFromFloat(0.5), "RARE.abc")
+		require.NoError(t, err)
+
+		assert.Equal(t, "123", fill.OrderID)
+		assert.Equal(t, "20", fill.Amount.String())
+		assert.Equal(t, 1, stub.count("POST /spot/orders"))
+		assert.Equal(t, 1, stub.count("GET /spot/orders/t-RARE.abc-1"))
+	})
+
+	t.Run("cancels the order given ctx is cancelled while it is being placed", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		stub := newGateStub(t)
+		stub.handle("/spot/orders", func(w http.ResponseWriter, r *http.Request) {
+			// gate.io takes the order, but the answer comes too late.
+			cancel()
+			time.Sleep(20 * time.Millisecond)
+			writeJSON(w, gateapi.Order{Id: "123", Text: "t-RARE.abc-1", CurrencyPair: "RARE_USDT", Status: "open", Amount: "20", Left: "20"})
+		})
+		stub.handle("/spot/orders/t-RARE.abc-1", func(w http.ResponseWriter, r *http.Request) {
+			status := "open"
+			if r.Method == http.MethodDelete {
+				status = "cancelled"
+			}
+			writeJSON(w, gateapi.Order{Id: "123", Text: "t-RARE.abc-1", CurrencyPair: "RARE_USDT", Status: status, Amount: "20", Left: "16", FilledTotal: "2"})
+		})
+		stub.handle("/spot/orders/123", func(w http.ResponseWriter, r *http.Request) {
+			writeJSON(w, gateapi.Order{Id: "123", Text: "t-RARE.abc-1", CurrencyPair: "RARE_USDT", Status: "cancelled", Amount: "20", Left: "16", FilledTotal: "2"})
+		})
+
+		fill, err := stub.gate(t).PurchaseCoin(ctx, "RARE", decimal.NewFromFloat(0.5), "RARE.abc")
+		assert.True(t, errors.Is(err, context.Canceled))
+		assert.Equal(t, "4", fill.Amount.String())
+		assert.Equal(t, 1, stub.count("DELETE /spot/orders/123"))
+	})
+
+	t.Run("places the order again given it did not go through", func(t *testing.T) {
+		var (
+			stub   = newGateStub(t)
+			posted []string
+		)
+		stub.handle("/spot/orders", func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodGet {
+				writeJSON(w, []gateapi.Order{})
+				return
+			}
+			var o gateapi.Order
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&o))
+			posted = append(posted, o.Text)
+			if len(posted) == 1 {
+				gateError(http.StatusServiceUnavailable, "TOO_BUSY")(w, r)
+				return
+			}
+			o.Id, o.Status, o.Left, o.FilledTotal = "123", "closed", "0", "10"
+			writeJSON(w, o)
+		})
+		stub.handle("/spot/orders/", gateError(http.StatusNotFound, "ORDER_NOT_FOUND"))
+
+		fill, err := stub.gate(t).PurchaseCoin(ctx, "RARE", decimal.NewFromFloat(0.5), "RARE.abc")
+		require.NoError(t, err)
+
+		assert.Equal(t, "20", fill.Amount.String())
+		assert.Equal(t, []string{"t-RARE.abc-1", "t-RARE.abc-1"}, posted)
+	})
+
+	t.Run("rounds the order to the pair's precision", func(t *testing.T) {
+		stub := newGateStub(t)
+		stub.pairs[0].AmountPrecision, stub.pairs[0].Precision = 2, 3
+		orders := stub.iocHandler(t, func(n int, o gateapi.Order) (string, string) {
+			return "0", "10"
+		})
+
+		_, err := stub.gate(t).PurchaseCoin(ctx, "RARE", decimal.RequireFromString("0.33333"), "")
+		require.NoError(t, err)
+
+		require.Len(t, *orders, 1)
+		// the price rounds up, so the amount comes down to stay within the 10 USDT budget.
+		assert.Equal(t, "29.94", (*orders)[0].Amount)
+		assert.Equal(t, "0.334", (*orders)[0].Price)
+	})
+
+	t.Run("ErrOrderTooSmall given the order is under the pair minimum", func(t *testing.T) {
+		stub := newGateStub(t)
+		stub.pairs[0].MinQuoteAmount = "20"
+
+		_, err := stub.gate(t).PurchaseCoin(ctx, "RARE", decimal.NewFromFloat(0.5), "")
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, trader.ErrOrderTooSmall))
+
+		var tooSmall *exchange.OrderTooSmallError
+		require.True(t, errors.As(err, &tooSmall))
+		assert.Equal(t, "RARE_USDT", tooSmall.CurrencyPair)
+		assert.Equal(t, "20", tooSmall.MinQuoteAmount.String())
+		assert.Equal(t, 0, stub.count("POST /spot/orders"))
+	})
+
+	t.Run("looks up a pair listed since the pairs were last fetched", func(t *testing.T) {
+		stub := newGateStub(t)
+		stub.iocHandler(t, func(n int, o gateapi.Order) (string, string) {
+			return "0", "10"
+		})
+		g := stub.gate(t)
+
+		_, err := g.PurchaseCoin(ctx, "RARE", decimal.NewFromFloat(0.5), "")
+		require.NoError(t, err)
+
+		stub.pairs = append(stub.pairs, gateapi.CurrencyPair{Id: "NEW_USDT", Base: "NEW", Quote: "USDT", AmountPrecision: 2, Precision: 2})
+
+		_, err = g.PurchaseCoin(ctx, "NEW", decimal.NewFromFloat(0.5), "")
+		require.NoError(t, err)
+		assert.Equal(t, 1, stub.count("GET /spot/currency_pairs"))
+		assert.Equal(t, 1, stub.count("GET /spot/currency_pairs/NEW_USDT"))
+	})
+}
@@ -0,0 +1,657 @@
+package exchange
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/moonr-app/crypto-signal-trading-bot/internal/trader"
+)
+
+const (
+	// DefaultKuCoinBasePath is KuCoin's spot REST API.
+	DefaultKuCoinBasePath = "https://api.kucoin.com"
+
+	kucoinSymbolFmtString = "%s-" + quoteCurrency
+	kucoinAccountType     = "trade"
+	kucoinKeyVersion      = "2"
+	kucoinCodeOK          = "200000"
+	// kucoinPageSize is as many items as KuCoin lists in one page.
+	kucoinPageSize = 500
+	// kucoinFillsWindow is the longest span KuCoin lists fills for in one query.
+	kucoinFillsWindow = 7 * 24 * time.Hour
+	// symbolMissRefreshInterval is how often a symbol that isn't known can have every symbol fetched again.
+	symbolMissRefreshInterval = 10 * time.Second
+)
+
+// defaultKuCoinTakerFeeRate is KuCoin's standard spot taker fee, used when the account's own rate can't be fetched.
+var defaultKuCoinTakerFeeRate = decimal.NewFromFloat(0.001)
+
+// kucoinCodeKinds maps the codes KuCoin puts in error bodies onto the trader error they mean.
+var kucoinCodeKinds = map[string]error{
+	"200004": trader.ErrInsufficientBalance,
+	"400001": trader.ErrExchangeAuth,
+	"400002": trader.ErrExchangeAuth,
+	"400003": trader.ErrExchangeAuth,
+	"400004": trader.ErrExchangeAuth,
+	"400005": trader.ErrExchangeAuth,
+	"400006": trader.ErrExchangeAuth,
+	"400007": trader.ErrExchangeAuth,
+	"411100": trader.ErrExchangeAuth,
+	"900001": trader.ErrInvalidPair,
+	"429000": trader.ErrRateLimited,
+	"500000": trader.ErrExchangeUnavailable,
+}
+
+// KuCoinError is a KuCoin failure decoded into one of the trader errors, e.g. errors.Is(err, trader.ErrRateLimited).
+// Kind is nil for failures it can't place.
+type KuCoinError struct {
+	StatusCode int
+	Code       string
+	Message    string
+	Kind       error
+	err        error
+}
+
+func (e *KuCoinError) Error() string {
+	switch {
+	case e.Kind == nil:
+		return fmt.Sprintf("kucoin: %s %s", e.Code, e.Message)
+	case e.Code == "":
+		return fmt.Sprintf("kucoin: %s: %v", e.Kind, e.err)
+	default:
+		return fmt.Sprintf("kucoin: %s: %s %s", e.Kind, e.Code, e.Message)
+	}
+}
+
+func (e *KuCoinError) Is(target error) bool {
+	return e.Kind != nil && target == e.Kind
+}
+
+func (e *KuCoinError) Unwrap() error {
+	return e.err
+}
+
+// KuCoinCredentials are what KuCoin signs requests with. The passphrase is the one chosen when the API key was made.
+type KuCoinCredentials struct {
+	Key        string
+	Secret     string
+	Passphrase string
+}
+
+// KuCoin trades USDT spot pairs on KuCoin. It does the same job as GateIO for the buyer and the seller, though it
+// doesn't sweep the book, re-quote sells or place brackets.
+type KuCoin struct {
+	client       *http.Client
+	basePath     string
+	creds        KuCoinCredentials
+	retry        retryPolicy
+	pollInterval time.Duration
+	timeout      time.Duration
+	testMode     bool
+	toSpend      decimal.Decimal
+	prices       *priceCache
+
+	lock sync.Mutex
+	// symbols is keyed by KuCoin's symbol, e.g. BTC-USDT.
+	symbols     map[string]kucoinSymbol
+	refreshedAt time.Time
+	// missedAt is when the symbols were last fetched again for one that wasn't known.
+	missedAt time.Time
+	feeRates map[string]feeRate
+}
+
+// NewKuCoin builds a KuCoin client. Of the options, only WithBasePath, WithOrderTimeout, WithOrderPollInterval,
+// WithRetryPolicy and WithRateLimits apply. The credentials are only needed outside test mode.
+func NewKuCoin(testMode bool, toSpend decimal.Decimal, creds KuCoinCredentials, opts ...Option) (*KuCoin, error) {
+	if toSpend.LessThanOrEqual(decimal.NewFromInt(0)) {
+		return nil, errors.New("cannot have a 0 or less value for toSpend ")
+	}
+	if !testMode && (creds.Key == "" || creds.Secret == "" || creds.Passphrase == "") {
+		return nil, errors.New("kucoin needs an API key, secret and passphrase")
+	}
+
+	o := options{
+		basePath:          DefaultKuCoinBasePath,
+		orderTimeout:      defaultOrderTimeout,
+		orderPollInterval: defaultOrderPollInterval,
+		retry:             retryPolicy{attempts: defaultRetryAttempts, backoff: defaultRetryBackoff},
+		publicLimit:       defaultKuCoinPublicLimit,
+		privateLimit:      defaultKuCoinPrivateLimit,
+		orderLimit:        defaultKuCoinOrderLimit,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return &KuCoin{
+		client: &http.Client{
+			Transport: newRateLimiter(http.DefaultTransport, kucoinEndpoints, o.publicLimit, o.privateLimit, o.orderLimit),
+		},
+		basePath:     strings.TrimSuffix(o.basePath, "/"),
+		creds:        creds,
+		retry:        o.retry,
+		pollInterval: o.orderPollInterval,
+		timeout:      o.orderTimeout,
+		testMode:     testMode,
+		toSpend:      toSpend,
+		prices:       newPriceCache(),
+		symbols:      make(map[string]kucoinSymbol),
+		feeRates:     make(map[string]feeRate),
+	}, nil
+}
+
+type kucoinResponse struct {
+	Code string          `json:"code"`
+	Msg  string          `json:"msg"`
+	Data json.RawMessage `json:"data"`
+}
+
+type kucoinPage struct {
+	TotalPage int               `json:"totalPage"`
+	Items     []json.RawMessage `json:"items"`
+}
+
+// do calls KuCoin, retrying it while it fails with a temporary error, and decodes its data into out.
+func (k *KuCoin) do(ctx context.Context, method, path string, query url.Values, body, out interface{}) error {
+	return k.retry.do(ctx, func() (*http.Response, error) {
+		return nil, k.call(ctx, method, path, query, body, out)
+	})
+}
+
+// call makes a single signed request. Every error it returns is either a KuCoinError or ctx's.
+func (k *KuCoin) call(ctx context.Context, method, path string, query url.Values, body, out interface{}) error {
+	endpoint := path
+	if len(query) > 0 {
+		endpoint += "?" + query.Encode()
+	}
+
+	var payload []byte
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to encode request: %w", err)
+		}
+		payload = b
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, k.basePath+endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	k.sign(req, method, endpoint, payload, time.Now())
+
+	res, err := k.client.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return &KuCoinError{Kind: trader.ErrExchangeUnavailable, err: err}
+	}
+	defer res.Body.Close()
+
+	raw, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return &KuCoinError{StatusCode: res.StatusCode, Kind: trader.ErrExchangeUnavailable, err: err}
+	}
+
+	var envelope kucoinResponse
+	// a body that isn't an envelope is still placed by its status code below.
+	_ = json.Unmarshal(raw, &envelope)
+
+	if res.StatusCode != http.StatusOK || envelope.Code != kucoinCodeOK {
+		return kucoinError(res.StatusCode, envelope)
+	}
+
+	if out == nil || len(envelope.Data) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(envelope.Data, out); err != nil {
+		return fmt.Errorf("failed to decode %s %s: %w", method, path, err)
+	}
+	return nil
+}
+
+// sign adds the headers KuCoin authenticates a request with: the body, path and time signed with the secret, and the
+// passphrase signed with it too, as version 2 keys expect.
+func (k *KuCoin) sign(req *http.Request, method, endpoint string, payload []byte, at time.Time) {
+	timestamp := strconv.FormatInt(at.UnixNano()/int64(time.Millisecond), 10)
+
+	req.Header.Set("KC-API-KEY", k.creds.Key)
+	req.Header.Set("KC-API-TIMESTAMP", timestamp)
+	req.Header.Set("KC-API-SIGN", kucoinSignature(k.creds.Secret, timestamp+method+endpoint+string(payload)))
+	req.Header.Set("KC-API-PASSPHRASE", kucoinSignature(k.creds.Secret, k.creds.Passphrase))
+	req.Header.Set("KC-API-KEY-VERSION", kucoinKeyVersion)
+}
+
+func kucoinSignature(secret, message string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(message))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func kucoinError(statusCode int, envelope kucoinResponse) error {
+	e := &KuCoinError{StatusCode: statusCode, Code: envelope.Code, Message: envelope.Msg, Kind: kucoinCodeKinds[envelope.Code]}
+	if e.Kind == nil {
+		switch {
+		case statusCode == http.StatusTooManyRequests:
+			e.Kind = trader.ErrRateLimited
+		case statusCode == http.StatusUnauthorized || statusCode == http.StatusForbidden:
+			e.Kind = trader.ErrExchangeAuth
+		case statusCode >= http.StatusInternalServerError:
+			e.Kind = trader.ErrExchangeUnavailable
+		}
+	}
+	return e
+}
+
+type kucoinSymbol struct {
+	Symbol         string `json:"symbol"`
+	BaseCurrency   string `json:"baseCurrency"`
+	QuoteCurrency  string `json:"quoteCurrency"`
+	BaseMinSize    string `json:"baseMinSize"`
+	QuoteMinSize   string `json:"quoteMinSize"`
+	BaseIncrement  string `json:"baseIncrement"`
+	PriceIncrement string `json:"priceIncrement"`
+	EnableTrading  bool   `json:"enableTrading"`
+}
+
+// meta is the symbol's increments and minimums in the form gate.io's pairs take, so orders are rounded the same way.
+func (s kucoinSymbol) meta() (pairMeta, error) {
+	var ds [4]decimal.Decimal
+	for i, v := range []string{s.BaseIncrement, s.PriceIncrement, s.BaseMinSize, s.QuoteMinSize} {
+		d, err := parseDecimal(v)
+		if err != nil {
+			return pairMeta{}, fmt.Errorf("invalid increment or minimum %q for %s: %w", v, s.Symbol, err)
+		}
+		ds[i] = d
+	}
+
+	return pairMeta{
+		amountPrecision: incrementPrecision(ds[0]),
+		pricePrecision:  incrementPrecision(ds[1]),
+		minBaseAmount:   ds[2],
+		minQuoteAmount:  ds[3],
+	}, nil
+}
+
+// incrementPrecision is the number of decimal places in an increment such as 0.0001. KuCoin's increments are all
+// powers of ten.
+func incrementPrecision(inc decimal.Decimal) int32 {
+	s := inc.String()
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		return int32(len(s) - i - 1)
+	}
+	return 0
+}
+
+// symbol returns KuCoin's symbol for the pair, fetching every symbol again once they are older than
+// pairRefreshInterval. A symbol that isn't known fetches them again too, at most once every
+// symbolMissRefreshInterval, so a coin listed since the last refresh can still be bought. It returns
+// trader.ErrInvalidPair if KuCoin doesn't list it.
+func (k *KuCoin) symbol(ctx context.Context, symbol string) (kucoinSymbol, error) {
+	k.lock.Lock()
+	stale := time.Since(k.refreshedAt) > pairRefreshInterval
+	k.lock.Unlock()
+
+	if stale {
+		if err := k.refreshSymbols(ctx); err != nil {
+			return kucoinSymbol{}, err
+		}
+	}
+
+	k.lock.Lock()
+	s, ok := k.symbols[symbol]
+	missed := !ok && !stale && time.Since(k.missedAt) > symbolMissRefreshInterval
+	if missed {
+		k.missedAt = time.Now()
+	}
+	k.lock.Unlock()
+
+	if missed {
+		if err := k.refreshSymbols(ctx); err != nil {
+			return kucoinSymbol{}, err
+		}
+		k.lock.Lock()
+		s, ok = k.symbols[symbol]
+		k.lock.Unlock()
+	}
+	if !ok {
+		return kucoinSymbol{}, fmt.Errorf("%w: %s", trader.ErrInvalidPair, symbol)
+	}
+	return s, nil
+}
+
+// refreshSymbols fetches every symbol KuCoin lists.
+func (k *KuCoin) refreshSymbols(ctx context.Context) error {
+	var listed []kucoinSymbol
+	if err := k.do(ctx, http.MethodGet, "/api/v2/symbols", nil, nil, &listed); err != nil {
+		return fmt.Errorf("failed to list symbols: %w", err)
+	}
+
+	symbols := make(map[string]kucoinSymbol, len(listed))
+	for _, s := range listed {
+		symbols[s.Symbol] = s
+	}
+
+	k.lock.Lock()
+	k.symbols = symbols
+	k.refreshedAt = time.Now()
+	k.lock.Unlock()
+	return nil
+}
+
+// ResolveSymbol returns the coin as KuCoin lists it. KuCoin is only asked about the symbol, so chain and contract
+// aren't used.
+func (k *KuCoin) ResolveSymbol(ctx context.Context, coin, chain, contract string) (string, error) {
+	resolved := strings.ToUpper(coin)
+
+	_, err := k.symbol(ctx, fmt.Sprintf(kucoinSymbolFmtString, resolved))
+	if errors.Is(err, trader.ErrInvalidPair) {
+		return "", fmt.Errorf("%w: %s", trader.ErrCoinUnsupported, coin)
+	}
+	if err != nil {
+		return "", err
+	}
+	return resolved, nil
+}
+
+// CheckSupport reports whether coin's USDT pair is trading on KuCoin.
+func (k *KuCoin) CheckSupport(ctx context.Context, coin string) (bool, error) {
+	s, err := k.symbol(ctx, fmt.Sprintf(kucoinSymbolFmtString, coin))
+	if errors.Is(err, trader.ErrInvalidPair) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to check support for currency: %w", err)
+	}
+	return s.EnableTrading, nil
+}
+
+type kucoinStats struct {
+	Time     int64  `json:"time"`
+	Symbol   string `json:"symbol"`
+	Buy      string `json:"buy"`
+	Sell     string `json:"sell"`
+	Last     string `json:"last"`
+	VolValue string `json:"volValue"`
+}
+
+// GetPrice returns coin's latest prices from KuCoin's 24 hour stats, unless they were fetched within maxAge.
+func (k *KuCoin) GetPrice(ctx context.Context, coin string, maxAge time.Duration) (trader.PriceSnapshot, error) {
+	symbol := fmt.Sprintf(kucoinSymbolFmtString, coin)
+
+	if p, ok := k.prices.get(symbol, maxAge); ok && hasPrice(p) {
+		return p, nil
+	}
+
+	// a pair KuCoin doesn't list is an error, not empty stats.
+	if _, err := k.symbol(ctx, symbol); err != nil {
+		return trader.PriceSnapshot{}, err
+	}
+
+	var stats kucoinStats
+	if err := k.do(ctx, http.MethodGet, "/api/v1/market/stats", url.Values{"symbol": {symbol}}, nil, &stats); err != nil {
+		return trader.PriceSnapshot{}, fmt.Errorf("failed to get stats for %s: %w", symbol, err)
+	}
+
+	observedAt := time.Now()
+	if stats.Time > 0 {
+		observedAt = time.Unix(0, stats.Time*int64(time.Millisecond))
+	}
+
+	p := trader.PriceSnapshot{
+		Bid:        parsePrice(ctx, symbol, stats.Buy, decimal.Zero),
+		Ask:        parsePrice(ctx, symbol, stats.Sell, decimal.Zero),
+		Last:       parsePrice(ctx, symbol, stats.Last, decimal.Zero),
+		Volume:     parsePrice(ctx, symbol, stats.VolValue, decimal.Zero),
+		ObservedAt: observedAt,
+	}
+	k.prices.set(symbol, p)
+	return p, nil
+}
+
+type kucoinAccount struct {
+	Currency  string `json:"currency"`
+	Type      string `json:"type"`
+	Balance   string `json:"balance"`
+	Available string `json:"available"`
+	Holds     string `json:"holds"`
+}
+
+func (k *KuCoin) accounts(ctx context.Context, currency string) ([]kucoinAccount, error) {
+	query := url.Values{"type": {kucoinAccountType}}
+	if currency != "" {
+		query.Set("currency", strings.ToUpper(currency))
+	}
+
+	var accounts []kucoinAccount
+	if err := k.do(ctx, http.MethodGet, "/api/v1/accounts", query, nil, &accounts); err != nil {
+		return nil, fmt.Errorf("failed to list trade accounts: %w", err)
+	}
+	return accounts, nil
+}
+
+func (k *KuCoin) GetBalanceForCoin(ctx context.Context, coin string) (decimal.Decimal, error) {
+	accounts, err := k.accounts(ctx, coin)
+	if err != nil {
+		return nilReturnCurr, err
+	}
+
+	for _, a := range accounts {
+		if strings.EqualFold(a.Currency, coin) {
+			d, err := decimal.NewFromString(a.Available)
+			if err != nil {
+				return nilReturnCurr, errors.New("couldn't convert balance to decimal")
+			}
+			return d, nil
+		}
+	}
+	return nilReturnCurr, fmt.Errorf("didn't find coin %s in balances", coin)
+}
+
+// Holdings returns every currency in the trade account, available and held for orders, keyed by upper case currency.
+func (k *KuCoin) Holdings(ctx context.Context) (map[string]trader.Holding, error) {
+	accounts, err := k.accounts(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+
+	holdings := make(map[string]trader.Holding, len(accounts))
+	for _, a := range accounts {
+		available, err := parseDecimal(a.Available)
+		if err != nil {
+			return nil, fmt.Errorf("invalid available balance for %s: %w", a.Currency, err)
+		}
+		locked, err := parseDecimal(a.Holds)
+		if err != nil {
+			return nil, fmt.Errorf("invalid held balance for %s: %w", a.Currency, err)
+		}
+		holdings[strings.ToUpper(a.Currency)] = trader.Holding{Available: available, Locked: locked}
+	}
+	return holdings, nil
+}
+
+type kucoinFee struct {
+	Symbol       string `json:"symbol"`
+	TakerFeeRate string `json:"takerFeeRate"`
+	MakerFeeRate string `json:"makerFeeRate"`
+}
+
+// SellFeeRate returns the account's taker fee rate for coin, as a sell can end up taking liquidity. It falls back to
+// KuCoin's standard rate if the account's can't be fetched.
+func (k *KuCoin) SellFeeRate(ctx context.Context, coin string) (decimal.Decimal, error) {
+	symbol := fmt.Sprintf(kucoinSymbolFmtString, coin)
+
+	k.lock.Lock()
+	cached, ok := k.feeRates[symbol]
+	k.lock.Unlock()
+	if ok && time.Since(cached.fetchedAt) < feeRateRefreshInterval {
+		return cached.rate, nil
+	}
+
+	var fees []kucoinFee
+	if err := k.do(ctx, http.MethodGet, "/api/v1/trade-fees", url.Values{"symbols": {symbol}}, nil, &fees); err != nil || len(fees) == 0 {
+		return defaultKuCoinTakerFeeRate, nil
+	}
+
+	rate, err := decimal.NewFromString(fees[0].TakerFeeRate)
+	if err != nil {
+		return defaultKuCoinTakerFeeRate, nil
+	}
+
+	k.lock.Lock()
+	k.feeRates[symbol] = feeRate{rate: rate, fetchedAt: time.Now()}
+	k.lock.Unlock()
+	return rate, nil
+}
+
+// OpenOrders returns every active order in the trade account. Orders with one of the bot's client IDs are marked as
+// its own.
+func (k *KuCoin) OpenOrders(ctx context.Context) ([]trader.OpenOrder, error) {
+	query := url.Values{
+		"status":    {"active"},
+		"tradeType": {"TRADE"},
+		"pageSize":  {strconv.Itoa(kucoinPageSize)},
+	}
+
+	var listed []kucoinOrder
+	if err := k.page(ctx, "/api/v1/orders", query, &listed); err != nil {
+		return nil, fmt.Errorf("failed to list open orders: %w", err)
+	}
+
+	orders := make([]trader.OpenOrder, 0, len(listed))
+	for _, o := range listed {
+		size, err := parseDecimal(o.Size)
+		if err != nil {
+			return nil, fmt.Errorf("invalid order size: %w", err)
+		}
+		dealt, err := parseDecimal(o.DealSize)
+		if err != nil {
+			return nil, fmt.Errorf("invalid order deal size: %w", err)
+		}
+		orders = append(orders, trader.OpenOrder{
+			Coin:    kucoinBaseCurrency(o.Symbol),
+			OrderID: o.ID,
+			Side:    o.Side,
+			Left:    size.Sub(dealt),
+			Ours:    strings.HasPrefix(o.ClientOid, clientIDPrefix),
+		})
+	}
+	return orders, nil
+}
+
+type kucoinFill struct {
+	OrderID   string `json:"orderId"`
+	Side      string `json:"side"`
+	Price     string `json:"price"`
+	Size      string `json:"size"`
+	CreatedAt int64  `json:"createdAt"`
+}
+
+// Trades returns the account's trades in coin since since.
+func (k *KuCoin) Trades(ctx context.Context, coin string, since time.Time) ([]trader.Trade, error) {
+	var listed []kucoinFill
+	// KuCoin won't list fills over more than a week at a time, so longer spans are walked a week at once.
+	for start, now := since, time.Now(); start.Before(now); start = start.Add(kucoinFillsWindow) {
+		end := start.Add(kucoinFillsWindow)
+		if end.After(now) {
+			end = now
+		}
+		query := url.Values{
+			"symbol":   {fmt.Sprintf(kucoinSymbolFmtString, coin)},
+			"startAt":  {strconv.FormatInt(start.UnixNano()/int64(time.Millisecond), 10)},
+			"endAt":    {strconv.FormatInt(end.UnixNano()/int64(time.Millisecond), 10)},
+			"pageSize": {strconv.Itoa(kucoinPageSize)},
+		}
+
+		var window []kucoinFill
+		if err := k.page(ctx, "/api/v1/fills", query, &window); err != nil {
+			return nil, fmt.Errorf("failed to list trades: %w", err)
+		}
+		listed = append(listed, window...)
+	}
+
+	trades := make([]trader.Trade, 0, len(listed))
+	for _, f := range listed {
+		amount, err := parseDecimal(f.Size)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trade amount: %w", err)
+		}
+		price, err := parseDecimal(f.Price)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trade price: %w", err)
+		}
+		trades = append(trades, trader.Trade{
+			OrderID: f.OrderID,
+			Side:    f.Side,
+			Amount:  amount,
+			Price:   price,
+			At:      time.Unix(0, f.CreatedAt*int64(time.Millisecond)),
+		})
+	}
+	return trades, nil
+}
+
+// page decodes the items of every page of a paginated list into out, which must point to a slice.
+func (k *KuCoin) page(ctx context.Context, path string, query url.Values, out interface{}) error {
+	var items []json.RawMessage
+	for current := 1; ; current++ {
+		q := make(url.Values, len(query)+1)
+		for key, v := range query {
+			q[key] = v
+		}
+		q.Set("currentPage", strconv.Itoa(current))
+
+		var p kucoinPage
+		if err := k.do(ctx, http.MethodGet, path, q, nil, &p); err != nil {
+			return err
+		}
+		items = append(items, p.Items...)
+		if current >= p.TotalPage || len(p.Items) == 0 {
+			break
+		}
+	}
+	if len(items) == 0 {
+		return nil
+	}
+
+	b, err := json.Marshal(items)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, out)
+}
+
+// PlaceBracket doesn't place anything, brackets are only supported on gate.io.
+func (k *KuCoin) PlaceBracket(ctx context.Context, coin string, fill trader.Fill) (trader.Bracket, error) {
+	return trader.Bracket{}, nil
+}
+
+// CheckBracket never finds anything executed, as no bracket is ever placed on KuCoin.
+func (k *KuCoin) CheckBracket(ctx context.Context, coin string, bracket trader.Bracket) (trader.Fill, bool, error) {
+	return trader.Fill{}, false, nil
+}
+
+func (k *KuCoin) CancelBracket(ctx context.Context, coin string, bracket trader.Bracket) error {
+	return nil
+}
+
+func kucoinBaseCurrency(symbol string) string {
+	return strings.SplitN(symbol, "-", 2)[0]
+}
@@ -0,0 +1,447 @@
+package exchange
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+
+	"github.com/moonr-app/crypto-signal-trading-bot/internal/shared/logging"
+	"github.com/moonr-app/crypto-signal-trading-bot/internal/trader"
+)
+
+const (
+	kucoinOrderTypeLimit  = "limit"
+	kucoinOrderTypeMarket = "market"
+	kucoinTimeInForceGTC  = "GTC"
+)
+
+type kucoinOrderRequest struct {
+	ClientOid   string `json:"clientOid"`
+	Side        string `json:"side"`
+	Symbol      string `json:"symbol"`
+	Type        string `json:"type"`
+	Price       string `json:"price,omitempty"`
+	Size        string `json:"size"`
+	TimeInForce string `json:"timeInForce,omitempty"`
+}
+
+type kucoinOrder struct {
+	ID          string `json:"id"`
+	ClientOid   string `json:"clientOid"`
+	Symbol      string `json:"symbol"`
+	Side        string `json:"side"`
+	Type        string `json:"type"`
+	Price       string `json:"price"`
+	Size        string `json:"size"`
+	DealSize    string `json:"dealSize"`
+	DealFunds   string `json:"dealFunds"`
+	Fee         string `json:"fee"`
+	FeeCurrency string `json:"feeCurrency"`
+	IsActive    bool   `json:"isActive"`
+}
+
+// PurchaseCoin places a limit buy at lastPrice and waits for it to fill, cancelling anything left after the order
+// timeout. The returned fill is what was really bought. Each order's client ID is made from signalID.
+//
+// If the account has less USDT available than the buy would spend, it spends what there is and reports the difference
+// as the fill's shortfall. It returns trader.ErrInsufficientBalance if there isn't enough for the symbol's minimum
+// order.
+func (k *KuCoin) PurchaseCoin(ctx context.Context, coin string, lastPrice decimal.Decimal, signalID string) (trader.Fill, error) {
+	var (
+		symbol = fmt.Sprintf(kucoinSymbolFmtString, coin)
+		ids    = buyClientIDs(coin, signalID)
+	)
+
+	if k.testMode {
+		logging.Info(ctx, "test mode, not really trading")
+		return trader.Fill{ClientOrderID: ids.next(), Price: lastPrice, Amount: k.toSpend.Div(lastPrice), FilledTotal: k.toSpend}, nil
+	}
+
+	spend, err := k.budget(ctx, symbol)
+	if err != nil {
+		return trader.Fill{}, err
+	}
+
+	fill, err := k.place(ctx, kucoinOrderRequest{
+		ClientOid:   ids.next(),
+		Side:        sideTypeBuy,
+		Symbol:      symbol,
+		Type:        kucoinOrderTypeLimit,
+		TimeInForce: kucoinTimeInForceGTC,
+	}, spend.Div(lastPrice), lastPrice)
+	fill.Shortfall = k.toSpend.Sub(spend)
+	if err != nil {
+		return fill, err
+	}
+
+	return kucoinValueFees(fill), nil
+}
+
+// budget is how much USDT a buy of symbol can spend: the configured amount, or less if that's more than the trade
+// account has available. It returns trader.ErrInsufficientBalance if what's available is under the symbol's minimum
+// order.
+func (k *KuCoin) budget(ctx context.Context, symbol string) (decimal.Decimal, error) {
+	accounts, err := k.accounts(ctx, quoteCurrency)
+	if err != nil {
+		return decimal.Zero, err
+	}
+
+	available := decimal.Zero
+	for _, a := range accounts {
+		if !strings.EqualFold(a.Currency, quoteCurrency) {
+			continue
+		}
+		d, err := parseDecimal(a.Available)
+		if err != nil {
+			return decimal.Zero, fmt.Errorf("invalid available balance for %s: %w", a.Currency, err)
+		}
+		available = available.Add(d)
+	}
+	if available.GreaterThanOrEqual(k.toSpend) {
+		return k.toSpend, nil
+	}
+
+	s, err := k.symbol(ctx, symbol)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	m, err := s.meta()
+	if err != nil {
+		return decimal.Zero, err
+	}
+	if !available.IsPositive() || available.LessThan(m.minQuoteAmount) {
+		return decimal.Zero, fmt.Errorf("%w: only %s %s available to buy %s", trader.ErrInsufficientBalance, available, quoteCurrency, symbol)
+	}
+
+	logging.Warn(
+		ctx,
+		"not enough available to spend the full amount, buying less",
+		zap.String("symbol", symbol),
+		zap.String("available", available.String()),
+	)
+	return available, nil
+}
+
+// Sell rests a limit sell at the best bid until the order timeout, then sells anything left at market. lastPrice is
+// only used in test mode. Only amount is ever sold, and if the account holds less than amount the difference is
+// reported as the fill's shortfall. Each order's client ID is made from the client ID of the order that bought the
+// coin.
+func (k *KuCoin) Sell(ctx context.Context, coin string, amount decimal.Decimal, lastPrice decimal.Decimal, buyClientOrderID string) (trader.Fill, error) {
+	var (
+		symbol = fmt.Sprintf(kucoinSymbolFmtString, coin)
+		ids    = sellClientIDs(coin, buyClientOrderID)
+	)
+
+	if k.testMode {
+		logging.Info(ctx, "test mode, not really trading")
+		return trader.Fill{ClientOrderID: ids.next(), Price: lastPrice, Amount: amount, FilledTotal: amount.Mul(lastPrice)}, nil
+	}
+
+	bal, err := k.GetBalanceForCoin(ctx, coin)
+	if err != nil {
+		return trader.Fill{}, fmt.Errorf("failed to get coin balance:%w", err)
+	}
+
+	var (
+		toSell    = decimal.Min(amount, bal)
+		shortfall = amount.Sub(toSell)
+	)
+
+	if shortfall.IsPositive() {
+		logging.Warn(
+			ctx,
+			"holding less than the bot bought",
+			zap.String("coin", coin),
+			zap.String("owned", amount.String()),
+			zap.String("available", bal.String()),
+		)
+	}
+
+	if !toSell.IsPositive() {
+		return trader.Fill{Shortfall: shortfall}, nil
+	}
+
+	price, err := k.GetPrice(ctx, coin, 0)
+	if err != nil {
+		return trader.Fill{Shortfall: shortfall}, fmt.Errorf("failed to price sell: %w", err)
+	}
+
+	logging.Info(ctx, "about to try and sell", zap.String("amount", toSell.String()), zap.String("bid", price.SellPrice().String()))
+
+	fill, err := k.place(ctx, kucoinOrderRequest{
+		ClientOid:   ids.next(),
+		Side:        sideTypeSell,
+		Symbol:      symbol,
+		Type:        kucoinOrderTypeLimit,
+		TimeInForce: kucoinTimeInForceGTC,
+	}, toSell, price.SellPrice())
+	if err != nil && !errors.Is(err, trader.ErrOrderNotFilled) {
+		fill.Shortfall = shortfall
+		return fill, err
+	}
+
+	if left := toSell.Sub(fill.Amount); left.IsPositive() {
+		rest, err := k.place(ctx, kucoinOrderRequest{
+			ClientOid: ids.next(),
+			Side:      sideTypeSell,
+			Symbol:    symbol,
+			Type:      kucoinOrderTypeMarket,
+		}, left, price.SellPrice())
+		switch {
+		case errors.Is(err, trader.ErrOrderTooSmall):
+			logging.Warn(ctx, "too little left to sell at market", zap.String("left", left.String()))
+		case err != nil && !errors.Is(err, trader.ErrOrderNotFilled):
+			logging.Error(ctx, "failed to sell the rest at market", zap.String("left", left.String()), zap.Error(err))
+		}
+		fill = mergeFills(fill, rest)
+	}
+
+	fill.Left = toSell.Sub(fill.Amount)
+	fill.Shortfall = shortfall
+	if fill.Amount.IsZero() {
+		return fill, fmt.Errorf("%w: sell of %s on %s", trader.ErrOrderNotFilled, toSell, symbol)
+	}
+	fill = kucoinValueFees(fill)
+
+	logging.Info(ctx, "and sold!", zap.String("amount", fill.Amount.String()), zap.String("left", fill.Left.String()))
+	return fill, nil
+}
+
+// place rounds size, and price for a limit order, to what the symbol allows, places the order and waits for it to
+// be finished with. price is also what a market order's size is checked against the symbol's minimums at.
+func (k *KuCoin) place(ctx context.Context, req kucoinOrderRequest, size, price decimal.Decimal) (trader.Fill, error) {
+	s, err := k.symbol(ctx, req.Symbol)
+	if err != nil {
+		return trader.Fill{}, err
+	}
+	m, err := s.meta()
+	if err != nil {
+		return trader.Fill{}, err
+	}
+
+	size, price = m.amount(size), m.price(price, req.Side)
+	if err := m.check(req.Symbol, size, price); err != nil {
+		return trader.Fill{}, err
+	}
+	req.Size = size.String()
+	if req.Type == kucoinOrderTypeLimit {
+		req.Price = price.String()
+	}
+
+	id, err := k.create(ctx, req)
+	if err != nil && ctx.Err() != nil {
+		// ctx may have been done with the order already on its way to KuCoin, where it would rest unwatched.
+		return k.abandon(ctx, req.ClientOid, err)
+	}
+	if err != nil {
+		return trader.Fill{}, fmt.Errorf("failed to create order: %w", err)
+	}
+
+	final, err := k.await(ctx, id)
+	if err != nil {
+		// some of it may have filled before it went wrong, and that's the caller's all the same.
+		fill, ferr := fillFromKuCoinOrder(final)
+		if ferr != nil {
+			return trader.Fill{}, err
+		}
+		return fill, err
+	}
+
+	fill, err := fillFromKuCoinOrder(final)
+	if err != nil {
+		return trader.Fill{}, err
+	}
+
+	if fill.Amount.IsZero() {
+		return fill, fmt.Errorf("%w: order %s on %s", trader.ErrOrderNotFilled, final.ID, final.Symbol)
+	}
+	return fill, nil
+}
+
+// create places req and returns its order ID. As with gate.io, an order is only placed again straight away when it
+// was rate limited. After any other failure it is looked for by its client ID first.
+func (k *KuCoin) create(ctx context.Context, req kucoinOrderRequest) (string, error) {
+	for attempt := 1; ; attempt++ {
+		var created struct {
+			OrderID string `json:"orderId"`
+		}
+		err := k.retry.doIf(ctx, isRateLimited, func() (*http.Response, error) {
+			return nil, k.call(ctx, http.MethodPost, "/api/v1/orders", nil, req, &created)
+		})
+		if err == nil || !errors.Is(err, trader.ErrExchangeUnavailable) {
+			return created.OrderID, err
+		}
+
+		found, ok, ferr := k.find(ctx, req.ClientOid)
+		if ferr != nil {
+			return "", fmt.Errorf("%w, and couldn't check whether it was placed: %v", err, ferr)
+		}
+		if ok {
+			logging.Info(ctx, "order was placed after all", zap.String("client_order_id", req.ClientOid), zap.String("order_id", found.ID))
+			return found.ID, nil
+		}
+		if attempt >= k.retry.attempts {
+			return "", err
+		}
+
+		logging.Warn(ctx, "order was not placed, placing it again", zap.String("client_order_id", req.ClientOid), zap.Error(err))
+	}
+}
+
+// abandon cancels the order with clientOid if KuCoin took it even though placing it failed with cause, returning
+// whatever filled.
+func (k *KuCoin) abandon(ctx context.Context, clientOid string, cause error) (trader.Fill, error) {
+	cctx, cancel := detach(ctx)
+	defer cancel()
+
+	found, ok, err := k.find(cctx, clientOid)
+	if err != nil {
+		return trader.Fill{}, fmt.Errorf("failed to create order: %w, and couldn't check whether it was placed: %v", cause, err)
+	}
+	if !ok {
+		return trader.Fill{}, fmt.Errorf("failed to create order: %w", cause)
+	}
+
+	if found.IsActive {
+		found, err = k.cancel(cctx, found, "gave up on order, cancelling")
+		if err != nil {
+			cause = fmt.Errorf("%w, and %v", cause, err)
+		}
+	}
+
+	fill, ferr := fillFromKuCoinOrder(found)
+	if ferr != nil {
+		return trader.Fill{}, cause
+	}
+	return fill, cause
+}
+
+// find looks for an order by its client ID. KuCoin answers with no order, or an error that isn't temporary, when
+// there isn't one.
+func (k *KuCoin) find(ctx context.Context, clientOid string) (kucoinOrder, bool, error) {
+	var o kucoinOrder
+	err := k.do(ctx, http.MethodGet, "/api/v1/order/client-order/"+url.PathEscape(clientOid), nil, nil, &o)
+	if err != nil {
+		if trader.IsTemporary(err) {
+			return kucoinOrder{}, false, err
+		}
+		return kucoinOrder{}, false, nil
+	}
+	return o, o.ID != "", nil
+}
+
+// await polls the order until it is no longer active, cancelling it once the order timeout has passed or ctx is
+// done. On error it still returns the order as last seen, so what filled isn't lost.
+func (k *KuCoin) await(ctx context.Context, id string) (kucoinOrder, error) {
+	var (
+		deadline = time.Now().Add(k.timeout)
+		ticker   = time.NewTicker(k.pollInterval)
+		path     = "/api/v1/orders/" + url.PathEscape(id)
+		last     = kucoinOrder{ID: id}
+	)
+	defer ticker.Stop()
+
+	for {
+		if time.Now().After(deadline) {
+			return k.cancel(ctx, last, "order not filled in time, cancelling")
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			// left alone, the order would rest on the book with nothing watching it.
+			cctx, cancel := detach(ctx)
+			defer cancel()
+
+			final, err := k.cancel(cctx, last, "gave up on order, cancelling")
+			if err != nil {
+				return final, fmt.Errorf("%w, and %v", ctx.Err(), err)
+			}
+			return final, ctx.Err()
+		}
+
+		var o kucoinOrder
+		if err := k.call(background(ctx), http.MethodGet, path, nil, nil, &o); err != nil {
+			// the next poll is the retry.
+			logging.Warn(ctx, "failed to poll order", zap.String("order_id", id), zap.Error(err))
+			continue
+		}
+		if !o.IsActive {
+			return o, nil
+		}
+		last = o
+	}
+}
+
+// cancel cancels the order and returns what it came to. KuCoin only answers a cancel with the order's ID, so it is
+// fetched again afterwards, whether or not the cancel went through, as the order may have finished in the meantime.
+// Only if it is still active, or can't be fetched, is that an error; the order is then returned as last seen.
+func (k *KuCoin) cancel(ctx context.Context, order kucoinOrder, msg string) (kucoinOrder, error) {
+	logging.Info(ctx, msg, zap.String("order_id", order.ID))
+
+	path := "/api/v1/orders/" + url.PathEscape(order.ID)
+	cerr := k.do(ctx, http.MethodDelete, path, nil, nil, nil)
+
+	var o kucoinOrder
+	if err := k.do(ctx, http.MethodGet, path, nil, nil, &o); err != nil {
+		if cerr != nil {
+			return order, fmt.Errorf("failed to cancel order %s: %w", order.ID, cerr)
+		}
+		return order, fmt.Errorf("failed to get cancelled order %s: %w", order.ID, err)
+	}
+	if cerr != nil && o.IsActive {
+		return o, fmt.Errorf("failed to cancel order %s: %w", order.ID, cerr)
+	}
+	return o, nil
+}
+
+func fillFromKuCoinOrder(o kucoinOrder) (trader.Fill, error) {
+	size, err := parseDecimal(o.Size)
+	if err != nil {
+		return trader.Fill{}, fmt.Errorf("invalid order size: %w", err)
+	}
+	dealt, err := parseDecimal(o.DealSize)
+	if err != nil {
+		return trader.Fill{}, fmt.Errorf("invalid order deal size: %w", err)
+	}
+	funds, err := parseDecimal(o.DealFunds)
+	if err != nil {
+		return trader.Fill{}, fmt.Errorf("invalid order deal funds: %w", err)
+	}
+	fee, err := parseDecimal(o.Fee)
+	if err != nil {
+		return trader.Fill{}, fmt.Errorf("invalid order fee: %w", err)
+	}
+
+	price := decimal.Zero
+	if dealt.IsPositive() {
+		price = funds.Div(dealt)
+	}
+
+	return trader.Fill{
+		OrderID:       o.ID,
+		ClientOrderID: o.ClientOid,
+		Price:         price,
+		Amount:        dealt,
+		FilledTotal:   funds,
+		Fee:           fee,
+		FeeCurrency:   o.FeeCurrency,
+		Left:          size.Sub(dealt),
+	}, nil
+}
+
+// kucoinValueFees sets fill's QuoteFee. KuCoin takes its fee in the quote currency unless KCS deduction is on, which
+// is left out like a fee taken from the coin.
+func kucoinValueFees(fill trader.Fill) trader.Fill {
+	if strings.EqualFold(fill.FeeCurrency, quoteCurrency) {
+		fill.QuoteFee = fill.Fee
+	}
+	return fill
+}
@@ -0,0 +1,618 @@
+package exchange_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/moonr-app/crypto-signal-trading-bot/internal/exchange"
+	"github.com/moonr-app/crypto-signal-trading-bot/internal/trader"
+)
+
+var kucoinCreds = exchange.KuCoinCredentials{Key: "key", Secret: "secret", Passphrase: "passphrase"}
+
+// kucoinStub is a stand-in for the parts of the KuCoin API a test needs. Every request must be signed with
+// kucoinCreds, or it is turned away the way KuCoin would. Symbols are always served, and so are the trade account's
+// balances, from accounts.
+type kucoinStub struct {
+	mux      *http.ServeMux
+	srv      *httptest.Server
+	lock     sync.Mutex
+	hits     map[string]int
+	accounts []map[string]string
+}
+
+func newKuCoinStub(t *testing.T) *kucoinStub {
+	t.Helper()
+
+	s := &kucoinStub{mux: http.NewServeMux(), hits: make(map[string]int)}
+	s.srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.lock.Lock()
+		s.hits[r.Method+" "+r.URL.Path]++
+		s.lock.Unlock()
+
+		body, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+		if !kucoinSigned(r, body) {
+			kucoinFail(w, http.StatusUnauthorized, "400005", "Invalid KC-API-SIGN")
+			return
+		}
+		s.mux.ServeHTTP(w, r)
+	}))
+	t.Cleanup(s.srv.Close)
+
+	s.handle("/api/v2/symbols", func(w http.ResponseWriter, r *http.Request) {
+		kucoinData(w, []map[string]interface{}{
+			{"symbol": "RARE-USDT", "baseCurrency": "RARE", "quoteCurrency": "USDT", "baseMinSize": "1", "quoteMinSize": "0.1", "baseIncrement": "0.01", "priceIncrement": "0.0001", "enableTrading": true},
+			{"symbol": "HALT-USDT", "baseCurrency": "HALT", "quoteCurrency": "USDT", "baseMinSize": "1", "quoteMinSize": "0.1", "baseIncrement": "0.01", "priceIncrement": "0.0001", "enableTrading": false},
+		})
+	})
+
+	// plenty to buy with.
+	s.accounts = []map[string]string{{"currency": "USDT", "type": "trade", "balance": "1000", "available": "1000", "holds": "0"}}
+	s.handle("/api/v1/accounts", func(w http.ResponseWriter, r *http.Request) {
+		s.lock.Lock()
+		defer s.lock.Unlock()
+
+		var (
+			accounts []map[string]string
+			query    = r.URL.Query()
+		)
+		for _, a := range s.accounts {
+			if query.Get("type") != a["type"] || (query.Get("currency") != "" && query.Get("currency") != a["currency"]) {
+				continue
+			}
+			accounts = append(accounts, a)
+		}
+		kucoinData(w, accounts)
+	})
+	return s
+}
+
+func kucoinSigned(r *http.Request, body []byte) bool {
+	sign := func(msg string) string {
+		mac := hmac.New(sha256.New, []byte(kucoinCreds.Secret))
+		mac.Write([]byte(msg))
+		return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+	}
+
+	ts := r.Header.Get("KC-API-TIMESTAMP")
+	if _, err := strconv.ParseInt(ts, 10, 64); err != nil {
+		return false
+	}
+	return r.Header.Get("KC-API-KEY") == kucoinCreds.Key &&
+		r.Header.Get("KC-API-KEY-VERSION") == "2" &&
+		r.Header.Get("KC-API-PASSPHRASE") == sign(kucoinCreds.Passphrase) &&
+		r.Header.Get("KC-API-SIGN") == sign(ts+r.Method+r.URL.RequestURI()+string(body))
+}
+
+func kucoinData(w http.ResponseWriter, data interface{}) {
+	writeJSON(w, map[string]interface{}{"code": "200000", "data": data})
+}
+
+func kucoinFail(w http.ResponseWriter, status int, code, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]string{"code": code, "msg": msg})
+}
+
+func (s *kucoinStub) handle(pattern string, fn http.HandlerFunc) {
+	s.mux.HandleFunc(pattern, fn)
+}
+
+func (s *kucoinStub) count(methodAndPath string) int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.hits[methodAndPath]
+}
+
+func (s *kucoinStub) kucoin(t *testing.T, opts ...exchange.Option) *exchange.KuCoin {
+	t.Helper()
+
+	opts = append([]exchange.Option{
+		exchange.WithBasePath(s.srv.URL),
+		exchange.WithOrderPollInterval(time.Millisecond),
+		exchange.WithRetryPolicy(3, time.Millisecond),
+	}, opts...)
+
+	k, err := exchange.NewKuCoin(false, decimal.NewFromInt(10), kucoinCreds, opts...)
+	require.NoError(t, err)
+	return k
+}
+
+func (s *kucoinStub) stats(bid, ask string) {
+	s.handle("/api/v1/market/stats", func(w http.ResponseWriter, r *http.Request) {
+		kucoinData(w, map[string]interface{}{
+			"time": time.Now().UnixNano() / int64(time.Millisecond), "symbol": r.URL.Query().Get("symbol"),
+			"buy": bid, "sell": ask, "last": "0.5", "volValue": "123456.7",
+		})
+	})
+}
+
+// balance sets what the trade account has available of currency.
+func (s *kucoinStub) balance(currency, available string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for _, a := range s.accounts {
+		if a["currency"] == currency {
+			a["balance"], a["available"] = available, available
+			return
+		}
+	}
+	s.accounts = append(s.accounts, map[string]string{"currency": currency, "type": "trade", "balance": available, "available": available, "holds": "0"})
+}
+
+// orders serves POST /api/v1/orders by keeping each order, and GET on /api/v1/orders/{id} from dealt, which decides
+// how much of the nth order has filled and whether it is still active. DELETE cancels.
+func (s *kucoinStub) orders(t *testing.T, dealt func(n int, o map[string]string) (size string, active bool)) *[]map[string]string {
+	var (
+		orders    []map[string]string
+		cancelled = make(map[string]bool)
+	)
+
+	s.handle("/api/v1/orders", func(w http.ResponseWriter, r *http.Request) {
+		var o map[string]string
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&o))
+		orders = append(orders, o)
+		kucoinData(w, map[string]string{"orderId": strconv.Itoa(len(orders))})
+	})
+	s.handle("/api/v1/orders/", func(w http.ResponseWriter, r *http.Request) {
+		id := r.URL.Path[len("/api/v1/orders/"):]
+		n, err := strconv.Atoi(id)
+		require.NoError(t, err)
+		o := orders[n-1]
+
+		if r.Method == http.MethodDelete {
+			cancelled[id] = true
+			kucoinData(w, map[string][]string{"cancelledOrderIds": {id}})
+			return
+		}
+
+		size, active := dealt(n, o)
+		price := decimal.RequireFromString("0.5")
+		if o["price"] != "" {
+			price = decimal.RequireFromString(o["price"])
+		}
+		funds := decimal.RequireFromString(size).Mul(price)
+		kucoinData(w, map[string]interface{}{
+			"id": id, "clientOid": o["clientOid"], "symbol": o["symbol"], "side": o["side"], "type": o["type"],
+			"price": o["price"], "size": o["size"], "dealSize": size, "dealFunds": funds.String(),
+			"fee": funds.Mul(decimal.NewFromFloat(0.001)).String(), "feeCurrency": "USDT",
+			"isActive": active && !cancelled[id],
+		})
+	})
+	return &orders
+}
+
+func TestNewKuCoin(t *testing.T) {
+	t.Run("err given no passphrase", func(t *testing.T) {
+		_, err := exchange.NewKuCoin(false, decimal.NewFromInt(10), exchange.KuCoinCredentials{Key: "key", Secret: "secret"})
+		require.Error(t, err)
+	})
+
+	t.Run("needs no credentials in test mode", func(t *testing.T) {
+		_, err := exchange.NewKuCoin(true, decimal.NewFromInt(10), exchange.KuCoinCredentials{})
+		require.NoError(t, err)
+	})
+}
+
+func TestKuCoin_Errors(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("ErrExchangeAuth given KuCoin rejects the signature", func(t *testing.T) {
+		stub := newKuCoinStub(t)
+		k, err := exchange.NewKuCoin(false, decimal.NewFromInt(10), exchange.KuCoinCredentials{Key: "key", Secret: "wrong", Passphrase: "passphrase"}, exchange.WithBasePath(stub.srv.URL))
+		require.NoError(t, err)
+
+		_, err = k.CheckSupport(ctx, "RARE")
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, trader.ErrExchangeAuth))
+
+		var kerr *exchange.KuCoinError
+		require.True(t, errors.As(err, &kerr))
+		assert.Equal(t, "400005", kerr.Code)
+	})
+
+	t.Run("retries given it was rate limited", func(t *testing.T) {
+		stub := newKuCoinStub(t)
+		stub.balance("RARE", "3")
+		listed := stub.mux
+		stub.mux = http.NewServeMux()
+		stub.handle("/", listed.ServeHTTP)
+
+		var calls int
+		stub.handle("/api/v1/accounts", func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			if calls == 1 {
+				kucoinFail(w, http.StatusTooManyRequests, "429000", "Too Many Requests")
+				return
+			}
+			listed.ServeHTTP(w, r)
+		})
+
+		bal, err := stub.kucoin(t).GetBalanceForCoin(ctx, "RARE")
+		require.NoError(t, err)
+		assert.Equal(t, "3", bal.String())
+		assert.Equal(t, 2, calls)
+	})
+
+	t.Run("ErrInsufficientBalance given the account can't cover the order", func(t *testing.T) {
+		stub := newKuCoinStub(t)
+		stub.handle("/api/v1/orders", func(w http.ResponseWriter, r *http.Request) {
+			kucoinFail(w, http.StatusOK, "200004", "Balance insufficient!")
+		})
+
+		_, err := stub.kucoin(t).PurchaseCoin(ctx, "RARE", decimal.NewFromFloat(0.5), "")
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, trader.ErrInsufficientBalance))
+		assert.Equal(t, 1, stub.count("POST /api/v1/orders"))
+	})
+}
+
+func TestKuCoin_Support(t *testing.T) {
+	ctx := context.Background()
+	stub := newKuCoinStub(t)
+	k := stub.kucoin(t)
+
+	supported, err := k.CheckSupport(ctx, "RARE")
+	require.NoError(t, err)
+	assert.True(t, supported)
+
+	supported, err = k.CheckSupport(ctx, "HALT")
+	require.NoError(t, err)
+	assert.False(t, supported)
+
+	supported, err = k.CheckSupport(ctx, "NOPE")
+	require.NoError(t, err)
+	assert.False(t, supported)
+
+	resolved, err := k.ResolveSymbol(ctx, "rare", "", "")
+	require.NoError(t, err)
+	assert.Equal(t, "RARE", resolved)
+
+	_, err = k.ResolveSymbol(ctx, "NOPE", "", "")
+	assert.True(t, errors.Is(err, trader.ErrCoinUnsupported))
+
+	// symbols are listed once, and once more for the symbol that wasn't listed.
+	assert.Equal(t, 2, stub.count("GET /api/v2/symbols"))
+}
+
+func TestKuCoin_SymbolListedSinceRefresh(t *testing.T) {
+	ctx := context.Background()
+	stub := newKuCoinStub(t)
+	listed := stub.mux
+	stub.mux = http.NewServeMux()
+	stub.handle("/", listed.ServeHTTP)
+
+	var fresh bool
+	stub.handle("/api/v2/symbols", func(w http.ResponseWriter, r *http.Request) {
+		symbols := []map[string]interface{}{
+			{"symbol": "RARE-USDT", "baseCurrency": "RARE", "quoteCurrency": "USDT", "baseMinSize": "1", "quoteMinSize": "0.1", "baseIncrement": "0.01", "priceIncrement": "0.0001", "enableTrading": true},
+		}
+		if fresh {
+			symbols = append(symbols, map[string]interface{}{"symbol": "FRESH-USDT", "baseCurrency": "FRESH", "quoteCurrency": "USDT", "baseMinSize": "1", "quoteMinSize": "0.1", "baseIncrement": "0.01", "priceIncrement": "0.0001", "enableTrading": true})
+		}
+		kucoinData(w, symbols)
+	})
+	k := stub.kucoin(t)
+
+	supported, err := k.CheckSupport(ctx, "RARE")
+	require.NoError(t, err)
+	assert.True(t, supported)
+
+	fresh = true
+	resolved, err := k.ResolveSymbol(ctx, "fresh", "", "")
+	require.NoError(t, err)
+	assert.Equal(t, "FRESH", resolved)
+	assert.Equal(t, 2, stub.count("GET /api/v2/symbols"))
+
+	// another miss straight after doesn't list them all again.
+	_, err = k.ResolveSymbol(ctx, "NOPE", "", "")
+	assert.True(t, errors.Is(err, trader.ErrCoinUnsupported))
+	assert.Equal(t, 2, stub.count("GET /api/v2/symbols"))
+}
+
+func TestKuCoin_GetPrice(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("snapshot from the stats, cached for maxAge", func(t *testing.T) {
+		stub := newKuCoinStub(t)
+		stub.stats("0.49", "0.51")
+		k := stub.kucoin(t)
+
+		p, err := k.GetPrice(ctx, "RARE", time.Minute)
+		require.NoError(t, err)
+		assert.Equal(t, "0.49", p.Bid.String())
+		assert.Equal(t, "0.51", p.Ask.String())
+		assert.Equal(t, "0.5", p.Last.String())
+		assert.Equal(t, "123456.7", p.Volume.String())
+
+		_, err = k.GetPrice(ctx, "RARE", time.Minute)
+		require.NoError(t, err)
+		assert.Equal(t, 1, stub.count("GET /api/v1/market/stats"))
+	})
+
+	t.Run("ErrInvalidPair given KuCoin doesn't list it", func(t *testing.T) {
+		stub := newKuCoinStub(t)
+		stub.stats("0.49", "0.51")
+
+		_, err := stub.kucoin(t).GetPrice(ctx, "NOPE", time.Minute)
+		assert.True(t, errors.Is(err, trader.ErrInvalidPair))
+		assert.Equal(t, 0, stub.count("GET /api/v1/market/stats"))
+	})
+}
+
+func TestKuCoin_PurchaseCoin(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("places a rounded limit buy and waits for it to fill", func(t *testing.T) {
+		stub := newKuCoinStub(t)
+		orders := stub.orders(t, func(n int, o map[string]string) (string, bool) {
+			return o["size"], false
+		})
+
+		fill, err := stub.kucoin(t).PurchaseCoin(ctx, "RARE", decimal.NewFromFloat(0.33333), "sig-1")
+		require.NoError(t, err)
+
+		require.Len(t, *orders, 1)
+		o := (*orders)[0]
+		assert.Equal(t, "t-sig-1-1", o["clientOid"])
+		assert.Equal(t, "buy", o["side"])
+		assert.Equal(t, "RARE-USDT", o["symbol"])
+		assert.Equal(t, "limit", o["type"])
+		assert.Equal(t, "GTC", o["timeInForce"])
+		assert.Equal(t, "0.3334", o["price"])
+		assert.Equal(t, "30", o["size"])
+
+		assert.Equal(t, "1", fill.OrderID)
+		assert.Equal(t, "t-sig-1-1", fill.ClientOrderID)
+		assert.Equal(t, "30", fill.Amount.String())
+		assert.Equal(t, "10.002", fill.FilledTotal.String())
+		assert.Equal(t, "0.010002", fill.QuoteFee.String())
+		assert.True(t, fill.Left.IsZero())
+	})
+
+	t.Run("buys with what there is given the account is short", func(t *testing.T) {
+		stub := newKuCoinStub(t)
+		stub.balance("USDT", "4")
+		orders := stub.orders(t, func(n int, o map[string]string) (string, bool) {
+			return o["size"], false
+		})
+
+		fill, err := stub.kucoin(t).PurchaseCoin(ctx, "RARE", decimal.NewFromFloat(0.5), "")
+		require.NoError(t, err)
+		require.Len(t, *orders, 1)
+		assert.Equal(t, "8", (*orders)[0]["size"])
+		assert.Equal(t, "6", fill.Shortfall.String())
+	})
+
+	t.Run("ErrInsufficientBalance given less than the symbol's minimum order", func(t *testing.T) {
+		stub := newKuCoinStub(t)
+		stub.balance("USDT", "0.05")
+
+		_, err := stub.kucoin(t).PurchaseCoin(ctx, "RARE", decimal.NewFromFloat(0.5), "")
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, trader.ErrInsufficientBalance))
+		assert.Equal(t, 0, stub.count("POST /api/v1/orders"))
+	})
+
+	t.Run("cancels what is left after the order timeout", func(t *testing.T) {
+		stub := newKuCoinStub(t)
+		stub.orders(t, func(n int, o map[string]string) (string, bool) {
+			return "5", true
+		})
+
+		fill, err := stub.kucoin(t, exchange.WithOrderTimeout(20*time.Millisecond)).PurchaseCoin(ctx, "RARE", decimal.NewFromFloat(0.5), "")
+		require.NoError(t, err)
+		assert.Equal(t, 1, stub.count("DELETE /api/v1/orders/1"))
+		assert.Equal(t, "5", fill.Amount.String())
+		assert.Equal(t, "15", fill.Left.String())
+	})
+
+	t.Run("cancels the order and returns what filled given ctx is cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		stub := newKuCoinStub(t)
+		polls := 0
+		stub.orders(t, func(n int, o map[string]string) (string, bool) {
+			if polls++; polls == 2 {
+				cancel()
+			}
+			return "5", true
+		})
+
+		fill, err := stub.kucoin(t).PurchaseCoin(ctx, "RARE", decimal.NewFromFloat(0.5), "")
+		assert.True(t, errors.Is(err, context.Canceled))
+		assert.Equal(t, 1, stub.count("DELETE /api/v1/orders/1"))
+		assert.Equal(t, "5", fill.Amount.String())
+		assert.Equal(t, "15", fill.Left.String())
+	})
+
+	t.Run("ErrOrderNotFilled given nothing filled", func(t *testing.T) {
+		stub := newKuCoinStub(t)
+		stub.orders(t, func(n int, o map[string]string) (string, bool) {
+			return "0", true
+		})
+
+		_, err := stub.kucoin(t, exchange.WithOrderTimeout(5*time.Millisecond)).PurchaseCoin(ctx, "RARE", decimal.NewFromFloat(0.5), "")
+		assert.True(t, errors.Is(err, trader.ErrOrderNotFilled))
+	})
+
+	t.Run("ErrOrderTooSmall given the order is under the minimum", func(t *testing.T) {
+		stub := newKuCoinStub(t)
+
+		_, err := stub.kucoin(t).PurchaseCoin(ctx, "RARE", decimal.NewFromInt(20), "")
+		assert.True(t, errors.Is(err, trader.ErrOrderTooSmall))
+		assert.Equal(t, 0, stub.count("POST /api/v1/orders"))
+	})
+
+	t.Run("finds the order by its client ID given placing it failed", func(t *testing.T) {
+		stub := newKuCoinStub(t)
+		orders := stub.orders(t, func(n int, o map[string]string) (string, bool) {
+			return o["size"], false
+		})
+		placed := stub.mux
+		stub.mux = http.NewServeMux()
+		stub.handle("/api/v1/orders", func(w http.ResponseWriter, r *http.Request) {
+			// KuCoin took the order, but the answer never arrived.
+			placed.ServeHTTP(httptest.NewRecorder(), r)
+			kucoinFail(w, http.StatusServiceUnavailable, "", "")
+		})
+		stub.handle("/api/v1/orders/", placed.ServeHTTP)
+		stub.handle("/api/v2/symbols", placed.ServeHTTP)
+		stub.handle("/api/v1/accounts", placed.ServeHTTP)
+		stub.handle("/api/v1/order/client-order/", func(w http.ResponseWriter, r *http.Request) {
+			kucoinData(w, map[string]string{"id": "1", "clientOid": (*orders)[0]["clientOid"]})
+		})
+
+		fill, err := stub.kucoin(t).PurchaseCoin(ctx, "RARE", decimal.NewFromFloat(0.5), "sig")
+		require.NoError(t, err)
+		assert.Len(t, *orders, 1)
+		assert.Equal(t, "20", fill.Amount.String())
+		assert.Equal(t, 1, stub.count("GET /api/v1/order/client-order/t-sig-1"))
+	})
+
+	t.Run("places nothing in test mode", func(t *testing.T) {
+		k, err := exchange.NewKuCoin(true, decimal.NewFromInt(10), exchange.KuCoinCredentials{})
+		require.NoError(t, err)
+
+		fill, err := k.PurchaseCoin(ctx, "RARE", decimal.NewFromFloat(0.5), "")
+		require.NoError(t, err)
+		assert.Equal(t, "20", fill.Amount.String())
+	})
+}
+
+func TestKuCoin_Sell(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("rests at the bid, then sells the rest at market", func(t *testing.T) {
+		stub := newKuCoinStub(t)
+		stub.stats("0.49", "0.51")
+		stub.balance("RARE", "100")
+		orders := stub.orders(t, func(n int, o map[string]string) (string, bool) {
+			if n == 1 {
+				return "4", true
+			}
+			return o["size"], false
+		})
+
+		fill, err := stub.kucoin(t, exchange.WithOrderTimeout(20*time.Millisecond)).Sell(ctx, "RARE", decimal.NewFromInt(10), decimal.Zero, "t-sig-1")
+		require.NoError(t, err)
+
+		require.Len(t, *orders, 2)
+		assert.Equal(t, "limit", (*orders)[0]["type"])
+		assert.Equal(t, "0.49", (*orders)[0]["price"])
+		assert.Equal(t, "t-sig-1.s-1", (*orders)[0]["clientOid"])
+		assert.Equal(t, "market", (*orders)[1]["type"])
+		assert.Equal(t, "6", (*orders)[1]["size"])
+
+		assert.Equal(t, "10", fill.Amount.String())
+		assert.True(t, fill.Left.IsZero())
+		assert.Equal(t, "4.96", fill.FilledTotal.String())
+	})
+
+	t.Run("sells no more than the account holds", func(t *testing.T) {
+		stub := newKuCoinStub(t)
+		stub.stats("0.49", "0.51")
+		stub.balance("RARE", "7")
+		orders := stub.orders(t, func(n int, o map[string]string) (string, bool) {
+			return o["size"], false
+		})
+
+		fill, err := stub.kucoin(t).Sell(ctx, "RARE", decimal.NewFromInt(10), decimal.Zero, "")
+		require.NoError(t, err)
+		require.Len(t, *orders, 1)
+		assert.Equal(t, "7", (*orders)[0]["size"])
+		assert.Equal(t, "3", fill.Shortfall.String())
+	})
+}
+
+func TestKuCoin_Account(t *testing.T) {
+	var (
+		ctx     = context.Background()
+		since   = time.Now().Add(-10 * 24 * time.Hour).Truncate(time.Second)
+		sinceMs = since.Unix() * 1000
+		windows [][2]int64
+	)
+	stub := newKuCoinStub(t)
+	stub.accounts = []map[string]string{
+		{"currency": "rare", "type": "trade", "available": "3", "holds": "1"},
+		{"currency": "rare", "type": "main", "available": "100", "holds": "0"},
+	}
+	stub.handle("/api/v1/orders", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "active", r.URL.Query().Get("status"))
+		// one order a page, to check every page is read.
+		pages := map[string][]map[string]interface{}{
+			"1": {{"id": "1", "clientOid": "t-sig-1", "symbol": "RARE-USDT", "side": "sell", "size": "10", "dealSize": "4", "isActive": true}},
+			"2": {{"id": "2", "clientOid": "", "symbol": "RARE-USDT", "side": "buy", "size": "5", "dealSize": "0", "isActive": true}},
+		}
+		kucoinData(w, map[string]interface{}{"totalPage": 2, "items": pages[r.URL.Query().Get("currentPage")]})
+	})
+	stub.handle("/api/v1/fills", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "RARE-USDT", r.URL.Query().Get("symbol"))
+		start, err := strconv.ParseInt(r.URL.Query().Get("startAt"), 10, 64)
+		require.NoError(t, err)
+		end, err := strconv.ParseInt(r.URL.Query().Get("endAt"), 10, 64)
+		require.NoError(t, err)
+		windows = append(windows, [2]int64{start, end})
+
+		var items []map[string]interface{}
+		if start == sinceMs {
+			items = append(items, map[string]interface{}{"orderId": "1", "side": "sell", "price": "0.5", "size": "4", "createdAt": sinceMs + 1000})
+		}
+		kucoinData(w, map[string]interface{}{"totalPage": 1, "items": items})
+	})
+	stub.handle("/api/v1/trade-fees", func(w http.ResponseWriter, r *http.Request) {
+		kucoinData(w, []map[string]string{{"symbol": "RARE-USDT", "takerFeeRate": "0.0008", "makerFeeRate": "0.0008"}})
+	})
+	k := stub.kucoin(t)
+
+	holdings, err := k.Holdings(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "4", holdings["RARE"].Total().String())
+
+	orders, err := k.OpenOrders(ctx)
+	require.NoError(t, err)
+	require.Len(t, orders, 2)
+	assert.Equal(t, "RARE", orders[0].Coin)
+	assert.Equal(t, "1", orders[0].OrderID)
+	assert.Equal(t, "6", orders[0].Left.String())
+	assert.True(t, orders[0].Ours)
+	assert.False(t, orders[1].Ours)
+
+	trades, err := k.Trades(ctx, "RARE", since)
+	require.NoError(t, err)
+	require.Len(t, trades, 1)
+	assert.Equal(t, "4", trades[0].Amount.String())
+	assert.Equal(t, since.Unix()+1, trades[0].At.Unix())
+
+	// KuCoin lists at most a week of fills at once.
+	week := int64(7 * 24 * time.Hour / time.Millisecond)
+	require.Len(t, windows, 2)
+	assert.Equal(t, [2]int64{sinceMs, sinceMs + week}, windows[0])
+	assert.Equal(t, sinceMs+week, windows[1][0])
+	assert.LessOrEqual(t, windows[1][1]-windows[1][0], week)
+
+	rate, err := k.SellFeeRate(ctx, "RARE")
+	require.NoError(t, err)
+	assert.Equal(t, "0.0008", rate.String())
+}
@@ -0,0 +1,337 @@
+package exchange
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/antihax/optional"
+	"github.com/gateio/gateapi-go/v6"
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+
+	"github.com/moonr-app/crypto-signal-trading-bot/internal/shared/logging"
+	"github.com/moonr-app/crypto-signal-trading-bot/internal/trader"
+)
+
+const (
+	orderStatusOpen     = "open"
+	orderStatusFinished = "finished"
+
+	defaultOrderPollInterval = 500 * time.Millisecond
+	defaultOrderTimeout      = 30 * time.Second
+
+	// cleanupTimeout bounds cancelling an order after the caller's context is done.
+	cleanupTimeout = 10 * time.Second
+)
+
+// detachedContext keeps a context's values but not its deadline or cancellation, so an order can still be cancelled
+// after whatever placed it has given up.
+type detachedContext struct{ context.Context }
+
+func (detachedContext) Deadline() (time.Time, bool) { return time.Time{}, false }
+func (detachedContext) Done() <-chan struct{}       { return nil }
+func (detachedContext) Err() error                  { return nil }
+
+// detach is ctx without its cancellation, bounded by cleanupTimeout instead.
+func detach(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(detachedContext{ctx}, cleanupTimeout)
+}
+
+// orderManager places orders and follows them until they are finished with, so callers only ever see what
+// really filled. Anything still open after timeout, or once the caller's context is done, is cancelled, and whatever
+// filled before then is returned alongside any error.
+type orderManager struct {
+	api          *gateapi.APIClient
+	catalogue    *catalogue
+	retry        retryPolicy
+	pollInterval time.Duration
+	timeout      time.Duration
+}
+
+func (m *orderManager) place(ctx context.Context, order gateapi.Order) (trader.Fill, error) {
+	return m.placeFor(ctx, order, m.timeout)
+}
+
+// placeFor is place with its own timeout, for callers that re-quote more often than the order timeout.
+// The order is rounded to its pair's precision first, and never sent if it is under the pair's minimums.
+func (m *orderManager) placeFor(ctx context.Context, order gateapi.Order, timeout time.Duration) (trader.Fill, error) {
+	order, err := m.catalogue.conform(ctx, order)
+	if err != nil {
+		return trader.Fill{}, err
+	}
+
+	since := time.Now()
+	created, err := m.create(ctx, order)
+	if err != nil && ctx.Err() != nil && order.Text != "" {
+		// ctx may have been done with the order already on its way to gate.io, where it would rest unwatched.
+		return m.abandon(ctx, order, since, err)
+	}
+	if err != nil {
+		return trader.Fill{}, fmt.Errorf("failed to create order: %w", err)
+	}
+
+	final, err := m.await(ctx, created, timeout)
+	if err != nil {
+		// some of it may have filled before it went wrong, and that's the caller's all the same.
+		fill, ferr := fillFromOrder(final)
+		if ferr != nil {
+			return trader.Fill{}, err
+		}
+		return fill, err
+	}
+
+	fill, err := fillFromOrder(final)
+	if err != nil {
+		return trader.Fill{}, err
+	}
+
+	if fill.Amount.IsZero() {
+		return fill, fmt.Errorf("%w: order %s on %s", trader.ErrOrderNotFilled, final.Id, final.CurrencyPair)
+	}
+	return fill, nil
+}
+
+// create places order. Placing an order is only retried straight away when it was rate limited, as gate.io may have
+// taken it anyway after any other failure. For those, the order is looked for by its client ID first, and only
+// placed again if it isn't there, so nothing is ever bought or sold twice.
+func (m *orderManager) create(ctx context.Context, order gateapi.Order) (gateapi.Order, error) {
+	since := time.Now()
+
+	for attempt := 1; ; attempt++ {
+		var created gateapi.Order
+		err := m.retry.doIf(ctx, isRateLimited, func() (res *http.Response, err error) {
+			created, res, err = m.api.SpotApi.CreateOrder(ctx, order)
+			return res, err
+		})
+		if err == nil || order.Text == "" || !errors.Is(err, trader.ErrExchangeUnavailable) {
+			return created, err
+		}
+
+		found, ok, ferr := m.find(ctx, order, since)
+		if ferr != nil {
+			return gateapi.Order{}, fmt.Errorf("%w, and couldn't check whether it was placed: %v", err, ferr)
+		}
+		if ok {
+			logging.Info(ctx, "order was placed after all", zap.String("client_order_id", order.Text), zap.String("order_id", found.Id))
+			return found, nil
+		}
+		if attempt >= m.retry.attempts {
+			return gateapi.Order{}, err
+		}
+
+		logging.Warn(ctx, "order was not placed, placing it again", zap.String("client_order_id", order.Text), zap.Error(err))
+	}
+}
+
+// abandon cancels order if gate.io took it even though placing it failed with cause, returning whatever filled.
+func (m *orderManager) abandon(ctx context.Context, order gateapi.Order, since time.Time, cause error) (trader.Fill, error) {
+	cctx, cancel := detach(ctx)
+	defer cancel()
+
+	found, ok, err := m.find(cctx, order, since)
+	if err != nil {
+		return trader.Fill{}, fmt.Errorf("failed to create order: %w, and couldn't check whether it was placed: %v", cause, err)
+	}
+	if !ok {
+		return trader.Fill{}, fmt.Errorf("failed to create order: %w", cause)
+	}
+
+	if found.Status == orderStatusOpen {
+		found, err = m.cancel(cctx, found, "gave up on order, cancelling")
+		if err != nil {
+			cause = fmt.Errorf("%w, and %v", cause, err)
+		}
+	}
+
+	fill, ferr := fillFromOrder(found)
+	if ferr != nil {
+		return trader.Fill{}, cause
+	}
+	return fill, cause
+}
+
+// find looks for an order by its client ID, first among the open orders and then among those finished since.
+func (m *orderManager) find(ctx context.Context, order gateapi.Order, since time.Time) (gateapi.Order, bool, error) {
+	var open gateapi.Order
+	err := m.retry.do(ctx, func() (res *http.Response, err error) {
+		open, res, err = m.api.SpotApi.GetOrder(ctx, order.Text, order.CurrencyPair, &gateapi.GetOrderOpts{Account: optional.NewString(accountType)})
+		return res, err
+	})
+	if err == nil {
+		return open, true, nil
+	}
+	// gate.io only finds orders by client ID while they are open, anything else is looked for below.
+	if trader.IsTemporary(err) {
+		return gateapi.Order{}, false, err
+	}
+
+	var finished []gateapi.Order
+	err = m.retry.do(ctx, func() (res *http.Response, err error) {
+		finished, res, err = m.api.SpotApi.ListOrders(ctx, order.CurrencyPair, orderStatusFinished, &gateapi.ListOrdersOpts{
+			Account: optional.NewString(accountType),
+			Side:    optional.NewString(order.Side),
+			From:    optional.NewInt64(since.Unix()),
+		})
+		return res, err
+	})
+	if err != nil {
+		return gateapi.Order{}, false, err
+	}
+
+	for _, o := range finished {
+		if o.Text == order.Text {
+			return o, true, nil
+		}
+	}
+	return gateapi.Order{}, false, nil
+}
+
+// await polls the order until it is no longer open, cancelling it once the timeout has passed or ctx is done. On
+// error it still returns the order as last seen, so what filled isn't lost.
+func (m *orderManager) await(ctx context.Context, order gateapi.Order, timeout time.Duration) (gateapi.Order, error) {
+	var (
+		deadline = time.Now().Add(timeout)
+		ticker   = time.NewTicker(m.pollInterval)
+	)
+	defer ticker.Stop()
+
+	for order.Status == orderStatusOpen || order.Status == "" {
+		if time.Now().After(deadline) {
+			return m.cancel(ctx, order, "order not filled in time, cancelling")
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			// left alone, the order would rest on the book with nothing watching it.
+			cctx, cancel := detach(ctx)
+			defer cancel()
+
+			final, err := m.cancel(cctx, order, "gave up on order, cancelling")
+			if err != nil {
+				return final, fmt.Errorf("%w, and %v", ctx.Err(), err)
+			}
+			return final, ctx.Err()
+		}
+
+		o, res, err := m.api.SpotApi.GetOrder(background(ctx), order.Id, order.CurrencyPair, &gateapi.GetOrderOpts{Account: optional.NewString(accountType)})
+		if err := classify(res, err); err != nil {
+			// the next poll is the retry.
+			logging.Warn(ctx, "failed to poll order", zap.String("order_id", order.Id), zap.Error(err))
+			continue
+		}
+		order = o
+	}
+
+	return order, nil
+}
+
+// cancel cancels the order, returning it as it was left. If cancelling fails the order is fetched again, as it may
+// have finished in the meantime; only if it is still open, or can't be fetched, is that an error.
+func (m *orderManager) cancel(ctx context.Context, order gateapi.Order, msg string) (gateapi.Order, error) {
+	logging.Info(
+		ctx,
+		msg,
+		zap.String("order_id", order.Id),
+		zap.String("currency_pair", order.CurrencyPair),
+		zap.String("left", order.Left),
+	)
+
+	var cancelled gateapi.Order
+	err := m.retry.do(ctx, func() (res *http.Response, err error) {
+		cancelled, res, err = m.api.SpotApi.CancelOrder(ctx, order.Id, order.CurrencyPair, &gateapi.CancelOrderOpts{Account: optional.NewString(accountType)})
+		return res, err
+	})
+	if err == nil {
+		return cancelled, nil
+	}
+
+	var latest gateapi.Order
+	ferr := m.retry.do(ctx, func() (res *http.Response, err error) {
+		latest, res, err = m.api.SpotApi.GetOrder(ctx, order.Id, order.CurrencyPair, &gateapi.GetOrderOpts{Account: optional.NewString(accountType)})
+		return res, err
+	})
+	if ferr != nil {
+		return order, fmt.Errorf("failed to cancel order %s: %w", order.Id, err)
+	}
+	if latest.Status != orderStatusOpen {
+		return latest, nil
+	}
+	return latest, fmt.Errorf("failed to cancel order %s: %w", order.Id, err)
+}
+
+func fillFromOrder(o gateapi.Order) (trader.Fill, error) {
+	amount, err := parseDecimal(o.Amount)
+	if err != nil {
+		return trader.Fill{}, fmt.Errorf("invalid order amount: %w", err)
+	}
+	left, err := parseDecimal(o.Left)
+	if err != nil {
+		return trader.Fill{}, fmt.Errorf("invalid order left: %w", err)
+	}
+	filledTotal, err := parseDecimal(o.FilledTotal)
+	if err != nil {
+		return trader.Fill{}, fmt.Errorf("invalid order filled total: %w", err)
+	}
+	fee, err := parseDecimal(o.Fee)
+	if err != nil {
+		return trader.Fill{}, fmt.Errorf("invalid order fee: %w", err)
+	}
+	gtFee, err := parseDecimal(o.GtFee)
+	if err != nil {
+		return trader.Fill{}, fmt.Errorf("invalid order gt fee: %w", err)
+	}
+
+	filled := amount.Sub(left)
+
+	// fill_price is deprecated by gate.io in favour of filled_total, so work out the average price from that.
+	price := decimal.Zero
+	if filled.IsPositive() {
+		price = filledTotal.Div(filled)
+	}
+
+	return trader.Fill{
+		OrderID:       o.Id,
+		ClientOrderID: o.Text,
+		Price:         price,
+		Amount:        filled,
+		FilledTotal:   filledTotal,
+		Fee:           fee,
+		FeeCurrency:   o.FeeCurrency,
+		GtFee:         gtFee,
+		Left:          left,
+	}, nil
+}
+
+// mergeFills adds the fill of a follow-up order to what has been filled so far. Left is for the caller to work out,
+// as only it knows how much was wanted in total.
+func mergeFills(total trader.Fill, next trader.Fill) trader.Fill {
+	if next.OrderID != "" {
+		total.OrderID = next.OrderID
+		total.ClientOrderID = next.ClientOrderID
+	}
+	if next.FeeCurrency != "" {
+		total.FeeCurrency = next.FeeCurrency
+	}
+
+	total.Amount = total.Amount.Add(next.Amount)
+	total.FilledTotal = total.FilledTotal.Add(next.FilledTotal)
+	total.Fee = total.Fee.Add(next.Fee)
+	total.GtFee = total.GtFee.Add(next.GtFee)
+
+	if total.Amount.IsPositive() {
+		total.Price = total.FilledTotal.Div(total.Amount)
+	}
+	return total
+}
+
+// parseDecimal treats an empty string as zero, gate.io omits fields that haven't been set yet.
+func parseDecimal(s string) (decimal.Decimal, error) {
+	if s == "" {
+		return decimal.Zero, nil
+	}
+	return decimal.NewFromString(s)
+}
@@ -0,0 +1,116 @@
+package exchange
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gateio/gateapi-go/v6"
+	"github.com/shopspring/decimal"
+
+	"github.com/moonr-app/crypto-signal-trading-bot/internal/trader"
+)
+
+// OrderTooSmallError is returned instead of sending an order gate.io would reject for being under a pair's minimums.
+// It matches trader.ErrOrderTooSmall.
+type OrderTooSmallError struct {
+	CurrencyPair   string
+	Amount         decimal.Decimal
+	Total          decimal.Decimal
+	MinBaseAmount  decimal.Decimal
+	MinQuoteAmount decimal.Decimal
+}
+
+func (e *OrderTooSmallError) Error() string {
+	return fmt.Sprintf(
+		"order of %s (%s total) on %s is under the minimum of %s (%s total)",
+		e.Amount, e.Total, e.CurrencyPair, e.MinBaseAmount, e.MinQuoteAmount,
+	)
+}
+
+func (e *OrderTooSmallError) Unwrap() error {
+	return trader.ErrOrderTooSmall
+}
+
+// pairMeta is what gate.io needs an order on a pair to look like.
+type pairMeta struct {
+	amountPrecision int32
+	pricePrecision  int32
+	minBaseAmount   decimal.Decimal
+	minQuoteAmount  decimal.Decimal
+}
+
+func newPairMeta(p gateapi.CurrencyPair) (pairMeta, error) {
+	minBase, err := parseDecimal(p.MinBaseAmount)
+	if err != nil {
+		return pairMeta{}, fmt.Errorf("invalid min base amount for %s: %w", p.Id, err)
+	}
+	minQuote, err := parseDecimal(p.MinQuoteAmount)
+	if err != nil {
+		return pairMeta{}, fmt.Errorf("invalid min quote amount for %s: %w", p.Id, err)
+	}
+
+	return pairMeta{
+		amountPrecision: p.AmountPrecision,
+		pricePrecision:  p.Precision,
+		minBaseAmount:   minBase,
+		minQuoteAmount:  minQuote,
+	}, nil
+}
+
+// amount rounds down, so an order never asks for more than there is to spend or sell.
+func (m pairMeta) amount(d decimal.Decimal) decimal.Decimal {
+	return d.RoundFloor(m.amountPrecision)
+}
+
+// price rounds towards the market: buys up and sells down, so rounding never leaves an order behind the price it
+// was meant to take.
+func (m pairMeta) price(d decimal.Decimal, side string) decimal.Decimal {
+	if side == sideTypeBuy {
+		return d.RoundCeil(m.pricePrecision)
+	}
+	return d.RoundFloor(m.pricePrecision)
+}
+
+func (m pairMeta) check(currencyPair string, amount, price decimal.Decimal) error {
+	total := amount.Mul(price)
+	if amount.IsZero() || amount.LessThan(m.minBaseAmount) || total.LessThan(m.minQuoteAmount) {
+		return &OrderTooSmallError{
+			CurrencyPair:   currencyPair,
+			Amount:         amount,
+			Total:          total,
+			MinBaseAmount:  m.minBaseAmount,
+			MinQuoteAmount: m.minQuoteAmount,
+		}
+	}
+	return nil
+}
+
+// conform rounds order to what its pair allows and checks it isn't under the pair's minimums.
+func (c *catalogue) conform(ctx context.Context, order gateapi.Order) (gateapi.Order, error) {
+	m, err := c.get(ctx, order.CurrencyPair)
+	if err != nil {
+		return gateapi.Order{}, err
+	}
+
+	amount, err := decimal.NewFromString(order.Amount)
+	if err != nil {
+		return gateapi.Order{}, fmt.Errorf("invalid order amount: %w", err)
+	}
+	price, err := decimal.NewFromString(order.Price)
+	if err != nil {
+		return gateapi.Order{}, fmt.Errorf("invalid order price: %w", err)
+	}
+
+	rounded := m.price(price, order.Side)
+	if order.Side == sideTypeBuy && rounded.GreaterThan(price) {
+		// a buy's price rounds up, so the amount comes down to keep the total the order was sized to spend.
+		amount = amount.Mul(price).Div(rounded)
+	}
+	amount, price = m.amount(amount), rounded
+	if err := m.check(order.CurrencyPair, amount, price); err != nil {
+		return gateapi.Order{}, err
+	}
+
+	order.Amount, order.Price = amount.String(), price.String()
+	return order, nil
+}
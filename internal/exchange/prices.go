@@ -0,0 +1,181 @@
+package exchange
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/antihax/optional"
+	"github.com/gateio/gateapi-go/v6"
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+
+	"github.com/moonr-app/crypto-signal-trading-bot/internal/shared/logging"
+	"github.com/moonr-app/crypto-signal-trading-bot/internal/trader"
+)
+
+// priceCache holds the latest price snapshot of each pair, from whichever of the stream, the ticker poll or REST saw
+// it last.
+type priceCache struct {
+	lock  sync.Mutex
+	snaps map[string]trader.PriceSnapshot
+}
+
+func newPriceCache() *priceCache {
+	return &priceCache{snaps: make(map[string]trader.PriceSnapshot)}
+}
+
+// get returns pair's snapshot if it was observed within maxAge. Nothing is ever fresh enough for a maxAge of zero.
+func (c *priceCache) get(pair string, maxAge time.Duration) (trader.PriceSnapshot, bool) {
+	c.lock.Lock()
+	p, ok := c.snaps[pair]
+	c.lock.Unlock()
+
+	if !ok || p.Age() > maxAge || maxAge <= 0 {
+		return trader.PriceSnapshot{}, false
+	}
+	return p, true
+}
+
+func (c *priceCache) set(pair string, p trader.PriceSnapshot) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.snaps[pair] = p
+}
+
+// update changes part of pair's snapshot, leaving the prices apply doesn't touch as they were, and marks it observed now.
+func (c *priceCache) update(pair string, apply func(p *trader.PriceSnapshot)) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	p := c.snaps[pair]
+	apply(&p)
+	p.ObservedAt = time.Now()
+	c.snaps[pair] = p
+}
+
+func (c *priceCache) delete(pair string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	delete(c.snaps, pair)
+}
+
+// pollPrices lists every ticker each interval until ctx is done. It's how prices are cached when they aren't streamed.
+func (g *GateIO) pollPrices(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		// the next tick is the retry.
+		tickers, res, err := g.api.SpotApi.ListTickers(background(ctx), &gateapi.ListTickersOpts{})
+		if err := classify(res, err); err != nil {
+			if ctx.Err() == nil {
+				logging.Error(ctx, "failed to list tickers", zap.Error(err))
+			}
+			continue
+		}
+
+		storeTickers(ctx, g.prices, g.quotes, tickers, time.Now())
+	}
+}
+
+// storeTickers caches a snapshot of every pair in tickers quoted in one of quotes, observed at.
+func storeTickers(ctx context.Context, prices *priceCache, quotes []string, tickers []gateapi.Ticker, at time.Time) {
+	for _, t := range tickers {
+		if quotedIn(t.CurrencyPair, quotes) {
+			prices.set(t.CurrencyPair, snapshotFromTicker(ctx, t, at))
+		}
+	}
+}
+
+// GetPrice returns coin's latest prices in USDT, on the pair it is held through or else its most liquid one. See
+// heldRoute.
+func (g *GateIO) GetPrice(ctx context.Context, coin string, maxAge time.Duration) (trader.PriceSnapshot, error) {
+	r, err := g.heldRoute(ctx, coin)
+	if err != nil {
+		return trader.PriceSnapshot{}, err
+	}
+
+	p, err := g.pairPrice(ctx, r.pair, maxAge)
+	if err != nil {
+		return trader.PriceSnapshot{}, err
+	}
+	return r.reportPrice(p), nil
+}
+
+// pairPrice returns currencyPair's latest prices, in its quote currency. They come from the stream or the ticker
+// cache if they were observed within maxAge, or else from gate.io, in which case they are cached for the next call.
+func (g *GateIO) pairPrice(ctx context.Context, currencyPair string, maxAge time.Duration) (trader.PriceSnapshot, error) {
+	if g.stream != nil {
+		g.stream.watch(ctx, currencyPair)
+	}
+	if p, ok := g.prices.get(currencyPair, maxAge); ok && hasPrice(p) {
+		return p, nil
+	}
+
+	// a pair gate.io doesn't list is an error, not an empty ticker.
+	if _, err := g.catalogue.get(ctx, currencyPair); err != nil {
+		return trader.PriceSnapshot{}, err
+	}
+
+	var ticks []gateapi.Ticker
+	err := g.retry.do(ctx, func() (res *http.Response, err error) {
+		ticks, res, err = g.api.SpotApi.ListTickers(ctx, &gateapi.ListTickersOpts{CurrencyPair: optional.NewString(currencyPair)})
+		return res, err
+	})
+	if err != nil {
+		return trader.PriceSnapshot{}, err
+	}
+
+	if len(ticks) < 1 {
+		return trader.PriceSnapshot{}, fmt.Errorf("expected at least one ticker, got none")
+	}
+
+	logging.Info(
+		ctx,
+		"got tickers",
+		zap.String("last", ticks[0].Last),
+		zap.String("lowest_ask", ticks[0].LowestAsk),
+		zap.String("highest_bid", ticks[0].HighestBid),
+	)
+
+	p := snapshotFromTicker(ctx, ticks[0], time.Now())
+	g.prices.set(currencyPair, p)
+	return p, nil
+}
+
+// hasPrice is false for pairs that are listed but haven't traded yet, e.g. ahead of a listing.
+func hasPrice(p trader.PriceSnapshot) bool {
+	return p.Bid.IsPositive() || p.Ask.IsPositive() || p.Last.IsPositive()
+}
+
+func snapshotFromTicker(ctx context.Context, t gateapi.Ticker, at time.Time) trader.PriceSnapshot {
+	return trader.PriceSnapshot{
+		Bid:        parsePrice(ctx, t.CurrencyPair, t.HighestBid, decimal.Zero),
+		Ask:        parsePrice(ctx, t.CurrencyPair, t.LowestAsk, decimal.Zero),
+		Last:       parsePrice(ctx, t.CurrencyPair, t.Last, decimal.Zero),
+		Volume:     parsePrice(ctx, t.CurrencyPair, t.QuoteVolume, decimal.Zero),
+		ObservedAt: at,
+	}
+}
+
+// parsePrice returns prev if v is empty or not a number.
+func parsePrice(ctx context.Context, pair, v string, prev decimal.Decimal) decimal.Decimal {
+	if v == "" {
+		return prev
+	}
+	d, err := decimal.NewFromString(v)
+	if err != nil {
+		logging.Error(ctx, "invalid ticker price", zap.String("ticker", pair), zap.String("price", v))
+		return prev
+	}
+	return d
+}
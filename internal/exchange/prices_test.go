@@ -0,0 +1,86 @@
+package exchange_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gateio/gateapi-go/v6"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/moonr-app/crypto-signal-trading-bot/internal/exchange"
+)
+
+func TestGateIO_GetPrice(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("snapshot has every price the ticker quotes", func(t *testing.T) {
+		stub := newGateStub(t)
+		stub.handle("/spot/tickers", func(w http.ResponseWriter, r *http.Request) {
+			writeJSON(w, []gateapi.Ticker{{CurrencyPair: "RARE_USDT", Last: "0.5", LowestAsk: "0.51", HighestBid: "0.49", QuoteVolume: "12000"}})
+		})
+
+		price, err := stub.gate(t).GetPrice(ctx, "RARE", time.Minute)
+		require.NoError(t, err)
+		assert.Equal(t, "0.49", price.Bid.String())
+		assert.Equal(t, "0.51", price.Ask.String())
+		assert.Equal(t, "0.5", price.Last.String())
+		assert.Equal(t, "12000", price.Volume.String())
+		assert.Less(t, int64(price.Age()), int64(time.Second))
+		assert.Equal(t, "0.51", price.BuyPrice().String())
+		assert.Equal(t, "0.49", price.SellPrice().String())
+	})
+
+	t.Run("served from the cache within maxAge", func(t *testing.T) {
+		stub := tickerStub(t)
+		g := stub.gate(t)
+
+		for i := 0; i < 3; i++ {
+			_, err := g.GetPrice(ctx, "RARE", time.Minute)
+			require.NoError(t, err)
+		}
+		assert.Equal(t, 1, stub.count("GET /spot/tickers"))
+
+		time.Sleep(20 * time.Millisecond)
+		_, err := g.GetPrice(ctx, "RARE", 10*time.Millisecond)
+		require.NoError(t, err)
+		assert.Equal(t, 2, stub.count("GET /spot/tickers"))
+	})
+
+	t.Run("err given gate.io doesn't list the pair", func(t *testing.T) {
+		stub := tickerStub(t)
+
+		_, err := stub.gate(t).GetPrice(ctx, "NOPE", time.Minute)
+		require.Error(t, err)
+		assert.Equal(t, 0, stub.count("GET /spot/tickers"))
+	})
+
+	t.Run("polled prices are used until the context ends", func(t *testing.T) {
+		stub := tickerStub(t)
+
+		pollCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		g, err := exchange.NewGateIO(pollCtx, false, decimal.NewFromInt(10), 5*time.Millisecond, exchange.WithBasePath(stub.srv.URL))
+		require.NoError(t, err)
+
+		// the first poll is cached by the time the second is made.
+		require.Eventually(t, func() bool {
+			return stub.count("GET /spot/tickers") > 1
+		}, time.Second, time.Millisecond)
+
+		price, err := g.GetPrice(ctx, "RARE", time.Second)
+		require.NoError(t, err)
+		assert.Equal(t, "0.5", price.Last.String())
+		assert.Equal(t, 0, stub.count("GET /spot/currency_pairs/RARE_USDT"))
+
+		cancel()
+		time.Sleep(10 * time.Millisecond)
+		polls := stub.count("GET /spot/tickers")
+		time.Sleep(30 * time.Millisecond)
+		assert.Equal(t, polls, stub.count("GET /spot/tickers"))
+	})
+}
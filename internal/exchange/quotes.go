@@ -0,0 +1,286 @@
+package exchange
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+
+	"github.com/moonr-app/crypto-signal-trading-bot/internal/shared/logging"
+	"github.com/moonr-app/crypto-signal-trading-bot/internal/trader"
+)
+
+const (
+	// routeLiquidityMaxAge is how old the prices can be that decide which of a coin's pairs is the most liquid.
+	// Volumes are over 24 hours, so they hardly move from one minute to the next.
+	routeLiquidityMaxAge = time.Minute
+	// rateMaxAge is how old the price of a quote currency can be when converting to or from the reporting currency.
+	rateMaxAge = time.Minute
+)
+
+// defaultQuoteCurrencies are what coins are bought with, given gate.io lists a pair for them.
+var defaultQuoteCurrencies = []string{quoteCurrency, "USDC", "BTC", "ETH"}
+
+// route is the pair a coin is traded through, and what the pair's quote currency is worth in the reporting
+// currency. Prices and fills go in and out of GateIO in the reporting currency, and are converted at rate.
+type route struct {
+	pair  string
+	quote string
+	// rate is the price of one of quote in the reporting currency.
+	rate decimal.Decimal
+}
+
+func pairID(coin, quote string) string {
+	return strings.ToUpper(coin) + "_" + quote
+}
+
+// quoteOf is the quote currency of a pair such as BTC_USDT.
+func quoteOf(currencyPair string) string {
+	if i := strings.LastIndexByte(currencyPair, '_'); i >= 0 {
+		return currencyPair[i+1:]
+	}
+	return ""
+}
+
+func quotedIn(currencyPair string, quotes []string) bool {
+	q := quoteOf(currencyPair)
+	for _, quote := range quotes {
+		if q == quote {
+			return true
+		}
+	}
+	return false
+}
+
+func (r route) reported() bool {
+	return r.quote == quoteCurrency
+}
+
+// report converts d from the quote currency into the reporting currency.
+func (r route) report(d decimal.Decimal) decimal.Decimal {
+	if r.reported() {
+		return d
+	}
+	return d.Mul(r.rate)
+}
+
+// toQuote converts d from the reporting currency into the quote currency.
+func (r route) toQuote(d decimal.Decimal) decimal.Decimal {
+	if r.reported() {
+		return d
+	}
+	return d.Div(r.rate)
+}
+
+func (r route) reportPrice(p trader.PriceSnapshot) trader.PriceSnapshot {
+	p.Bid, p.Ask, p.Last, p.Volume = r.report(p.Bid), r.report(p.Ask), r.report(p.Last), r.report(p.Volume)
+	return p
+}
+
+// reportFill converts what fill was traded at into the reporting currency. Its fee is left in whatever currency it
+// was paid in, valueFees values it.
+func (r route) reportFill(fill trader.Fill) trader.Fill {
+	fill.Price, fill.FilledTotal = r.report(fill.Price), r.report(fill.FilledTotal)
+	return fill
+}
+
+// route picks which of coin's pairs to trade through: whichever has traded the most over the last 24 hours, valued
+// in the reporting currency. Ties go to the quote currency configured first. It returns trader.ErrInvalidPair if
+// coin has no pair in any of the quote currencies.
+func (g *GateIO) route(ctx context.Context, coin string) (route, error) {
+	pairs, err := g.pairsFor(ctx, coin)
+	if err != nil {
+		return route{}, err
+	}
+	return g.mostLiquid(ctx, pairs)
+}
+
+// buyRoute picks which of coin's pairs a buy goes through: the most liquid of those whose quote currency the account
+// has enough of available to spend the whole budget. Given none has, it is the USDT pair, or the most liquid if coin
+// has none, and the buy spends what there is.
+func (g *GateIO) buyRoute(ctx context.Context, coin string) (route, error) {
+	pairs, err := g.pairsFor(ctx, coin)
+	if err != nil {
+		return route{}, err
+	}
+	if len(pairs) == 1 {
+		return g.routeFor(ctx, pairs[0])
+	}
+
+	holdings, err := g.cachedHoldings(ctx, buyBalanceMaxAge)
+	if err != nil {
+		return route{}, err
+	}
+
+	var funded []string
+	for _, pair := range pairs {
+		r, err := g.routeFor(ctx, pair)
+		if err != nil {
+			logging.Warn(ctx, "failed to value quote balance, not buying through it", zap.String("currency_pair", pair), zap.Error(err))
+			continue
+		}
+		if r.report(holdings[r.quote].Available).GreaterThanOrEqual(g.toSpend) {
+			funded = append(funded, pair)
+		}
+	}
+	if len(funded) > 0 {
+		return g.mostLiquid(ctx, funded)
+	}
+
+	for _, pair := range pairs {
+		if quoteOf(pair) == quoteCurrency {
+			return g.routeFor(ctx, pair)
+		}
+	}
+	return g.mostLiquid(ctx, pairs)
+}
+
+// heldRoute is the route coin is held through: the pair it was bought through, so it's sold back into the currency
+// that paid for it. Coins bought without a pair recorded go through route.
+func (g *GateIO) heldRoute(ctx context.Context, coin string) (route, error) {
+	pair, err := g.held.get(ctx, coin)
+	if err != nil {
+		return route{}, fmt.Errorf("failed to find which pair %s was bought through: %w", coin, err)
+	}
+	if pair == "" {
+		return g.route(ctx, coin)
+	}
+	return g.routeFor(ctx, pair)
+}
+
+// pairsFor is coin's pairs in the configured quote currencies. It returns trader.ErrInvalidPair if there are none.
+func (g *GateIO) pairsFor(ctx context.Context, coin string) ([]string, error) {
+	pairs, err := g.catalogue.pairsFor(ctx, coin)
+	if err != nil {
+		return nil, err
+	}
+	if len(pairs) == 0 {
+		return nil, fmt.Errorf("%w: no pair for %s in %s", trader.ErrInvalidPair, coin, strings.Join(g.quotes, ", "))
+	}
+	return pairs, nil
+}
+
+// mostLiquid is the route through whichever of pairs has traded the most over the last 24 hours. See route.
+func (g *GateIO) mostLiquid(ctx context.Context, pairs []string) (route, error) {
+	if len(pairs) == 1 {
+		return g.routeFor(ctx, pairs[0])
+	}
+
+	var (
+		best      route
+		bestValue = decimal.NewFromInt(-1)
+		firstErr  error
+	)
+	for _, pair := range pairs {
+		r, err := g.routeFor(ctx, pair)
+		if err == nil {
+			var p trader.PriceSnapshot
+			p, err = g.pairPrice(ctx, pair, routeLiquidityMaxAge)
+			if err == nil {
+				if value := r.report(p.Volume); value.GreaterThan(bestValue) {
+					best, bestValue = r, value
+				}
+				continue
+			}
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	if best.pair == "" {
+		return route{}, firstErr
+	}
+	return best, nil
+}
+
+// routeFor is the route through currencyPair, with its quote currency's rate.
+func (g *GateIO) routeFor(ctx context.Context, currencyPair string) (route, error) {
+	r := route{pair: currencyPair, quote: quoteOf(currencyPair), rate: decimal.NewFromInt(1)}
+	if r.reported() {
+		return r, nil
+	}
+
+	p, err := g.pairPrice(ctx, r.quote+"_"+quoteCurrency, rateMaxAge)
+	if err != nil {
+		return route{}, fmt.Errorf("failed to get the rate of %s: %w", r.quote, err)
+	}
+	if !p.Last.IsPositive() {
+		return route{}, fmt.Errorf("no rate for %s", r.quote)
+	}
+	r.rate = p.Last
+	return r, nil
+}
+
+// heldPairs remembers the pair each coin was bought through. Positions bought before the bot started are loaded from
+// positions the first time one is asked for.
+type heldPairs struct {
+	positions PositionStore
+
+	lock sync.Mutex
+	// pairs is keyed by upper case coin.
+	pairs  map[string]string
+	loaded bool
+}
+
+func newHeldPairs(positions PositionStore) *heldPairs {
+	return &heldPairs{positions: positions, pairs: make(map[string]string)}
+}
+
+func (h *heldPairs) set(coin, pair string) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.pairs[strings.ToUpper(coin)] = pair
+}
+
+// get returns the pair coin was bought through, or "" if it isn't known.
+func (h *heldPairs) get(ctx context.Context, coin string) (string, error) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	if !h.loaded && h.positions != nil {
+		details, err := h.positions.GetCoinsToConsider(ctx)
+		if err != nil {
+			return "", err
+		}
+		for _, d := range details {
+			key := strings.ToUpper(d.Coin)
+			if _, ok := h.pairs[key]; !ok && d.Pair != "" {
+				h.pairs[key] = d.Pair
+			}
+		}
+	}
+	h.loaded = true
+	return h.pairs[strings.ToUpper(coin)], nil
+}
+
+// ParseQuoteCurrencies parses a comma separated list of quote currencies, e.g. "USDT,BTC". Order matters, the first is
+// preferred when pairs are as liquid as each other. USDT is added if it's missing, as it's what everything is
+// reported in.
+func ParseQuoteCurrencies(s string) ([]string, error) {
+	var quotes []string
+	seen := make(map[string]bool)
+	for _, q := range strings.Split(s, ",") {
+		q = strings.ToUpper(strings.TrimSpace(q))
+		if q == "" {
+			continue
+		}
+		if seen[q] {
+			return nil, fmt.Errorf("quote currency %s is given twice", q)
+		}
+		seen[q] = true
+		quotes = append(quotes, q)
+	}
+
+	if len(quotes) == 0 {
+		return nil, nil
+	}
+	if !seen[quoteCurrency] {
+		quotes = append(quotes, quoteCurrency)
+	}
+	return quotes, nil
+}
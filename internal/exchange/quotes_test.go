@@ -0,0 +1,192 @@
+package exchange_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gateio/gateapi-go/v6"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/moonr-app/crypto-signal-trading-bot/internal/exchange"
+	"github.com/moonr-app/crypto-signal-trading-bot/internal/trader"
+)
+
+// quotedStub lists RARE in USDT and BTC, with far more of it traded against BTC. A BTC is worth 50000 USDT.
+func quotedStub(t *testing.T) *gateStub {
+	return quoted(newGateStub(t))
+}
+
+func quoted(stub *gateStub) *gateStub {
+	stub.pairs = append(stub.pairs,
+		gateapi.CurrencyPair{Id: "RARE_BTC", Base: "RARE", Quote: "BTC", AmountPrecision: 8, Precision: 10, TradeStatus: "tradable"},
+		gateapi.CurrencyPair{Id: "BTC_USDT", Base: "BTC", Quote: "USDT", AmountPrecision: 8, Precision: 2, TradeStatus: "tradable"},
+	)
+
+	tickers := map[string]gateapi.Ticker{
+		"RARE_USDT": {CurrencyPair: "RARE_USDT", Last: "0.5", LowestAsk: "0.51", HighestBid: "0.49", QuoteVolume: "1000"},
+		"RARE_BTC":  {CurrencyPair: "RARE_BTC", Last: "0.00001", LowestAsk: "0.0000102", HighestBid: "0.0000098", QuoteVolume: "1"},
+		"BTC_USDT":  {CurrencyPair: "BTC_USDT", Last: "50000"},
+	}
+	stub.handle("/spot/tickers", func(w http.ResponseWriter, r *http.Request) {
+		if pair := r.URL.Query().Get("currency_pair"); pair != "" {
+			writeJSON(w, []gateapi.Ticker{tickers[pair]})
+			return
+		}
+		var all []gateapi.Ticker
+		for _, t := range tickers {
+			all = append(all, t)
+		}
+		writeJSON(w, all)
+	})
+	return stub
+}
+
+func TestGateIO_QuoteCurrencies(t *testing.T) {
+	var (
+		ctx    = context.Background()
+		ladder = []decimal.Decimal{decimal.NewFromInt(1)}
+	)
+
+	t.Run("prices are from the most liquid pair, in USDT", func(t *testing.T) {
+		price, err := quotedStub(t).gate(t).GetPrice(ctx, "RARE", time.Minute)
+		require.NoError(t, err)
+		assert.Equal(t, "0.5", price.Last.String())
+		assert.Equal(t, "0.49", price.Bid.String())
+		assert.Equal(t, "0.51", price.Ask.String())
+		assert.Equal(t, "50000", price.Volume.String())
+	})
+
+	t.Run("buys through the most liquid pair with the budget converted", func(t *testing.T) {
+		stub := quotedStub(t)
+		stub.orderBook([][]string{{"0.00001", "100"}})
+		orders := stub.iocHandler(t, func(n int, o gateapi.Order) (string, string) {
+			return "0", "0.0002"
+		})
+
+		fill, err := stub.gate(t, exchange.WithSweepBuy(ladder...)).PurchaseCoin(ctx, "RARE", decimal.NewFromFloat(0.5), "")
+		require.NoError(t, err)
+
+		require.Len(t, *orders, 1)
+		assert.Equal(t, "RARE_BTC", (*orders)[0].CurrencyPair)
+		assert.Equal(t, "0.00001", (*orders)[0].Price)
+
+		amount, err := decimal.NewFromString((*orders)[0].Amount)
+		require.NoError(t, err)
+		assert.Equal(t, "20", amount.String())
+
+		assert.Equal(t, "10", fill.FilledTotal.String())
+		assert.Equal(t, "0.5", fill.Price.Round(4).String())
+		assert.Equal(t, "RARE_BTC", fill.Pair)
+	})
+
+	t.Run("buys through a pair the account can pay for in full", func(t *testing.T) {
+		stub := quotedStub(t)
+		stub.accounts = []gateapi.SpotAccount{{Currency: "USDT", Available: "1000"}, {Currency: "BTC", Available: "0.0001"}}
+		orders := stub.iocHandler(t, func(n int, o gateapi.Order) (string, string) {
+			return "0", "10"
+		})
+
+		fill, err := stub.gate(t).PurchaseCoin(ctx, "RARE", decimal.NewFromFloat(0.5), "")
+		require.NoError(t, err)
+
+		require.Len(t, *orders, 1)
+		assert.Equal(t, "RARE_USDT", (*orders)[0].CurrencyPair)
+		assert.Equal(t, "RARE_USDT", fill.Pair)
+		assert.True(t, fill.Shortfall.IsZero())
+	})
+
+	t.Run("sells back through the pair it was bought through", func(t *testing.T) {
+		stub := newSellStub(t, "20", [][]string{{"0.5", "100"}})
+		quoted(stub.gateStub)
+		stub.polled = func(o gateapi.Order) gateapi.Order {
+			return withStatus(o, "closed", "0", "10")
+		}
+		positions := fakePositions{{Coin: "RARE", Pair: "RARE_USDT"}}
+
+		fill, err := stub.gate(t, exchange.WithPositions(positions)).Sell(ctx, "RARE", decimal.NewFromInt(20), decimal.Zero, "")
+		require.NoError(t, err)
+
+		orders := stub.posted()
+		require.Len(t, orders, 1)
+		assert.Equal(t, "RARE_USDT", orders[0].CurrencyPair)
+		assert.Equal(t, "RARE_USDT", fill.Pair)
+		assert.Equal(t, "10", fill.FilledTotal.String())
+	})
+
+	t.Run("prices a held coin on the pair it was bought through", func(t *testing.T) {
+		positions := fakePositions{{Coin: "RARE", Pair: "RARE_USDT"}, {Coin: "OTHER"}}
+
+		price, err := quotedStub(t).gate(t, exchange.WithPositions(positions)).GetPrice(ctx, "RARE", time.Minute)
+		require.NoError(t, err)
+		assert.Equal(t, "1000", price.Volume.String())
+	})
+
+	t.Run("err given the positions can't be read", func(t *testing.T) {
+		_, err := quotedStub(t).gate(t, exchange.WithPositions(failingPositions{})).GetPrice(ctx, "RARE", time.Minute)
+		assert.True(t, errors.Is(err, errPositions))
+	})
+
+	t.Run("a limit buy is priced in the pair's quote currency", func(t *testing.T) {
+		stub := quotedStub(t)
+		orders := stub.iocHandler(t, func(n int, o gateapi.Order) (string, string) {
+			return "0", "0.0002"
+		})
+
+		fill, err := stub.gate(t).PurchaseCoin(ctx, "RARE", decimal.NewFromFloat(0.5), "")
+		require.NoError(t, err)
+
+		require.Len(t, *orders, 1)
+		assert.Equal(t, "RARE_BTC", (*orders)[0].CurrencyPair)
+		assert.Equal(t, "0.00001", (*orders)[0].Price)
+		assert.Equal(t, "20", (*orders)[0].Amount)
+		assert.Equal(t, "10", fill.FilledTotal.String())
+	})
+
+	t.Run("only the configured quote currencies are used", func(t *testing.T) {
+		stub := quotedStub(t)
+		stub.orderBook([][]string{{"0.5", "100"}})
+		orders := stub.iocHandler(t, func(n int, o gateapi.Order) (string, string) {
+			return "0", "10"
+		})
+
+		g := stub.gate(t, exchange.WithSweepBuy(ladder...), exchange.WithQuoteCurrencies("USDT"))
+		fill, err := g.PurchaseCoin(ctx, "RARE", decimal.NewFromFloat(0.5), "")
+		require.NoError(t, err)
+
+		require.Len(t, *orders, 1)
+		assert.Equal(t, "RARE_USDT", (*orders)[0].CurrencyPair)
+		assert.Equal(t, "10", fill.FilledTotal.String())
+	})
+}
+
+var errPositions = errors.New("positions unavailable")
+
+type failingPositions struct{}
+
+func (failingPositions) GetCoinsToConsider(ctx context.Context) ([]trader.SellingDetails, error) {
+	return nil, errPositions
+}
+
+func TestParseQuoteCurrencies(t *testing.T) {
+	t.Run("keeps the order given and adds USDT", func(t *testing.T) {
+		quotes, err := exchange.ParseQuoteCurrencies(" btc, eth ")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"BTC", "ETH", "USDT"}, quotes)
+	})
+
+	t.Run("nothing given empty", func(t *testing.T) {
+		quotes, err := exchange.ParseQuoteCurrencies("")
+		require.NoError(t, err)
+		assert.Empty(t, quotes)
+	})
+
+	t.Run("err given a currency twice", func(t *testing.T) {
+		_, err := exchange.ParseQuoteCurrencies("USDT,BTC,usdt")
+		require.Error(t, err)
+	})
+}
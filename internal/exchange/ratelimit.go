@@ -0,0 +1,179 @@
+package exchange
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// backgroundReserve is the fraction of each bucket that background calls leave for trading, so polling and price
+// caching never use up the requests an order needs.
+const backgroundReserve = 0.2
+
+// RateLimit is how many requests can be made to a group of endpoints in Per.
+type RateLimit struct {
+	Requests int
+	Per      time.Duration
+}
+
+// gate.io's published limits. Market data is limited per IP, the rest per API key.
+var (
+	defaultPublicLimit  = RateLimit{Requests: 200, Per: 10 * time.Second}
+	defaultPrivateLimit = RateLimit{Requests: 150, Per: 10 * time.Second}
+	defaultOrderLimit   = RateLimit{Requests: 10, Per: time.Second}
+)
+
+// KuCoin's limits, kept under those of its tightest private endpoint, the fills list.
+var (
+	defaultKuCoinPublicLimit  = RateLimit{Requests: 30, Per: 3 * time.Second}
+	defaultKuCoinPrivateLimit = RateLimit{Requests: 18, Per: 3 * time.Second}
+	defaultKuCoinOrderLimit   = RateLimit{Requests: 45, Per: 3 * time.Second}
+)
+
+// endpoints groups an exchange's paths by the limit they count against. Any path in neither group is private.
+type endpoints struct {
+	// public are the market data endpoints the bot uses, which don't need a key.
+	public []string
+	// orders are the endpoints that place or cancel orders when called with anything but GET.
+	orders []string
+}
+
+var gateEndpoints = endpoints{
+	public: []string{
+		"/spot/currencies",
+		"/spot/currency_pairs",
+		"/spot/tickers",
+		"/spot/order_book",
+		"/spot/trades",
+		"/wallet/currency_chains",
+		"/futures/usdt/contracts",
+	},
+	orders: []string{
+		"/spot/orders",
+		"/spot/price_orders",
+		"/spot/batch_orders",
+		"/spot/cancel_batch_orders",
+		"/futures/usdt/orders",
+		"/futures/usdt/price_orders",
+	},
+}
+
+var kucoinEndpoints = endpoints{
+	public: []string{
+		"/api/v2/symbols",
+		"/api/v1/market/",
+	},
+	orders: []string{
+		"/api/v1/orders",
+	},
+}
+
+type priorityKey struct{}
+
+// background marks calls made with ctx as ones that can wait, e.g. polling, so they never take the last of a bucket.
+func background(ctx context.Context) context.Context {
+	return context.WithValue(ctx, priorityKey{}, true)
+}
+
+func isBackground(ctx context.Context) bool {
+	b, _ := ctx.Value(priorityKey{}).(bool)
+	return b
+}
+
+// rateLimiter holds every request to an exchange until its endpoint group's bucket has a token for it.
+type rateLimiter struct {
+	next      http.RoundTripper
+	endpoints endpoints
+	public    *tokenBucket
+	private   *tokenBucket
+	orders    *tokenBucket
+}
+
+func newRateLimiter(next http.RoundTripper, endpoints endpoints, public, private, orders RateLimit) *rateLimiter {
+	return &rateLimiter{
+		next:      next,
+		endpoints: endpoints,
+		public:    newTokenBucket(public),
+		private:   newTokenBucket(private),
+		orders:    newTokenBucket(orders),
+	}
+}
+
+func (l *rateLimiter) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	if err := l.bucket(req).wait(ctx, isBackground(ctx)); err != nil {
+		return nil, err
+	}
+	return l.next.RoundTrip(req)
+}
+
+func (l *rateLimiter) bucket(req *http.Request) *tokenBucket {
+	if req.Method != http.MethodGet {
+		for _, p := range l.endpoints.orders {
+			if strings.Contains(req.URL.Path, p) {
+				return l.orders
+			}
+		}
+	}
+	for _, p := range l.endpoints.public {
+		if strings.Contains(req.URL.Path, p) {
+			return l.public
+		}
+	}
+	return l.private
+}
+
+// tokenBucket lets through a burst of requests and then one every perToken.
+type tokenBucket struct {
+	lock     sync.Mutex
+	tokens   float64
+	burst    float64
+	reserve  float64
+	perToken time.Duration
+	last     time.Time
+}
+
+func newTokenBucket(limit RateLimit) *tokenBucket {
+	burst := float64(limit.Requests)
+	return &tokenBucket{
+		tokens:   burst,
+		burst:    burst,
+		reserve:  burst * backgroundReserve,
+		perToken: limit.Per / time.Duration(limit.Requests),
+		last:     time.Now(),
+	}
+}
+
+// wait blocks until a token is free, or ctx is done. Background callers also wait for the reserve to be free.
+func (b *tokenBucket) wait(ctx context.Context, background bool) error {
+	need := 1.0
+	if background {
+		need = math.Min(need+b.reserve, b.burst)
+	}
+
+	for {
+		b.lock.Lock()
+		now := time.Now()
+		b.tokens = math.Min(b.burst, b.tokens+float64(now.Sub(b.last))/float64(b.perToken))
+		b.last = now
+
+		if b.tokens >= need {
+			b.tokens--
+			b.lock.Unlock()
+			return nil
+		}
+		wait := time.Duration((need - b.tokens) * float64(b.perToken))
+		b.lock.Unlock()
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package exchange_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gateio/gateapi-go/v6"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/moonr-app/crypto-signal-trading-bot/internal/exchange"
+)
+
+func TestGateIO_RateLimits(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("holds calls once the burst is used up", func(t *testing.T) {
+		stub := newGateStub(t)
+		stub.handle("/spot/tickers", func(w http.ResponseWriter, r *http.Request) {
+			writeJSON(w, []gateapi.Ticker{{CurrencyPair: "RARE_USDT", Last: "0.5"}})
+		})
+		g := stub.gate(t, exchange.WithRateLimits(exchange.RateLimit{Requests: 2, Per: 100 * time.Millisecond}, exchange.RateLimit{}, exchange.RateLimit{}))
+
+		start := time.Now()
+		for i := 0; i < 3; i++ {
+			_, err := g.GetPrice(ctx, "RARE", 0)
+			require.NoError(t, err)
+		}
+
+		// the pairs and three tickers, the first two from the burst and the rest a token every 50ms.
+		assert.GreaterOrEqual(t, int64(time.Since(start)), int64(90*time.Millisecond))
+		assert.Equal(t, 3, stub.count("GET /spot/tickers"))
+	})
+
+	t.Run("order polling leaves the last of the budget for trading", func(t *testing.T) {
+		stub := newGateStub(t)
+		stub.orderHandler(t, func(n int) gateapi.Order {
+			if n < 4 {
+				return gateapi.Order{Id: "123", CurrencyPair: "RARE_USDT", Status: "open", Amount: "20", Left: "20"}
+			}
+			return gateapi.Order{Id: "123", CurrencyPair: "RARE_USDT", Status: "closed", Amount: "20", Left: "0", FilledTotal: "10"}
+		}, func() gateapi.Order {
+			return gateapi.Order{Id: "123", CurrencyPair: "RARE_USDT", Status: "cancelled", Amount: "20", Left: "20"}
+		})
+		stub.accounts = append(stub.accounts, gateapi.SpotAccount{Currency: "RARE", Available: "20"})
+		// a balance check and four polls for the first buy, a balance check for the second and one to spare.
+		g := stub.gate(t, exchange.WithRateLimits(exchange.RateLimit{}, exchange.RateLimit{Requests: 7, Per: time.Hour}, exchange.RateLimit{}))
+
+		_, err := g.PurchaseCoin(ctx, "RARE", decimal.NewFromFloat(0.5), "")
+		require.NoError(t, err)
+		require.Equal(t, 4, stub.count("GET /spot/orders/123"))
+
+		// one request left, which polling won't touch...
+		pollCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+		defer cancel()
+		_, err = g.PurchaseCoin(pollCtx, "RARE", decimal.NewFromFloat(0.5), "")
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, context.DeadlineExceeded))
+		assert.Equal(t, 4, stub.count("GET /spot/orders/123"))
+		// the order given up on is still cancelled, cancelling draws on the order bucket.
+		assert.Equal(t, 1, stub.count("DELETE /spot/orders/123"))
+
+		// ...but trading will.
+		tradeCtx, cancel := context.WithTimeout(ctx, time.Second)
+		defer cancel()
+		bal, err := g.GetBalanceForCoin(tradeCtx, "RARE")
+		require.NoError(t, err)
+		assert.Equal(t, "20", bal.String())
+	})
+}
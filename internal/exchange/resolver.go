@@ -0,0 +1,276 @@
+package exchange
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gateio/gateapi-go/v6"
+	"go.uber.org/zap"
+
+	"github.com/moonr-app/crypto-signal-trading-bot/internal/shared/logging"
+	"github.com/moonr-app/crypto-signal-trading-bot/internal/trader"
+)
+
+const (
+	resolverRefreshInterval = 5 * time.Minute
+	tradeStatusTradable     = "tradable"
+	denominationPrefix      = "1000"
+)
+
+// ErrContractMismatch is returned when the exchange lists the symbol, but as a different token to the signal's
+// contract. It is also a trader.ErrCoinUnsupported.
+var ErrContractMismatch = fmt.Errorf("%w: contract is a different token", trader.ErrCoinUnsupported)
+
+type Doer interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+type currencyChain struct {
+	Chain           string `json:"chain"`
+	ContractAddress string `json:"contract_address"`
+}
+
+// Resolver maps the symbol a signal was announced with onto the gate.io currency that should be traded for it.
+type Resolver struct {
+	api     *gateapi.APIClient
+	doer    Doer
+	aliases map[string]string
+	// quotes are the quote currencies a coin can be bought with, it's tradable given a pair in any of them.
+	quotes []string
+
+	lock        *sync.Mutex
+	tradable    map[string]struct{}
+	refreshedAt time.Time
+}
+
+func NewResolver(api *gateapi.APIClient, doer Doer, aliases map[string]string) *Resolver {
+	upper := make(map[string]string, len(aliases))
+	for k, v := range aliases {
+		upper[strings.ToUpper(k)] = strings.ToUpper(v)
+	}
+
+	return &Resolver{
+		api:      api,
+		doer:     doer,
+		aliases:  upper,
+		quotes:   []string{quoteCurrency},
+		lock:     &sync.Mutex{},
+		tradable: make(map[string]struct{}),
+	}
+}
+
+// Resolve returns the gate.io currency for symbol.
+// An alias always wins. Otherwise the symbol and its 1000x denomination (1000SATS vs SATS) are both candidates,
+// and if more than one of them is tradable the symbol is ambiguous and trader.ErrCoinAmbiguous is returned.
+// If the signal came with a contract address, the candidate must be that contract on gate.io.
+// Given no candidate was tradable at the last refresh, each is looked up on gate.io, as it may have been listed
+// since. Symbols with no tradable candidate even then return trader.ErrCoinUnsupported.
+func (r *Resolver) Resolve(ctx context.Context, symbol, chain, contract string) (string, error) {
+	if err := r.refresh(ctx); err != nil {
+		return "", fmt.Errorf("failed to refresh currency pairs: %w", err)
+	}
+
+	var candidates []string
+	for _, c := range r.candidates(symbol) {
+		if r.isTradable(c) {
+			candidates = append(candidates, c)
+		}
+	}
+	if len(candidates) == 0 {
+		var err error
+		if candidates, err = r.listedSince(ctx, r.candidates(symbol)); err != nil {
+			return "", fmt.Errorf("failed to look up %s: %w", symbol, err)
+		}
+	}
+
+	if contract != "" {
+		var err error
+		candidates, err = r.matchContract(ctx, candidates, chain, contract)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	switch len(candidates) {
+	case 0:
+		return "", fmt.Errorf("%w: %s has no tradable gate.io pair", trader.ErrCoinUnsupported, symbol)
+	case 1:
+		return candidates[0], nil
+	default:
+		return "", fmt.Errorf("%w: %s could be any of %s", trader.ErrCoinAmbiguous, symbol, strings.Join(candidates, ", "))
+	}
+}
+
+func (r *Resolver) candidates(symbol string) []string {
+	symbol = strings.ToUpper(strings.TrimSpace(symbol))
+
+	if alias, ok := r.aliases[symbol]; ok {
+		return []string{alias}
+	}
+
+	if strings.HasPrefix(symbol, denominationPrefix) && len(symbol) > len(denominationPrefix) {
+		return []string{symbol, strings.TrimPrefix(symbol, denominationPrefix)}
+	}
+	return []string{symbol, denominationPrefix + symbol}
+}
+
+func (r *Resolver) isTradable(currency string) bool {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	_, ok := r.tradable[currency]
+	return ok
+}
+
+// listedSince is those of candidates gate.io has a tradable pair for now, looked up one by one, for coins listed since
+// the pairs were last refreshed. Each one found is remembered until the next refresh.
+func (r *Resolver) listedSince(ctx context.Context, candidates []string) ([]string, error) {
+	var listed []string
+	for _, c := range candidates {
+		for _, q := range r.quotes {
+			p, res, err := r.api.SpotApi.GetCurrencyPair(ctx, pairID(c, q))
+			if err := classify(res, err); err != nil {
+				if errors.Is(err, trader.ErrInvalidPair) {
+					continue
+				}
+				return nil, err
+			}
+			if p.TradeStatus != tradeStatusTradable {
+				continue
+			}
+
+			r.lock.Lock()
+			r.tradable[c] = struct{}{}
+			r.lock.Unlock()
+			listed = append(listed, c)
+			break
+		}
+	}
+	return listed, nil
+}
+
+// matchContract keeps the candidates listed on gate.io with the given contract. Candidates gate.io has no chain
+// information for are kept, since there is nothing to check them against.
+func (r *Resolver) matchContract(ctx context.Context, candidates []string, chain, contract string) ([]string, error) {
+	var (
+		matched   []string
+		unchecked []string
+	)
+
+	for _, c := range candidates {
+		chains, err := r.currencyChains(ctx, c)
+		if err != nil {
+			logging.Warn(ctx, "failed to get currency chains", zap.String("currency", c), zap.Error(err))
+			unchecked = append(unchecked, c)
+			continue
+		}
+
+		known := false
+		for _, cc := range chains {
+			if cc.ContractAddress == "" || (chain != "" && !strings.EqualFold(cc.Chain, chain)) {
+				continue
+			}
+			known = true
+			if strings.EqualFold(cc.ContractAddress, contract) {
+				matched = append(matched, c)
+				break
+			}
+		}
+
+		if !known {
+			unchecked = append(unchecked, c)
+		}
+	}
+
+	if len(matched) > 0 {
+		return matched, nil
+	}
+
+	if len(candidates) > 0 && len(unchecked) == 0 {
+		return nil, fmt.Errorf("%w: gate.io lists a different token to %s", ErrContractMismatch, contract)
+	}
+	return unchecked, nil
+}
+
+func (r *Resolver) refresh(ctx context.Context) error {
+	r.lock.Lock()
+	fresh := time.Since(r.refreshedAt) < resolverRefreshInterval
+	r.lock.Unlock()
+	if fresh {
+		return nil
+	}
+
+	pairs, res, err := r.api.SpotApi.ListCurrencyPairs(ctx)
+	if err := classify(res, err); err != nil {
+		return err
+	}
+
+	r.update(pairs)
+	return nil
+}
+
+// update replaces what is tradable with pairs, e.g. when they have been listed for something else.
+func (r *Resolver) update(pairs []gateapi.CurrencyPair) {
+	tradable := make(map[string]struct{}, len(pairs))
+	for _, p := range pairs {
+		if p.TradeStatus == tradeStatusTradable && quotedIn(pairID(p.Base, p.Quote), r.quotes) {
+			tradable[strings.ToUpper(p.Base)] = struct{}{}
+		}
+	}
+
+	r.lock.Lock()
+	r.tradable = tradable
+	r.refreshedAt = time.Now()
+	r.lock.Unlock()
+}
+
+// currencyChains calls the public currency_chains endpoint, which the generated client doesn't cover.
+func (r *Resolver) currencyChains(ctx context.Context, currency string) ([]currencyChain, error) {
+	u := r.api.GetConfig().BasePath + "/wallet/currency_chains?currency=" + url.QueryEscape(currency)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create currency chains req: %w", err)
+	}
+	req.Header.Add("Accept", "application/json")
+
+	res, err := r.doer.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error doing: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("bad response from gate.io: %d", res.StatusCode)
+	}
+
+	var chains []currencyChain
+	if err := json.NewDecoder(res.Body).Decode(&chains); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return chains, nil
+}
+
+// ParseAliases parses a comma separated list of symbol=currency pairs, e.g. "1000SATS=SATS,LUNA=LUNC".
+func ParseAliases(s string) (map[string]string, error) {
+	aliases := make(map[string]string)
+	if strings.TrimSpace(s) == "" {
+		return aliases, nil
+	}
+
+	for _, pair := range strings.Split(s, ",") {
+		parts := strings.SplitN(pair, "=", 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" || strings.TrimSpace(parts[1]) == "" {
+			return nil, fmt.Errorf("invalid symbol alias %q", pair)
+		}
+		aliases[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+	}
+	return aliases, nil
+}
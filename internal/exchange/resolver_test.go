@@ -0,0 +1,171 @@
+package exchange_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gateio/gateapi-go/v6"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/moonr-app/crypto-signal-trading-bot/internal/exchange"
+	"github.com/moonr-app/crypto-signal-trading-bot/internal/trader"
+)
+
+const contract = "0x1111111111111111111111111111111111111111"
+
+// writeJSON writes v the way gate.io does; the generated client refuses to decode without the content type.
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func newResolverServer(t *testing.T, pairs []gateapi.CurrencyPair, chains map[string][]map[string]string) *httptest.Server {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/spot/currency_pairs", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, pairs)
+	})
+	mux.HandleFunc("/spot/currency_pairs/", func(w http.ResponseWriter, r *http.Request) {
+		for _, p := range pairs {
+			if r.URL.Path == "/spot/currency_pairs/"+p.Id {
+				writeJSON(w, p)
+				return
+			}
+		}
+		gateError(http.StatusBadRequest, "INVALID_CURRENCY_PAIR")(w, r)
+	})
+	mux.HandleFunc("/wallet/currency_chains", func(w http.ResponseWriter, r *http.Request) {
+		c, ok := chains[r.URL.Query().Get("currency")]
+		if !ok {
+			c = []map[string]string{}
+		}
+		writeJSON(w, c)
+	})
+
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func newResolver(srv *httptest.Server, aliases map[string]string) *exchange.Resolver {
+	cfg := gateapi.NewConfiguration()
+	cfg.BasePath = srv.URL
+	return exchange.NewResolver(gateapi.NewAPIClient(cfg), http.DefaultClient, aliases)
+}
+
+func tradable(base string) gateapi.CurrencyPair {
+	return gateapi.CurrencyPair{Id: base + "_USDT", Base: base, Quote: "USDT", TradeStatus: "tradable"}
+}
+
+func TestResolver_Resolve(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("resolves a listed symbol regardless of case", func(t *testing.T) {
+		srv := newResolverServer(t, []gateapi.CurrencyPair{tradable("RARE")}, nil)
+
+		cur, err := newResolver(srv, nil).Resolve(ctx, "rare", "", "")
+		require.NoError(t, err)
+		assert.Equal(t, "RARE", cur)
+	})
+
+	t.Run("resolves a 1000x denomination to the listed currency", func(t *testing.T) {
+		srv := newResolverServer(t, []gateapi.CurrencyPair{tradable("SATS")}, nil)
+
+		cur, err := newResolver(srv, nil).Resolve(ctx, "1000SATS", "", "")
+		require.NoError(t, err)
+		assert.Equal(t, "SATS", cur)
+	})
+
+	t.Run("refuses given more than one candidate is listed", func(t *testing.T) {
+		srv := newResolverServer(t, []gateapi.CurrencyPair{tradable("SATS"), tradable("1000SATS")}, nil)
+
+		_, err := newResolver(srv, nil).Resolve(ctx, "1000sats", "", "")
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, trader.ErrCoinAmbiguous))
+	})
+
+	t.Run("alias wins over ambiguity", func(t *testing.T) {
+		srv := newResolverServer(t, []gateapi.CurrencyPair{tradable("SATS"), tradable("1000SATS")}, nil)
+
+		cur, err := newResolver(srv, map[string]string{"1000sats": "sats"}).Resolve(ctx, "1000SATS", "", "")
+		require.NoError(t, err)
+		assert.Equal(t, "SATS", cur)
+	})
+
+	t.Run("unsupported given no tradable pair", func(t *testing.T) {
+		untradable := tradable("RARE")
+		untradable.TradeStatus = "untradable"
+		srv := newResolverServer(t, []gateapi.CurrencyPair{untradable, {Id: "RARE_BTC", Base: "RARE", Quote: "BTC", TradeStatus: "tradable"}}, nil)
+
+		_, err := newResolver(srv, nil).Resolve(ctx, "RARE", "", "")
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, trader.ErrCoinUnsupported))
+	})
+
+	t.Run("resolves a symbol listed since the pairs were refreshed", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/spot/currency_pairs":
+				writeJSON(w, []gateapi.CurrencyPair{})
+			case "/spot/currency_pairs/FRESH_USDT":
+				writeJSON(w, tradable("FRESH"))
+			default:
+				gateError(http.StatusBadRequest, "INVALID_CURRENCY_PAIR")(w, r)
+			}
+		}))
+		defer srv.Close()
+		resolver := newResolver(srv, nil)
+
+		_, err := resolver.Resolve(ctx, "RARE", "", "")
+		require.True(t, errors.Is(err, trader.ErrCoinUnsupported))
+
+		// FRESH was listed after the refresh above, so only the live lookup finds it.
+		cur, err := resolver.Resolve(ctx, "fresh", "", "")
+		require.NoError(t, err)
+		assert.Equal(t, "FRESH", cur)
+	})
+
+	t.Run("contract picks between candidates", func(t *testing.T) {
+		srv := newResolverServer(t, []gateapi.CurrencyPair{tradable("SATS"), tradable("1000SATS")}, map[string][]map[string]string{
+			"SATS":     {{"chain": "ETH", "contract_address": "0x2222222222222222222222222222222222222222"}},
+			"1000SATS": {{"chain": "ETH", "contract_address": contract}},
+		})
+
+		cur, err := newResolver(srv, nil).Resolve(ctx, "1000SATS", "ETH", contract)
+		require.NoError(t, err)
+		assert.Equal(t, "1000SATS", cur)
+	})
+
+	t.Run("unsupported given gate.io lists a different contract", func(t *testing.T) {
+		srv := newResolverServer(t, []gateapi.CurrencyPair{tradable("PEPE")}, map[string][]map[string]string{
+			"PEPE": {{"chain": "ETH", "contract_address": "0x2222222222222222222222222222222222222222"}},
+		})
+
+		_, err := newResolver(srv, nil).Resolve(ctx, "PEPE", "ETH", contract)
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, trader.ErrCoinUnsupported))
+	})
+
+	t.Run("trusts the symbol given gate.io has no chain information", func(t *testing.T) {
+		srv := newResolverServer(t, []gateapi.CurrencyPair{tradable("PEPE")}, nil)
+
+		cur, err := newResolver(srv, nil).Resolve(ctx, "PEPE", "ETH", contract)
+		require.NoError(t, err)
+		assert.Equal(t, "PEPE", cur)
+	})
+}
+
+func TestParseAliases(t *testing.T) {
+	a, err := exchange.ParseAliases("1000SATS=SATS, LUNA=LUNC")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"1000SATS": "SATS", "LUNA": "LUNC"}, a)
+
+	_, err = exchange.ParseAliases("LUNA=")
+	require.Error(t, err)
+}
@@ -0,0 +1,60 @@
+package exchange
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/moonr-app/crypto-signal-trading-bot/internal/shared/logging"
+	"github.com/moonr-app/crypto-signal-trading-bot/internal/trader"
+)
+
+const (
+	defaultRetryAttempts = 3
+	defaultRetryBackoff  = 250 * time.Millisecond
+	maxRetryBackoff      = 5 * time.Second
+)
+
+// retryPolicy retries exchange calls that failed for reasons that may go away, doubling the wait each time.
+type retryPolicy struct {
+	attempts int
+	backoff  time.Duration
+}
+
+// do makes call, retrying it while it fails with a temporary error. The error returned is always classified.
+func (p retryPolicy) do(ctx context.Context, call func() (*http.Response, error)) error {
+	return p.doIf(ctx, trader.IsTemporary, call)
+}
+
+// doIf is do for calls that are only safe to retry for some temporary errors. Placing an order is the main one:
+// after a server error gate.io may have placed it anyway, whereas a rate limited request was never looked at.
+func (p retryPolicy) doIf(ctx context.Context, retryable func(error) bool, call func() (*http.Response, error)) error {
+	wait := p.backoff
+
+	for attempt := 1; ; attempt++ {
+		err := classify(call())
+		if err == nil || attempt >= p.attempts || !retryable(err) {
+			return err
+		}
+
+		logging.Warn(ctx, "exchange call failed, retrying", zap.Int("attempt", attempt), zap.Duration("wait", wait), zap.Error(err))
+
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return err
+		}
+
+		wait *= 2
+		if wait > maxRetryBackoff {
+			wait = maxRetryBackoff
+		}
+	}
+}
+
+func isRateLimited(err error) bool {
+	return errors.Is(err, trader.ErrRateLimited)
+}
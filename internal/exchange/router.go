@@ -0,0 +1,379 @@
+package exchange
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+
+	"github.com/moonr-app/crypto-signal-trading-bot/internal/shared/logging"
+	"github.com/moonr-app/crypto-signal-trading-bot/internal/trader"
+)
+
+const (
+	// VenueGateIO and VenueKuCoin are the names positions are recorded against.
+	VenueGateIO = "gateio"
+	VenueKuCoin = "kucoin"
+
+	// routePriceMaxAge is how old a venue's price can be when comparing liquidity. It's the same age the buyer
+	// allows, so the price the buyer asks for next is already cached.
+	routePriceMaxAge = time.Minute
+)
+
+// minLiquidityShare is how much of the most liquid venue's volume a venue needs for its priority to count. Below it,
+// a coin is bought where it trades more, as a thin book costs more in slippage than any preference is worth.
+var minLiquidityShare = decimal.NewFromFloat(0.5)
+
+// VenueExchange is everything the buyer and the seller need of an exchange.
+type VenueExchange interface {
+	trader.ExchangePurchaser
+	trader.SellingExchange
+}
+
+// Venue is an exchange the Router can trade on.
+type Venue struct {
+	Name     string
+	Exchange VenueExchange
+}
+
+// PositionStore is where the Router finds out which venue each open position was bought on, and GateIO which pair,
+// e.g. after a restart.
+type PositionStore interface {
+	GetCoinsToConsider(ctx context.Context) ([]trader.SellingDetails, error)
+}
+
+// Router spreads trading over several venues. Each signalled coin is bought on a venue that supports it, preferring
+// venues earlier in the list unless one further down is much more liquid. The venue a coin was bought on is
+// remembered, so everything the seller does with it goes to that venue.
+type Router struct {
+	venues    []Venue
+	positions PositionStore
+
+	lock sync.Mutex
+	// placed is the venue each coin is being bought on or is held on, by upper case coin.
+	placed map[string]Venue
+	// symbols is what each venue calls a resolved coin, by upper case coin and then venue name.
+	symbols map[string]map[string]string
+}
+
+// NewRouter routes over venues, given in order of priority. Positions without a venue, bought before the bot traded
+// on more than one, are taken to be on the first.
+func NewRouter(positions PositionStore, venues ...Venue) (*Router, error) {
+	if len(venues) == 0 {
+		return nil, errors.New("router needs at least one venue")
+	}
+
+	seen := make(map[string]bool, len(venues))
+	for _, v := range venues {
+		switch {
+		case v.Name == "":
+			return nil, errors.New("every venue needs a name")
+		case seen[v.Name]:
+			return nil, fmt.Errorf("venue %s is given twice", v.Name)
+		}
+		seen[v.Name] = true
+	}
+
+	return &Router{
+		venues:    venues,
+		positions: positions,
+		placed:    make(map[string]Venue),
+		symbols:   make(map[string]map[string]string),
+	}, nil
+}
+
+// ResolveSymbol resolves coin on every venue, and returns the symbol from the first, in priority order, that lists
+// it. What each venue resolved it to is remembered, and that venue is only ever asked about the coin by its own
+// symbol, e.g. 1000SATS on one and SATS on another. A coin any venue finds ambiguous, or lists as a different token
+// to the contract, is returned as that error straight away. It returns trader.ErrCoinUnsupported only if no venue
+// lists it.
+func (r *Router) ResolveSymbol(ctx context.Context, coin, chain, contract string) (string, error) {
+	var (
+		resolved = make(map[string]string, len(r.venues))
+		first    string
+		firstErr error
+	)
+	for _, v := range r.venues {
+		symbol, err := v.Exchange.ResolveSymbol(ctx, coin, chain, contract)
+		switch {
+		case err == nil:
+			resolved[v.Name] = symbol
+			if first == "" {
+				first = symbol
+			}
+		case errors.Is(err, trader.ErrCoinAmbiguous), errors.Is(err, ErrContractMismatch):
+			return "", fmt.Errorf("%s: %w", v.Name, err)
+		case !errors.Is(err, trader.ErrCoinUnsupported) && firstErr == nil:
+			firstErr = fmt.Errorf("%s: %w", v.Name, err)
+		}
+	}
+
+	if first == "" {
+		if firstErr != nil {
+			return "", firstErr
+		}
+		return "", fmt.Errorf("%w: %s", trader.ErrCoinUnsupported, coin)
+	}
+
+	r.lock.Lock()
+	r.symbols[strings.ToUpper(first)] = resolved
+	r.lock.Unlock()
+	return first, nil
+}
+
+// symbolOn is what venue v calls coin, and whether v lists it. Coins that weren't resolved through the router,
+// e.g. positions held from before a restart, are taken to be called the same everywhere.
+func (r *Router) symbolOn(v Venue, coin string) (string, bool) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	resolved, ok := r.symbols[strings.ToUpper(coin)]
+	if !ok {
+		return coin, true
+	}
+	symbol, ok := resolved[v.Name]
+	return symbol, ok
+}
+
+// CheckSupport picks the venue coin will be bought on, and reports whether there is one. See pick.
+func (r *Router) CheckSupport(ctx context.Context, coin string) (bool, error) {
+	v, ok, err := r.pick(ctx, coin)
+	if err != nil || !ok {
+		return false, err
+	}
+
+	logging.Info(ctx, "routing coin", zap.String("coin", coin), zap.String("venue", v.Name))
+
+	r.lock.Lock()
+	r.placed[strings.ToUpper(coin)] = v
+	r.lock.Unlock()
+	return true, nil
+}
+
+type routeCandidate struct {
+	venue  Venue
+	volume decimal.Decimal
+}
+
+// pick returns the first venue in priority order that trades coin with at least minLiquidityShare of the most
+// liquid venue's volume. A venue that fails to answer is passed over, and its error is only returned if no venue
+// can be picked.
+func (r *Router) pick(ctx context.Context, coin string) (Venue, bool, error) {
+	var (
+		candidates []routeCandidate
+		best       = decimal.Zero
+		firstErr   error
+	)
+
+	for _, v := range r.venues {
+		symbol, listed := r.symbolOn(v, coin)
+		if !listed {
+			continue
+		}
+
+		supported, err := v.Exchange.CheckSupport(ctx, symbol)
+		if err == nil && supported {
+			var p trader.PriceSnapshot
+			p, err = v.Exchange.GetPrice(ctx, symbol, routePriceMaxAge)
+			if err == nil {
+				candidates = append(candidates, routeCandidate{venue: v, volume: p.Volume})
+				best = decimal.Max(best, p.Volume)
+				continue
+			}
+		}
+		if err != nil && !errors.Is(err, trader.ErrInvalidPair) {
+			logging.Warn(ctx, "venue failed to quote coin, passing it over", zap.String("venue", v.Name), zap.String("coin", coin), zap.Error(err))
+			if firstErr == nil {
+				firstErr = fmt.Errorf("%s: %w", v.Name, err)
+			}
+		}
+	}
+
+	enough := best.Mul(minLiquidityShare)
+	for _, c := range candidates {
+		if c.volume.GreaterThanOrEqual(enough) {
+			return c.venue, true, nil
+		}
+	}
+	return Venue{}, false, firstErr
+}
+
+// venue returns the venue coin is being bought on or is held on, and what that venue calls it. Coins the router
+// hasn't placed are looked for among the open positions, and anything not found there is on the first venue.
+func (r *Router) venue(ctx context.Context, coin string) (Venue, string, error) {
+	v, err := r.placedOn(ctx, coin)
+	if err != nil {
+		return Venue{}, "", err
+	}
+
+	symbol, ok := r.symbolOn(v, coin)
+	if !ok {
+		symbol = coin
+	}
+	return v, symbol, nil
+}
+
+func (r *Router) placedOn(ctx context.Context, coin string) (Venue, error) {
+	key := strings.ToUpper(coin)
+
+	r.lock.Lock()
+	v, ok := r.placed[key]
+	r.lock.Unlock()
+	if ok {
+		return v, nil
+	}
+
+	if r.positions != nil {
+		details, err := r.positions.GetCoinsToConsider(ctx)
+		if err != nil {
+			return Venue{}, fmt.Errorf("failed to find which venue %s is on: %w", coin, err)
+		}
+
+		for _, d := range details {
+			if strings.ToUpper(d.Coin) != key {
+				continue
+			}
+			v, ok := r.named(d.Venue)
+			if !ok {
+				return Venue{}, fmt.Errorf("%s is held on %s, which isn't configured", coin, d.Venue)
+			}
+			r.lock.Lock()
+			r.placed[key] = v
+			r.lock.Unlock()
+			return v, nil
+		}
+	}
+	return r.venues[0], nil
+}
+
+// named returns the venue called name, or the first for positions recorded without one.
+func (r *Router) named(name string) (Venue, bool) {
+	if name == "" {
+		return r.venues[0], true
+	}
+	for _, v := range r.venues {
+		if v.Name == name {
+			return v, true
+		}
+	}
+	return Venue{}, false
+}
+
+func (r *Router) GetPrice(ctx context.Context, coin string, maxAge time.Duration) (trader.PriceSnapshot, error) {
+	v, symbol, err := r.venue(ctx, coin)
+	if err != nil {
+		return trader.PriceSnapshot{}, err
+	}
+	return v.Exchange.GetPrice(ctx, symbol, maxAge)
+}
+
+// PurchaseCoin buys coin on the venue CheckSupport picked for it, and records the venue on the fill.
+func (r *Router) PurchaseCoin(ctx context.Context, coin string, lastPrice decimal.Decimal, signalID string) (trader.Fill, error) {
+	v, symbol, err := r.venue(ctx, coin)
+	if err != nil {
+		return trader.Fill{}, err
+	}
+
+	fill, err := v.Exchange.PurchaseCoin(ctx, symbol, lastPrice, signalID)
+	fill.Venue = v.Name
+	return fill, err
+}
+
+func (r *Router) PlaceBracket(ctx context.Context, coin string, fill trader.Fill) (trader.Bracket, error) {
+	v, symbol, err := r.venue(ctx, coin)
+	if err != nil {
+		return trader.Bracket{}, err
+	}
+	return v.Exchange.PlaceBracket(ctx, symbol, fill)
+}
+
+// Sell sells coin on the venue it was bought on, and records the venue on the fill.
+func (r *Router) Sell(ctx context.Context, coin string, amount decimal.Decimal, lastPrice decimal.Decimal, buyClientOrderID string) (trader.Fill, error) {
+	v, symbol, err := r.venue(ctx, coin)
+	if err != nil {
+		return trader.Fill{}, err
+	}
+
+	fill, err := v.Exchange.Sell(ctx, symbol, amount, lastPrice, buyClientOrderID)
+	fill.Venue = v.Name
+	return fill, err
+}
+
+func (r *Router) GetBalanceForCoin(ctx context.Context, coin string) (decimal.Decimal, error) {
+	v, symbol, err := r.venue(ctx, coin)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	return v.Exchange.GetBalanceForCoin(ctx, symbol)
+}
+
+func (r *Router) CheckBracket(ctx context.Context, coin string, bracket trader.Bracket) (trader.Fill, bool, error) {
+	v, symbol, err := r.venue(ctx, coin)
+	if err != nil {
+		return trader.Fill{}, false, err
+	}
+
+	fill, executed, err := v.Exchange.CheckBracket(ctx, symbol, bracket)
+	fill.Venue = v.Name
+	return fill, executed, err
+}
+
+func (r *Router) CancelBracket(ctx context.Context, coin string, bracket trader.Bracket) error {
+	v, symbol, err := r.venue(ctx, coin)
+	if err != nil {
+		return err
+	}
+	return v.Exchange.CancelBracket(ctx, symbol, bracket)
+}
+
+func (r *Router) SellFeeRate(ctx context.Context, coin string) (decimal.Decimal, error) {
+	v, symbol, err := r.venue(ctx, coin)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	return v.Exchange.SellFeeRate(ctx, symbol)
+}
+
+func (r *Router) Trades(ctx context.Context, coin string, since time.Time) ([]trader.Trade, error) {
+	v, symbol, err := r.venue(ctx, coin)
+	if err != nil {
+		return nil, err
+	}
+	return v.Exchange.Trades(ctx, symbol, since)
+}
+
+// Holdings adds up what every venue holds of each currency.
+func (r *Router) Holdings(ctx context.Context) (map[string]trader.Holding, error) {
+	total := make(map[string]trader.Holding)
+	for _, v := range r.venues {
+		holdings, err := v.Exchange.Holdings(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", v.Name, err)
+		}
+		for cur, h := range holdings {
+			t := total[cur]
+			t.Available = t.Available.Add(h.Available)
+			t.Locked = t.Locked.Add(h.Locked)
+			total[cur] = t
+		}
+	}
+	return total, nil
+}
+
+// OpenOrders lists the open orders on every venue.
+func (r *Router) OpenOrders(ctx context.Context) ([]trader.OpenOrder, error) {
+	var orders []trader.OpenOrder
+	for _, v := range r.venues {
+		open, err := v.Exchange.OpenOrders(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", v.Name, err)
+		}
+		orders = append(orders, open...)
+	}
+	return orders, nil
+}
@@ -0,0 +1,301 @@
+package exchange_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/moonr-app/crypto-signal-trading-bot/internal/exchange"
+	"github.com/moonr-app/crypto-signal-trading-bot/internal/trader"
+)
+
+// fakeVenue lists the coins in volumes, each with that much traded, and records what it's asked to buy and sell.
+// Signalled coins resolve to their alias if they have one, or to resolveErr if set. Anything else it's asked panics.
+type fakeVenue struct {
+	exchange.VenueExchange
+	volumes    map[string]int64
+	aliases    map[string]string
+	resolveErr error
+	err        error
+	bought     []string
+	sold       []string
+	holdings   map[string]trader.Holding
+}
+
+func (f *fakeVenue) ResolveSymbol(ctx context.Context, coin, chain, contract string) (string, error) {
+	if f.resolveErr != nil {
+		return "", f.resolveErr
+	}
+	if alias, ok := f.aliases[coin]; ok {
+		coin = alias
+	}
+	if _, ok := f.volumes[coin]; !ok {
+		return "", trader.ErrCoinUnsupported
+	}
+	return coin, nil
+}
+
+func (f *fakeVenue) CheckSupport(ctx context.Context, coin string) (bool, error) {
+	if f.err != nil {
+		return false, f.err
+	}
+	_, ok := f.volumes[coin]
+	return ok, nil
+}
+
+func (f *fakeVenue) GetPrice(ctx context.Context, coin string, maxAge time.Duration) (trader.PriceSnapshot, error) {
+	return trader.PriceSnapshot{Last: decimal.NewFromInt(1), Volume: decimal.NewFromInt(f.volumes[coin])}, nil
+}
+
+func (f *fakeVenue) PurchaseCoin(ctx context.Context, coin string, lastPrice decimal.Decimal, signalID string) (trader.Fill, error) {
+	f.bought = append(f.bought, coin)
+	return trader.Fill{Amount: decimal.NewFromInt(1)}, nil
+}
+
+func (f *fakeVenue) Sell(ctx context.Context, coin string, amount decimal.Decimal, lastPrice decimal.Decimal, buyClientOrderID string) (trader.Fill, error) {
+	f.sold = append(f.sold, coin)
+	return trader.Fill{Amount: amount}, nil
+}
+
+func (f *fakeVenue) Holdings(ctx context.Context) (map[string]trader.Holding, error) {
+	return f.holdings, nil
+}
+
+type fakePositions []trader.SellingDetails
+
+func (p fakePositions) GetCoinsToConsider(ctx context.Context) ([]trader.SellingDetails, error) {
+	return p, nil
+}
+
+func newRouter(t *testing.T, positions exchange.PositionStore, venues ...*fakeVenue) *exchange.Router {
+	t.Helper()
+
+	names := []string{"first", "second"}
+	var vs []exchange.Venue
+	for i, v := range venues {
+		vs = append(vs, exchange.Venue{Name: names[i], Exchange: v})
+	}
+
+	r, err := exchange.NewRouter(positions, vs...)
+	require.NoError(t, err)
+	return r
+}
+
+func TestRouter_Buy(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("buys on the first venue with enough liquidity", func(t *testing.T) {
+		var (
+			first  = &fakeVenue{volumes: map[string]int64{"RARE": 60}}
+			second = &fakeVenue{volumes: map[string]int64{"RARE": 100}}
+			r      = newRouter(t, nil, first, second)
+		)
+
+		supported, err := r.CheckSupport(ctx, "RARE")
+		require.NoError(t, err)
+		require.True(t, supported)
+
+		fill, err := r.PurchaseCoin(ctx, "RARE", decimal.NewFromInt(1), "")
+		require.NoError(t, err)
+		assert.Equal(t, "first", fill.Venue)
+		assert.Equal(t, []string{"RARE"}, first.bought)
+		assert.Empty(t, second.bought)
+	})
+
+	t.Run("buys on a lower priority venue given it is much more liquid", func(t *testing.T) {
+		var (
+			first  = &fakeVenue{volumes: map[string]int64{"RARE": 10}}
+			second = &fakeVenue{volumes: map[string]int64{"RARE": 100}}
+			r      = newRouter(t, nil, first, second)
+		)
+
+		_, err := r.CheckSupport(ctx, "RARE")
+		require.NoError(t, err)
+
+		fill, err := r.PurchaseCoin(ctx, "RARE", decimal.NewFromInt(1), "")
+		require.NoError(t, err)
+		assert.Equal(t, "second", fill.Venue)
+		assert.Equal(t, []string{"RARE"}, second.bought)
+	})
+
+	t.Run("buys on the only venue listing the coin", func(t *testing.T) {
+		var (
+			first  = &fakeVenue{volumes: map[string]int64{}}
+			second = &fakeVenue{volumes: map[string]int64{"RARE": 1}}
+			r      = newRouter(t, nil, first, second)
+		)
+
+		resolved, err := r.ResolveSymbol(ctx, "RARE", "", "")
+		require.NoError(t, err)
+		assert.Equal(t, "RARE", resolved)
+
+		supported, err := r.CheckSupport(ctx, "RARE")
+		require.NoError(t, err)
+		require.True(t, supported)
+
+		fill, err := r.PurchaseCoin(ctx, "RARE", decimal.NewFromInt(1), "")
+		require.NoError(t, err)
+		assert.Equal(t, "second", fill.Venue)
+	})
+
+	t.Run("trades the coin on each venue by the symbol that venue resolved it to", func(t *testing.T) {
+		var (
+			first  = &fakeVenue{volumes: map[string]int64{"1000SATS": 10}, aliases: map[string]string{"SATS": "1000SATS"}}
+			second = &fakeVenue{volumes: map[string]int64{"SATS": 100}}
+			r      = newRouter(t, nil, first, second)
+		)
+
+		resolved, err := r.ResolveSymbol(ctx, "SATS", "", "")
+		require.NoError(t, err)
+		assert.Equal(t, "1000SATS", resolved)
+
+		supported, err := r.CheckSupport(ctx, resolved)
+		require.NoError(t, err)
+		require.True(t, supported)
+
+		fill, err := r.PurchaseCoin(ctx, resolved, decimal.NewFromInt(1), "")
+		require.NoError(t, err)
+		assert.Equal(t, "second", fill.Venue)
+		assert.Equal(t, []string{"SATS"}, second.bought)
+
+		_, err = r.Sell(ctx, resolved, decimal.NewFromInt(1), decimal.NewFromInt(1), "")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"SATS"}, second.sold)
+	})
+
+	t.Run("doesn't buy on a venue that doesn't list the resolved coin", func(t *testing.T) {
+		var (
+			first  = &fakeVenue{volumes: map[string]int64{"1000SATS": 10}, aliases: map[string]string{"SATS": "1000SATS"}}
+			second = &fakeVenue{volumes: map[string]int64{"1000SATS": 100}, resolveErr: trader.ErrCoinUnsupported}
+			r      = newRouter(t, nil, first, second)
+		)
+
+		resolved, err := r.ResolveSymbol(ctx, "SATS", "", "")
+		require.NoError(t, err)
+
+		_, err = r.CheckSupport(ctx, resolved)
+		require.NoError(t, err)
+
+		fill, err := r.PurchaseCoin(ctx, resolved, decimal.NewFromInt(1), "")
+		require.NoError(t, err)
+		assert.Equal(t, "first", fill.Venue)
+	})
+
+	t.Run("err given any venue can't tell which coin is meant", func(t *testing.T) {
+		for _, resolveErr := range []error{trader.ErrCoinAmbiguous, exchange.ErrContractMismatch} {
+			r := newRouter(t, nil,
+				&fakeVenue{volumes: map[string]int64{"RARE": 1}},
+				&fakeVenue{resolveErr: fmt.Errorf("%w: RARE", resolveErr)},
+			)
+
+			_, err := r.ResolveSymbol(ctx, "RARE", "", "")
+			assert.True(t, errors.Is(err, resolveErr), err)
+		}
+	})
+
+	t.Run("passes over a venue that fails", func(t *testing.T) {
+		var (
+			first  = &fakeVenue{volumes: map[string]int64{"RARE": 100}, err: trader.ErrExchangeUnavailable}
+			second = &fakeVenue{volumes: map[string]int64{"RARE": 1}}
+			r      = newRouter(t, nil, first, second)
+		)
+
+		supported, err := r.CheckSupport(ctx, "RARE")
+		require.NoError(t, err)
+		assert.True(t, supported)
+	})
+
+	t.Run("unsupported given no venue lists the coin", func(t *testing.T) {
+		r := newRouter(t, nil, &fakeVenue{}, &fakeVenue{})
+
+		_, err := r.ResolveSymbol(ctx, "RARE", "", "")
+		assert.True(t, errors.Is(err, trader.ErrCoinUnsupported))
+
+		supported, err := r.CheckSupport(ctx, "RARE")
+		require.NoError(t, err)
+		assert.False(t, supported)
+	})
+
+	t.Run("err given every venue listing the coin fails", func(t *testing.T) {
+		r := newRouter(t, nil, &fakeVenue{err: trader.ErrRateLimited}, &fakeVenue{})
+
+		_, err := r.CheckSupport(ctx, "RARE")
+		assert.True(t, errors.Is(err, trader.ErrRateLimited))
+	})
+}
+
+func TestRouter_Sell(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("sells on the venue the coin was bought on", func(t *testing.T) {
+		var (
+			first  = &fakeVenue{volumes: map[string]int64{"RARE": 1}}
+			second = &fakeVenue{volumes: map[string]int64{"RARE": 100}}
+			r      = newRouter(t, nil, first, second)
+		)
+
+		_, err := r.CheckSupport(ctx, "RARE")
+		require.NoError(t, err)
+
+		fill, err := r.Sell(ctx, "RARE", decimal.NewFromInt(1), decimal.NewFromInt(1), "")
+		require.NoError(t, err)
+		assert.Equal(t, "second", fill.Venue)
+		assert.Equal(t, []string{"RARE"}, second.sold)
+	})
+
+	t.Run("finds the venue of a position bought before a restart", func(t *testing.T) {
+		var (
+			first     = &fakeVenue{}
+			second    = &fakeVenue{}
+			positions = fakePositions{{Coin: "RARE", Venue: "second"}, {Coin: "OLD"}}
+			r         = newRouter(t, positions, first, second)
+		)
+
+		_, err := r.Sell(ctx, "RARE", decimal.NewFromInt(1), decimal.NewFromInt(1), "")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"RARE"}, second.sold)
+
+		// positions from before there was more than one venue are on the first.
+		_, err = r.Sell(ctx, "OLD", decimal.NewFromInt(1), decimal.NewFromInt(1), "")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"OLD"}, first.sold)
+	})
+
+	t.Run("err given the position is on a venue that isn't configured", func(t *testing.T) {
+		r := newRouter(t, fakePositions{{Coin: "RARE", Venue: "gone"}}, &fakeVenue{})
+
+		_, err := r.Sell(ctx, "RARE", decimal.NewFromInt(1), decimal.NewFromInt(1), "")
+		require.Error(t, err)
+	})
+
+	t.Run("adds up holdings across venues", func(t *testing.T) {
+		var (
+			first  = &fakeVenue{holdings: map[string]trader.Holding{"RARE": {Available: decimal.NewFromInt(1)}}}
+			second = &fakeVenue{holdings: map[string]trader.Holding{"RARE": {Locked: decimal.NewFromInt(2)}, "USDT": {Available: decimal.NewFromInt(5)}}}
+			r      = newRouter(t, nil, first, second)
+		)
+
+		holdings, err := r.Holdings(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, "3", holdings["RARE"].Total().String())
+		assert.Equal(t, "5", holdings["USDT"].Total().String())
+	})
+}
+
+func TestNewRouter(t *testing.T) {
+	t.Run("err given no venues", func(t *testing.T) {
+		_, err := exchange.NewRouter(nil)
+		require.Error(t, err)
+	})
+
+	t.Run("err given a venue twice", func(t *testing.T) {
+		_, err := exchange.NewRouter(nil, exchange.Venue{Name: "a", Exchange: &fakeVenue{}}, exchange.Venue{Name: "a", Exchange: &fakeVenue{}})
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,307 @@
+package exchange
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/moonr-app/crypto-signal-trading-bot/internal/shared/logging"
+	"github.com/moonr-app/crypto-signal-trading-bot/internal/shared/websocket"
+	"github.com/moonr-app/crypto-signal-trading-bot/internal/trader"
+)
+
+// DefaultStreamURL is gate.io's spot websocket.
+const DefaultStreamURL = "wss://api.gateio.ws/ws/v4/"
+
+const (
+	channelTickers    = "spot.tickers"
+	channelBookTicker = "spot.book_ticker"
+	channelPing       = "spot.ping"
+
+	eventSubscribe   = "subscribe"
+	eventUnsubscribe = "unsubscribe"
+	eventUpdate      = "update"
+
+	defaultStreamPingInterval = 10 * time.Second
+	// defaultStreamIdleTimeout is how long a pair nobody has asked the price of stays subscribed.
+	defaultStreamIdleTimeout = 15 * time.Minute
+
+	streamDialTimeout = 10 * time.Second
+	streamMinBackoff  = 250 * time.Millisecond
+	streamMaxBackoff  = 30 * time.Second
+	// streamMissedPings is how many pings can go unanswered before the connection is given up on.
+	streamMissedPings = 3
+)
+
+// priceStream keeps prices for the pairs the bot cares about up to date from gate.io's websocket. Pairs are
+// subscribed to the first time their price is asked for, and dropped once nobody has asked for a while, so it only
+// follows coins being bought or held.
+type priceStream struct {
+	url          string
+	pingInterval time.Duration
+	idleTimeout  time.Duration
+
+	prices *priceCache
+
+	lock sync.Mutex
+	// wanted is every pair to subscribe to, with when its price was last asked for.
+	wanted map[string]time.Time
+	conn   *websocket.Conn
+}
+
+type streamRequest struct {
+	Time    int64    `json:"time"`
+	Channel string   `json:"channel"`
+	Event   string   `json:"event"`
+	Payload []string `json:"payload,omitempty"`
+}
+
+type streamMessage struct {
+	Channel string          `json:"channel"`
+	Event   string          `json:"event"`
+	Error   *streamError    `json:"error"`
+	Result  json.RawMessage `json:"result"`
+}
+
+type streamError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+type streamTicker struct {
+	CurrencyPair string `json:"currency_pair"`
+	Last         string `json:"last"`
+	LowestAsk    string `json:"lowest_ask"`
+	HighestBid   string `json:"highest_bid"`
+	QuoteVolume  string `json:"quote_volume"`
+}
+
+// streamBookTicker is the best bid and ask. encoding/json matches keys regardless of case, so the sizes have to be
+// declared for the prices not to be overwritten by them.
+type streamBookTicker struct {
+	CurrencyPair string `json:"s"`
+	Bid          string `json:"b"`
+	BidSize      string `json:"B"`
+	Ask          string `json:"a"`
+	AskSize      string `json:"A"`
+}
+
+func newPriceStream(url string, prices *priceCache) *priceStream {
+	return &priceStream{
+		url:          url,
+		pingInterval: defaultStreamPingInterval,
+		idleTimeout:  defaultStreamIdleTimeout,
+		prices:       prices,
+		wanted:       make(map[string]time.Time),
+	}
+}
+
+// watch makes sure pair is subscribed to, and counts as asking for its price.
+func (s *priceStream) watch(ctx context.Context, pair string) {
+	s.lock.Lock()
+	_, ok := s.wanted[pair]
+	s.wanted[pair] = time.Now()
+	conn := s.conn
+	s.lock.Unlock()
+
+	if ok || conn == nil {
+		// already subscribed, or will be once connected.
+		return
+	}
+	if err := send(conn, eventSubscribe, pair); err != nil {
+		logging.Error(ctx, "failed to subscribe to prices", zap.String("pair", pair), zap.Error(err))
+	}
+}
+
+// run keeps the stream connected until ctx is done, resubscribing to every wanted pair after each reconnect.
+func (s *priceStream) run(ctx context.Context) {
+	backoff := streamMinBackoff
+
+	for {
+		dialCtx, cancel := context.WithTimeout(ctx, streamDialTimeout)
+		conn, err := websocket.Dial(dialCtx, s.url)
+		cancel()
+
+		if err == nil {
+			backoff = streamMinBackoff
+			if err = s.serve(ctx, conn); ctx.Err() == nil {
+				logging.Error(ctx, "price stream dropped, reconnecting", zap.Error(err))
+			}
+		} else if ctx.Err() == nil {
+			logging.Error(ctx, "failed to connect to price stream", zap.Error(err), zap.Duration("retry_in", backoff))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		if err != nil {
+			backoff *= 2
+			if backoff > streamMaxBackoff {
+				backoff = streamMaxBackoff
+			}
+		}
+	}
+}
+
+// serve reads from conn until it fails or ctx is done.
+func (s *priceStream) serve(ctx context.Context, conn *websocket.Conn) error {
+	connCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	s.lock.Lock()
+	s.conn = conn
+	pairs := make([]string, 0, len(s.wanted))
+	for pair := range s.wanted {
+		pairs = append(pairs, pair)
+	}
+	s.lock.Unlock()
+
+	defer func() {
+		s.lock.Lock()
+		s.conn = nil
+		s.lock.Unlock()
+		conn.Close()
+	}()
+
+	if len(pairs) > 0 {
+		if err := send(conn, eventSubscribe, pairs...); err != nil {
+			return err
+		}
+	}
+
+	go func() {
+		// closing the connection is what stops the read below when ctx is done.
+		<-connCtx.Done()
+		conn.Close()
+	}()
+	go s.keepAlive(connCtx, conn)
+
+	for {
+		// gate.io answers every ping, so a connection silent for a few of them has gone.
+		_ = conn.SetReadDeadline(time.Now().Add(streamMissedPings * s.pingInterval))
+		msg, err := conn.ReadMessage()
+		if err != nil {
+			return err
+		}
+		s.handle(ctx, msg)
+	}
+}
+
+// keepAlive pings gate.io and unsubscribes from pairs nobody has asked about for a while.
+func (s *priceStream) keepAlive(ctx context.Context, conn *websocket.Conn) {
+	ticker := time.NewTicker(s.pingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if err := write(conn, streamRequest{Time: time.Now().Unix(), Channel: channelPing}); err != nil {
+			return
+		}
+
+		if idle := s.dropIdle(); len(idle) > 0 {
+			if err := send(conn, eventUnsubscribe, idle...); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (s *priceStream) dropIdle() []string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	var idle []string
+	for pair, asked := range s.wanted {
+		if time.Since(asked) > s.idleTimeout {
+			idle = append(idle, pair)
+			delete(s.wanted, pair)
+			s.prices.delete(pair)
+		}
+	}
+	return idle
+}
+
+func (s *priceStream) handle(ctx context.Context, raw []byte) {
+	var msg streamMessage
+	if err := json.Unmarshal(raw, &msg); err != nil {
+		logging.Error(ctx, "invalid price stream message", zap.Error(err))
+		return
+	}
+
+	if msg.Error != nil {
+		logging.Error(ctx, "price stream error",
+			zap.String("channel", msg.Channel),
+			zap.String("event", msg.Event),
+			zap.Int("code", msg.Error.Code),
+			zap.String("message", msg.Error.Message),
+		)
+		return
+	}
+	if msg.Event != eventUpdate {
+		return
+	}
+
+	switch msg.Channel {
+	case channelTickers:
+		var t streamTicker
+		if err := json.Unmarshal(msg.Result, &t); err != nil {
+			logging.Error(ctx, "invalid ticker update", zap.Error(err))
+			return
+		}
+		s.update(t.CurrencyPair, func(p *trader.PriceSnapshot) {
+			p.Last = parsePrice(ctx, t.CurrencyPair, t.Last, p.Last)
+			p.Bid = parsePrice(ctx, t.CurrencyPair, t.HighestBid, p.Bid)
+			p.Ask = parsePrice(ctx, t.CurrencyPair, t.LowestAsk, p.Ask)
+			p.Volume = parsePrice(ctx, t.CurrencyPair, t.QuoteVolume, p.Volume)
+		})
+	case channelBookTicker:
+		var b streamBookTicker
+		if err := json.Unmarshal(msg.Result, &b); err != nil {
+			logging.Error(ctx, "invalid book ticker update", zap.Error(err))
+			return
+		}
+		s.update(b.CurrencyPair, func(p *trader.PriceSnapshot) {
+			p.Bid = parsePrice(ctx, b.CurrencyPair, b.Bid, p.Bid)
+			p.Ask = parsePrice(ctx, b.CurrencyPair, b.Ask, p.Ask)
+		})
+	}
+}
+
+func (s *priceStream) update(pair string, apply func(p *trader.PriceSnapshot)) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if _, ok := s.wanted[pair]; !ok {
+		// a late update for a pair already dropped.
+		return
+	}
+	s.prices.update(pair, apply)
+}
+
+// send subscribes or unsubscribes pairs on both price channels.
+func send(conn *websocket.Conn, event string, pairs ...string) error {
+	for _, channel := range []string{channelTickers, channelBookTicker} {
+		if err := write(conn, streamRequest{Time: time.Now().Unix(), Channel: channel, Event: event, Payload: pairs}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func write(conn *websocket.Conn, req streamRequest) error {
+	b, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+	return conn.WriteMessage(b)
+}
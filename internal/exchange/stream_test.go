@@ -0,0 +1,194 @@
+package exchange_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gateio/gateapi-go/v6"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/moonr-app/crypto-signal-trading-bot/internal/exchange"
+	"github.com/moonr-app/crypto-signal-trading-bot/internal/shared/websocket"
+)
+
+// streamStub is a stand-in for gate.io's spot websocket. It records every subscription, and updates are pushed to it
+// by the test.
+type streamStub struct {
+	srv         *httptest.Server
+	lock        sync.Mutex
+	conns       []*websocket.Conn
+	subscribed  []string
+	connections int
+}
+
+func newStreamStub(t *testing.T) *streamStub {
+	t.Helper()
+
+	s := &streamStub{}
+	s.srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := websocket.Upgrade(w, r)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		s.lock.Lock()
+		s.conns = append(s.conns, conn)
+		s.connections++
+		s.lock.Unlock()
+
+		for {
+			msg, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+
+			var req struct {
+				Channel string   `json:"channel"`
+				Event   string   `json:"event"`
+				Payload []string `json:"payload"`
+			}
+			require.NoError(t, json.Unmarshal(msg, &req))
+			if req.Event != "subscribe" {
+				continue
+			}
+
+			s.lock.Lock()
+			for _, pair := range req.Payload {
+				s.subscribed = append(s.subscribed, req.Channel+" "+pair)
+			}
+			s.lock.Unlock()
+		}
+	}))
+	t.Cleanup(s.srv.Close)
+	return s
+}
+
+func (s *streamStub) url() string {
+	return "ws" + strings.TrimPrefix(s.srv.URL, "http")
+}
+
+func (s *streamStub) subscriptions() []string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return append([]string(nil), s.subscribed...)
+}
+
+func (s *streamStub) connected() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.connections
+}
+
+// push sends an update on channel to every open connection.
+func (s *streamStub) push(t *testing.T, channel string, result interface{}) {
+	t.Helper()
+
+	msg, err := json.Marshal(map[string]interface{}{"time": time.Now().Unix(), "channel": channel, "event": "update", "result": result})
+	require.NoError(t, err)
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for _, conn := range s.conns {
+		_ = conn.WriteMessage(msg)
+	}
+}
+
+// drop closes every open connection.
+func (s *streamStub) drop() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for _, conn := range s.conns {
+		conn.Close()
+	}
+	s.conns = nil
+	s.subscribed = nil
+}
+
+func tickerStub(t *testing.T) *gateStub {
+	stub := newGateStub(t)
+	stub.handle("/spot/tickers", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, []gateapi.Ticker{{CurrencyPair: "RARE_USDT", Last: "0.5"}})
+	})
+	return stub
+}
+
+func TestGateIO_PriceStream(t *testing.T) {
+	t.Run("prices come from the stream once subscribed", func(t *testing.T) {
+		var (
+			stream = newStreamStub(t)
+			stub   = tickerStub(t)
+			g      = stub.gate(t, exchange.WithPriceStream(stream.url()))
+			ctx    = context.Background()
+		)
+
+		// nothing has been streamed yet.
+		price, err := g.GetPrice(ctx, "RARE", time.Minute)
+		require.NoError(t, err)
+		assert.Equal(t, "0.5", price.Last.String())
+
+		require.Eventually(t, func() bool {
+			return len(stream.subscriptions()) == 2
+		}, time.Second, 5*time.Millisecond)
+		assert.ElementsMatch(t, []string{"spot.tickers RARE_USDT", "spot.book_ticker RARE_USDT"}, stream.subscriptions())
+
+		stream.push(t, "spot.book_ticker", map[string]string{"s": "RARE_USDT", "b": "0.6", "B": "100", "a": "0.61", "A": "200"})
+
+		assert.Eventually(t, func() bool {
+			price, err := g.GetPrice(ctx, "RARE", time.Minute)
+			return err == nil && price.Ask.String() == "0.61" && price.Bid.String() == "0.6"
+		}, time.Second, 5*time.Millisecond)
+		assert.Equal(t, 1, stub.count("GET /spot/tickers"))
+	})
+
+	t.Run("resubscribes after the connection drops", func(t *testing.T) {
+		var (
+			stream = newStreamStub(t)
+			stub   = tickerStub(t)
+			g      = stub.gate(t, exchange.WithPriceStream(stream.url()))
+			ctx    = context.Background()
+		)
+
+		_, err := g.GetPrice(ctx, "RARE", time.Minute)
+		require.NoError(t, err)
+		require.Eventually(t, func() bool {
+			return len(stream.subscriptions()) == 2
+		}, time.Second, 5*time.Millisecond)
+
+		stream.drop()
+
+		require.Eventually(t, func() bool {
+			return len(stream.subscriptions()) == 2
+		}, 2*time.Second, 5*time.Millisecond)
+		assert.Equal(t, 2, stream.connected())
+
+		stream.push(t, "spot.tickers", map[string]string{"currency_pair": "RARE_USDT", "last": "0.7", "lowest_ask": "0.71", "highest_bid": "0.69"})
+
+		assert.Eventually(t, func() bool {
+			price, err := g.GetPrice(ctx, "RARE", time.Minute)
+			return err == nil && price.Last.String() == "0.7" && price.Ask.String() == "0.71"
+		}, time.Second, 5*time.Millisecond)
+	})
+
+	t.Run("falls back to REST given the stream can't connect", func(t *testing.T) {
+		var (
+			stub = tickerStub(t)
+			g    = stub.gate(t, exchange.WithPriceStream("ws://127.0.0.1:1/"))
+			ctx  = context.Background()
+		)
+
+		for i := 0; i < 2; i++ {
+			price, err := g.GetPrice(ctx, "RARE", 0)
+			require.NoError(t, err)
+			assert.Equal(t, "0.5", price.Last.String())
+		}
+		assert.Equal(t, 2, stub.count("GET /spot/tickers"))
+	})
+}
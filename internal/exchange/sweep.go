@@ -0,0 +1,165 @@
+package exchange
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/antihax/optional"
+	"github.com/gateio/gateapi-go/v6"
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+
+	"github.com/moonr-app/crypto-signal-trading-bot/internal/shared/logging"
+	"github.com/moonr-app/crypto-signal-trading-bot/internal/trader"
+)
+
+const (
+	timeInForceImmediateOrCancel = "ioc"
+	orderBookDepth               = 50
+)
+
+var errEmptyBook = errors.New("order book has no asks")
+
+// sweepBuy spends budget, in the pair's quote currency, with an immediate-or-cancel order priced to take the ask side
+// of the book up to a maximum slippage from the best ask. If nothing fills, the book is read again and the next,
+// wider, step of the ladder is tried.
+func (g *GateIO) sweepBuy(ctx context.Context, currencyPair string, budget decimal.Decimal, ids *clientIDs) (trader.Fill, error) {
+	for i, slippage := range g.slippageLadder {
+		var book gateapi.OrderBook
+		err := g.retry.do(ctx, func() (res *http.Response, err error) {
+			book, res, err = g.api.SpotApi.ListOrderBook(ctx, currencyPair, &gateapi.ListOrderBookOpts{Limit: optional.NewInt32(orderBookDepth)})
+			return res, err
+		})
+		if err != nil {
+			return trader.Fill{}, fmt.Errorf("failed to get order book: %w", err)
+		}
+
+		limit, amount, expected, err := sweepAsks(book.Asks, budget, slippage)
+		if err != nil {
+			return trader.Fill{}, err
+		}
+
+		logging.Info(
+			ctx,
+			"sweeping asks",
+			zap.String("currency_pair", currencyPair),
+			zap.Int("attempt", i+1),
+			zap.String("max_slippage_percentage", slippage.String()),
+			zap.String("limit_price", limit.String()),
+			zap.String("amount", amount.String()),
+			zap.String("expected_vwap", expected.String()),
+		)
+
+		fill, err := g.orders.place(ctx, gateapi.Order{
+			CurrencyPair: currencyPair,
+			Account:      accountType,
+			Side:         sideTypeBuy,
+			TimeInForce:  timeInForceImmediateOrCancel,
+			Price:        limit.String(),
+			Amount:       amount.String(),
+			Text:         ids.next(),
+		})
+		if errors.Is(err, trader.ErrOrderNotFilled) {
+			logging.Warn(ctx, "sweep did not fill", zap.String("currency_pair", currencyPair), zap.Int("attempt", i+1))
+			continue
+		}
+		if err != nil {
+			return fill, err
+		}
+
+		logging.Info(ctx, "sweep filled", zap.String("vwap", fill.Price.String()), zap.String("amount", fill.Amount.String()))
+		return fill, nil
+	}
+
+	return trader.Fill{}, fmt.Errorf("%w: %s after %d attempts", trader.ErrOrderNotFilled, currencyPair, len(g.slippageLadder))
+}
+
+// sweepAsks works out how much of budget can be spent walking up the asks without paying more than slippagePercentage
+// over the best ask. It returns the limit price to send, the base amount to ask for and the volume weighted average
+// price that should fill at if the book doesn't move. gate.io sets aside amount at the limit price for the order
+// whatever it fills at, so amount is what budget buys at the limit rather than what it would buy walking the book.
+func sweepAsks(asks [][]string, budget decimal.Decimal, slippagePercentage decimal.Decimal) (limit, amount, vwap decimal.Decimal, err error) {
+	if len(asks) == 0 || len(asks[0]) < 2 {
+		return decimal.Zero, decimal.Zero, decimal.Zero, errEmptyBook
+	}
+
+	best, err := decimal.NewFromString(asks[0][0])
+	if err != nil {
+		return decimal.Zero, decimal.Zero, decimal.Zero, fmt.Errorf("invalid ask price: %w", err)
+	}
+
+	var (
+		hundred   = decimal.NewFromInt(100)
+		maxPrice  = best.Mul(hundred.Add(slippagePercentage)).Div(hundred)
+		remaining = budget
+		spent     = decimal.Zero
+		walked    = decimal.Zero
+	)
+
+	for _, level := range asks {
+		if len(level) < 2 || remaining.LessThanOrEqual(decimal.Zero) {
+			break
+		}
+
+		price, err := decimal.NewFromString(level[0])
+		if err != nil {
+			return decimal.Zero, decimal.Zero, decimal.Zero, fmt.Errorf("invalid ask price: %w", err)
+		}
+		if price.GreaterThan(maxPrice) {
+			break
+		}
+
+		size, err := decimal.NewFromString(level[1])
+		if err != nil {
+			return decimal.Zero, decimal.Zero, decimal.Zero, fmt.Errorf("invalid ask size: %w", err)
+		}
+
+		cost := price.Mul(size)
+		if cost.GreaterThan(remaining) {
+			size = remaining.Div(price)
+			cost = remaining
+		}
+
+		walked = walked.Add(size)
+		spent = spent.Add(cost)
+		remaining = remaining.Sub(cost)
+		limit = price
+	}
+
+	// if the book within the cap is thinner than the budget, price at the cap and let the order take whatever
+	// arrives before it is matched.
+	if remaining.IsPositive() {
+		limit = maxPrice
+		walked = walked.Add(remaining.Div(maxPrice))
+		spent = spent.Add(remaining)
+	}
+
+	return limit, spent.Div(limit), spent.Div(walked), nil
+}
+
+// ParseSlippageLadder parses a comma separated list of max slippage percentages, e.g. "1,2.5,5". Each step must be
+// wider than the one before it, there is no point retrying at a tighter cap.
+func ParseSlippageLadder(s string) ([]decimal.Decimal, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+
+	var ladder []decimal.Decimal
+	for _, step := range strings.Split(s, ",") {
+		d, err := decimal.NewFromString(strings.TrimSpace(step))
+		if err != nil {
+			return nil, fmt.Errorf("invalid slippage %q: %w", step, err)
+		}
+		if d.IsNegative() {
+			return nil, fmt.Errorf("slippage %q cannot be negative", step)
+		}
+		if len(ladder) > 0 && !d.GreaterThan(ladder[len(ladder)-1]) {
+			return nil, fmt.Errorf("slippage %q must be wider than the step before it", step)
+		}
+		ladder = append(ladder, d)
+	}
+	return ladder, nil
+}
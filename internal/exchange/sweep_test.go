@@ -0,0 +1,160 @@
+package exchange_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/gateio/gateapi-go/v6"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/moonr-app/crypto-signal-trading-bot/internal/exchange"
+	"github.com/moonr-app/crypto-signal-trading-bot/internal/trader"
+)
+
+// iocHandler serves POST /spot/orders the way gate.io answers an immediate-or-cancel order: already finished with.
+// filled decides how much of each order is matched and at what total.
+func (s *gateStub) iocHandler(t testing.TB, filled func(n int, o gateapi.Order) (left, total string)) *[]gateapi.Order {
+	var orders []gateapi.Order
+
+	s.handle("/spot/orders", func(w http.ResponseWriter, r *http.Request) {
+		var o gateapi.Order
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&o))
+		orders = append(orders, o)
+
+		o.Id = "123"
+		o.Left, o.FilledTotal = filled(len(orders), o)
+		o.Status = "closed"
+		if o.Left != "0" {
+			o.Status = "cancelled"
+		}
+		writeJSON(w, o)
+	})
+	return &orders
+}
+
+func (s *gateStub) orderBook(asks [][]string) {
+	s.handle("/spot/order_book", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, gateapi.OrderBook{Asks: asks})
+	})
+}
+
+func TestGateIO_PurchaseCoin_Sweep(t *testing.T) {
+	var (
+		ctx    = context.Background()
+		ladder = []decimal.Decimal{decimal.NewFromInt(1), decimal.NewFromInt(5)}
+	)
+
+	t.Run("sweeps the asks with an ioc order and reports the vwap", func(t *testing.T) {
+		stub := newGateStub(t)
+		stub.orderBook([][]string{{"0.5", "10"}, {"0.501", "20"}, {"0.6", "100"}})
+		orders := stub.iocHandler(t, func(n int, o gateapi.Order) (string, string) {
+			// all 10 at 0.5 and the rest at 0.501.
+			return "0", "9.99"
+		})
+
+		fill, err := stub.gate(t, exchange.WithSweepBuy(ladder...)).PurchaseCoin(ctx, "RARE", decimal.NewFromFloat(0.4), "")
+		require.NoError(t, err)
+
+		require.Len(t, *orders, 1)
+		assert.Equal(t, "ioc", (*orders)[0].TimeInForce)
+		assert.Equal(t, "buy", (*orders)[0].Side)
+		assert.Equal(t, "RARE_USDT", (*orders)[0].CurrencyPair)
+		assert.Equal(t, "0.501", (*orders)[0].Price)
+
+		// sized at the limit, so gate.io never sets aside more than the budget.
+		amount, err := decimal.NewFromString((*orders)[0].Amount)
+		require.NoError(t, err)
+		assert.Equal(t, "19.96", amount.Round(2).String())
+		assert.True(t, amount.Mul(decimal.RequireFromString("0.501")).LessThanOrEqual(decimal.NewFromInt(10)))
+
+		assert.Equal(t, "123", fill.OrderID)
+		assert.Equal(t, "9.99", fill.FilledTotal.String())
+		assert.Equal(t, "0.5005", fill.Price.Round(4).String())
+	})
+
+	t.Run("prices at the slippage cap given the book is too thin", func(t *testing.T) {
+		stub := newGateStub(t)
+		stub.orderBook([][]string{{"0.5", "2"}, {"0.6", "100"}})
+		orders := stub.iocHandler(t, func(n int, o gateapi.Order) (string, string) {
+			return "0", "10"
+		})
+
+		_, err := stub.gate(t, exchange.WithSweepBuy(ladder...)).PurchaseCoin(ctx, "RARE", decimal.NewFromFloat(0.5), "")
+		require.NoError(t, err)
+
+		require.Len(t, *orders, 1)
+		assert.Equal(t, "0.505", (*orders)[0].Price)
+	})
+
+	t.Run("steps up the ladder given nothing fills", func(t *testing.T) {
+		stub := newGateStub(t)
+		stub.orderBook([][]string{{"0.5", "2"}, {"0.6", "100"}})
+		orders := stub.iocHandler(t, func(n int, o gateapi.Order) (string, string) {
+			if n == 1 {
+				return o.Amount, "0"
+			}
+			return "0", "10"
+		})
+
+		fill, err := stub.gate(t, exchange.WithSweepBuy(ladder...)).PurchaseCoin(ctx, "RARE", decimal.NewFromFloat(0.5), "")
+		require.NoError(t, err)
+		assert.Equal(t, "10", fill.FilledTotal.String())
+
+		require.Len(t, *orders, 2)
+		assert.Equal(t, "0.505", (*orders)[0].Price)
+		assert.Equal(t, "0.525", (*orders)[1].Price)
+		assert.Equal(t, 2, stub.count("GET /spot/order_book"))
+	})
+
+	t.Run("ErrOrderNotFilled given no step of the ladder fills", func(t *testing.T) {
+		stub := newGateStub(t)
+		stub.orderBook([][]string{{"0.5", "2"}})
+		orders := stub.iocHandler(t, func(n int, o gateapi.Order) (string, string) {
+			return o.Amount, "0"
+		})
+
+		_, err := stub.gate(t, exchange.WithSweepBuy(ladder...)).PurchaseCoin(ctx, "RARE", decimal.NewFromFloat(0.5), "")
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, trader.ErrOrderNotFilled))
+		assert.Len(t, *orders, 2)
+	})
+
+	t.Run("err given the book has no asks", func(t *testing.T) {
+		stub := newGateStub(t)
+		stub.orderBook(nil)
+
+		_, err := stub.gate(t, exchange.WithSweepBuy(ladder...)).PurchaseCoin(ctx, "RARE", decimal.NewFromFloat(0.5), "")
+		require.Error(t, err)
+		assert.Equal(t, 0, stub.count("POST /spot/orders"))
+	})
+}
+
+func TestParseSlippageLadder(t *testing.T) {
+	t.Run("parses each step", func(t *testing.T) {
+		ladder, err := exchange.ParseSlippageLadder("1, 2.5,5")
+		require.NoError(t, err)
+		require.Len(t, ladder, 3)
+		assert.Equal(t, "2.5", ladder[1].String())
+	})
+
+	t.Run("nil given empty", func(t *testing.T) {
+		ladder, err := exchange.ParseSlippageLadder(" ")
+		require.NoError(t, err)
+		assert.Nil(t, ladder)
+	})
+
+	t.Run("err given a step is not wider than the last", func(t *testing.T) {
+		_, err := exchange.ParseSlippageLadder("2,1")
+		require.Error(t, err)
+	})
+
+	t.Run("err given a step is not a number", func(t *testing.T) {
+		_, err := exchange.ParseSlippageLadder("1,lots")
+		require.Error(t, err)
+	})
+}
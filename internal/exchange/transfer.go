@@ -0,0 +1,110 @@
+package exchange
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gateio/gateapi-go/v6"
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+
+	"github.com/moonr-app/crypto-signal-trading-bot/internal/shared/logging"
+)
+
+const (
+	// subAccountDirectionTo moves funds from the main account into a sub-account.
+	subAccountDirectionTo = "to"
+	// futuresAccount holds the margin of the USDT perpetuals, so it can't take profit while the bot shorts.
+	futuresAccount = "futures"
+)
+
+// transferAccounts are the main account's own accounts, other than spot, that gate.io will transfer to.
+var transferAccounts = map[string]bool{"margin": true, futuresAccount: true, "delivery": true, "cross_margin": true}
+
+// ProfitDestination is where swept profit is moved to: the spot account of a sub-account, or another of the main
+// account's own accounts.
+type ProfitDestination struct {
+	// SubAccount is the user ID of the sub-account.
+	SubAccount string
+	// Account is one of the main account's accounts, e.g. futures. It's only used if SubAccount is empty.
+	Account string
+}
+
+func (d ProfitDestination) IsZero() bool {
+	return d.SubAccount == "" && d.Account == ""
+}
+
+func (d ProfitDestination) String() string {
+	if d.SubAccount != "" {
+		return "sub-account " + d.SubAccount
+	}
+	return d.Account + " account"
+}
+
+// ParseProfitDestination parses where profit is swept to: either the numeric user ID of a sub-account, e.g. 10023456,
+// or one of margin, futures, delivery or cross_margin. Empty means profit isn't swept. NewGateIO refuses futures
+// when shorts are enabled.
+func ParseProfitDestination(s string) (ProfitDestination, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "" {
+		return ProfitDestination{}, nil
+	}
+
+	if strings.Trim(s, "0123456789") == "" {
+		return ProfitDestination{SubAccount: s}, nil
+	}
+	if transferAccounts[s] {
+		return ProfitDestination{Account: s}, nil
+	}
+	return ProfitDestination{}, fmt.Errorf("%s is neither a sub-account user ID nor an account gate.io transfers to", s)
+}
+
+// WithProfitDestination sets where TransferProfit moves profit to.
+func WithProfitDestination(d ProfitDestination) Option {
+	return func(o *options) {
+		o.profitTo = d
+	}
+}
+
+// TransferProfit moves amount of USDT out of the spot account to the configured ProfitDestination, and returns
+// where it went. Transfers aren't retried unless they were rate limited, as one that timed out may well have gone
+// through.
+func (g *GateIO) TransferProfit(ctx context.Context, amount decimal.Decimal) (string, error) {
+	to := g.profitTo
+	if to.IsZero() {
+		return "", errors.New("no profit destination configured")
+	}
+
+	if g.testMode {
+		logging.Info(ctx, "test mode, not transferring profit", zap.String("amount", amount.String()), zap.String("to", to.String()))
+		return to.String(), nil
+	}
+
+	err := g.retry.doIf(ctx, isRateLimited, func() (*http.Response, error) {
+		if to.SubAccount != "" {
+			return g.api.WalletApi.TransferWithSubAccount(ctx, gateapi.SubAccountTransfer{
+				Currency:       quoteCurrency,
+				SubAccount:     to.SubAccount,
+				Direction:      subAccountDirectionTo,
+				Amount:         amount.String(),
+				SubAccountType: accountType,
+			})
+		}
+		return g.api.WalletApi.Transfer(ctx, gateapi.Transfer{
+			Currency: quoteCurrency,
+			From:     accountType,
+			To:       to.Account,
+			Amount:   amount.String(),
+		})
+	})
+	g.balances.clear()
+	if err != nil {
+		return "", fmt.Errorf("failed to transfer %s %s to %s: %w", amount, quoteCurrency, to, err)
+	}
+
+	logging.Info(ctx, "transferred profit", zap.String("amount", amount.String()), zap.String("to", to.String()))
+	return to.String(), nil
+}
@@ -0,0 +1,102 @@
+package exchange_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gateio/gateapi-go/v6"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/moonr-app/crypto-signal-trading-bot/internal/exchange"
+)
+
+func TestGateIO_TransferProfit(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("err given profit would be swept into the margin for shorts", func(t *testing.T) {
+		stub := newGateStub(t)
+
+		_, err := exchange.NewGateIO(ctx, false, decimal.NewFromInt(10), time.Hour,
+			exchange.WithBasePath(stub.srv.URL),
+			exchange.WithProfitDestination(exchange.ProfitDestination{Account: "futures"}),
+			shortsOption(),
+		)
+		require.Error(t, err)
+	})
+
+	t.Run("moves USDT to the sub-account's spot account", func(t *testing.T) {
+		stub := newGateStub(t)
+		var sent gateapi.SubAccountTransfer
+		stub.handle("/wallet/sub_account_transfers", func(w http.ResponseWriter, r *http.Request) {
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&sent))
+			w.WriteHeader(http.StatusNoContent)
+		})
+
+		g := stub.gate(t, exchange.WithProfitDestination(exchange.ProfitDestination{SubAccount: "10023456"}))
+		to, err := g.TransferProfit(ctx, decimal.NewFromFloat(12.5))
+		require.NoError(t, err)
+
+		assert.Equal(t, "sub-account 10023456", to)
+		assert.Equal(t, gateapi.SubAccountTransfer{
+			Currency: "USDT", SubAccount: "10023456", Direction: "to", Amount: "12.5", SubAccountType: "spot",
+		}, sent)
+	})
+
+	t.Run("moves USDT to another of the account's own accounts", func(t *testing.T) {
+		stub := newGateStub(t)
+		var sent gateapi.Transfer
+		stub.handle("/wallet/transfers", func(w http.ResponseWriter, r *http.Request) {
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&sent))
+			w.WriteHeader(http.StatusNoContent)
+		})
+
+		g := stub.gate(t, exchange.WithProfitDestination(exchange.ProfitDestination{Account: "futures"}))
+		to, err := g.TransferProfit(ctx, decimal.NewFromInt(3))
+		require.NoError(t, err)
+
+		assert.Equal(t, "futures account", to)
+		assert.Equal(t, gateapi.Transfer{Currency: "USDT", From: "spot", To: "futures", Amount: "3"}, sent)
+	})
+
+	t.Run("not retried given gate.io is unavailable", func(t *testing.T) {
+		stub := newGateStub(t)
+		stub.handle("/wallet/transfers", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		})
+
+		g := stub.gate(t, exchange.WithProfitDestination(exchange.ProfitDestination{Account: "futures"}))
+		_, err := g.TransferProfit(ctx, decimal.NewFromInt(3))
+		require.Error(t, err)
+		assert.Equal(t, 1, stub.count("POST /wallet/transfers"))
+	})
+
+	t.Run("err given no destination", func(t *testing.T) {
+		_, err := newGateStub(t).gate(t).TransferProfit(ctx, decimal.NewFromInt(3))
+		require.Error(t, err)
+	})
+}
+
+func TestParseProfitDestination(t *testing.T) {
+	for _, tc := range []struct {
+		in   string
+		want exchange.ProfitDestination
+	}{
+		{in: "", want: exchange.ProfitDestination{}},
+		{in: " 10023456 ", want: exchange.ProfitDestination{SubAccount: "10023456"}},
+		{in: "Futures", want: exchange.ProfitDestination{Account: "futures"}},
+	} {
+		got, err := exchange.ParseProfitDestination(tc.in)
+		require.NoError(t, err)
+		assert.Equal(t, tc.want, got, tc.in)
+	}
+
+	for _, in := range []string{"spot", "funding", "sub-1"} {
+		_, err := exchange.ParseProfitDestination(in)
+		assert.Error(t, err, in)
+	}
+}
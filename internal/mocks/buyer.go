@@ -10,6 +10,7 @@ import (
 	time "time"
 
 	gomock "github.com/golang/mock/gomock"
+	trader "github.com/moonr-app/crypto-signal-trading-bot/internal/trader"
 	decimal "github.com/shopspring/decimal"
 )
 
@@ -102,18 +103,46 @@ func (mr *MockPurchaseDBMockRecorder) CheckUniqueCoin(ctx, coin interface{}) *go
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckUniqueCoin", reflect.TypeOf((*MockPurchaseDB)(nil).CheckUniqueCoin), ctx, coin)
 }
 
+// StoreBracket mocks base method.
+func (m *MockPurchaseDB) StoreBracket(ctx context.Context, coin string, bracket trader.Bracket) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "StoreBracket", ctx, coin, bracket)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// StoreBracket indicates an expected call of StoreBracket.
+func (mr *MockPurchaseDBMockRecorder) StoreBracket(ctx, coin, bracket interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StoreBracket", reflect.TypeOf((*MockPurchaseDB)(nil).StoreBracket), ctx, coin, bracket)
+}
+
+// StoreCoinFiltered mocks base method.
+func (m *MockPurchaseDB) StoreCoinFiltered(ctx context.Context, coin, source, reason string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "StoreCoinFiltered", ctx, coin, source, reason)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// StoreCoinFiltered indicates an expected call of StoreCoinFiltered.
+func (mr *MockPurchaseDBMockRecorder) StoreCoinFiltered(ctx, coin, source, reason interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StoreCoinFiltered", reflect.TypeOf((*MockPurchaseDB)(nil).StoreCoinFiltered), ctx, coin, source, reason)
+}
+
 // StoreCoinPurchased mocks base method.
-func (m *MockPurchaseDB) StoreCoinPurchased(ctx context.Context, coin string, purchasePrice, amountPurchased decimal.Decimal, timeout time.Time) error {
+func (m *MockPurchaseDB) StoreCoinPurchased(ctx context.Context, coin string, fill trader.Fill, timeout time.Time) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "StoreCoinPurchased", ctx, coin, purchasePrice, amountPurchased, timeout)
+	ret := m.ctrl.Call(m, "StoreCoinPurchased", ctx, coin, fill, timeout)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // StoreCoinPurchased indicates an expected call of StoreCoinPurchased.
-func (mr *MockPurchaseDBMockRecorder) StoreCoinPurchased(ctx, coin, purchasePrice, amountPurchased, timeout interface{}) *gomock.Call {
+func (mr *MockPurchaseDBMockRecorder) StoreCoinPurchased(ctx, coin, fill, timeout interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StoreCoinPurchased", reflect.TypeOf((*MockPurchaseDB)(nil).StoreCoinPurchased), ctx, coin, purchasePrice, amountPurchased, timeout)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StoreCoinPurchased", reflect.TypeOf((*MockPurchaseDB)(nil).StoreCoinPurchased), ctx, coin, fill, timeout)
 }
 
 // StoreCoinUnsupported mocks base method.
@@ -168,33 +197,62 @@ func (mr *MockExchangePurchaserMockRecorder) CheckSupport(ctx, coin interface{})
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckSupport", reflect.TypeOf((*MockExchangePurchaser)(nil).CheckSupport), ctx, coin)
 }
 
-// GetLastPrice mocks base method.
-func (m *MockExchangePurchaser) GetLastPrice(ctx context.Context, coin string) (decimal.Decimal, error) {
+// GetPrice mocks base method.
+func (m *MockExchangePurchaser) GetPrice(ctx context.Context, coin string, maxAge time.Duration) (trader.PriceSnapshot, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetLastPrice", ctx, coin)
-	ret0, _ := ret[0].(decimal.Decimal)
+	ret := m.ctrl.Call(m, "GetPrice", ctx, coin, maxAge)
+	ret0, _ := ret[0].(trader.PriceSnapshot)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// GetLastPrice indicates an expected call of GetLastPrice.
-func (mr *MockExchangePurchaserMockRecorder) GetLastPrice(ctx, coin interface{}) *gomock.Call {
+// GetPrice indicates an expected call of GetPrice.
+func (mr *MockExchangePurchaserMockRecorder) GetPrice(ctx, coin, maxAge interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLastPrice", reflect.TypeOf((*MockExchangePurchaser)(nil).GetLastPrice), ctx, coin)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPrice", reflect.TypeOf((*MockExchangePurchaser)(nil).GetPrice), ctx, coin, maxAge)
+}
+
+// PlaceBracket mocks base method.
+func (m *MockExchangePurchaser) PlaceBracket(ctx context.Context, coin string, fill trader.Fill) (trader.Bracket, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PlaceBracket", ctx, coin, fill)
+	ret0, _ := ret[0].(trader.Bracket)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PlaceBracket indicates an expected call of PlaceBracket.
+func (mr *MockExchangePurchaserMockRecorder) PlaceBracket(ctx, coin, fill interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PlaceBracket", reflect.TypeOf((*MockExchangePurchaser)(nil).PlaceBracket), ctx, coin, fill)
 }
 
 // PurchaseCoin mocks base method.
-func (m *MockExchangePurchaser) PurchaseCoin(ctx context.Context, coin string, lastPrice decimal.Decimal) (decimal.Decimal, decimal.Decimal, error) {
+func (m *MockExchangePurchaser) PurchaseCoin(ctx context.Context, coin string, lastPrice decimal.Decimal, signalID string) (trader.Fill, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "PurchaseCoin", ctx, coin, lastPrice)
-	ret0, _ := ret[0].(decimal.Decimal)
-	ret1, _ := ret[1].(decimal.Decimal)
-	ret2, _ := ret[2].(error)
-	return ret0, ret1, ret2
+	ret := m.ctrl.Call(m, "PurchaseCoin", ctx, coin, lastPrice, signalID)
+	ret0, _ := ret[0].(trader.Fill)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
 // PurchaseCoin indicates an expected call of PurchaseCoin.
-func (mr *MockExchangePurchaserMockRecorder) PurchaseCoin(ctx, coin, lastPrice interface{}) *gomock.Call {
+func (mr *MockExchangePurchaserMockRecorder) PurchaseCoin(ctx, coin, lastPrice, signalID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PurchaseCoin", reflect.TypeOf((*MockExchangePurchaser)(nil).PurchaseCoin), ctx, coin, lastPrice, signalID)
+}
+
+// ResolveSymbol mocks base method.
+func (m *MockExchangePurchaser) ResolveSymbol(ctx context.Context, coin, chain, contract string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ResolveSymbol", ctx, coin, chain, contract)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ResolveSymbol indicates an expected call of ResolveSymbol.
+func (mr *MockExchangePurchaserMockRecorder) ResolveSymbol(ctx, coin, chain, contract interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PurchaseCoin", reflect.TypeOf((*MockExchangePurchaser)(nil).PurchaseCoin), ctx, coin, lastPrice)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResolveSymbol", reflect.TypeOf((*MockExchangePurchaser)(nil).ResolveSymbol), ctx, coin, chain, contract)
 }
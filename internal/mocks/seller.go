@@ -7,6 +7,7 @@ package mocks
 import (
 	context "context"
 	reflect "reflect"
+	time "time"
 
 	gomock "github.com/golang/mock/gomock"
 	trader "github.com/moonr-app/crypto-signal-trading-bot/internal/trader"
@@ -65,6 +66,34 @@ func (mr *MockSellingDBMockRecorder) MarkCoinAsCompleted(ctx, coin interface{})
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkCoinAsCompleted", reflect.TypeOf((*MockSellingDB)(nil).MarkCoinAsCompleted), ctx, coin)
 }
 
+// StoreBracket mocks base method.
+func (m *MockSellingDB) StoreBracket(ctx context.Context, coin string, bracket trader.Bracket) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "StoreBracket", ctx, coin, bracket)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// StoreBracket indicates an expected call of StoreBracket.
+func (mr *MockSellingDBMockRecorder) StoreBracket(ctx, coin, bracket interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StoreBracket", reflect.TypeOf((*MockSellingDB)(nil).StoreBracket), ctx, coin, bracket)
+}
+
+// UpdateCoinAmount mocks base method.
+func (m *MockSellingDB) UpdateCoinAmount(ctx context.Context, coin string, amount decimal.Decimal) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateCoinAmount", ctx, coin, amount)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateCoinAmount indicates an expected call of UpdateCoinAmount.
+func (mr *MockSellingDBMockRecorder) UpdateCoinAmount(ctx, coin, amount interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateCoinAmount", reflect.TypeOf((*MockSellingDB)(nil).UpdateCoinAmount), ctx, coin, amount)
+}
+
 // MockSellingExchange is a mock of SellingExchange interface.
 type MockSellingExchange struct {
 	ctrl     *gomock.Controller
@@ -88,6 +117,36 @@ func (m *MockSellingExchange) EXPECT() *MockSellingExchangeMockRecorder {
 	return m.recorder
 }
 
+// CancelBracket mocks base method.
+func (m *MockSellingExchange) CancelBracket(ctx context.Context, coin string, bracket trader.Bracket) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CancelBracket", ctx, coin, bracket)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CancelBracket indicates an expected call of CancelBracket.
+func (mr *MockSellingExchangeMockRecorder) CancelBracket(ctx, coin, bracket interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CancelBracket", reflect.TypeOf((*MockSellingExchange)(nil).CancelBracket), ctx, coin, bracket)
+}
+
+// CheckBracket mocks base method.
+func (m *MockSellingExchange) CheckBracket(ctx context.Context, coin string, bracket trader.Bracket) (trader.Fill, bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CheckBracket", ctx, coin, bracket)
+	ret0, _ := ret[0].(trader.Fill)
+	ret1, _ := ret[1].(bool)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// CheckBracket indicates an expected call of CheckBracket.
+func (mr *MockSellingExchangeMockRecorder) CheckBracket(ctx, coin, bracket interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckBracket", reflect.TypeOf((*MockSellingExchange)(nil).CheckBracket), ctx, coin, bracket)
+}
+
 // GetBalanceForCoin mocks base method.
 func (m *MockSellingExchange) GetBalanceForCoin(ctx context.Context, coin string) (decimal.Decimal, error) {
 	m.ctrl.T.Helper()
@@ -103,32 +162,107 @@ func (mr *MockSellingExchangeMockRecorder) GetBalanceForCoin(ctx, coin interface
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetBalanceForCoin", reflect.TypeOf((*MockSellingExchange)(nil).GetBalanceForCoin), ctx, coin)
 }
 
-// GetLastPrice mocks base method.
-func (m *MockSellingExchange) GetLastPrice(ctx context.Context, coin string) (decimal.Decimal, error) {
+// GetPrice mocks base method.
+func (m *MockSellingExchange) GetPrice(ctx context.Context, coin string, maxAge time.Duration) (trader.PriceSnapshot, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetLastPrice", ctx, coin)
-	ret0, _ := ret[0].(decimal.Decimal)
+	ret := m.ctrl.Call(m, "GetPrice", ctx, coin, maxAge)
+	ret0, _ := ret[0].(trader.PriceSnapshot)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// GetLastPrice indicates an expected call of GetLastPrice.
-func (mr *MockSellingExchangeMockRecorder) GetLastPrice(ctx, coin interface{}) *gomock.Call {
+// GetPrice indicates an expected call of GetPrice.
+func (mr *MockSellingExchangeMockRecorder) GetPrice(ctx, coin, maxAge interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLastPrice", reflect.TypeOf((*MockSellingExchange)(nil).GetLastPrice), ctx, coin)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPrice", reflect.TypeOf((*MockSellingExchange)(nil).GetPrice), ctx, coin, maxAge)
+}
+
+// Holdings mocks base method.
+func (m *MockSellingExchange) Holdings(ctx context.Context) (map[string]trader.Holding, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Holdings", ctx)
+	ret0, _ := ret[0].(map[string]trader.Holding)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Holdings indicates an expected call of Holdings.
+func (mr *MockSellingExchangeMockRecorder) Holdings(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Holdings", reflect.TypeOf((*MockSellingExchange)(nil).Holdings), ctx)
+}
+
+// OpenOrders mocks base method.
+func (m *MockSellingExchange) OpenOrders(ctx context.Context) ([]trader.OpenOrder, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "OpenOrders", ctx)
+	ret0, _ := ret[0].([]trader.OpenOrder)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// OpenOrders indicates an expected call of OpenOrders.
+func (mr *MockSellingExchangeMockRecorder) OpenOrders(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OpenOrders", reflect.TypeOf((*MockSellingExchange)(nil).OpenOrders), ctx)
+}
+
+// PlaceBracket mocks base method.
+func (m *MockSellingExchange) PlaceBracket(ctx context.Context, coin string, fill trader.Fill) (trader.Bracket, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PlaceBracket", ctx, coin, fill)
+	ret0, _ := ret[0].(trader.Bracket)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PlaceBracket indicates an expected call of PlaceBracket.
+func (mr *MockSellingExchangeMockRecorder) PlaceBracket(ctx, coin, fill interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PlaceBracket", reflect.TypeOf((*MockSellingExchange)(nil).PlaceBracket), ctx, coin, fill)
 }
 
 // Sell mocks base method.
-func (m *MockSellingExchange) Sell(ctx context.Context, coin string, amount, lastPrice decimal.Decimal) (decimal.Decimal, error) {
+func (m *MockSellingExchange) Sell(ctx context.Context, coin string, amount, lastPrice decimal.Decimal, buyClientOrderID string) (trader.Fill, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Sell", ctx, coin, amount, lastPrice)
-	ret0, _ := ret[0].(decimal.Decimal)
+	ret := m.ctrl.Call(m, "Sell", ctx, coin, amount, lastPrice, buyClientOrderID)
+	ret0, _ := ret[0].(trader.Fill)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // Sell indicates an expected call of Sell.
-func (mr *MockSellingExchangeMockRecorder) Sell(ctx, coin, amount, lastPrice interface{}) *gomock.Call {
+func (mr *MockSellingExchangeMockRecorder) Sell(ctx, coin, amount, lastPrice, buyClientOrderID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Sell", reflect.TypeOf((*MockSellingExchange)(nil).Sell), ctx, coin, amount, lastPrice, buyClientOrderID)
+}
+
+// SellFeeRate mocks base method.
+func (m *MockSellingExchange) SellFeeRate(ctx context.Context, coin string) (decimal.Decimal, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SellFeeRate", ctx, coin)
+	ret0, _ := ret[0].(decimal.Decimal)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SellFeeRate indicates an expected call of SellFeeRate.
+func (mr *MockSellingExchangeMockRecorder) SellFeeRate(ctx, coin interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SellFeeRate", reflect.TypeOf((*MockSellingExchange)(nil).SellFeeRate), ctx, coin)
+}
+
+// Trades mocks base method.
+func (m *MockSellingExchange) Trades(ctx context.Context, coin string, since time.Time) ([]trader.Trade, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Trades", ctx, coin, since)
+	ret0, _ := ret[0].([]trader.Trade)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Trades indicates an expected call of Trades.
+func (mr *MockSellingExchangeMockRecorder) Trades(ctx, coin, since interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Sell", reflect.TypeOf((*MockSellingExchange)(nil).Sell), ctx, coin, amount, lastPrice)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Trades", reflect.TypeOf((*MockSellingExchange)(nil).Trades), ctx, coin, since)
 }
@@ -59,6 +59,30 @@ func (mr *MockNotifierMockRecorder) NotifyPurchased(ctx, coin, price, amount int
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NotifyPurchased", reflect.TypeOf((*MockNotifier)(nil).NotifyPurchased), ctx, coin, price, amount)
 }
 
+// NotifyShortClosed mocks base method.
+func (m *MockNotifier) NotifyShortClosed(ctx context.Context, contract string, pnl decimal.Decimal) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "NotifyShortClosed", ctx, contract, pnl)
+}
+
+// NotifyShortClosed indicates an expected call of NotifyShortClosed.
+func (mr *MockNotifierMockRecorder) NotifyShortClosed(ctx, contract, pnl interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NotifyShortClosed", reflect.TypeOf((*MockNotifier)(nil).NotifyShortClosed), ctx, contract, pnl)
+}
+
+// NotifyShorted mocks base method.
+func (m *MockNotifier) NotifyShorted(ctx context.Context, contract string, amount, price decimal.Decimal) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "NotifyShorted", ctx, contract, amount, price)
+}
+
+// NotifyShorted indicates an expected call of NotifyShorted.
+func (mr *MockNotifierMockRecorder) NotifyShorted(ctx, contract, amount, price interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NotifyShorted", reflect.TypeOf((*MockNotifier)(nil).NotifyShorted), ctx, contract, amount, price)
+}
+
 // NotifySold mocks base method.
 func (m *MockNotifier) NotifySold(ctx context.Context, coin string, amount, pricePerCoin decimal.Decimal) {
 	m.ctrl.T.Helper()
@@ -71,16 +95,16 @@ func (mr *MockNotifierMockRecorder) NotifySold(ctx, coin, amount, pricePerCoin i
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NotifySold", reflect.TypeOf((*MockNotifier)(nil).NotifySold), ctx, coin, amount, pricePerCoin)
 }
 
-// NotifyTimeout mocks base method.
-func (m *MockNotifier) NotifyTimeout(ctx context.Context, coin string) {
+// NotifySwept mocks base method.
+func (m *MockNotifier) NotifySwept(ctx context.Context, amount decimal.Decimal, destination string) {
 	m.ctrl.T.Helper()
-	m.ctrl.Call(m, "NotifyTimeout", ctx, coin)
+	m.ctrl.Call(m, "NotifySwept", ctx, amount, destination)
 }
 
-// NotifyTimeout indicates an expected call of NotifyTimeout.
-func (mr *MockNotifierMockRecorder) NotifyTimeout(ctx, coin interface{}) *gomock.Call {
+// NotifySwept indicates an expected call of NotifySwept.
+func (mr *MockNotifierMockRecorder) NotifySwept(ctx, amount, destination interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NotifyTimeout", reflect.TypeOf((*MockNotifier)(nil).NotifyTimeout), ctx, coin)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NotifySwept", reflect.TypeOf((*MockNotifier)(nil).NotifySwept), ctx, amount, destination)
 }
 
 // NotifyUnsupported mocks base method.
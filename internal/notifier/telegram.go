@@ -18,6 +18,9 @@ var (
 	coinUnsupportedFmtString = "[%s] Wanted to buy coin %s but it was unsupported by gate.io :("
 	purchaseFmtString        = "[%s] Just bought %s of %s at %s per coin."
 	soldFmtString            = "[%s] Just sold %s of %s coin at %s per coin."
+	sweptFmtString           = "[%s] Moved %s USDT of profit to %s."
+	shortedFmtString         = "[%s] Just shorted %s of %s at %s per coin."
+	shortClosedFmtString     = "[%s] Short on %s closed, making %s USDT."
 )
 
 type Doer interface {
@@ -122,3 +125,57 @@ func (t Telegram) NotifySold(ctx context.Context, coin string, amount decimal.De
 		logging.Error(ctx, "failed to perform notify sold request", zap.Error(err))
 	}
 }
+
+func (t Telegram) NotifySwept(ctx context.Context, amount decimal.Decimal, destination string) {
+	if t.noOp {
+		return
+	}
+	text := fmt.Sprintf(sweptFmtString, t.botOwner, amount, destination)
+	urlWithText := fmt.Sprintf(urlFmtString, text)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, urlWithText, nil)
+	if err != nil {
+		logging.Error(ctx, "failed to build notify swept request", zap.Error(err))
+		return
+	}
+
+	if _, err := t.doer.Do(req); err != nil {
+		logging.Error(ctx, "failed to perform notify swept request", zap.Error(err))
+	}
+}
+
+func (t Telegram) NotifyShorted(ctx context.Context, contract string, amount decimal.Decimal, price decimal.Decimal) {
+	if t.noOp {
+		return
+	}
+	text := fmt.Sprintf(shortedFmtString, t.botOwner, amount, contract, price)
+	urlWithText := fmt.Sprintf(urlFmtString, text)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, urlWithText, nil)
+	if err != nil {
+		logging.Error(ctx, "failed to build notify shorted request", zap.Error(err))
+		return
+	}
+
+	if _, err := t.doer.Do(req); err != nil {
+		logging.Error(ctx, "failed to perform notify shorted request", zap.Error(err))
+	}
+}
+
+func (t Telegram) NotifyShortClosed(ctx context.Context, contract string, pnl decimal.Decimal) {
+	if t.noOp {
+		return
+	}
+	text := fmt.Sprintf(shortClosedFmtString, t.botOwner, contract, pnl)
+	urlWithText := fmt.Sprintf(urlFmtString, text)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, urlWithText, nil)
+	if err != nil {
+		logging.Error(ctx, "failed to build notify short closed request", zap.Error(err))
+		return
+	}
+
+	if _, err := t.doer.Do(req); err != nil {
+		logging.Error(ctx, "failed to perform notify short closed request", zap.Error(err))
+	}
+}
@@ -23,9 +23,14 @@ import (
 
 const (
 	tableName          = "coin_history"
+	sweepTableName     = "profit_sweeps"
+	shortTableName     = "short_positions"
 	statusAwaitingSale = "AWAITING_SALE"
 	statusCompleted    = "COMPLETED"
 	statusUnsupported  = "UNSUPPORTED"
+	statusFiltered     = "FILTERED"
+	statusShortOpen    = "OPEN"
+	statusShortClosed  = "CLOSED"
 )
 
 type CoinItem struct {
@@ -35,6 +40,52 @@ type CoinItem struct {
 	PurchaseTime   time.Time
 	TimeoutTime    time.Time
 	PurchaseStatus string
+	SignalSource   string `dynamodbav:",omitempty"`
+	FilterReason   string `dynamodbav:",omitempty"`
+	OrderID        string `dynamodbav:",omitempty"`
+	ClientOrderID  string `dynamodbav:",omitempty"`
+	FilledTotal    string `dynamodbav:",omitempty"`
+	Fee            string `dynamodbav:",omitempty"`
+	FeeCurrency    string `dynamodbav:",omitempty"`
+	GtFee          string `dynamodbav:",omitempty"`
+	QuoteFee       string `dynamodbav:",omitempty"`
+	CostPrice      string `dynamodbav:",omitempty"`
+	AmountLeft     string `dynamodbav:",omitempty"`
+	TakeProfitID   string `dynamodbav:",omitempty"`
+	StopID         string `dynamodbav:",omitempty"`
+	Venue          string `dynamodbav:",omitempty"`
+	Pair           string `dynamodbav:",omitempty"`
+}
+
+// SweepItem is a ledger entry for profit moved out of the trading account.
+type SweepItem struct {
+	// SweepID is when the sweep was made followed by the coin whose sale it came from, so IDs sort by time.
+	SweepID     string
+	Coin        string
+	Profit      string
+	Amount      string
+	Destination string
+	SweptAt     time.Time
+}
+
+// ShortItem is a short on a perpetual futures contract. Contracts are only ever shorted once, so it's keyed on the
+// contract.
+type ShortItem struct {
+	Contract        string
+	CoinSymbol      string
+	Contracts       int64
+	Amount          string
+	EntryPrice      string
+	Margin          string
+	Leverage        int64
+	StopPrice       string
+	TakeProfitPrice string
+	TakeProfitID    string `dynamodbav:",omitempty"`
+	StopID          string `dynamodbav:",omitempty"`
+	OpenedAt        time.Time
+	ShortStatus     string
+	Pnl             string `dynamodbav:",omitempty"`
+	ClosedAt        time.Time
 }
 
 type Dynamo struct {
@@ -85,12 +136,25 @@ func (d *Dynamo) GetCoinsToConsider(ctx context.Context) ([]trader.SellingDetail
 		if err != nil {
 			return nil, fmt.Errorf("failed to convert PurchasePrice to decimal: %w", err)
 		}
+		// rows from before fees were tracked have no cost price, the seller falls back to the purchase price.
+		cprice, err := parseOptionalDecimal(detail.CostPrice)
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert CostPrice to decimal: %w", err)
+		}
 		details = append(details, trader.SellingDetails{
 			Coin:            detail.CoinSymbol,
 			AmountPurchased: pamt,
 			PurchaseTime:    detail.PurchaseTime,
 			Timeout:         detail.TimeoutTime,
 			PurchasePrice:   pprice,
+			CostPrice:       cprice,
+			Bracket: trader.Bracket{
+				TakeProfitOrderID: detail.TakeProfitID,
+				StopOrderID:       detail.StopID,
+			},
+			ClientOrderID: detail.ClientOrderID,
+			Venue:         detail.Venue,
+			Pair:          detail.Pair,
 		})
 	}
 	return details, nil
@@ -120,6 +184,61 @@ func (d *Dynamo) MarkCoinAsCompleted(ctx context.Context, coin string) error {
 	return nil
 }
 
+// UpdateCoinAmount sets how much of a coin is still held, after part of it has been sold.
+func (d *Dynamo) UpdateCoinAmount(ctx context.Context, coin string, amount decimal.Decimal) error {
+	input := &dynamodb.UpdateItemInput{
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":a": {
+				S: aws.String(amount.String()),
+			},
+		},
+		TableName: aws.String(tableName),
+		Key: map[string]*dynamodb.AttributeValue{
+			"CoinSymbol": {
+				S: aws.String(coin),
+			},
+		},
+		ReturnValues:     aws.String("UPDATED_NEW"),
+		UpdateExpression: aws.String("set PurchaseAmount = :a"),
+	}
+
+	if _, err := d.session.UpdateItemWithContext(ctx, input); err != nil {
+		return fmt.Errorf("failed to update coin amount: %w", err)
+	}
+	return nil
+}
+
+// StoreBracket records the exchange orders protecting a position. An empty bracket removes them.
+func (d *Dynamo) StoreBracket(ctx context.Context, coin string, bracket trader.Bracket) error {
+	input := &dynamodb.UpdateItemInput{
+		TableName: aws.String(tableName),
+		Key: map[string]*dynamodb.AttributeValue{
+			"CoinSymbol": {
+				S: aws.String(coin),
+			},
+		},
+		ReturnValues:     aws.String("UPDATED_NEW"),
+		UpdateExpression: aws.String("remove TakeProfitID, StopID"),
+	}
+
+	if !bracket.IsZero() {
+		input.ExpressionAttributeValues = map[string]*dynamodb.AttributeValue{
+			":t": {
+				S: aws.String(bracket.TakeProfitOrderID),
+			},
+			":s": {
+				S: aws.String(bracket.StopOrderID),
+			},
+		}
+		input.UpdateExpression = aws.String("set TakeProfitID = :t, StopID = :s")
+	}
+
+	if _, err := d.session.UpdateItemWithContext(ctx, input); err != nil {
+		return fmt.Errorf("failed to store bracket: %w", err)
+	}
+	return nil
+}
+
 func (d *Dynamo) CheckUniqueCoin(ctx context.Context, coin string) bool {
 	filter := expression.Name("CoinSymbol").Equal(expression.Value(coin))
 	expr, err := expression.NewBuilder().WithFilter(filter).Build()
@@ -164,14 +283,50 @@ func (d Dynamo) StoreCoinUnsupported(ctx context.Context, coin string) error {
 	return nil
 }
 
-func (d *Dynamo) StoreCoinPurchased(ctx context.Context, coin string, purchasePrice decimal.Decimal, amountPurchased decimal.Decimal, timeout time.Time) error {
+// StoreCoinFiltered records a coin the buyer's filter skipped, and why, so skipped signals can be audited.
+func (d *Dynamo) StoreCoinFiltered(ctx context.Context, coin string, source string, reason string) error {
 	c := CoinItem{
 		CoinSymbol:     coin,
-		PurchasePrice:  purchasePrice.String(),
-		PurchaseAmount: amountPurchased.String(),
+		PurchaseStatus: statusFiltered,
+		SignalSource:   source,
+		FilterReason:   reason,
+	}
+	av, err := dynamodbattribute.MarshalMap(c)
+	if err != nil {
+		return err
+	}
+	input := &dynamodb.PutItemInput{
+		Item:      av,
+		TableName: aws.String(tableName),
+	}
+
+	_, err = d.session.PutItemWithContext(ctx, input)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// StoreCoinPurchased records a buy. The amount stored is what the bot owns after fees, so it never sells more than it bought.
+func (d *Dynamo) StoreCoinPurchased(ctx context.Context, coin string, fill trader.Fill, timeout time.Time) error {
+	c := CoinItem{
+		CoinSymbol:     coin,
+		PurchasePrice:  fill.Price.String(),
+		PurchaseAmount: fill.Received(coin).String(),
 		PurchaseTime:   time.Now(),
 		TimeoutTime:    timeout,
 		PurchaseStatus: statusAwaitingSale,
+		OrderID:        fill.OrderID,
+		ClientOrderID:  fill.ClientOrderID,
+		FilledTotal:    fill.FilledTotal.String(),
+		Fee:            fill.Fee.String(),
+		FeeCurrency:    fill.FeeCurrency,
+		GtFee:          fill.GtFee.String(),
+		QuoteFee:       fill.QuoteFee.String(),
+		CostPrice:      fill.CostPrice(coin).String(),
+		AmountLeft:     fill.Left.String(),
+		Venue:          fill.Venue,
+		Pair:           fill.Pair,
 	}
 	av, err := dynamodbattribute.MarshalMap(c)
 	if err != nil {
@@ -188,3 +343,179 @@ func (d *Dynamo) StoreCoinPurchased(ctx context.Context, coin string, purchasePr
 	}
 	return nil
 }
+
+func parseOptionalDecimal(s string) (decimal.Decimal, error) {
+	if s == "" {
+		return decimal.Zero, nil
+	}
+	return decimal.NewFromString(s)
+}
+
+// StoreProfitSweep adds sweep to the ledger of profit moved out of the trading account.
+func (d *Dynamo) StoreProfitSweep(ctx context.Context, sweep trader.ProfitSweep) error {
+	item := SweepItem{
+		SweepID:     sweep.At.UTC().Format(time.RFC3339Nano) + "-" + sweep.Coin,
+		Coin:        sweep.Coin,
+		Profit:      sweep.Profit.String(),
+		Amount:      sweep.Amount.String(),
+		Destination: sweep.Destination,
+		SweptAt:     sweep.At,
+	}
+	av, err := dynamodbattribute.MarshalMap(item)
+	if err != nil {
+		return err
+	}
+	input := &dynamodb.PutItemInput{
+		Item:      av,
+		TableName: aws.String(sweepTableName),
+	}
+
+	_, err = d.session.PutItemWithContext(ctx, input)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// CheckUniqueShort reports whether contract has never been shorted.
+func (d *Dynamo) CheckUniqueShort(ctx context.Context, contract string) bool {
+	input := &dynamodb.GetItemInput{
+		TableName: aws.String(shortTableName),
+		Key: map[string]*dynamodb.AttributeValue{
+			"Contract": {
+				S: aws.String(contract),
+			},
+		},
+	}
+
+	result, err := d.session.GetItemWithContext(ctx, input)
+	if err != nil {
+		return false
+	}
+
+	return len(result.Item) == 0
+}
+
+// GetOpenShorts returns every short the exchange hasn't closed yet.
+func (d *Dynamo) GetOpenShorts(ctx context.Context) ([]trader.ShortPosition, error) {
+	filter := expression.Name("ShortStatus").Equal(expression.Value(statusShortOpen))
+	expr, err := expression.NewBuilder().WithFilter(filter).Build()
+	if err != nil {
+		return nil, err
+	}
+
+	params := &dynamodb.ScanInput{
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+		FilterExpression:          expr.Filter(),
+		TableName:                 aws.String(shortTableName),
+	}
+
+	result, err := d.session.ScanWithContext(ctx, params)
+	if err != nil {
+		return nil, fmt.Errorf("query API call failed: %w", err)
+	}
+
+	var positions []trader.ShortPosition
+	for _, v := range result.Items {
+		item := ShortItem{}
+		if err := dynamodbattribute.UnmarshalMap(v, &item); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal short: %w", err)
+		}
+
+		p := trader.ShortPosition{
+			Coin:      item.CoinSymbol,
+			Contract:  item.Contract,
+			Contracts: item.Contracts,
+			Leverage:  item.Leverage,
+			Bracket: trader.Bracket{
+				TakeProfitOrderID: item.TakeProfitID,
+				StopOrderID:       item.StopID,
+			},
+			OpenedAt: item.OpenedAt,
+		}
+		for _, f := range []struct {
+			dst  *decimal.Decimal
+			src  string
+			name string
+		}{
+			{&p.Amount, item.Amount, "Amount"},
+			{&p.EntryPrice, item.EntryPrice, "EntryPrice"},
+			{&p.Margin, item.Margin, "Margin"},
+			{&p.StopPrice, item.StopPrice, "StopPrice"},
+			{&p.TakeProfitPrice, item.TakeProfitPrice, "TakeProfitPrice"},
+		} {
+			if *f.dst, err = parseOptionalDecimal(f.src); err != nil {
+				return nil, fmt.Errorf("failed to convert %s to decimal: %w", f.name, err)
+			}
+		}
+		positions = append(positions, p)
+	}
+	return positions, nil
+}
+
+// StoreShortOpened records a short the exchange has opened.
+func (d *Dynamo) StoreShortOpened(ctx context.Context, position trader.ShortPosition) error {
+	item := ShortItem{
+		Contract:        position.Contract,
+		CoinSymbol:      position.Coin,
+		Contracts:       position.Contracts,
+		Amount:          position.Amount.String(),
+		EntryPrice:      position.EntryPrice.String(),
+		Margin:          position.Margin.String(),
+		Leverage:        position.Leverage,
+		StopPrice:       position.StopPrice.String(),
+		TakeProfitPrice: position.TakeProfitPrice.String(),
+		TakeProfitID:    position.Bracket.TakeProfitOrderID,
+		StopID:          position.Bracket.StopOrderID,
+		OpenedAt:        position.OpenedAt,
+		ShortStatus:     statusShortOpen,
+	}
+	av, err := dynamodbattribute.MarshalMap(item)
+	if err != nil {
+		return err
+	}
+	input := &dynamodb.PutItemInput{
+		Item:      av,
+		TableName: aws.String(shortTableName),
+	}
+
+	_, err = d.session.PutItemWithContext(ctx, input)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// MarkShortClosed records that the short on contract was closed, and what it made.
+func (d *Dynamo) MarkShortClosed(ctx context.Context, contract string, pnl decimal.Decimal) error {
+	closedAt, err := dynamodbattribute.Marshal(time.Now())
+	if err != nil {
+		return err
+	}
+
+	input := &dynamodb.UpdateItemInput{
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":s": {
+				S: aws.String(statusShortClosed),
+			},
+			":p": {
+				S: aws.String(pnl.String()),
+			},
+			":c": closedAt,
+		},
+		TableName: aws.String(shortTableName),
+		Key: map[string]*dynamodb.AttributeValue{
+			"Contract": {
+				S: aws.String(contract),
+			},
+		},
+		ReturnValues:     aws.String("UPDATED_NEW"),
+		UpdateExpression: aws.String("set ShortStatus = :s, Pnl = :p, ClosedAt = :c"),
+	}
+
+	if _, err := d.session.UpdateItemWithContext(ctx, input); err != nil {
+		return fmt.Errorf("failed to mark short as closed: %w", err)
+	}
+	return nil
+}
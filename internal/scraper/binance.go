@@ -40,16 +40,23 @@ type binanceScrapeResponse struct {
 type Binance struct {
 	currentPageSize int
 	doer            Doer
+	titles          map[string]string
 }
 
 func NewBinance(doer Doer) *Binance {
-	return &Binance{doer: doer, currentPageSize: 1}
+	return &Binance{doer: doer, currentPageSize: 1, titles: make(map[string]string)}
 }
 
 func (b *Binance) Name() string {
 	return "binance"
 }
 
+// Title returns the announcement title coin was matched in, given it was the last coin scraped.
+func (b *Binance) Title(coin string) (string, bool) {
+	t, ok := b.titles[coin]
+	return t, ok
+}
+
 func (b *Binance) Scrape(ctx context.Context) (coin string, err error) {
 	if b.currentPageSize == 200 {
 		b.currentPageSize = 1
@@ -81,6 +88,8 @@ func (b *Binance) Scrape(ctx context.Context) (coin string, err error) {
 	if strings.Contains(lowerTitle, keyword) {
 		logging.Info(ctx, "got a match!", zap.String("title", lowerTitle))
 		s := r.FindString(lowerTitle)[1:]
+		// only the latest match is kept, it's all Title is asked about and the map would otherwise grow forever.
+		b.titles = map[string]string{s: scrapeRes.Data.Articles[0].Title}
 		return s, nil
 	}
 
@@ -97,6 +97,10 @@ func TestBinance_Scrape(t *testing.T) {
 			require.NoError(t, err)
 
 			require.Equal(t, v.expectedCoin, coin)
+
+			title, ok := binanceScraper.Title(coin)
+			require.True(t, ok)
+			require.Contains(t, title, "Binance Will List")
 		}
 
 	})
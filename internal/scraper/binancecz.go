@@ -37,16 +37,23 @@ type binanceCZScrapeResponse struct {
 type BinanceCZ struct {
 	currentPageSize int
 	doer            Doer
+	titles          map[string]string
 }
 
 func NewBinanceCZ(doer Doer) *BinanceCZ {
-	return &BinanceCZ{doer: doer, currentPageSize: 1}
+	return &BinanceCZ{doer: doer, currentPageSize: 1, titles: make(map[string]string)}
 }
 
 func (b *BinanceCZ) Name() string {
 	return "binanceCZ"
 }
 
+// Title returns the announcement title coin was matched in, given it was the last coin scraped.
+func (b *BinanceCZ) Title(coin string) (string, bool) {
+	t, ok := b.titles[coin]
+	return t, ok
+}
+
 func (b *BinanceCZ) Scrape(ctx context.Context) (coin string, err error) {
 	if b.currentPageSize == 200 {
 		b.currentPageSize = 1
@@ -79,6 +86,8 @@ func (b *BinanceCZ) Scrape(ctx context.Context) (coin string, err error) {
 		if strings.Contains(lowerTitle, keyword) {
 			logging.Info(ctx, "got a match!", zap.String("title", lowerTitle))
 			s := r.FindString(lowerTitle)[1:]
+			// only the latest match is kept, it's all Title is asked about and the map would otherwise grow forever.
+			b.titles = map[string]string{s: c.Articles[0].Title}
 			return s, nil
 		}
 	}
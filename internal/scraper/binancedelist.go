@@ -0,0 +1,130 @@
+package scraper
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/moonr-app/crypto-signal-trading-bot/internal/shared/logging"
+)
+
+const delistKeyword = "will delist"
+
+var (
+	// delistDate is where the list of coins in a delisting title ends, e.g. "binance will delist ant, vai on 2024-02-20".
+	delistDate = regexp.MustCompile(`\s+on\s+\d{4}-\d{2}-\d{2}.*$`)
+	// delistSeparator splits the list of coins, e.g. "ant, multi & vai" or "ant and vai".
+	delistSeparator = regexp.MustCompile(`\s*(?:,|&|\band\b)\s*`)
+	delistSymbol    = regexp.MustCompile(`^[a-z0-9]+$`)
+)
+
+// BinanceDelisting watches Binance's delisting announcements. A single announcement often names several coins, which
+// are returned one per scrape, and each announcement is only returned once.
+type BinanceDelisting struct {
+	currentPageSize int
+	doer            Doer
+	titles          map[string]string
+	// seen are the IDs of the announcements already returned.
+	seen    map[int]bool
+	pending []string
+}
+
+func NewBinanceDelisting(doer Doer) *BinanceDelisting {
+	return &BinanceDelisting{doer: doer, currentPageSize: 1, titles: make(map[string]string), seen: make(map[int]bool)}
+}
+
+func (b *BinanceDelisting) Name() string {
+	return "binanceDelisting"
+}
+
+// Title returns the announcement title coin was matched in, given it was in the last announcement scraped.
+func (b *BinanceDelisting) Title(coin string) (string, bool) {
+	t, ok := b.titles[coin]
+	return t, ok
+}
+
+func (b *BinanceDelisting) Scrape(ctx context.Context) (coin string, err error) {
+	if len(b.pending) > 0 {
+		coin, b.pending = b.pending[0], b.pending[1:]
+		return coin, nil
+	}
+
+	if b.currentPageSize == 200 {
+		b.currentPageSize = 1
+	}
+	url := fmt.Sprintf("https://www.binance.com/bapi/composite/v1/public/cms/article/catalog/list/query?catalogId=161&pageNo=1&pageSize=%d", b.currentPageSize)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create binance req: %w", err)
+	}
+	res, err := b.doer.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("error doing: %w", err)
+	}
+
+	var scrapeRes binanceScrapeResponse
+	if err := json.NewDecoder(res.Body).Decode(&scrapeRes); err != nil {
+		return "", fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	b.currentPageSize++
+
+	if len(scrapeRes.Data.Articles) == 0 {
+		return "", ErrNoCoin
+	}
+
+	article := scrapeRes.Data.Articles[0]
+	if b.seen[article.ID] {
+		return "", ErrNoCoin
+	}
+
+	coins := delistedCoins(article.Title)
+	if len(coins) == 0 {
+		return "", ErrNoCoin
+	}
+	b.seen[article.ID] = true
+
+	logging.Info(ctx, "got a delisting!", zap.String("title", article.Title))
+	// only this announcement's coins are kept, the ones before it have all been returned.
+	b.titles = make(map[string]string, len(coins))
+	for _, c := range coins {
+		b.titles[c] = article.Title
+	}
+	coin, b.pending = coins[0], coins[1:]
+	return coin, nil
+}
+
+// delistedCoins are the coins a delisting announcement's title names, lower case, e.g. "Binance Will Delist ANT,
+// MULTI & VAI on 2024-02-20" or "Binance Will Delist Terra (LUNA)". Titles that aren't about delisting coins, such as
+// the removal of trading pairs, name none.
+func delistedCoins(title string) []string {
+	lowerTitle := strings.ToLower(title)
+
+	i := strings.Index(lowerTitle, delistKeyword)
+	if i < 0 {
+		return nil
+	}
+	list := delistDate.ReplaceAllString(lowerTitle[i+len(delistKeyword):], "")
+
+	if m := r.FindAllStringSubmatch(list, -1); len(m) > 0 {
+		var coins []string
+		for _, s := range m {
+			coins = append(coins, strings.TrimSpace(s[1]))
+		}
+		return coins
+	}
+
+	var coins []string
+	for _, s := range delistSeparator.Split(strings.TrimSpace(list), -1) {
+		if delistSymbol.MatchString(s) {
+			coins = append(coins, s)
+		}
+	}
+	return coins
+}
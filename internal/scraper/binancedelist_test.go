@@ -0,0 +1,127 @@
+package scraper_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/moonr-app/crypto-signal-trading-bot/internal/mocks"
+	"github.com/moonr-app/crypto-signal-trading-bot/internal/scraper"
+	"github.com/stretchr/testify/require"
+)
+
+func delistingResponse(id int, title string) *http.Response {
+	body := fmt.Sprintf(`{"data":{"articles":[{"id":%d,"code":"e75ededcc356463a94786de743009a31","title":%q,"body":null,"type":null,"catalogId":null,"catalogName":null,"publishDate":null}]}}`, id, title)
+	return &http.Response{Body: io.NopCloser(bytes.NewReader([]byte(body)))}
+}
+
+func TestBinanceDelisting_Scrape(t *testing.T) {
+	t.Run("returns an error given failure to scrape", func(t *testing.T) {
+		var (
+			ctrl = gomock.NewController(t)
+			doer = mocks.NewMockDoer(ctrl)
+		)
+		defer ctrl.Finish()
+
+		doer.EXPECT().Do(gomock.Any()).Return(nil, errors.New("some-error"))
+
+		coin, err := scraper.NewBinanceDelisting(doer).Scrape(context.Background())
+		require.Empty(t, coin)
+		require.Error(t, err)
+	})
+
+	t.Run("returns error no coin given the pairs being removed rather than coins", func(t *testing.T) {
+		var (
+			ctrl = gomock.NewController(t)
+			doer = mocks.NewMockDoer(ctrl)
+		)
+		defer ctrl.Finish()
+
+		doer.EXPECT().Do(gomock.Any()).Return(delistingResponse(1, "Notice of Removal of Spot Trading Pairs - 2024-02-23"), nil)
+
+		coin, err := scraper.NewBinanceDelisting(doer).Scrape(context.Background())
+		require.Empty(t, coin)
+		require.True(t, errors.Is(err, scraper.ErrNoCoin))
+	})
+
+	t.Run("returns every coin in an announcement once", func(t *testing.T) {
+		var (
+			ctrl = gomock.NewController(t)
+			doer = mocks.NewMockDoer(ctrl)
+		)
+		defer ctrl.Finish()
+
+		const title = "Binance Will Delist ANT, MULTI, VAI & XMR on 2024-02-20"
+		doer.EXPECT().Do(gomock.Any()).Return(delistingResponse(2, title), nil)
+		doer.EXPECT().Do(gomock.Any()).Return(delistingResponse(2, title), nil)
+
+		binanceScraper := scraper.NewBinanceDelisting(doer)
+
+		for _, expected := range []string{"ant", "multi", "vai", "xmr"} {
+			coin, err := binanceScraper.Scrape(context.Background())
+			require.NoError(t, err)
+			require.Equal(t, expected, coin)
+
+			got, ok := binanceScraper.Title(coin)
+			require.True(t, ok)
+			require.Equal(t, title, got)
+		}
+
+		coin, err := binanceScraper.Scrape(context.Background())
+		require.Empty(t, coin)
+		require.True(t, errors.Is(err, scraper.ErrNoCoin))
+	})
+
+	t.Run("keeps only the titles of the last announcement", func(t *testing.T) {
+		var (
+			ctrl = gomock.NewController(t)
+			doer = mocks.NewMockDoer(ctrl)
+		)
+		defer ctrl.Finish()
+
+		doer.EXPECT().Do(gomock.Any()).Return(delistingResponse(20, "Binance Will Delist BEAM on 2021-12-06"), nil)
+		doer.EXPECT().Do(gomock.Any()).Return(delistingResponse(21, "Binance Will Delist Terra (LUNA)"), nil)
+
+		binanceScraper := scraper.NewBinanceDelisting(doer)
+		for _, expected := range []string{"beam", "luna"} {
+			coin, err := binanceScraper.Scrape(context.Background())
+			require.NoError(t, err)
+			require.Equal(t, expected, coin)
+		}
+
+		_, ok := binanceScraper.Title("beam")
+		require.False(t, ok)
+		_, ok = binanceScraper.Title("luna")
+		require.True(t, ok)
+	})
+
+	t.Run("returns a match given varying title types", func(t *testing.T) {
+		tests := []struct {
+			title        string
+			expectedCoin string
+		}{
+			{title: "Binance Will Delist Terra (LUNA)", expectedCoin: "luna"},
+			{title: "Binance Will Delist BEAM on 2021-12-06", expectedCoin: "beam"},
+			{title: "Binance Will Delist BTCST and DREP on 2022-03-04", expectedCoin: "btcst"},
+		}
+
+		var (
+			ctrl = gomock.NewController(t)
+			doer = mocks.NewMockDoer(ctrl)
+		)
+		defer ctrl.Finish()
+
+		for i, v := range tests {
+			doer.EXPECT().Do(gomock.Any()).Return(delistingResponse(10+i, v.title), nil)
+
+			coin, err := scraper.NewBinanceDelisting(doer).Scrape(context.Background())
+			require.NoError(t, err)
+			require.Equal(t, v.expectedCoin, coin)
+		}
+	})
+}
@@ -0,0 +1,338 @@
+package scraper
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/moonr-app/crypto-signal-trading-bot/internal/shared/logging"
+)
+
+const (
+	// UniswapV2Factory and UniswapV3Factory are the mainnet factory contracts watched by default.
+	UniswapV2Factory = "0x5c69bee701ef814a2b6a3edd4b1652cb9cc5aa6f"
+	UniswapV3Factory = "0x1f98431c8ad98523631ae4a59f267346ea31f984"
+
+	// keccak256("PairCreated(address,address,address,uint256)")
+	pairCreatedTopic = "0x0d3648bd0f6ba80134a33ba9275ac585d9d315f0ad8355cddefde31afa28d0e9"
+	// keccak256("PoolCreated(address,address,uint24,int24,address)")
+	poolCreatedTopic = "0x783cca1c0412dd0d695e784568c96da2e9c22ff989357a2e8b1d9b2b4e6b7118"
+	// first four bytes of keccak256("symbol()")
+	symbolSelector = "0x95d89b41"
+
+	maxBlockRange = 1000
+
+	// chainEthereum is the chain name gate.io uses for ethereum mainnet.
+	chainEthereum = "ETH"
+)
+
+// quoteTokens are the tokens new pools are usually opened against, they are never the interesting side of a pair.
+var quoteTokens = map[string]struct{}{
+	"0xc02aaa39b223fe8d0a0e5c4f27ead9083c756cc2": {}, // WETH
+	"0xa0b86991c6218b36c1d19d4a2e9eb0ce3606eb48": {}, // USDC
+	"0xdac17f958d2ee523a2206206994597c13d831ec7": {}, // USDT
+	"0x6b175474e89094c44da98b954eedeac495271d0f": {}, // DAI
+}
+
+type rpcRequest struct {
+	JSONRPC string        `json:"jsonrpc"`
+	ID      int           `json:"id"`
+	Method  string        `json:"method"`
+	Params  []interface{} `json:"params"`
+}
+
+type rpcResponse struct {
+	Result json.RawMessage `json:"result"`
+	Error  *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+type rpcLog struct {
+	Address     string   `json:"address"`
+	Topics      []string `json:"topics"`
+	BlockNumber string   `json:"blockNumber"`
+}
+
+// Ethereum watches DEX factory contracts over JSON-RPC for newly created liquidity pools.
+// A pool appearing is a much weaker signal than an exchange announcement, so its matches are low confidence.
+type Ethereum struct {
+	doer       Doer
+	rpcURL     string
+	factories  []string
+	requestID  int
+	lastBlock  uint64
+	pending    []string
+	seenTokens map[string]struct{}
+	contracts  map[string]string
+}
+
+func NewEthereum(doer Doer, rpcURL string, factories ...string) *Ethereum {
+	if len(factories) == 0 {
+		factories = []string{UniswapV2Factory, UniswapV3Factory}
+	}
+
+	lower := make([]string, 0, len(factories))
+	for _, f := range factories {
+		lower = append(lower, strings.ToLower(f))
+	}
+
+	return &Ethereum{
+		doer:       doer,
+		rpcURL:     rpcURL,
+		factories:  lower,
+		seenTokens: make(map[string]struct{}),
+		contracts:  make(map[string]string),
+	}
+}
+
+func (e *Ethereum) Name() string {
+	return "ethereum"
+}
+
+func (e *Ethereum) Confidence() Confidence {
+	return ConfidenceLow
+}
+
+// Contract returns the chain and token address a symbol returned by Scrape was seen at.
+func (e *Ethereum) Contract(coin string) (chain string, contract string, ok bool) {
+	contract, ok = e.contracts[coin]
+	if !ok {
+		return "", "", false
+	}
+	return chainEthereum, contract, true
+}
+
+// Scrape returns the symbol of one token that has had a pool created since the last call.
+// The first call only records the current block, so history is never replayed on startup.
+func (e *Ethereum) Scrape(ctx context.Context) (coin string, err error) {
+	if len(e.pending) > 0 {
+		return e.popPending(), nil
+	}
+
+	latest, err := e.blockNumber(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to get block number: %w", err)
+	}
+
+	if e.lastBlock == 0 {
+		e.lastBlock = latest
+		return "", ErrNoCoin
+	}
+
+	if latest <= e.lastBlock {
+		return "", ErrNoCoin
+	}
+
+	to := latest
+	if to-e.lastBlock > maxBlockRange {
+		to = e.lastBlock + maxBlockRange
+	}
+
+	logs, err := e.getLogs(ctx, e.lastBlock+1, to)
+	if err != nil {
+		return "", fmt.Errorf("failed to get logs: %w", err)
+	}
+	e.lastBlock = to
+
+	for _, l := range logs {
+		for _, token := range newTokens(l) {
+			if _, ok := e.seenTokens[token]; ok {
+				continue
+			}
+			e.seenTokens[token] = struct{}{}
+
+			symbol, err := e.symbol(ctx, token)
+			if err != nil {
+				logging.Warn(ctx, "failed to resolve token symbol", zap.String("token", token), zap.Error(err))
+				continue
+			}
+
+			logging.Info(ctx, "got a new pool!", zap.String("token", token), zap.String("symbol", symbol))
+			e.pending = append(e.pending, symbol)
+			e.contracts[symbol] = token
+		}
+	}
+
+	if len(e.pending) == 0 {
+		return "", ErrNoCoin
+	}
+	return e.popPending(), nil
+}
+
+func (e *Ethereum) popPending() string {
+	coin := e.pending[0]
+	e.pending = e.pending[1:]
+	return coin
+}
+
+// newTokens returns the non-quote tokens of a PairCreated/PoolCreated log. Both events index token0 and token1
+// as the first two topics after the signature.
+func newTokens(l rpcLog) []string {
+	if len(l.Topics) < 3 {
+		return nil
+	}
+
+	var tokens []string
+	for _, topic := range l.Topics[1:3] {
+		token := topicToAddress(topic)
+		if _, ok := quoteTokens[token]; ok {
+			continue
+		}
+		tokens = append(tokens, token)
+	}
+	return tokens
+}
+
+func topicToAddress(topic string) string {
+	topic = strings.ToLower(strings.TrimPrefix(topic, "0x"))
+	if len(topic) < 40 {
+		return "0x" + topic
+	}
+	return "0x" + topic[len(topic)-40:]
+}
+
+func (e *Ethereum) blockNumber(ctx context.Context) (uint64, error) {
+	var res string
+	if err := e.call(ctx, "eth_blockNumber", []interface{}{}, &res); err != nil {
+		return 0, err
+	}
+	return parseHexUint(res)
+}
+
+func (e *Ethereum) getLogs(ctx context.Context, from, to uint64) ([]rpcLog, error) {
+	filter := map[string]interface{}{
+		"fromBlock": fmt.Sprintf("0x%x", from),
+		"toBlock":   fmt.Sprintf("0x%x", to),
+		"address":   e.factories,
+		"topics":    []interface{}{[]string{pairCreatedTopic, poolCreatedTopic}},
+	}
+
+	var logs []rpcLog
+	if err := e.call(ctx, "eth_getLogs", []interface{}{filter}, &logs); err != nil {
+		return nil, err
+	}
+	return logs, nil
+}
+
+func (e *Ethereum) symbol(ctx context.Context, token string) (string, error) {
+	msg := map[string]string{
+		"to":   token,
+		"data": symbolSelector,
+	}
+
+	var res string
+	if err := e.call(ctx, "eth_call", []interface{}{msg, "latest"}, &res); err != nil {
+		return "", err
+	}
+
+	raw, err := hex.DecodeString(strings.TrimPrefix(res, "0x"))
+	if err != nil {
+		return "", fmt.Errorf("invalid eth_call result: %w", err)
+	}
+
+	symbol, err := decodeABIString(raw)
+	if err != nil {
+		return "", err
+	}
+	if symbol == "" {
+		return "", errors.New("token has an empty symbol")
+	}
+	return symbol, nil
+}
+
+// decodeABIString decodes a symbol() return value. Most tokens return a dynamic string, but some older ones
+// (MKR being the famous example) return a bytes32.
+func decodeABIString(raw []byte) (string, error) {
+	const word = 32
+
+	if len(raw) == word {
+		return strings.TrimSpace(string(bytes.TrimRight(raw, "\x00"))), nil
+	}
+
+	if len(raw) < 2*word {
+		return "", fmt.Errorf("abi string too short: %d bytes", len(raw))
+	}
+
+	// the checks are written so a hostile offset or length can't overflow past them.
+	size := uint64(len(raw))
+	offset := abiUint(raw[:word])
+	if offset > size || word > size-offset {
+		return "", errors.New("abi string offset out of range")
+	}
+
+	length := abiUint(raw[offset : offset+word])
+	start := offset + word
+	if length > size-start {
+		return "", errors.New("abi string length out of range")
+	}
+
+	return strings.TrimSpace(string(raw[start : start+length])), nil
+}
+
+// abiUint reads the low 8 bytes of a 32 byte ABI word, anything larger is not a sensible offset or length anyway.
+func abiUint(w []byte) uint64 {
+	return binary.BigEndian.Uint64(w[len(w)-8:])
+}
+
+func parseHexUint(s string) (uint64, error) {
+	n, err := strconv.ParseUint(strings.TrimPrefix(s, "0x"), 16, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid hex quantity %q: %w", s, err)
+	}
+	return n, nil
+}
+
+func (e *Ethereum) call(ctx context.Context, method string, params []interface{}, out interface{}) error {
+	e.requestID++
+
+	body, err := json.Marshal(rpcRequest{
+		JSONRPC: "2.0",
+		ID:      e.requestID,
+		Method:  method,
+		Params:  params,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s request: %w", method, err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.rpcURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create %s req: %w", method, err)
+	}
+	req.Header.Add("Content-Type", "application/json")
+
+	res, err := e.doer.Do(req)
+	if err != nil {
+		return fmt.Errorf("error doing: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("bad response from json-rpc endpoint: %d", res.StatusCode)
+	}
+
+	var rpcRes rpcResponse
+	if err := json.NewDecoder(res.Body).Decode(&rpcRes); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	if rpcRes.Error != nil {
+		return fmt.Errorf("%s failed: %d %s", method, rpcRes.Error.Code, rpcRes.Error.Message)
+	}
+
+	if err := json.Unmarshal(rpcRes.Result, out); err != nil {
+		return fmt.Errorf("failed to decode %s result: %w", method, err)
+	}
+	return nil
+}
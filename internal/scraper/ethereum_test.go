@@ -0,0 +1,198 @@
+package scraper_test
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/moonr-app/crypto-signal-trading-bot/internal/scraper"
+)
+
+const (
+	weth     = "0xc02aaa39b223fe8d0a0e5c4f27ead9083c756cc2"
+	newToken = "0x1111111111111111111111111111111111111111"
+	oldToken = "0x2222222222222222222222222222222222222222"
+)
+
+// rpcStub is a tiny stand-in for an ethereum JSON-RPC node.
+type rpcStub struct {
+	block   uint64
+	logs    []map[string]interface{}
+	symbols map[string]string
+	methods []string
+}
+
+func (s *rpcStub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		ID     int               `json:"id"`
+		Method string            `json:"method"`
+		Params []json.RawMessage `json:"params"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	s.methods = append(s.methods, req.Method)
+
+	var result interface{}
+	switch req.Method {
+	case "eth_blockNumber":
+		result = fmt.Sprintf("0x%x", s.block)
+	case "eth_getLogs":
+		result = s.logs
+	case "eth_call":
+		var msg struct {
+			To string `json:"to"`
+		}
+		_ = json.Unmarshal(req.Params[0], &msg)
+		sym, ok := s.symbols[msg.To]
+		if !ok {
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"jsonrpc": "2.0", "id": req.ID, "error": map[string]interface{}{"code": -32000, "message": "execution reverted"},
+			})
+			return
+		}
+		result = sym
+	}
+
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{"jsonrpc": "2.0", "id": req.ID, "result": result})
+}
+
+func topic(addr string) string {
+	return "0x000000000000000000000000" + strings.TrimPrefix(addr, "0x")
+}
+
+func abiString(s string) string {
+	word := func(n int) string { return fmt.Sprintf("%064x", n) }
+	data := hex.EncodeToString([]byte(s))
+	data += strings.Repeat("0", (64-len(data)%64)%64)
+	return "0x" + word(32) + word(len(s)) + data
+}
+
+func TestEthereum_Scrape(t *testing.T) {
+	t.Run("first scrape records the current block and returns no coin", func(t *testing.T) {
+		stub := &rpcStub{block: 100}
+		srv := httptest.NewServer(stub)
+		defer srv.Close()
+
+		eth := scraper.NewEthereum(http.DefaultClient, srv.URL)
+
+		coin, err := eth.Scrape(context.Background())
+		require.Empty(t, coin)
+		require.True(t, errors.Is(err, scraper.ErrNoCoin))
+		require.Equal(t, []string{"eth_blockNumber"}, stub.methods)
+	})
+
+	t.Run("returns the non quote token of each new pool", func(t *testing.T) {
+		stub := &rpcStub{
+			block: 100,
+			symbols: map[string]string{
+				newToken: abiString("PEPE"),
+				oldToken: "0x" + hex.EncodeToString([]byte("MKR")) + strings.Repeat("0", 58),
+			},
+		}
+		srv := httptest.NewServer(stub)
+		defer srv.Close()
+
+		eth := scraper.NewEthereum(http.DefaultClient, srv.URL)
+		ctx := context.Background()
+
+		_, err := eth.Scrape(ctx)
+		require.True(t, errors.Is(err, scraper.ErrNoCoin))
+
+		stub.block = 105
+		stub.logs = []map[string]interface{}{
+			{"address": scraper.UniswapV2Factory, "topics": []string{"0x0d36", topic(newToken), topic(weth)}},
+			{"address": scraper.UniswapV3Factory, "topics": []string{"0x783c", topic(weth), topic(oldToken), topic("0xbb8")}},
+			{"address": scraper.UniswapV3Factory, "topics": []string{"0x783c", topic(newToken), topic(weth), topic("0xbb8")}},
+		}
+
+		coin, err := eth.Scrape(ctx)
+		require.NoError(t, err)
+		require.Equal(t, "PEPE", coin)
+
+		chain, contract, ok := eth.Contract(coin)
+		require.True(t, ok)
+		require.Equal(t, "ETH", chain)
+		require.Equal(t, newToken, contract)
+
+		stub.logs = nil
+		coin, err = eth.Scrape(ctx)
+		require.NoError(t, err)
+		require.Equal(t, "MKR", coin)
+
+		coin, err = eth.Scrape(ctx)
+		require.Empty(t, coin)
+		require.True(t, errors.Is(err, scraper.ErrNoCoin))
+	})
+
+	t.Run("skips tokens whose symbol cannot be resolved", func(t *testing.T) {
+		stub := &rpcStub{block: 100}
+		srv := httptest.NewServer(stub)
+		defer srv.Close()
+
+		eth := scraper.NewEthereum(http.DefaultClient, srv.URL)
+		ctx := context.Background()
+
+		_, _ = eth.Scrape(ctx)
+
+		stub.block = 101
+		stub.logs = []map[string]interface{}{
+			{"address": scraper.UniswapV2Factory, "topics": []string{"0x0d36", topic(newToken), topic(weth)}},
+		}
+
+		coin, err := eth.Scrape(ctx)
+		require.Empty(t, coin)
+		require.True(t, errors.Is(err, scraper.ErrNoCoin))
+	})
+
+	t.Run("skips tokens whose symbol offset or length would overflow", func(t *testing.T) {
+		word := func(n uint64) string { return fmt.Sprintf("%064x", n) }
+		stub := &rpcStub{
+			block: 100,
+			symbols: map[string]string{
+				newToken: "0x" + word(1<<64-32) + word(4),
+				oldToken: "0x" + word(32) + word(1<<64-1),
+			},
+		}
+		srv := httptest.NewServer(stub)
+		defer srv.Close()
+
+		eth := scraper.NewEthereum(http.DefaultClient, srv.URL)
+		ctx := context.Background()
+
+		_, _ = eth.Scrape(ctx)
+
+		stub.block = 101
+		stub.logs = []map[string]interface{}{
+			{"address": scraper.UniswapV2Factory, "topics": []string{"0x0d36", topic(newToken), topic(weth)}},
+			{"address": scraper.UniswapV2Factory, "topics": []string{"0x0d36", topic(oldToken), topic(weth)}},
+		}
+
+		coin, err := eth.Scrape(ctx)
+		require.Empty(t, coin)
+		require.True(t, errors.Is(err, scraper.ErrNoCoin))
+	})
+
+	t.Run("returns an error given the node errors", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+		defer srv.Close()
+
+		eth := scraper.NewEthereum(http.DefaultClient, srv.URL)
+
+		coin, err := eth.Scrape(context.Background())
+		require.Empty(t, coin)
+		require.Error(t, err)
+		require.False(t, errors.Is(err, scraper.ErrNoCoin))
+	})
+}
@@ -12,3 +12,11 @@ var (
 type Doer interface {
 	Do(req *http.Request) (*http.Response, error)
 }
+
+// Confidence is how far a single match from a scraper can be trusted on its own, from 0 to 1.
+type Confidence float64
+
+const (
+	ConfidenceLow  Confidence = 0.25
+	ConfidenceHigh Confidence = 1
+)
@@ -0,0 +1,85 @@
+package websocket_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/moonr-app/crypto-signal-trading-bot/internal/shared/websocket"
+)
+
+func echoServer(t *testing.T) string {
+	t.Helper()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := websocket.Upgrade(w, r)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		for {
+			msg, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			if err := conn.WriteMessage(msg); err != nil {
+				return
+			}
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	return "ws" + strings.TrimPrefix(srv.URL, "http")
+}
+
+func TestDial(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	t.Run("messages go there and back", func(t *testing.T) {
+		conn, err := websocket.Dial(ctx, echoServer(t))
+		require.NoError(t, err)
+		defer conn.Close()
+
+		for _, msg := range []string{"hello", strings.Repeat("a", 200), strings.Repeat("b", 70000)} {
+			require.NoError(t, conn.WriteMessage([]byte(msg)))
+
+			got, err := conn.ReadMessage()
+			require.NoError(t, err)
+			assert.Equal(t, msg, string(got))
+		}
+	})
+
+	t.Run("ErrClosed given the server closes", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			conn, err := websocket.Upgrade(w, r)
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}))
+		defer srv.Close()
+
+		conn, err := websocket.Dial(ctx, "ws"+strings.TrimPrefix(srv.URL, "http"))
+		require.NoError(t, err)
+		defer conn.Close()
+
+		_, err = conn.ReadMessage()
+		assert.Equal(t, websocket.ErrClosed, err)
+	})
+
+	t.Run("err given the server doesn't speak websocket", func(t *testing.T) {
+		srv := httptest.NewServer(http.NotFoundHandler())
+		defer srv.Close()
+
+		_, err := websocket.Dial(ctx, "ws"+strings.TrimPrefix(srv.URL, "http"))
+		require.Error(t, err)
+	})
+}
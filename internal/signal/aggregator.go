@@ -0,0 +1,221 @@
+package signal
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/moonr-app/crypto-signal-trading-bot/internal/scraper"
+	"github.com/moonr-app/crypto-signal-trading-bot/internal/shared/logging"
+)
+
+// defaultWeight is used for sources that have no configured weight and don't report their own confidence.
+const defaultWeight = float64(scraper.ConfidenceHigh)
+
+type Source interface {
+	Scrape(ctx context.Context) (coin string, err error)
+	Name() string
+}
+
+type confident interface {
+	Confidence() scraper.Confidence
+}
+
+// Contracted is implemented by sources that know which on chain token a coin they returned is.
+type Contracted interface {
+	Contract(coin string) (chain string, contract string, ok bool)
+}
+
+// Titled is implemented by sources that can say which announcement a coin they returned came from.
+type Titled interface {
+	Title(coin string) (string, bool)
+}
+
+type observation struct {
+	source string
+	weight float64
+	at     time.Time
+}
+
+// Aggregator merges matches for the same coin from several sources and only lets a coin through once the
+// combined weight of the distinct sources that reported it, within window, reaches threshold.
+// A source weighted at or above threshold on its own still fires straight away.
+type Aggregator struct {
+	threshold float64
+	window    time.Duration
+	weights   map[string]float64
+
+	lock         *sync.Mutex
+	observations map[string][]observation
+	fired        map[string]time.Time
+}
+
+func NewAggregator(threshold float64, window time.Duration, weights map[string]float64) *Aggregator {
+	if weights == nil {
+		weights = make(map[string]float64)
+	}
+	return &Aggregator{
+		threshold:    threshold,
+		window:       window,
+		weights:      weights,
+		lock:         &sync.Mutex{},
+		observations: make(map[string][]observation),
+		fired:        make(map[string]time.Time),
+	}
+}
+
+// Wrap returns a Source that reports s's matches to the aggregator and only returns coins that have
+// passed the threshold. Anything below it is reported as scraper.ErrNoCoin.
+func (a *Aggregator) Wrap(s Source) Source {
+	return &aggregatedSource{source: s, aggregator: a, weight: a.weightFor(s)}
+}
+
+func (a *Aggregator) weightFor(s Source) float64 {
+	if w, ok := a.weights[s.Name()]; ok {
+		return w
+	}
+	if c, ok := s.(confident); ok {
+		return float64(c.Confidence())
+	}
+	return defaultWeight
+}
+
+// Observe records that source saw coin at the given time and returns the combined score and whether
+// this observation pushed the coin over the threshold.
+func (a *Aggregator) Observe(source string, weight float64, coin string, at time.Time) (float64, bool) {
+	key := strings.ToLower(coin)
+
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	a.prune(at)
+
+	if firedAt, ok := a.fired[key]; ok {
+		if at.Sub(firedAt) < a.window {
+			return 0, false
+		}
+		delete(a.fired, key)
+	}
+
+	var (
+		kept  []observation
+		best  = map[string]float64{source: weight}
+		score float64
+	)
+	for _, o := range a.observations[key] {
+		if at.Sub(o.at) > a.window {
+			continue
+		}
+		kept = append(kept, o)
+		if o.weight > best[o.source] {
+			best[o.source] = o.weight
+		}
+	}
+	for _, w := range best {
+		score += w
+	}
+
+	if score < a.threshold {
+		a.observations[key] = append(kept, observation{source: source, weight: weight, at: at})
+		return score, false
+	}
+
+	delete(a.observations, key)
+	a.fired[key] = at
+	return score, true
+}
+
+// prune forgets every coin with nothing left inside the window as of at, so coins only ever seen once don't stay for
+// the life of the process.
+func (a *Aggregator) prune(at time.Time) {
+	for key, firedAt := range a.fired {
+		if at.Sub(firedAt) >= a.window {
+			delete(a.fired, key)
+		}
+	}
+
+	for key, observations := range a.observations {
+		expired := true
+		for _, o := range observations {
+			if at.Sub(o.at) <= a.window {
+				expired = false
+				break
+			}
+		}
+		if expired {
+			delete(a.observations, key)
+		}
+	}
+}
+
+type aggregatedSource struct {
+	source     Source
+	aggregator *Aggregator
+	weight     float64
+}
+
+func (s *aggregatedSource) Name() string {
+	return s.source.Name()
+}
+
+func (s *aggregatedSource) Contract(coin string) (string, string, bool) {
+	if c, ok := s.source.(Contracted); ok {
+		return c.Contract(coin)
+	}
+	return "", "", false
+}
+
+func (s *aggregatedSource) Title(coin string) (string, bool) {
+	if t, ok := s.source.(Titled); ok {
+		return t.Title(coin)
+	}
+	return "", false
+}
+
+func (s *aggregatedSource) Scrape(ctx context.Context) (string, error) {
+	coin, err := s.source.Scrape(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	score, ok := s.aggregator.Observe(s.source.Name(), s.weight, coin, time.Now())
+	if !ok {
+		logging.Info(
+			ctx,
+			"signal below threshold",
+			zap.String("coin", coin),
+			zap.String("source", s.source.Name()),
+			zap.Float64("score", score),
+		)
+		return "", scraper.ErrNoCoin
+	}
+
+	logging.Info(ctx, "signal confirmed", zap.String("coin", coin), zap.Float64("score", score))
+	return coin, nil
+}
+
+// ParseWeights parses a comma separated list of source=weight pairs, e.g. "coinbase=0.5,ethereum=0.25".
+func ParseWeights(s string) (map[string]float64, error) {
+	weights := make(map[string]float64)
+	if strings.TrimSpace(s) == "" {
+		return weights, nil
+	}
+
+	for _, pair := range strings.Split(s, ",") {
+		parts := strings.SplitN(pair, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid source weight %q", pair)
+		}
+		w, err := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid weight for source %q: %w", parts[0], err)
+		}
+		weights[strings.TrimSpace(parts[0])] = w
+	}
+	return weights, nil
+}
@@ -0,0 +1,138 @@
+package signal_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/moonr-app/crypto-signal-trading-bot/internal/mocks"
+	"github.com/moonr-app/crypto-signal-trading-bot/internal/scraper"
+	"github.com/moonr-app/crypto-signal-trading-bot/internal/signal"
+)
+
+func TestAggregator_Observe(t *testing.T) {
+	now := time.Now()
+
+	t.Run("high confidence source fires immediately", func(t *testing.T) {
+		a := signal.NewAggregator(1, time.Minute, nil)
+
+		score, ok := a.Observe("binance", 1, "rare", now)
+		assert.True(t, ok)
+		assert.Equal(t, float64(1), score)
+	})
+
+	t.Run("low confidence sources fire once combined score passes threshold", func(t *testing.T) {
+		a := signal.NewAggregator(1, time.Minute, nil)
+
+		_, ok := a.Observe("coinbase", 0.5, "RARE", now)
+		assert.False(t, ok)
+
+		_, ok = a.Observe("coinbase", 0.5, "RARE", now.Add(time.Second))
+		assert.False(t, ok, "the same source repeating itself is not confirmation")
+
+		score, ok := a.Observe("ethereum", 0.5, "rare", now.Add(2*time.Second))
+		assert.True(t, ok)
+		assert.Equal(t, float64(1), score)
+
+		_, ok = a.Observe("binance", 1, "rare", now.Add(3*time.Second))
+		assert.False(t, ok, "a coin only fires once per window")
+	})
+
+	t.Run("observations outside the window are forgotten", func(t *testing.T) {
+		a := signal.NewAggregator(1, time.Minute, nil)
+
+		_, ok := a.Observe("coinbase", 0.5, "rare", now)
+		assert.False(t, ok)
+
+		_, ok = a.Observe("ethereum", 0.5, "rare", now.Add(2*time.Minute))
+		assert.False(t, ok)
+	})
+}
+
+type lowConfidenceScraper struct {
+	*mocks.MockScraper
+}
+
+func (lowConfidenceScraper) Confidence() scraper.Confidence {
+	return scraper.ConfidenceLow
+}
+
+func TestAggregator_Wrap(t *testing.T) {
+	t.Run("returns no coin until confirmed", func(t *testing.T) {
+		var (
+			ctrl     = gomock.NewController(t)
+			coinbase = mocks.NewMockScraper(ctrl)
+			binance  = mocks.NewMockScraper(ctrl)
+			ctx      = context.Background()
+		)
+		defer ctrl.Finish()
+
+		coinbase.EXPECT().Name().Return("coinbase").AnyTimes()
+		binance.EXPECT().Name().Return("binance").AnyTimes()
+
+		a := signal.NewAggregator(1, time.Minute, map[string]float64{"coinbase": 0.5, "binance": 0.5})
+		wrappedCoinbase, wrappedBinance := a.Wrap(coinbase), a.Wrap(binance)
+
+		coinbase.EXPECT().Scrape(ctx).Return("RARE", nil)
+		coin, err := wrappedCoinbase.Scrape(ctx)
+		require.True(t, errors.Is(err, scraper.ErrNoCoin))
+		require.Empty(t, coin)
+
+		binance.EXPECT().Scrape(ctx).Return("rare", nil)
+		coin, err = wrappedBinance.Scrape(ctx)
+		require.NoError(t, err)
+		require.Equal(t, "rare", coin)
+	})
+
+	t.Run("uses the scrapers own confidence given no configured weight", func(t *testing.T) {
+		var (
+			ctrl = gomock.NewController(t)
+			s    = mocks.NewMockScraper(ctrl)
+			ctx  = context.Background()
+		)
+		defer ctrl.Finish()
+
+		s.EXPECT().Name().Return("ethereum").AnyTimes()
+		s.EXPECT().Scrape(ctx).Return("pepe", nil).Times(2)
+
+		wrapped := signal.NewAggregator(1, time.Minute, nil).Wrap(lowConfidenceScraper{s})
+
+		for i := 0; i < 2; i++ {
+			_, err := wrapped.Scrape(ctx)
+			require.True(t, errors.Is(err, scraper.ErrNoCoin))
+		}
+	})
+
+	t.Run("passes scrape errors through", func(t *testing.T) {
+		var (
+			ctrl = gomock.NewController(t)
+			s    = mocks.NewMockScraper(ctrl)
+			ctx  = context.Background()
+		)
+		defer ctrl.Finish()
+
+		s.EXPECT().Name().Return("binance").AnyTimes()
+		s.EXPECT().Scrape(ctx).Return("", errors.New("some-err"))
+
+		_, err := signal.NewAggregator(1, time.Minute, nil).Wrap(s).Scrape(ctx)
+		require.EqualError(t, err, "some-err")
+	})
+}
+
+func TestParseWeights(t *testing.T) {
+	w, err := signal.ParseWeights("coinbase=0.5, ethereum=0.25")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]float64{"coinbase": 0.5, "ethereum": 0.25}, w)
+
+	w, err = signal.ParseWeights("")
+	require.NoError(t, err)
+	assert.Empty(t, w)
+
+	_, err = signal.ParseWeights("coinbase")
+	require.Error(t, err)
+}
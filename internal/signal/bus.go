@@ -0,0 +1,59 @@
+package signal
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxIDCoinLength is how much of the coin goes into a signal's ID.
+const maxIDCoinLength = 12
+
+// Signal is a single match from a source that a coin may be worth buying.
+type Signal struct {
+	Coin       string
+	Source     string
+	ReceivedAt time.Time
+	// Title is the announcement the coin was found in, if there was one.
+	Title string
+	// Chain and Contract identify the token on chain, if the source knows it.
+	Chain    string
+	Contract string
+}
+
+// ID identifies the signal in the client IDs of the orders it leads to. It is made from the coin and when the signal
+// was received, and kept short enough to fit.
+func (s Signal) ID() string {
+	coin := strings.ToUpper(s.Coin)
+	if len(coin) > maxIDCoinLength {
+		coin = coin[:maxIDCoinLength]
+	}
+	if s.ReceivedAt.IsZero() {
+		return coin
+	}
+	return coin + "." + strconv.FormatInt(s.ReceivedAt.Unix(), 36)
+}
+
+// Bus carries signals from the scrapers to whatever is deciding what to buy.
+type Bus struct {
+	signals chan Signal
+}
+
+func NewBus(size int) *Bus {
+	return &Bus{signals: make(chan Signal, size)}
+}
+
+// Publish blocks until the signal is queued or ctx is done.
+func (b *Bus) Publish(ctx context.Context, s Signal) error {
+	select {
+	case b.signals <- s:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (b *Bus) Signals() <-chan Signal {
+	return b.signals
+}
@@ -0,0 +1,25 @@
+package signal_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/moonr-app/crypto-signal-trading-bot/internal/signal"
+)
+
+func TestSignal_ID(t *testing.T) {
+	t.Run("made from the coin and when it was received", func(t *testing.T) {
+		sig := signal.Signal{Coin: "rare", ReceivedAt: time.Unix(1700000000, 0)}
+		assert.Equal(t, "RARE.s44we8", sig.ID())
+	})
+	t.Run("same signal same ID", func(t *testing.T) {
+		at := time.Now()
+		assert.Equal(t, signal.Signal{Coin: "RARE", ReceivedAt: at}.ID(), signal.Signal{Coin: "RARE", ReceivedAt: at, Source: "other"}.ID())
+	})
+	t.Run("long coins are cut short", func(t *testing.T) {
+		sig := signal.Signal{Coin: "AVERYLONGCOINNAME"}
+		assert.Equal(t, "AVERYLONGCOI", sig.ID())
+	})
+}
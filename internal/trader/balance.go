@@ -0,0 +1,80 @@
+package trader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+
+	"github.com/moonr-app/crypto-signal-trading-bot/internal/shared/logging"
+)
+
+// ErrLowBalance is reported when an account has less USDT available than the floor it's meant to keep.
+var ErrLowBalance = errors.New("balance is under the floor")
+
+// BalanceSource is an exchange account whose balance is watched.
+type BalanceSource interface {
+	// Holdings is everything the account holds, by upper case currency.
+	Holdings(ctx context.Context) (map[string]Holding, error)
+}
+
+// BalanceWatcher warns the team when an account has less USDT available to spend than a floor, before buys start
+// being cut down or skipped. It warns once each time the balance drops under the floor, not on every check.
+type BalanceWatcher struct {
+	name     string
+	source   BalanceSource
+	notifier Notifier
+	floor    decimal.Decimal
+	interval time.Duration
+	// low is set once the team has been warned, until the balance is back over the floor.
+	low bool
+}
+
+// NewBalanceWatcher watches the account source, called name in warnings, every interval.
+func NewBalanceWatcher(name string, source BalanceSource, notifier Notifier, floor decimal.Decimal, interval time.Duration) *BalanceWatcher {
+	return &BalanceWatcher{name: name, source: source, notifier: notifier, floor: floor, interval: interval}
+}
+
+// Run checks the balance straight away and then every interval until ctx is done.
+func (w *BalanceWatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		// the next tick is the retry.
+		if err := w.Check(ctx); err != nil && ctx.Err() == nil {
+			logging.Error(ctx, "failed to check balance", zap.String("account", w.name), zap.Error(err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Check warns the team if the balance has dropped under the floor since it was last over it.
+func (w *BalanceWatcher) Check(ctx context.Context) error {
+	holdings, err := w.source.Holdings(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get holdings: %w", err)
+	}
+
+	available := holdings[quoteCurrency].Available
+	if available.GreaterThanOrEqual(w.floor) {
+		w.low = false
+		return nil
+	}
+	if w.low {
+		return nil
+	}
+
+	w.low = true
+	logging.Warn(ctx, "balance is low", zap.String("account", w.name), zap.String("available", available.String()))
+	w.notifier.NotifyError(ctx, fmt.Errorf("%w: %s has %s %s available, top it up to at least %s", ErrLowBalance, w.name, available, quoteCurrency, w.floor))
+	return nil
+}
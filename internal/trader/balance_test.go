@@ -0,0 +1,65 @@
+package trader_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/moonr-app/crypto-signal-trading-bot/internal/mocks"
+	"github.com/moonr-app/crypto-signal-trading-bot/internal/trader"
+)
+
+func TestBalanceWatcher_Check(t *testing.T) {
+	var (
+		ctx   = context.Background()
+		floor = decimal.NewFromInt(50)
+	)
+
+	holding := func(available int64) map[string]trader.Holding {
+		return map[string]trader.Holding{"USDT": {Available: decimal.NewFromInt(available), Locked: decimal.NewFromInt(100)}}
+	}
+
+	t.Run("warns once each time the balance drops under the floor", func(t *testing.T) {
+		var (
+			ctrl     = gomock.NewController(t)
+			source   = mocks.NewMockBalanceSource(ctrl)
+			notifier = mocks.NewMockNotifier(ctrl)
+		)
+		defer ctrl.Finish()
+
+		gomock.InOrder(
+			source.EXPECT().Holdings(ctx).Return(holding(80), nil),
+			source.EXPECT().Holdings(ctx).Return(holding(40), nil),
+			notifier.EXPECT().NotifyError(ctx, gomock.Any()).Do(func(ctx context.Context, err error) {
+				assert.True(t, errors.Is(err, trader.ErrLowBalance))
+			}),
+			source.EXPECT().Holdings(ctx).Return(holding(30), nil),
+			source.EXPECT().Holdings(ctx).Return(holding(60), nil),
+			source.EXPECT().Holdings(ctx).Return(holding(10), nil),
+			notifier.EXPECT().NotifyError(ctx, gomock.Any()),
+		)
+
+		w := trader.NewBalanceWatcher("gateio", source, notifier, floor, 0)
+		for i := 0; i < 5; i++ {
+			require.NoError(t, w.Check(ctx))
+		}
+	})
+
+	t.Run("err given holdings can't be listed", func(t *testing.T) {
+		var (
+			ctrl   = gomock.NewController(t)
+			source = mocks.NewMockBalanceSource(ctrl)
+		)
+		defer ctrl.Finish()
+
+		source.EXPECT().Holdings(ctx).Return(nil, trader.ErrExchangeUnavailable)
+
+		err := trader.NewBalanceWatcher("gateio", source, nil, floor, 0).Check(ctx)
+		assert.True(t, errors.Is(err, trader.ErrExchangeUnavailable))
+	})
+}
@@ -0,0 +1,19 @@
+package trader
+
+import "errors"
+
+// ErrBracketExpiring is returned by CheckBracket when a bracket's legs are about to lapse, or already have, so it
+// should be placed again.
+var ErrBracketExpiring = errors.New("bracket is about to expire")
+
+// Bracket is the pair of orders left resting on the exchange after a buy, so a position is still protected if the
+// bot isn't running. Either ID may be empty if the exchange doesn't place brackets.
+type Bracket struct {
+	TakeProfitOrderID string
+	StopOrderID       string
+}
+
+// IsZero reports whether there is no bracket at all.
+func (b Bracket) IsZero() bool {
+	return b.TakeProfitOrderID == "" && b.StopOrderID == ""
+}
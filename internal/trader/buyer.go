@@ -9,15 +9,17 @@ import (
 	"github.com/moonr-app/crypto-signal-trading-bot/internal/shared/logging"
 
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/shopspring/decimal"
 
-	"github.com/moonr-app/crypto-signal-trading-bot/internal/scraper"
+	"github.com/moonr-app/crypto-signal-trading-bot/internal/signal"
 )
 
 var ErrNoNewCoin = errors.New("coin is not new")
 var ErrCoinUnsupported = errors.New("coin is not supported")
+var ErrCoinAmbiguous = errors.New("coin matches more than one exchange currency")
 
 type Scraper interface {
 	Scrape(ctx context.Context) (coin string, err error)
@@ -27,34 +29,40 @@ type Scraper interface {
 type PurchaseDB interface {
 	CheckUniqueCoin(ctx context.Context, coin string) bool
 	StoreCoinUnsupported(ctx context.Context, coin string) error
-	StoreCoinPurchased(ctx context.Context, coin string, purchasePrice decimal.Decimal, amountPurchased decimal.Decimal, timeout time.Time) error
+	StoreCoinFiltered(ctx context.Context, coin string, source string, reason string) error
+	StoreCoinPurchased(ctx context.Context, coin string, fill Fill, timeout time.Time) error
+	StoreBracket(ctx context.Context, coin string, bracket Bracket) error
 }
 
 type ExchangePurchaser interface {
+	// ResolveSymbol maps a signalled coin onto the exchange's own currency symbol.
+	// It returns ErrCoinUnsupported if there is no such currency, and ErrCoinAmbiguous if it can't be sure which one is meant.
+	ResolveSymbol(ctx context.Context, coin, chain, contract string) (string, error)
 	CheckSupport(ctx context.Context, coin string) (bool, error)
-	PurchaseCoin(ctx context.Context, coin string, lastPrice decimal.Decimal) (pricePurchased decimal.Decimal, amountPurchased decimal.Decimal, err error)
-	GetLastPrice(ctx context.Context, coin string) (decimal.Decimal, error)
+	// PurchaseCoin buys coin and returns what actually filled. It returns ErrOrderNotFilled if nothing did.
+	// signalID goes into the client IDs of the orders it places, so they can be found again if placing one fails.
+	PurchaseCoin(ctx context.Context, coin string, lastPrice decimal.Decimal, signalID string) (Fill, error)
+	// GetPrice returns the latest prices for coin, observed no longer than maxAge ago.
+	GetPrice(ctx context.Context, coin string, maxAge time.Duration) (PriceSnapshot, error)
+	// PlaceBracket leaves a take-profit and a stop resting on the exchange for what was bought.
+	// It returns an empty Bracket if the exchange isn't set up to place them.
+	PlaceBracket(ctx context.Context, coin string, fill Fill) (Bracket, error)
 }
 type Buyer struct {
 	db              PurchaseDB
 	notifier        Notifier
 	exchange        ExchangePurchaser
+	filter          *Filter
 	timeoutDuration time.Duration
 }
 
-func NewBuyer(db PurchaseDB, notifier Notifier, exchange ExchangePurchaser) *Buyer {
-	return &Buyer{db: db, notifier: notifier, exchange: exchange}
+// NewBuyer creates a Buyer. filter may be nil, in which case every signal is considered.
+func NewBuyer(db PurchaseDB, notifier Notifier, exchange ExchangePurchaser, filter *Filter) *Buyer {
+	return &Buyer{db: db, notifier: notifier, exchange: exchange, filter: filter}
 }
 
-func (b *Buyer) Buy(ctx context.Context, s Scraper) error {
-	coin, err := s.Scrape(ctx)
-	if err != nil {
-		if errors.Is(err, scraper.ErrNoCoin) {
-			return ErrNoNewCoin
-		}
-
-		return fmt.Errorf("error scraping: %w", err)
-	}
+func (b *Buyer) Buy(ctx context.Context, sig signal.Signal) error {
+	coin := sig.Coin
 
 	// see if we have a new coin.
 	// if yes, check to see if we haven't seen it before.
@@ -63,44 +71,124 @@ func (b *Buyer) Buy(ctx context.Context, s Scraper) error {
 		return ErrNoNewCoin
 	}
 
-	logging.Info(ctx, "new coin found", zap.String("name", s.Name()))
+	logging.Info(ctx, "new coin found", zap.String("name", sig.Source))
+
+	if reason := b.filter.Check(sig); reason != "" {
+		logging.Info(ctx, "coin filtered", zap.String("coin", coin), zap.String("reason", reason))
+		if err := b.db.StoreCoinFiltered(ctx, coin, sig.Source, reason); err != nil {
+			return fmt.Errorf("failed to store filtered coin: %w", err)
+		}
+		return fmt.Errorf("%w: %s", ErrCoinFiltered, reason)
+	}
+
+	resolved, err := b.exchange.ResolveSymbol(ctx, coin, sig.Chain, sig.Contract)
+	switch {
+	case errors.Is(err, ErrCoinUnsupported):
+		return b.storeUnsupported(ctx, coin)
+	case errors.Is(err, ErrCoinAmbiguous):
+		// refuse to guess, someone needs to add an alias for it.
+		b.notifier.NotifyError(ctx, err)
+		if err := b.db.StoreCoinUnsupported(ctx, coin); err != nil {
+			return fmt.Errorf("failed to store coin: %w", err)
+		}
+		return err
+	case err != nil:
+		return fmt.Errorf("failed to resolve coin: %w", err)
+	}
+
+	if !strings.EqualFold(resolved, coin) {
+		logging.Info(ctx, "resolved coin", zap.String("coin", coin), zap.String("resolved", resolved))
+		if newCoin := b.isCoinNew(ctx, resolved); !newCoin {
+			return ErrNoNewCoin
+		}
+		coin = resolved
+	}
 
 	// If we have not seen it before, check to see if we can purchase it on one of the supported exchanges.
 	supported, err := b.exchange.CheckSupport(ctx, coin)
+	if errors.Is(err, ErrInvalidPair) {
+		return b.storeUnsupported(ctx, coin)
+	}
 	if err != nil {
 		return fmt.Errorf("failed to call exchange: %w", err)
 	}
 
 	// If not, log to say we couldn't; store coin in DB.
 	if !supported {
-		b.notifier.NotifyUnsupported(ctx, coin)
-		if err := b.db.StoreCoinUnsupported(ctx, coin); err != nil {
-			return fmt.Errorf("failed to store coin: %w", err)
-		}
-		return ErrCoinUnsupported
+		return b.storeUnsupported(ctx, coin)
 	}
 
-	last, err := b.exchange.GetLastPrice(ctx, coin)
+	price, err := b.exchange.GetPrice(ctx, coin, buyPriceMaxAge)
+	if errors.Is(err, ErrInvalidPair) {
+		// the currency is listed but can't be bought with the quote currency.
+		return b.storeUnsupported(ctx, coin)
+	}
 	if err != nil {
-		logging.Error(ctx, "failed to get last price", zap.Error(err))
-		return fmt.Errorf("failed to get last price: %w", err)
+		logging.Error(ctx, "failed to get price", zap.Error(err))
+		return fmt.Errorf("failed to get price: %w", err)
+	}
+	if !price.BuyPrice().IsPositive() {
+		// listed but not trading yet; the next signal can try again.
+		return fmt.Errorf("no price for %s yet", coin)
 	}
 	// if we can, make a purchase; store coin in DB.
-	price, amount, err := b.exchange.PurchaseCoin(ctx, coin, last)
-	if err != nil {
+	fill, err := b.exchange.PurchaseCoin(ctx, coin, price.BuyPrice(), sig.ID())
+	if err != nil && fill.Amount.IsPositive() {
+		// some of it was bought before the buy went wrong, and that's held all the same, so it's stored and sold like
+		// any other buy rather than left for the coin to be bought again.
+		logging.Error(ctx, "buy failed part way", zap.String("coin", coin), zap.String("amount", fill.Amount.String()), zap.Error(err))
+		b.notifier.NotifyError(ctx, fmt.Errorf("bought %s %s before the buy failed: %w", fill.Amount, coin, err))
+		err = nil
+	}
+	switch {
+	case errors.Is(err, ErrInvalidPair):
+		return b.storeUnsupported(ctx, coin)
+	case errors.Is(err, ErrInsufficientBalance), errors.Is(err, ErrExchangeAuth):
+		// nothing will be bought until someone tops up the account or fixes the keys, so tell them.
+		b.notifier.NotifyError(ctx, fmt.Errorf("failed to buy %s: %w", coin, err))
+		return fmt.Errorf("failed to purchase coin: %w", err)
+	case err != nil:
 		return fmt.Errorf("failed to purchase coin: %w", err)
 	}
 
-	if err := b.db.StoreCoinPurchased(ctx, coin, price, amount, time.Now().Add(b.timeoutDuration)); err != nil {
+	if err := b.db.StoreCoinPurchased(ctx, coin, fill, time.Now().Add(b.timeoutDuration)); err != nil {
 		e := fmt.Errorf("failed to store coin purchase details: %w", err)
 		return e
 	}
 
-	b.notifier.NotifyPurchased(ctx, coin, price, amount)
+	b.notifier.NotifyPurchased(ctx, coin, fill.Price, fill.Amount)
+	if fill.Shortfall.IsPositive() {
+		// the buy went ahead with what there was, but the account needs topping up before the next one.
+		b.notifier.NotifyError(ctx, fmt.Errorf("%w: bought %s with %s %s less than usual", ErrInsufficientBalance, coin, fill.Shortfall, quoteCurrency))
+	}
+
+	// the coin is bought whatever happens to the bracket, the seller still watches it, so only shout about failures.
+	bracket, err := b.exchange.PlaceBracket(ctx, coin, fill)
+	if err != nil {
+		logging.Error(ctx, "failed to place bracket", zap.String("coin", coin), zap.Error(err))
+		b.notifier.NotifyError(ctx, fmt.Errorf("bought %s but failed to place bracket: %w", coin, err))
+		return nil
+	}
+	if bracket.IsZero() {
+		return nil
+	}
+
+	if err := b.db.StoreBracket(ctx, coin, bracket); err != nil {
+		logging.Error(ctx, "failed to store bracket", zap.String("coin", coin), zap.Error(err))
+		b.notifier.NotifyError(ctx, fmt.Errorf("bracket placed for %s but couldn't be stored, cancel it by hand: %w", coin, err))
+	}
 
 	return nil
 }
 
+func (b *Buyer) storeUnsupported(ctx context.Context, coin string) error {
+	b.notifier.NotifyUnsupported(ctx, coin)
+	if err := b.db.StoreCoinUnsupported(ctx, coin); err != nil {
+		return fmt.Errorf("failed to store coin: %w", err)
+	}
+	return ErrCoinUnsupported
+}
+
 func (b *Buyer) isCoinNew(ctx context.Context, coin string) bool {
 	return b.db.CheckUniqueCoin(ctx, coin)
 }
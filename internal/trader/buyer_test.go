@@ -3,6 +3,7 @@ package trader_test
 import (
 	"context"
 	"errors"
+	"fmt"
 	"testing"
 
 	"github.com/golang/mock/gomock"
@@ -11,45 +12,26 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/moonr-app/crypto-signal-trading-bot/internal/mocks"
+	"github.com/moonr-app/crypto-signal-trading-bot/internal/signal"
 	"github.com/moonr-app/crypto-signal-trading-bot/internal/trader"
 )
 
 func TestBuyer_Buy(t *testing.T) {
-	t.Run("error given scraper fails", func(t *testing.T) {
-		var (
-			ctrl    = gomock.NewController(t)
-			scraper = mocks.NewMockScraper(ctrl)
-			ctx     = context.Background()
-		)
-		defer ctrl.Finish()
-
-		b := trader.NewBuyer(nil, nil, nil)
-
-		scraper.EXPECT().Scrape(ctx).Return("", errors.New("some-err"))
-
-		err := b.Buy(ctx, scraper)
-		require.Error(t, err)
-
-		assert.Contains(t, err.Error(), "error scraping")
-	})
-
 	t.Run("ErrNoNewCoin given we get no new coin", func(t *testing.T) {
 		var (
-			ctrl    = gomock.NewController(t)
-			scraper = mocks.NewMockScraper(ctrl)
-			db      = mocks.NewMockPurchaseDB(ctrl)
-			ctx     = context.Background()
+			ctrl = gomock.NewController(t)
+			db   = mocks.NewMockPurchaseDB(ctrl)
+			ctx  = context.Background()
 
 			coinToCheck = "mattcoin"
 		)
 		defer ctrl.Finish()
 
-		b := trader.NewBuyer(db, nil, nil)
+		b := trader.NewBuyer(db, nil, nil, nil)
 
-		scraper.EXPECT().Scrape(ctx).Return(coinToCheck, nil)
 		db.EXPECT().CheckUniqueCoin(ctx, coinToCheck).Return(false)
 
-		err := b.Buy(ctx, scraper)
+		err := b.Buy(ctx, signal.Signal{Coin: coinToCheck, Source: "someScraper"})
 		require.Error(t, err)
 
 		assert.True(t, errors.Is(err, trader.ErrNoNewCoin))
@@ -57,7 +39,6 @@ func TestBuyer_Buy(t *testing.T) {
 	t.Run("err given we cant call exchange", func(t *testing.T) {
 		var (
 			ctrl     = gomock.NewController(t)
-			scraper  = mocks.NewMockScraper(ctrl)
 			db       = mocks.NewMockPurchaseDB(ctrl)
 			notifier = mocks.NewMockNotifier(ctrl)
 			exchange = mocks.NewMockExchangePurchaser(ctrl)
@@ -68,16 +49,15 @@ func TestBuyer_Buy(t *testing.T) {
 		)
 		defer ctrl.Finish()
 
-		b := trader.NewBuyer(db, notifier, exchange)
+		b := trader.NewBuyer(db, notifier, exchange, nil)
 
 		gomock.InOrder(
-			scraper.EXPECT().Scrape(ctx).Return(coinToCheck, nil),
 			db.EXPECT().CheckUniqueCoin(ctx, coinToCheck).Return(true),
-			scraper.EXPECT().Name().Return("someScraper"),
+			exchange.EXPECT().ResolveSymbol(ctx, coinToCheck, "", "").Return(coinToCheck, nil),
 			exchange.EXPECT().CheckSupport(ctx, coinToCheck).Return(false, errors.New("some-err")),
 		)
 
-		err := b.Buy(ctx, scraper)
+		err := b.Buy(ctx, signal.Signal{Coin: coinToCheck, Source: "someScraper"})
 		require.Error(t, err)
 
 		assert.Contains(t, err.Error(), "failed to call exchange")
@@ -85,7 +65,6 @@ func TestBuyer_Buy(t *testing.T) {
 	t.Run("NotifyUnsupported called given exchange doesnt support coin", func(t *testing.T) {
 		var (
 			ctrl     = gomock.NewController(t)
-			scraper  = mocks.NewMockScraper(ctrl)
 			db       = mocks.NewMockPurchaseDB(ctrl)
 			notifier = mocks.NewMockNotifier(ctrl)
 			exchange = mocks.NewMockExchangePurchaser(ctrl)
@@ -96,18 +75,17 @@ func TestBuyer_Buy(t *testing.T) {
 		)
 		defer ctrl.Finish()
 
-		b := trader.NewBuyer(db, notifier, exchange)
+		b := trader.NewBuyer(db, notifier, exchange, nil)
 
 		gomock.InOrder(
-			scraper.EXPECT().Scrape(ctx).Return(coinToCheck, nil),
 			db.EXPECT().CheckUniqueCoin(ctx, coinToCheck).Return(true),
-			scraper.EXPECT().Name().Return("someScraper"),
+			exchange.EXPECT().ResolveSymbol(ctx, coinToCheck, "", "").Return(coinToCheck, nil),
 			exchange.EXPECT().CheckSupport(ctx, coinToCheck).Return(false, nil),
 			notifier.EXPECT().NotifyUnsupported(ctx, coinToCheck),
 			db.EXPECT().StoreCoinUnsupported(ctx, coinToCheck).Return(nil),
 		)
 
-		err := b.Buy(ctx, scraper)
+		err := b.Buy(ctx, signal.Signal{Coin: coinToCheck, Source: "someScraper"})
 		require.Error(t, err)
 
 		assert.True(t, errors.Is(err, trader.ErrCoinUnsupported))
@@ -115,7 +93,6 @@ func TestBuyer_Buy(t *testing.T) {
 	t.Run("err given we cant purchase coin", func(t *testing.T) {
 		var (
 			ctrl     = gomock.NewController(t)
-			scraper  = mocks.NewMockScraper(ctrl)
 			db       = mocks.NewMockPurchaseDB(ctrl)
 			notifier = mocks.NewMockNotifier(ctrl)
 			exchange = mocks.NewMockExchangePurchaser(ctrl)
@@ -127,25 +104,141 @@ func TestBuyer_Buy(t *testing.T) {
 		)
 		defer ctrl.Finish()
 
-		b := trader.NewBuyer(db, notifier, exchange)
+		b := trader.NewBuyer(db, notifier, exchange, nil)
 
 		gomock.InOrder(
-			scraper.EXPECT().Scrape(ctx).Return(coinToCheck, nil),
 			db.EXPECT().CheckUniqueCoin(ctx, coinToCheck).Return(true),
-			scraper.EXPECT().Name().Return("someScraper"),
+			exchange.EXPECT().ResolveSymbol(ctx, coinToCheck, "", "").Return(coinToCheck, nil),
 			exchange.EXPECT().CheckSupport(ctx, coinToCheck).Return(true, nil),
-			exchange.EXPECT().GetLastPrice(ctx, coinToCheck).Return(lastPrice, nil),
-			exchange.EXPECT().PurchaseCoin(ctx, coinToCheck, lastPrice).Return(decimal.NewFromFloat(0), decimal.NewFromFloat(0), errors.New("some-err")),
+			exchange.EXPECT().GetPrice(ctx, coinToCheck, gomock.Any()).Return(trader.PriceSnapshot{Ask: lastPrice}, nil),
+			exchange.EXPECT().PurchaseCoin(ctx, coinToCheck, lastPrice, "MATTCOIN").Return(trader.Fill{}, errors.New("some-err")),
 		)
-		err := b.Buy(ctx, scraper)
+		err := b.Buy(ctx, signal.Signal{Coin: coinToCheck, Source: "someScraper"})
 		require.Error(t, err)
 
 		assert.Contains(t, err.Error(), "failed to purchase coin")
 	})
+	t.Run("stores what filled given the buy failed part way", func(t *testing.T) {
+		var (
+			ctrl     = gomock.NewController(t)
+			db       = mocks.NewMockPurchaseDB(ctrl)
+			notifier = mocks.NewMockNotifier(ctrl)
+			exchange = mocks.NewMockExchangePurchaser(ctrl)
+
+			ctx = context.Background()
+
+			coinToCheck = "mattcoin"
+			lastPrice   = decimal.NewFromFloat(32.3)
+			fill        = trader.Fill{OrderID: "123", Price: lastPrice, Amount: decimal.NewFromInt(2)}
+		)
+		defer ctrl.Finish()
+
+		b := trader.NewBuyer(db, notifier, exchange, nil)
+
+		gomock.InOrder(
+			db.EXPECT().CheckUniqueCoin(ctx, coinToCheck).Return(true),
+			exchange.EXPECT().ResolveSymbol(ctx, coinToCheck, "", "").Return(coinToCheck, nil),
+			exchange.EXPECT().CheckSupport(ctx, coinToCheck).Return(true, nil),
+			exchange.EXPECT().GetPrice(ctx, coinToCheck, gomock.Any()).Return(trader.PriceSnapshot{Ask: lastPrice}, nil),
+			exchange.EXPECT().PurchaseCoin(ctx, coinToCheck, lastPrice, "MATTCOIN").Return(fill, context.Canceled),
+			notifier.EXPECT().NotifyError(ctx, gomock.Any()),
+			db.EXPECT().StoreCoinPurchased(ctx, coinToCheck, fill, gomock.Any()).Return(nil),
+			notifier.EXPECT().NotifyPurchased(ctx, coinToCheck, lastPrice, fill.Amount),
+			exchange.EXPECT().PlaceBracket(ctx, coinToCheck, fill).Return(trader.Bracket{}, nil),
+		)
+		err := b.Buy(ctx, signal.Signal{Coin: coinToCheck, Source: "someScraper"})
+		assert.NoError(t, err)
+	})
+	t.Run("err given the pair has no price yet", func(t *testing.T) {
+		var (
+			ctrl     = gomock.NewController(t)
+			db       = mocks.NewMockPurchaseDB(ctrl)
+			notifier = mocks.NewMockNotifier(ctrl)
+			exchange = mocks.NewMockExchangePurchaser(ctrl)
+
+			ctx = context.Background()
+
+			coinToCheck = "mattcoin"
+		)
+		defer ctrl.Finish()
+
+		b := trader.NewBuyer(db, notifier, exchange, nil)
+
+		gomock.InOrder(
+			db.EXPECT().CheckUniqueCoin(ctx, coinToCheck).Return(true),
+			exchange.EXPECT().ResolveSymbol(ctx, coinToCheck, "", "").Return(coinToCheck, nil),
+			exchange.EXPECT().CheckSupport(ctx, coinToCheck).Return(true, nil),
+			exchange.EXPECT().GetPrice(ctx, coinToCheck, gomock.Any()).Return(trader.PriceSnapshot{}, nil),
+		)
+		err := b.Buy(ctx, signal.Signal{Coin: coinToCheck, Source: "someScraper"})
+		require.Error(t, err)
+
+		assert.Contains(t, err.Error(), "no price")
+	})
+
+	t.Run("NotifyUnsupported called given exchange doesnt list the pair", func(t *testing.T) {
+		var (
+			ctrl     = gomock.NewController(t)
+			db       = mocks.NewMockPurchaseDB(ctrl)
+			notifier = mocks.NewMockNotifier(ctrl)
+			exchange = mocks.NewMockExchangePurchaser(ctrl)
+
+			ctx = context.Background()
+
+			coinToCheck = "mattcoin"
+		)
+		defer ctrl.Finish()
+
+		b := trader.NewBuyer(db, notifier, exchange, nil)
+
+		gomock.InOrder(
+			db.EXPECT().CheckUniqueCoin(ctx, coinToCheck).Return(true),
+			exchange.EXPECT().ResolveSymbol(ctx, coinToCheck, "", "").Return(coinToCheck, nil),
+			exchange.EXPECT().CheckSupport(ctx, coinToCheck).Return(true, nil),
+			exchange.EXPECT().GetPrice(ctx, coinToCheck, gomock.Any()).Return(trader.PriceSnapshot{}, fmt.Errorf("wrapped: %w", trader.ErrInvalidPair)),
+			notifier.EXPECT().NotifyUnsupported(ctx, coinToCheck),
+			db.EXPECT().StoreCoinUnsupported(ctx, coinToCheck).Return(nil),
+		)
+
+		err := b.Buy(ctx, signal.Signal{Coin: coinToCheck, Source: "someScraper"})
+		require.Error(t, err)
+
+		assert.True(t, errors.Is(err, trader.ErrCoinUnsupported))
+	})
+	t.Run("notifies given not enough balance to buy", func(t *testing.T) {
+		var (
+			ctrl     = gomock.NewController(t)
+			db       = mocks.NewMockPurchaseDB(ctrl)
+			notifier = mocks.NewMockNotifier(ctrl)
+			exchange = mocks.NewMockExchangePurchaser(ctrl)
+
+			ctx = context.Background()
+
+			coinToCheck = "mattcoin"
+			lastPrice   = decimal.NewFromFloat(32.3)
+		)
+		defer ctrl.Finish()
+
+		b := trader.NewBuyer(db, notifier, exchange, nil)
+
+		gomock.InOrder(
+			db.EXPECT().CheckUniqueCoin(ctx, coinToCheck).Return(true),
+			exchange.EXPECT().ResolveSymbol(ctx, coinToCheck, "", "").Return(coinToCheck, nil),
+			exchange.EXPECT().CheckSupport(ctx, coinToCheck).Return(true, nil),
+			exchange.EXPECT().GetPrice(ctx, coinToCheck, gomock.Any()).Return(trader.PriceSnapshot{Ask: lastPrice}, nil),
+			exchange.EXPECT().PurchaseCoin(ctx, coinToCheck, lastPrice, "MATTCOIN").Return(trader.Fill{}, fmt.Errorf("wrapped: %w", trader.ErrInsufficientBalance)),
+			notifier.EXPECT().NotifyError(ctx, gomock.Any()).Do(func(_ context.Context, err error) {
+				assert.True(t, errors.Is(err, trader.ErrInsufficientBalance))
+			}),
+		)
+		err := b.Buy(ctx, signal.Signal{Coin: coinToCheck, Source: "someScraper"})
+		require.Error(t, err)
+
+		assert.True(t, errors.Is(err, trader.ErrInsufficientBalance))
+	})
 	t.Run("happy path; coin is purchased and notify is called", func(t *testing.T) {
 		var (
 			ctrl     = gomock.NewController(t)
-			scraper  = mocks.NewMockScraper(ctrl)
 			db       = mocks.NewMockPurchaseDB(ctrl)
 			notifier = mocks.NewMockNotifier(ctrl)
 			exchange = mocks.NewMockExchangePurchaser(ctrl)
@@ -155,23 +248,201 @@ func TestBuyer_Buy(t *testing.T) {
 			coinToCheck     = "mattcoin"
 			purchasePrice   = decimal.NewFromFloat(300)
 			purchasedAmount = decimal.NewFromFloat(30)
+			fill            = trader.Fill{OrderID: "123", Price: purchasePrice, Amount: purchasedAmount}
 			lastPrice       = decimal.NewFromFloat(69.69)
+			bracket         = trader.Bracket{TakeProfitOrderID: "1", StopOrderID: "2"}
 		)
 		defer ctrl.Finish()
 
-		b := trader.NewBuyer(db, notifier, exchange)
+		b := trader.NewBuyer(db, notifier, exchange, nil)
 
 		gomock.InOrder(
-			scraper.EXPECT().Scrape(ctx).Return(coinToCheck, nil),
 			db.EXPECT().CheckUniqueCoin(ctx, coinToCheck).Return(true),
-			scraper.EXPECT().Name().Return("myScraper"),
+			exchange.EXPECT().ResolveSymbol(ctx, coinToCheck, "", "").Return(coinToCheck, nil),
 			exchange.EXPECT().CheckSupport(ctx, coinToCheck).Return(true, nil),
-			exchange.EXPECT().GetLastPrice(ctx, coinToCheck).Return(lastPrice, nil),
-			exchange.EXPECT().PurchaseCoin(ctx, coinToCheck, lastPrice).Return(purchasePrice, purchasedAmount, nil),
-			db.EXPECT().StoreCoinPurchased(ctx, coinToCheck, purchasePrice, purchasedAmount, gomock.Any()).Return(nil),
+			exchange.EXPECT().GetPrice(ctx, coinToCheck, gomock.Any()).Return(trader.PriceSnapshot{Ask: lastPrice}, nil),
+			exchange.EXPECT().PurchaseCoin(ctx, coinToCheck, lastPrice, "MATTCOIN").Return(fill, nil),
+			db.EXPECT().StoreCoinPurchased(ctx, coinToCheck, fill, gomock.Any()).Return(nil),
 			notifier.EXPECT().NotifyPurchased(ctx, coinToCheck, purchasePrice, purchasedAmount),
+			exchange.EXPECT().PlaceBracket(ctx, coinToCheck, fill).Return(bracket, nil),
+			db.EXPECT().StoreBracket(ctx, coinToCheck, bracket).Return(nil),
 		)
-		err := b.Buy(ctx, scraper)
+		err := b.Buy(ctx, signal.Signal{Coin: coinToCheck, Source: "someScraper"})
 		assert.NoError(t, err)
 	})
+
+	t.Run("notifies given the buy was cut down to what the account had", func(t *testing.T) {
+		var (
+			ctrl     = gomock.NewController(t)
+			db       = mocks.NewMockPurchaseDB(ctrl)
+			notifier = mocks.NewMockNotifier(ctrl)
+			exchange = mocks.NewMockExchangePurchaser(ctrl)
+
+			ctx = context.Background()
+
+			coinToCheck = "mattcoin"
+			lastPrice   = decimal.NewFromFloat(2)
+			fill        = trader.Fill{Price: lastPrice, Amount: decimal.NewFromInt(2), Shortfall: decimal.NewFromInt(6)}
+		)
+		defer ctrl.Finish()
+
+		b := trader.NewBuyer(db, notifier, exchange, nil)
+
+		gomock.InOrder(
+			db.EXPECT().CheckUniqueCoin(ctx, coinToCheck).Return(true),
+			exchange.EXPECT().ResolveSymbol(ctx, coinToCheck, "", "").Return(coinToCheck, nil),
+			exchange.EXPECT().CheckSupport(ctx, coinToCheck).Return(true, nil),
+			exchange.EXPECT().GetPrice(ctx, coinToCheck, gomock.Any()).Return(trader.PriceSnapshot{Ask: lastPrice}, nil),
+			exchange.EXPECT().PurchaseCoin(ctx, coinToCheck, lastPrice, "MATTCOIN").Return(fill, nil),
+			db.EXPECT().StoreCoinPurchased(ctx, coinToCheck, fill, gomock.Any()).Return(nil),
+			notifier.EXPECT().NotifyPurchased(ctx, coinToCheck, fill.Price, fill.Amount),
+			notifier.EXPECT().NotifyError(ctx, gomock.Any()).Do(func(ctx context.Context, err error) {
+				assert.True(t, errors.Is(err, trader.ErrInsufficientBalance))
+			}),
+			exchange.EXPECT().PlaceBracket(ctx, coinToCheck, fill).Return(trader.Bracket{}, nil),
+		)
+		err := b.Buy(ctx, signal.Signal{Coin: coinToCheck, Source: "someScraper"})
+		assert.NoError(t, err)
+	})
+
+	t.Run("coin is still bought given bracket cannot be placed", func(t *testing.T) {
+		var (
+			ctrl     = gomock.NewController(t)
+			db       = mocks.NewMockPurchaseDB(ctrl)
+			notifier = mocks.NewMockNotifier(ctrl)
+			exchange = mocks.NewMockExchangePurchaser(ctrl)
+
+			ctx = context.Background()
+
+			coinToCheck = "mattcoin"
+			fill        = trader.Fill{OrderID: "123", Price: decimal.NewFromFloat(300), Amount: decimal.NewFromFloat(30)}
+			lastPrice   = decimal.NewFromFloat(69.69)
+		)
+		defer ctrl.Finish()
+
+		b := trader.NewBuyer(db, notifier, exchange, nil)
+
+		gomock.InOrder(
+			db.EXPECT().CheckUniqueCoin(ctx, coinToCheck).Return(true),
+			exchange.EXPECT().ResolveSymbol(ctx, coinToCheck, "", "").Return(coinToCheck, nil),
+			exchange.EXPECT().CheckSupport(ctx, coinToCheck).Return(true, nil),
+			exchange.EXPECT().GetPrice(ctx, coinToCheck, gomock.Any()).Return(trader.PriceSnapshot{Ask: lastPrice}, nil),
+			exchange.EXPECT().PurchaseCoin(ctx, coinToCheck, lastPrice, "MATTCOIN").Return(fill, nil),
+			db.EXPECT().StoreCoinPurchased(ctx, coinToCheck, fill, gomock.Any()).Return(nil),
+			notifier.EXPECT().NotifyPurchased(ctx, coinToCheck, fill.Price, fill.Amount),
+			exchange.EXPECT().PlaceBracket(ctx, coinToCheck, fill).Return(trader.Bracket{}, errors.New("some err")),
+			notifier.EXPECT().NotifyError(ctx, gomock.Any()),
+		)
+		err := b.Buy(ctx, signal.Signal{Coin: coinToCheck, Source: "someScraper"})
+		assert.NoError(t, err)
+	})
+	t.Run("stores coin as unsupported given it cannot be resolved", func(t *testing.T) {
+		var (
+			ctrl     = gomock.NewController(t)
+			db       = mocks.NewMockPurchaseDB(ctrl)
+			notifier = mocks.NewMockNotifier(ctrl)
+			exchange = mocks.NewMockExchangePurchaser(ctrl)
+
+			ctx = context.Background()
+
+			coinToCheck = "mattcoin"
+			contract    = "0x1111111111111111111111111111111111111111"
+		)
+		defer ctrl.Finish()
+
+		b := trader.NewBuyer(db, notifier, exchange, nil)
+
+		gomock.InOrder(
+			db.EXPECT().CheckUniqueCoin(ctx, coinToCheck).Return(true),
+			exchange.EXPECT().ResolveSymbol(ctx, coinToCheck, "ETH", contract).Return("", fmt.Errorf("%w: different token", trader.ErrCoinUnsupported)),
+			notifier.EXPECT().NotifyUnsupported(ctx, coinToCheck),
+			db.EXPECT().StoreCoinUnsupported(ctx, coinToCheck).Return(nil),
+		)
+
+		err := b.Buy(ctx, signal.Signal{Coin: coinToCheck, Source: "ethereum", Chain: "ETH", Contract: contract})
+		require.Error(t, err)
+
+		assert.True(t, errors.Is(err, trader.ErrCoinUnsupported))
+	})
+	t.Run("refuses to trade given coin is ambiguous", func(t *testing.T) {
+		var (
+			ctrl     = gomock.NewController(t)
+			db       = mocks.NewMockPurchaseDB(ctrl)
+			notifier = mocks.NewMockNotifier(ctrl)
+			exchange = mocks.NewMockExchangePurchaser(ctrl)
+
+			ctx = context.Background()
+
+			coinToCheck  = "1000mattcoin"
+			ambiguousErr = fmt.Errorf("%w: could be 1000MATTCOIN or MATTCOIN", trader.ErrCoinAmbiguous)
+		)
+		defer ctrl.Finish()
+
+		b := trader.NewBuyer(db, notifier, exchange, nil)
+
+		gomock.InOrder(
+			db.EXPECT().CheckUniqueCoin(ctx, coinToCheck).Return(true),
+			exchange.EXPECT().ResolveSymbol(ctx, coinToCheck, "", "").Return("", ambiguousErr),
+			notifier.EXPECT().NotifyError(ctx, ambiguousErr),
+			db.EXPECT().StoreCoinUnsupported(ctx, coinToCheck).Return(nil),
+		)
+
+		err := b.Buy(ctx, signal.Signal{Coin: coinToCheck, Source: "binance"})
+		require.Error(t, err)
+
+		assert.True(t, errors.Is(err, trader.ErrCoinAmbiguous))
+	})
+	t.Run("ErrNoNewCoin given resolved coin was already bought", func(t *testing.T) {
+		var (
+			ctrl     = gomock.NewController(t)
+			db       = mocks.NewMockPurchaseDB(ctrl)
+			exchange = mocks.NewMockExchangePurchaser(ctrl)
+
+			ctx = context.Background()
+
+			coinToCheck = "1000mattcoin"
+			resolved    = "MATTCOIN"
+		)
+		defer ctrl.Finish()
+
+		b := trader.NewBuyer(db, nil, exchange, nil)
+
+		gomock.InOrder(
+			db.EXPECT().CheckUniqueCoin(ctx, coinToCheck).Return(true),
+			exchange.EXPECT().ResolveSymbol(ctx, coinToCheck, "", "").Return(resolved, nil),
+			db.EXPECT().CheckUniqueCoin(ctx, resolved).Return(false),
+		)
+
+		err := b.Buy(ctx, signal.Signal{Coin: coinToCheck, Source: "binance"})
+		require.Error(t, err)
+
+		assert.True(t, errors.Is(err, trader.ErrNoNewCoin))
+	})
+	t.Run("stores coin as filtered before calling the exchange", func(t *testing.T) {
+		var (
+			ctrl     = gomock.NewController(t)
+			db       = mocks.NewMockPurchaseDB(ctrl)
+			exchange = mocks.NewMockExchangePurchaser(ctrl)
+
+			ctx = context.Background()
+
+			coinToCheck = "btc3l"
+		)
+		defer ctrl.Finish()
+
+		filter, err := trader.NewFilter(nil, []string{"*3L"}, nil, nil)
+		require.NoError(t, err)
+
+		b := trader.NewBuyer(db, nil, exchange, filter)
+
+		gomock.InOrder(
+			db.EXPECT().CheckUniqueCoin(ctx, coinToCheck).Return(true),
+			db.EXPECT().StoreCoinFiltered(ctx, coinToCheck, "binance", gomock.Any()).Return(nil),
+		)
+
+		err = b.Buy(ctx, signal.Signal{Coin: coinToCheck, Source: "binance"})
+		require.Error(t, err)
+
+		assert.True(t, errors.Is(err, trader.ErrCoinFiltered))
+	})
 }
@@ -0,0 +1,193 @@
+package trader
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/moonr-app/crypto-signal-trading-bot/internal/shared/logging"
+	"github.com/moonr-app/crypto-signal-trading-bot/internal/signal"
+)
+
+var ErrDuplicateSignal = errors.New("coin was signalled recently")
+
+type SignalBuyer interface {
+	Buy(ctx context.Context, sig signal.Signal) error
+}
+
+// Hook is called once for every signal the coordinator consumes, with the outcome of the buy decision.
+// err is nil if the coin was bought.
+type Hook func(ctx context.Context, sig signal.Signal, err error)
+
+// Coordinator is the single consumer of the signal bus. It drops signals for coins it has already seen within ttl,
+// so two scrapers matching the same coin can't both get past CheckUniqueCoin, and serialises buys per coin.
+type Coordinator struct {
+	buyer SignalBuyer
+	bus   *signal.Bus
+	ttl   time.Duration
+
+	lock     *sync.Mutex
+	seen     map[string]time.Time
+	coinLock map[string]*coinLock
+	hooks    []Hook
+}
+
+// coinLock serialises buys of one coin. It's dropped from the coordinator once nothing holds or waits on it.
+type coinLock struct {
+	sync.Mutex
+	refs int
+}
+
+func NewCoordinator(buyer SignalBuyer, bus *signal.Bus, ttl time.Duration) *Coordinator {
+	return &Coordinator{
+		buyer:    buyer,
+		bus:      bus,
+		ttl:      ttl,
+		lock:     &sync.Mutex{},
+		seen:     make(map[string]time.Time),
+		coinLock: make(map[string]*coinLock),
+	}
+}
+
+// Subscribe registers a hook. It must be called before Run.
+func (c *Coordinator) Subscribe(h Hook) {
+	c.hooks = append(c.hooks, h)
+}
+
+func (c *Coordinator) Run(ctx context.Context) error {
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	for {
+		select {
+		case sig := <-c.bus.Signals():
+			key := strings.ToLower(sig.Coin)
+
+			if !c.claim(key, sig.ReceivedAt) {
+				c.publish(ctx, sig, ErrDuplicateSignal)
+				continue
+			}
+
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				c.buy(ctx, key, sig)
+			}()
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// claim returns false if key has been claimed within the ttl.
+func (c *Coordinator) claim(key string, at time.Time) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if last, ok := c.seen[key]; ok && at.Sub(last) < c.ttl {
+		return false
+	}
+	c.seen[key] = at
+
+	for k, last := range c.seen {
+		if at.Sub(last) >= c.ttl {
+			delete(c.seen, k)
+		}
+	}
+	return true
+}
+
+// release forgets key so the next signal for it is tried again.
+func (c *Coordinator) release(key string) {
+	c.lock.Lock()
+	delete(c.seen, key)
+	c.lock.Unlock()
+}
+
+// lockCoin blocks until nothing else is buying key.
+func (c *Coordinator) lockCoin(key string) {
+	c.lock.Lock()
+	l, ok := c.coinLock[key]
+	if !ok {
+		l = &coinLock{}
+		c.coinLock[key] = l
+	}
+	l.refs++
+	c.lock.Unlock()
+
+	l.Lock()
+}
+
+// unlockCoin lets the next buy of key go ahead, and forgets the lock if there isn't one waiting.
+func (c *Coordinator) unlockCoin(key string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	l := c.coinLock[key]
+	l.refs--
+	if l.refs == 0 {
+		delete(c.coinLock, key)
+	}
+	l.Unlock()
+}
+
+func (c *Coordinator) buy(ctx context.Context, key string, sig signal.Signal) {
+	c.lockCoin(key)
+	defer c.unlockCoin(key)
+
+	err := c.buyer.Buy(ctx, sig)
+	switch {
+	case err == nil,
+		errors.Is(err, ErrNoNewCoin),
+		errors.Is(err, ErrCoinUnsupported),
+		errors.Is(err, ErrCoinAmbiguous),
+		errors.Is(err, ErrCoinFiltered):
+	default:
+		// the buy failed part way through, let the next signal for this coin have another go.
+		c.release(key)
+	}
+
+	c.publish(ctx, sig, err)
+}
+
+func (c *Coordinator) publish(ctx context.Context, sig signal.Signal, err error) {
+	for _, h := range c.hooks {
+		h(ctx, sig, err)
+	}
+}
+
+// LogHook logs the outcome of every signal.
+func LogHook(ctx context.Context, sig signal.Signal, err error) {
+	fields := []zap.Field{zap.String("coin", sig.Coin), zap.String("source", sig.Source)}
+
+	switch {
+	case err == nil:
+		logging.Info(ctx, "signal bought", fields...)
+	case errors.Is(err, ErrNoNewCoin), errors.Is(err, ErrDuplicateSignal):
+		logging.Debug(ctx, "signal skipped", append(fields, zap.Error(err))...)
+	case errors.Is(err, ErrCoinFiltered):
+		logging.Info(ctx, "signal filtered", append(fields, zap.Error(err))...)
+	default:
+		logging.Error(ctx, "signal failed", append(fields, zap.Error(err))...)
+	}
+}
+
+// NotifyHook sends unexpected buy failures to the notifier.
+func NotifyHook(n Notifier) Hook {
+	return func(ctx context.Context, sig signal.Signal, err error) {
+		switch {
+		case err == nil,
+			errors.Is(err, ErrNoNewCoin),
+			errors.Is(err, ErrDuplicateSignal),
+			errors.Is(err, ErrCoinUnsupported),
+			errors.Is(err, ErrCoinAmbiguous),
+			errors.Is(err, ErrCoinFiltered):
+		default:
+			n.NotifyError(ctx, err)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package trader_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/moonr-app/crypto-signal-trading-bot/internal/mocks"
+	"github.com/moonr-app/crypto-signal-trading-bot/internal/signal"
+	"github.com/moonr-app/crypto-signal-trading-bot/internal/trader"
+)
+
+type outcome struct {
+	sig signal.Signal
+	err error
+}
+
+// runCoordinator publishes sigs one at a time and waits for each outcome, so buys are deterministic.
+func runCoordinator(t *testing.T, buyer trader.SignalBuyer, ttl time.Duration, sigs ...signal.Signal) []outcome {
+	t.Helper()
+
+	var (
+		ctx, cancel = context.WithCancel(context.Background())
+		bus         = signal.NewBus(1)
+		c           = trader.NewCoordinator(buyer, bus, ttl)
+		outcomes    = make(chan outcome)
+		wg          sync.WaitGroup
+	)
+	defer cancel()
+
+	c.Subscribe(func(ctx context.Context, sig signal.Signal, err error) {
+		outcomes <- outcome{sig: sig, err: err}
+	})
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		_ = c.Run(ctx)
+	}()
+
+	var res []outcome
+	for _, sig := range sigs {
+		require.NoError(t, bus.Publish(ctx, sig))
+		res = append(res, <-outcomes)
+	}
+
+	cancel()
+	wg.Wait()
+	return res
+}
+
+func TestCoordinator_Run(t *testing.T) {
+	now := time.Now()
+
+	t.Run("signals for the same coin within the ttl only buy once", func(t *testing.T) {
+		var (
+			ctrl  = gomock.NewController(t)
+			buyer = mocks.NewMockSignalBuyer(ctrl)
+
+			first  = signal.Signal{Coin: "rare", Source: "binance", ReceivedAt: now}
+			second = signal.Signal{Coin: "RARE", Source: "coinbase", ReceivedAt: now.Add(time.Second)}
+		)
+		defer ctrl.Finish()
+
+		buyer.EXPECT().Buy(gomock.Any(), first).Return(nil)
+
+		res := runCoordinator(t, buyer, time.Minute, first, second)
+
+		assert.NoError(t, res[0].err)
+		assert.True(t, errors.Is(res[1].err, trader.ErrDuplicateSignal))
+		assert.Equal(t, second, res[1].sig)
+	})
+
+	t.Run("signals after the ttl are passed on", func(t *testing.T) {
+		var (
+			ctrl  = gomock.NewController(t)
+			buyer = mocks.NewMockSignalBuyer(ctrl)
+
+			first  = signal.Signal{Coin: "rare", Source: "binance", ReceivedAt: now}
+			second = signal.Signal{Coin: "rare", Source: "binance", ReceivedAt: now.Add(2 * time.Minute)}
+		)
+		defer ctrl.Finish()
+
+		gomock.InOrder(
+			buyer.EXPECT().Buy(gomock.Any(), first).Return(nil),
+			buyer.EXPECT().Buy(gomock.Any(), second).Return(trader.ErrNoNewCoin),
+		)
+
+		res := runCoordinator(t, buyer, time.Minute, first, second)
+
+		assert.NoError(t, res[0].err)
+		assert.True(t, errors.Is(res[1].err, trader.ErrNoNewCoin))
+	})
+
+	t.Run("a failed buy lets the next signal try again", func(t *testing.T) {
+		var (
+			ctrl  = gomock.NewController(t)
+			buyer = mocks.NewMockSignalBuyer(ctrl)
+
+			first  = signal.Signal{Coin: "rare", Source: "binance", ReceivedAt: now}
+			second = signal.Signal{Coin: "rare", Source: "binance", ReceivedAt: now.Add(time.Second)}
+		)
+		defer ctrl.Finish()
+
+		gomock.InOrder(
+			buyer.EXPECT().Buy(gomock.Any(), first).Return(errors.New("some-err")),
+			buyer.EXPECT().Buy(gomock.Any(), second).Return(nil),
+		)
+
+		res := runCoordinator(t, buyer, time.Minute, first, second)
+
+		assert.EqualError(t, res[0].err, "some-err")
+		assert.NoError(t, res[1].err)
+	})
+}
+
+func TestNotifyHook(t *testing.T) {
+	var (
+		ctrl     = gomock.NewController(t)
+		notifier = mocks.NewMockNotifier(ctrl)
+		ctx      = context.Background()
+		sig      = signal.Signal{Coin: "rare"}
+		someErr  = errors.New("some-err")
+	)
+	defer ctrl.Finish()
+
+	notifier.EXPECT().NotifyError(ctx, someErr)
+
+	hook := trader.NotifyHook(notifier)
+	hook(ctx, sig, nil)
+	hook(ctx, sig, trader.ErrNoNewCoin)
+	hook(ctx, sig, trader.ErrDuplicateSignal)
+	hook(ctx, sig, someErr)
+}
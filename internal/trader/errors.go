@@ -0,0 +1,17 @@
+package trader
+
+import "errors"
+
+// Errors an exchange can fail with, so callers can decide what to do without knowing which exchange it was.
+var (
+	ErrInsufficientBalance = errors.New("not enough balance on the exchange")
+	ErrInvalidPair         = errors.New("exchange does not list the currency pair")
+	ErrRateLimited         = errors.New("exchange rate limit hit")
+	ErrExchangeAuth        = errors.New("exchange rejected the API credentials")
+	ErrExchangeUnavailable = errors.New("exchange is unavailable")
+)
+
+// IsTemporary reports whether err is worth trying again later, as opposed to something that will keep failing.
+func IsTemporary(err error) bool {
+	return errors.Is(err, ErrRateLimited) || errors.Is(err, ErrExchangeUnavailable)
+}
@@ -0,0 +1,65 @@
+package trader
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+var ErrOrderNotFilled = errors.New("order was not filled")
+
+// Fill is what actually happened to an order on the exchange, as opposed to what was asked for.
+type Fill struct {
+	OrderID string
+	// ClientOrderID is the ID the bot gave the order, which can be used to find it on the exchange.
+	ClientOrderID string
+	// Price is the average price the order filled at.
+	Price decimal.Decimal
+	// Amount is how much of the base currency was bought or sold.
+	Amount decimal.Decimal
+	// FilledTotal is how much of the quote currency was spent or received.
+	FilledTotal decimal.Decimal
+	Fee         decimal.Decimal
+	FeeCurrency string
+	// GtFee is the part of the fee paid in GT, when gate.io's GT deduction is switched on.
+	GtFee decimal.Decimal
+	// QuoteFee is every fee that wasn't taken from the coin itself, valued in the quote currency.
+	QuoteFee decimal.Decimal
+	// Left is the part of the order that was never filled and has been cancelled.
+	Left decimal.Decimal
+	// Shortfall is how much of an order was never attempted because the account didn't hold it: of the coin for a
+	// sell, and of the USDT a buy usually spends for a buy.
+	Shortfall decimal.Decimal
+	// Venue is the exchange the order was placed on, when the bot trades on more than one.
+	Venue string
+	// Pair is the market the order was placed on, e.g. RARE_BTC, when the exchange can trade a coin through more
+	// than one quote currency. A position is sold back through the pair it was bought through.
+	Pair string
+}
+
+// Received is how much of coin a buy actually added to the account. Exchanges usually take the fee out of the coin
+// bought, so this is what the bot owns and can sell.
+func (f Fill) Received(coin string) decimal.Decimal {
+	if strings.EqualFold(f.FeeCurrency, coin) {
+		return f.Amount.Sub(f.Fee)
+	}
+	return f.Amount
+}
+
+// CostPrice is what each coin a buy added to the account really cost, fees included.
+func (f Fill) CostPrice(coin string) decimal.Decimal {
+	received := f.Received(coin)
+	if !received.IsPositive() {
+		return f.Price
+	}
+	return f.FilledTotal.Add(f.QuoteFee).Div(received)
+}
+
+// NetProceeds is what a sell actually added to the account once fees are paid.
+func (f Fill) NetProceeds() decimal.Decimal {
+	return f.FilledTotal.Sub(f.QuoteFee)
+}
+
+// ErrOrderTooSmall is returned when an order would be under the exchange's minimum size, so was never sent.
+var ErrOrderTooSmall = errors.New("order is below the exchange minimum")
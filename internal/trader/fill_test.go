@@ -0,0 +1,45 @@
+package trader_test
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/moonr-app/crypto-signal-trading-bot/internal/trader"
+)
+
+func TestFill_Received(t *testing.T) {
+	t.Run("takes the fee off given it was paid in the coin bought", func(t *testing.T) {
+		f := trader.Fill{Amount: decimal.NewFromInt(100), Fee: decimal.NewFromFloat(0.2), FeeCurrency: "rare"}
+		assert.Equal(t, "99.8", f.Received("RARE").String())
+	})
+
+	t.Run("whole amount given the fee was paid in something else", func(t *testing.T) {
+		f := trader.Fill{Amount: decimal.NewFromInt(100), Fee: decimal.NewFromFloat(0.02), FeeCurrency: "GT"}
+		assert.Equal(t, "100", f.Received("RARE").String())
+	})
+}
+
+func TestFill_CostPrice(t *testing.T) {
+	t.Run("spreads the quote fee over what was received", func(t *testing.T) {
+		f := trader.Fill{
+			Price: decimal.NewFromFloat(0.5), Amount: decimal.NewFromInt(20), FilledTotal: decimal.NewFromInt(10),
+			Fee: decimal.NewFromFloat(0.02), FeeCurrency: "USDT", QuoteFee: decimal.NewFromFloat(0.02),
+		}
+		assert.Equal(t, "0.501", f.CostPrice("RARE").String())
+	})
+
+	t.Run("takes a fee paid in the coin off what was received", func(t *testing.T) {
+		f := trader.Fill{
+			Price: decimal.NewFromFloat(0.5), Amount: decimal.NewFromInt(20), FilledTotal: decimal.NewFromInt(10),
+			Fee: decimal.NewFromInt(4), FeeCurrency: "RARE",
+		}
+		assert.Equal(t, "0.625", f.CostPrice("RARE").String())
+	})
+}
+
+func TestFill_NetProceeds(t *testing.T) {
+	f := trader.Fill{FilledTotal: decimal.NewFromInt(10), QuoteFee: decimal.NewFromFloat(0.02)}
+	assert.Equal(t, "9.98", f.NetProceeds().String())
+}
@@ -0,0 +1,112 @@
+package trader
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/moonr-app/crypto-signal-trading-bot/internal/signal"
+)
+
+var ErrCoinFiltered = errors.New("coin was filtered out")
+
+// Filter decides whether a signal is worth considering at all, before anything is asked of an exchange.
+// Symbol rules are glob patterns (e.g. "*3L") and keyword rules match anywhere in the announcement title,
+// both case insensitively. Deny rules always beat allow rules, and an empty allow list allows everything. Keyword
+// rules only apply to signals with a title, so signals from sources without announcements (e.g. Coinbase or
+// Ethereum) are judged by their symbol alone.
+type Filter struct {
+	allowSymbols  []string
+	denySymbols   []string
+	allowKeywords []string
+	denyKeywords  []string
+}
+
+func NewFilter(allowSymbols, denySymbols, allowKeywords, denyKeywords []string) (*Filter, error) {
+	f := &Filter{
+		allowSymbols:  upper(allowSymbols),
+		denySymbols:   upper(denySymbols),
+		allowKeywords: lower(allowKeywords),
+		denyKeywords:  lower(denyKeywords),
+	}
+
+	for _, p := range append(append([]string{}, f.allowSymbols...), f.denySymbols...) {
+		if _, err := path.Match(p, ""); err != nil {
+			return nil, fmt.Errorf("invalid symbol pattern %q: %w", p, err)
+		}
+	}
+	return f, nil
+}
+
+// Check returns a human readable reason if the signal should be skipped, or "" if it passes.
+func (f *Filter) Check(sig signal.Signal) string {
+	if f == nil {
+		return ""
+	}
+
+	var (
+		symbol = strings.ToUpper(sig.Coin)
+		title  = strings.ToLower(sig.Title)
+	)
+
+	for _, p := range f.denySymbols {
+		if ok, _ := path.Match(p, symbol); ok {
+			return fmt.Sprintf("symbol matches deny pattern %q", p)
+		}
+	}
+
+	for _, k := range f.denyKeywords {
+		if strings.Contains(title, k) {
+			return fmt.Sprintf("title contains deny keyword %q", k)
+		}
+	}
+
+	if len(f.allowSymbols) > 0 && !matchesAny(f.allowSymbols, symbol) {
+		return "symbol matches no allow pattern"
+	}
+
+	if len(f.allowKeywords) > 0 && title != "" && !containsAny(title, f.allowKeywords) {
+		return "title contains no allow keyword"
+	}
+
+	return ""
+}
+
+func matchesAny(patterns []string, symbol string) bool {
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, symbol); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func containsAny(s string, keywords []string) bool {
+	for _, k := range keywords {
+		if strings.Contains(s, k) {
+			return true
+		}
+	}
+	return false
+}
+
+func upper(in []string) []string {
+	var out []string
+	for _, s := range in {
+		if s = strings.TrimSpace(s); s != "" {
+			out = append(out, strings.ToUpper(s))
+		}
+	}
+	return out
+}
+
+func lower(in []string) []string {
+	var out []string
+	for _, s := range in {
+		if s = strings.TrimSpace(s); s != "" {
+			out = append(out, strings.ToLower(s))
+		}
+	}
+	return out
+}
@@ -0,0 +1,64 @@
+package trader_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/moonr-app/crypto-signal-trading-bot/internal/signal"
+	"github.com/moonr-app/crypto-signal-trading-bot/internal/trader"
+)
+
+func TestFilter_Check(t *testing.T) {
+	t.Run("nil filter allows everything", func(t *testing.T) {
+		var f *trader.Filter
+		assert.Empty(t, f.Check(signal.Signal{Coin: "btc3l"}))
+	})
+
+	t.Run("deny rules", func(t *testing.T) {
+		f, err := trader.NewFilter(nil, []string{"*3L", "*3S", "USD*"}, nil, []string{"Wrapped"})
+		require.NoError(t, err)
+
+		assert.NotEmpty(t, f.Check(signal.Signal{Coin: "btc3l"}))
+		assert.NotEmpty(t, f.Check(signal.Signal{Coin: "ETH3S"}))
+		assert.NotEmpty(t, f.Check(signal.Signal{Coin: "usdd"}))
+		assert.NotEmpty(t, f.Check(signal.Signal{Coin: "wbeth", Title: "Binance Will List Wrapped Beacon ETH (WBETH)"}))
+		assert.Empty(t, f.Check(signal.Signal{Coin: "rare", Title: "Binance Will List SuperRare (RARE)"}))
+	})
+
+	t.Run("allow rules", func(t *testing.T) {
+		f, err := trader.NewFilter([]string{"R*"}, nil, []string{"will list"}, nil)
+		require.NoError(t, err)
+
+		assert.Empty(t, f.Check(signal.Signal{Coin: "rare", Title: "Binance Will List SuperRare (RARE)"}))
+		assert.NotEmpty(t, f.Check(signal.Signal{Coin: "chess", Title: "Binance Will List Tranchess (CHESS)"}))
+		assert.NotEmpty(t, f.Check(signal.Signal{Coin: "ant"}))
+	})
+
+	t.Run("keyword rules skip signals without a title", func(t *testing.T) {
+		f, err := trader.NewFilter(nil, nil, []string{"will list"}, []string{"wrapped"})
+		require.NoError(t, err)
+
+		assert.Empty(t, f.Check(signal.Signal{Coin: "rad"}))
+	})
+
+	t.Run("deny beats allow", func(t *testing.T) {
+		f, err := trader.NewFilter([]string{"*"}, []string{"*3L"}, nil, nil)
+		require.NoError(t, err)
+
+		assert.NotEmpty(t, f.Check(signal.Signal{Coin: "btc3l"}))
+	})
+
+	t.Run("blank entries are ignored", func(t *testing.T) {
+		f, err := trader.NewFilter([]string{""}, []string{""}, []string{""}, []string{""})
+		require.NoError(t, err)
+
+		assert.Empty(t, f.Check(signal.Signal{Coin: "rare"}))
+	})
+
+	t.Run("err given invalid pattern", func(t *testing.T) {
+		_, err := trader.NewFilter(nil, []string{"[3L"}, nil, nil)
+		require.Error(t, err)
+	})
+}
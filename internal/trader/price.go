@@ -0,0 +1,50 @@
+package trader
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+const (
+	// buyPriceMaxAge is how old a price can be for a coin that's just been signalled. A buy is a limit order, so an old
+	// price only risks it not filling, which isn't worth a round trip to the exchange when every moment counts.
+	buyPriceMaxAge = time.Minute
+	// sellPriceMaxAge is how old a price can be when deciding whether a held coin has risen enough to sell.
+	sellPriceMaxAge = 5 * time.Second
+)
+
+// PriceSnapshot is what the exchange was quoting for a coin at a moment in time. Any price it didn't quote is zero.
+type PriceSnapshot struct {
+	// Bid is the best price anyone is paying, so roughly what selling would get.
+	Bid decimal.Decimal
+	// Ask is the best price anyone is selling at, so roughly what buying would cost.
+	Ask decimal.Decimal
+	// Last is the price of the last trade.
+	Last decimal.Decimal
+	// Volume is how much of the quote currency was traded in the last 24 hours.
+	Volume decimal.Decimal
+	// ObservedAt is when the exchange was quoting these prices.
+	ObservedAt time.Time
+}
+
+// BuyPrice is the ask, or the last trade price if nobody is selling.
+func (p PriceSnapshot) BuyPrice() decimal.Decimal {
+	if p.Ask.IsPositive() {
+		return p.Ask
+	}
+	return p.Last
+}
+
+// SellPrice is the bid, or the last trade price if nobody is buying.
+func (p PriceSnapshot) SellPrice() decimal.Decimal {
+	if p.Bid.IsPositive() {
+		return p.Bid
+	}
+	return p.Last
+}
+
+// Age is how long ago the snapshot was observed.
+func (p PriceSnapshot) Age() time.Duration {
+	return time.Since(p.ObservedAt)
+}
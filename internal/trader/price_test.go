@@ -0,0 +1,24 @@
+package trader_test
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/moonr-app/crypto-signal-trading-bot/internal/trader"
+)
+
+func TestPriceSnapshot(t *testing.T) {
+	t.Run("buys at the ask and sells at the bid", func(t *testing.T) {
+		p := trader.PriceSnapshot{Bid: decimal.NewFromFloat(0.49), Ask: decimal.NewFromFloat(0.51), Last: decimal.NewFromFloat(0.5)}
+		assert.Equal(t, "0.51", p.BuyPrice().String())
+		assert.Equal(t, "0.49", p.SellPrice().String())
+	})
+
+	t.Run("last trade price given the book is empty", func(t *testing.T) {
+		p := trader.PriceSnapshot{Last: decimal.NewFromFloat(0.5)}
+		assert.Equal(t, "0.5", p.BuyPrice().String())
+		assert.Equal(t, "0.5", p.SellPrice().String())
+	})
+}
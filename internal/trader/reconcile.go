@@ -0,0 +1,159 @@
+package trader
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+
+	"github.com/moonr-app/crypto-signal-trading-bot/internal/shared/logging"
+)
+
+const sideSell = "sell"
+
+// reconcileTolerance is how far, as a fraction of a position, the exchange can be out before it counts as a mismatch.
+// It covers fees and rounding.
+var reconcileTolerance = decimal.NewFromFloat(0.01)
+
+// Holding is how much of a currency the account has.
+type Holding struct {
+	Available decimal.Decimal
+	// Locked is tied up in open orders.
+	Locked decimal.Decimal
+}
+
+func (h Holding) Total() decimal.Decimal {
+	return h.Available.Add(h.Locked)
+}
+
+// OpenOrder is an order still working on the exchange.
+type OpenOrder struct {
+	Coin    string
+	OrderID string
+	Side    string
+	Left    decimal.Decimal
+	// Ours is set if the bot placed the order.
+	Ours bool
+}
+
+// Trade is one of the account's own trades.
+type Trade struct {
+	OrderID string
+	// Side is buy or sell.
+	Side   string
+	Amount decimal.Decimal
+	Price  decimal.Decimal
+	At     time.Time
+}
+
+// Reconcile compares every position awaiting sale with what the exchange really holds, after a crash or someone
+// trading by hand. Clear cases are fixed: a position sold outside the bot is marked completed, and one partly sold
+// has its amount brought down to what is left. Anything else is reported through the notifier, as are open orders
+// the bot placed for coins it no longer has a position in.
+func (s *Seller) Reconcile(ctx context.Context) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	coins, err := s.db.GetCoinsToConsider(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to read coins from db: %w", err)
+	}
+
+	holdings, err := s.exchange.Holdings(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get holdings: %w", err)
+	}
+
+	orders, err := s.exchange.OpenOrders(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get open orders: %w", err)
+	}
+
+	open := make(map[string][]OpenOrder)
+	for _, o := range orders {
+		coin := strings.ToUpper(o.Coin)
+		open[coin] = append(open[coin], o)
+	}
+
+	tracked := make(map[string]struct{}, len(coins))
+	for _, v := range coins {
+		coin := strings.ToUpper(v.Coin)
+		tracked[coin] = struct{}{}
+
+		if err := s.reconcilePosition(ctx, v, holdings[coin], open[coin]); err != nil {
+			return err
+		}
+	}
+
+	for coin, orders := range open {
+		if _, ok := tracked[coin]; ok {
+			continue
+		}
+		for _, o := range orders {
+			if o.Ours {
+				s.notifier.NotifyError(ctx, fmt.Errorf(
+					"%w: bot order %s to %s %s %s is still open but there is no position for it",
+					ErrInventoryMismatch, o.OrderID, o.Side, o.Left, coin,
+				))
+			}
+		}
+	}
+
+	logging.Info(ctx, "reconciled positions with the exchange", zap.Int("positions", len(coins)))
+	return nil
+}
+
+func (s *Seller) reconcilePosition(ctx context.Context, v SellingDetails, held Holding, open []OpenOrder) error {
+	var (
+		owned = v.AmountPurchased
+		slack = owned.Mul(reconcileTolerance)
+		total = held.Total()
+	)
+
+	if total.GreaterThanOrEqual(owned.Sub(slack)) {
+		return nil
+	}
+
+	if len(open) > 0 {
+		// something is still trading, it can't be known yet how this ends.
+		s.notifier.NotifyError(ctx, fmt.Errorf(
+			"%w: bot owns %s %s but the exchange holds %s and has %d open orders for it",
+			ErrInventoryMismatch, owned, v.Coin, total, len(open),
+		))
+		return nil
+	}
+
+	trades, err := s.exchange.Trades(ctx, v.Coin, v.PurchaseTime)
+	if err != nil {
+		return fmt.Errorf("failed to get trades for %s: %w", v.Coin, err)
+	}
+
+	sold := decimal.Zero
+	for _, t := range trades {
+		if t.Side == sideSell {
+			sold = sold.Add(t.Amount)
+		}
+	}
+
+	switch {
+	case sold.IsPositive() && total.LessThanOrEqual(slack):
+		logging.Info(ctx, "position was sold outside the bot, marking it completed", zap.String("coin", v.Coin), zap.String("sold", sold.String()))
+		if err := s.db.MarkCoinAsCompleted(ctx, v.Coin); err != nil {
+			return fmt.Errorf("failed to mark %s as completed: %w", v.Coin, err)
+		}
+	case sold.IsPositive() && total.Add(sold).GreaterThanOrEqual(owned.Sub(slack)):
+		logging.Info(ctx, "position was partly sold outside the bot, updating its amount", zap.String("coin", v.Coin), zap.String("held", total.String()))
+		if err := s.db.UpdateCoinAmount(ctx, v.Coin, total); err != nil {
+			return fmt.Errorf("failed to update amount of %s: %w", v.Coin, err)
+		}
+	default:
+		s.notifier.NotifyError(ctx, fmt.Errorf(
+			"%w: bot owns %s %s but the exchange holds %s and only %s was sold since it was bought",
+			ErrInventoryMismatch, owned, v.Coin, total, sold,
+		))
+	}
+	return nil
+}
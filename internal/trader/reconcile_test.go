@@ -0,0 +1,183 @@
+package trader_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/moonr-app/crypto-signal-trading-bot/internal/mocks"
+	"github.com/moonr-app/crypto-signal-trading-bot/internal/trader"
+)
+
+func TestSeller_Reconcile(t *testing.T) {
+	var (
+		bought = time.Now().Add(-time.Hour)
+		owned  = decimal.NewFromInt(30)
+		coins  = []trader.SellingDetails{{Coin: "mattcoin", AmountPurchased: owned, PurchaseTime: bought}}
+	)
+
+	t.Run("leaves positions the exchange agrees with alone", func(t *testing.T) {
+		var (
+			ctrl = gomock.NewController(t)
+			ctx  = context.Background()
+
+			db       = mocks.NewMockSellingDB(ctrl)
+			exchange = mocks.NewMockSellingExchange(ctrl)
+			notifier = mocks.NewMockNotifier(ctrl)
+		)
+		defer ctrl.Finish()
+
+		s := trader.NewSeller(notifier, db, exchange, 200)
+
+		gomock.InOrder(
+			db.EXPECT().GetCoinsToConsider(ctx).Return(coins, nil),
+			exchange.EXPECT().Holdings(ctx).Return(map[string]trader.Holding{
+				"MATTCOIN": {Available: decimal.NewFromInt(20), Locked: decimal.NewFromFloat(9.8)},
+			}, nil),
+			exchange.EXPECT().OpenOrders(ctx).Return(nil, nil),
+		)
+
+		require.NoError(t, s.Reconcile(ctx))
+	})
+
+	t.Run("marks a position sold by hand as completed", func(t *testing.T) {
+		var (
+			ctrl = gomock.NewController(t)
+			ctx  = context.Background()
+
+			db       = mocks.NewMockSellingDB(ctrl)
+			exchange = mocks.NewMockSellingExchange(ctrl)
+			notifier = mocks.NewMockNotifier(ctrl)
+		)
+		defer ctrl.Finish()
+
+		s := trader.NewSeller(notifier, db, exchange, 200)
+
+		gomock.InOrder(
+			db.EXPECT().GetCoinsToConsider(ctx).Return(coins, nil),
+			exchange.EXPECT().Holdings(ctx).Return(map[string]trader.Holding{}, nil),
+			exchange.EXPECT().OpenOrders(ctx).Return(nil, nil),
+			exchange.EXPECT().Trades(ctx, "mattcoin", bought).Return([]trader.Trade{
+				{Side: "sell", Amount: decimal.NewFromInt(30)},
+			}, nil),
+			db.EXPECT().MarkCoinAsCompleted(ctx, "mattcoin"),
+		)
+
+		require.NoError(t, s.Reconcile(ctx))
+	})
+
+	t.Run("brings a position partly sold by hand down to what is left", func(t *testing.T) {
+		var (
+			ctrl = gomock.NewController(t)
+			ctx  = context.Background()
+
+			db       = mocks.NewMockSellingDB(ctrl)
+			exchange = mocks.NewMockSellingExchange(ctrl)
+			notifier = mocks.NewMockNotifier(ctrl)
+
+			left = decimal.NewFromInt(12)
+		)
+		defer ctrl.Finish()
+
+		s := trader.NewSeller(notifier, db, exchange, 200)
+
+		gomock.InOrder(
+			db.EXPECT().GetCoinsToConsider(ctx).Return(coins, nil),
+			exchange.EXPECT().Holdings(ctx).Return(map[string]trader.Holding{"MATTCOIN": {Available: left}}, nil),
+			exchange.EXPECT().OpenOrders(ctx).Return(nil, nil),
+			exchange.EXPECT().Trades(ctx, "mattcoin", bought).Return([]trader.Trade{
+				{Side: "buy", Amount: decimal.NewFromInt(30)},
+				{Side: "sell", Amount: decimal.NewFromInt(10)},
+				{Side: "sell", Amount: decimal.NewFromInt(8)},
+			}, nil),
+			db.EXPECT().UpdateCoinAmount(ctx, "mattcoin", left),
+		)
+
+		require.NoError(t, s.Reconcile(ctx))
+	})
+
+	t.Run("reports what it can't explain", func(t *testing.T) {
+		var (
+			ctrl = gomock.NewController(t)
+			ctx  = context.Background()
+
+			db       = mocks.NewMockSellingDB(ctrl)
+			exchange = mocks.NewMockSellingExchange(ctrl)
+			notifier = mocks.NewMockNotifier(ctrl)
+		)
+		defer ctrl.Finish()
+
+		s := trader.NewSeller(notifier, db, exchange, 200)
+
+		gomock.InOrder(
+			db.EXPECT().GetCoinsToConsider(ctx).Return(coins, nil),
+			exchange.EXPECT().Holdings(ctx).Return(map[string]trader.Holding{"MATTCOIN": {Available: decimal.NewFromInt(5)}}, nil),
+			exchange.EXPECT().OpenOrders(ctx).Return(nil, nil),
+			exchange.EXPECT().Trades(ctx, "mattcoin", bought).Return(nil, nil),
+			notifier.EXPECT().NotifyError(ctx, gomock.Any()).Do(func(_ context.Context, err error) {
+				assert.True(t, errors.Is(err, trader.ErrInventoryMismatch))
+			}),
+		)
+
+		require.NoError(t, s.Reconcile(ctx))
+	})
+
+	t.Run("reports a position with orders still open instead of guessing", func(t *testing.T) {
+		var (
+			ctrl = gomock.NewController(t)
+			ctx  = context.Background()
+
+			db       = mocks.NewMockSellingDB(ctrl)
+			exchange = mocks.NewMockSellingExchange(ctrl)
+			notifier = mocks.NewMockNotifier(ctrl)
+		)
+		defer ctrl.Finish()
+
+		s := trader.NewSeller(notifier, db, exchange, 200)
+
+		gomock.InOrder(
+			db.EXPECT().GetCoinsToConsider(ctx).Return(coins, nil),
+			exchange.EXPECT().Holdings(ctx).Return(map[string]trader.Holding{"MATTCOIN": {Locked: decimal.NewFromInt(5)}}, nil),
+			exchange.EXPECT().OpenOrders(ctx).Return([]trader.OpenOrder{{Coin: "MATTCOIN", OrderID: "1", Side: "sell", Left: decimal.NewFromInt(5)}}, nil),
+			notifier.EXPECT().NotifyError(ctx, gomock.Any()).Do(func(_ context.Context, err error) {
+				assert.True(t, errors.Is(err, trader.ErrInventoryMismatch))
+			}),
+		)
+
+		require.NoError(t, s.Reconcile(ctx))
+	})
+
+	t.Run("reports bot orders left open for coins it has no position in", func(t *testing.T) {
+		var (
+			ctrl = gomock.NewController(t)
+			ctx  = context.Background()
+
+			db       = mocks.NewMockSellingDB(ctrl)
+			exchange = mocks.NewMockSellingExchange(ctrl)
+			notifier = mocks.NewMockNotifier(ctrl)
+		)
+		defer ctrl.Finish()
+
+		s := trader.NewSeller(notifier, db, exchange, 200)
+
+		gomock.InOrder(
+			db.EXPECT().GetCoinsToConsider(ctx).Return(nil, nil),
+			exchange.EXPECT().Holdings(ctx).Return(map[string]trader.Holding{}, nil),
+			exchange.EXPECT().OpenOrders(ctx).Return([]trader.OpenOrder{
+				{Coin: "OLD", OrderID: "1", Side: "sell", Left: decimal.NewFromInt(5), Ours: true},
+				{Coin: "MINE", OrderID: "2", Side: "buy", Left: decimal.NewFromInt(5)},
+			}, nil),
+			notifier.EXPECT().NotifyError(ctx, gomock.Any()).Do(func(_ context.Context, err error) {
+				assert.Contains(t, err.Error(), "bot order 1")
+			}),
+		)
+
+		require.NoError(t, s.Reconcile(ctx))
+	})
+}
@@ -2,7 +2,9 @@ package trader
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/shopspring/decimal"
@@ -11,23 +13,56 @@ import (
 	"github.com/moonr-app/crypto-signal-trading-bot/internal/shared/logging"
 )
 
+// ErrInventoryMismatch is reported when the exchange holds less of a coin than the bot bought.
+var ErrInventoryMismatch = errors.New("exchange balance doesn't match what the bot owns")
+
 type SellingDetails struct {
 	Coin            string
 	AmountPurchased decimal.Decimal
 	PurchasePrice   decimal.Decimal
-	PurchaseTime    time.Time
-	Timeout         time.Time
+	// CostPrice is what each coin cost including the buy's fees. It is zero for positions bought before fees were tracked.
+	CostPrice    decimal.Decimal
+	PurchaseTime time.Time
+	Timeout      time.Time
+	Bracket      Bracket
+	// ClientOrderID is the client ID of the order that bought the coin, empty for positions bought before they were used.
+	ClientOrderID string
+	// Venue is the exchange the coin was bought on, empty for positions bought before the bot traded on more than one.
+	Venue string
+	// Pair is the market the coin was bought through, empty if the exchange didn't say.
+	Pair string
 }
 
 type SellingDB interface {
 	GetCoinsToConsider(ctx context.Context) ([]SellingDetails, error)
 	MarkCoinAsCompleted(ctx context.Context, coin string) error
+	UpdateCoinAmount(ctx context.Context, coin string, amount decimal.Decimal) error
+	StoreBracket(ctx context.Context, coin string, bracket Bracket) error
 }
 
 type SellingExchange interface {
-	GetLastPrice(ctx context.Context, coin string) (decimal.Decimal, error)
-	Sell(ctx context.Context, coin string, amount decimal.Decimal, lastPrice decimal.Decimal) (decimal.Decimal, error)
+	// GetPrice returns the latest prices for coin, observed no longer than maxAge ago.
+	GetPrice(ctx context.Context, coin string, maxAge time.Duration) (PriceSnapshot, error)
+	// Sell sells amount of coin and returns what actually filled. It never sells more than the account holds; anything
+	// missing is reported as the fill's Shortfall. It returns ErrOrderNotFilled if nothing did fill, and
+	// ErrOrderTooSmall if amount is under what the exchange will take an order for. The client IDs of its orders are
+	// made from the client ID of the order that bought coin.
+	Sell(ctx context.Context, coin string, amount decimal.Decimal, lastPrice decimal.Decimal, buyClientOrderID string) (Fill, error)
 	GetBalanceForCoin(ctx context.Context, coin string) (decimal.Decimal, error)
+	// CheckBracket reports whether either leg of bracket has executed, returning what it sold if so.
+	// The other leg is cancelled as soon as one executes. It returns ErrBracketExpiring if the bracket needs placing
+	// again before the exchange drops it.
+	CheckBracket(ctx context.Context, coin string, bracket Bracket) (fill Fill, executed bool, err error)
+	CancelBracket(ctx context.Context, coin string, bracket Bracket) error
+	// PlaceBracket leaves a take-profit and a stop resting on the exchange for what fill bought.
+	PlaceBracket(ctx context.Context, coin string, fill Fill) (Bracket, error)
+	// SellFeeRate is the fraction of a sell's proceeds the exchange keeps as its fee.
+	SellFeeRate(ctx context.Context, coin string) (decimal.Decimal, error)
+	// Holdings is everything the account holds, by upper case currency.
+	Holdings(ctx context.Context) (map[string]Holding, error)
+	OpenOrders(ctx context.Context) ([]OpenOrder, error)
+	// Trades is the account's own trades in coin since since.
+	Trades(ctx context.Context, coin string, since time.Time) ([]Trade, error)
 }
 
 type Seller struct {
@@ -35,13 +70,36 @@ type Seller struct {
 	db                      SellingDB
 	exchange                SellingExchange
 	sellThresholdPercentage int64
+	// sweeper, if set, moves the profit of each sale out of the trading account.
+	sweeper *ProfitSweeper
+	// lock stops a reconcile and a selling pass changing the same positions at once.
+	lock *sync.Mutex
+	// mismatched are the coins someone has already been told the exchange holds too little of to sell.
+	mismatched map[string]bool
 }
 
 func NewSeller(notifier Notifier, db SellingDB, exchange SellingExchange, sellThresholdPercentage int64) *Seller {
-	return &Seller{notifier: notifier, db: db, exchange: exchange, sellThresholdPercentage: sellThresholdPercentage}
+	return &Seller{
+		notifier:                notifier,
+		db:                      db,
+		exchange:                exchange,
+		sellThresholdPercentage: sellThresholdPercentage,
+		lock:                    &sync.Mutex{},
+		mismatched:              make(map[string]bool),
+	}
+}
+
+// SetProfitSweeper has the profit of every sale from now on swept by p.
+func (s *Seller) SetProfitSweeper(p *ProfitSweeper) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.sweeper = p
 }
 
 func (s *Seller) MonitorAndSell(ctx context.Context) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
 	coins, err := s.db.GetCoinsToConsider(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to read coins from db: %w", err)
@@ -50,34 +108,232 @@ func (s *Seller) MonitorAndSell(ctx context.Context) error {
 	if len(coins) == 0 {
 		logging.Debug(ctx, "no coins to consider")
 	}
+	s.forgetMismatches(coins)
 
 	for _, v := range coins {
-		lastPrice, err := s.exchange.GetLastPrice(ctx, v.Coin)
+		if !v.Bracket.IsZero() {
+			fill, executed, err := s.exchange.CheckBracket(ctx, v.Coin, v.Bracket)
+			if errors.Is(err, ErrBracketExpiring) {
+				logging.Warn(ctx, "bracket about to expire, placing it again", zap.String("coin", v.Coin), zap.Error(err))
+				v.Bracket, err = s.renewBracket(ctx, v)
+			}
+			if IsTemporary(err) {
+				logging.Warn(ctx, "failed to check bracket, trying again next time", zap.String("coin", v.Coin), zap.Error(err))
+				continue
+			}
+			if err != nil {
+				return fmt.Errorf("failed to check bracket: %w", err)
+			}
+			if executed {
+				if err := s.recordSale(ctx, v, fill, false); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+
+		price, err := s.exchange.GetPrice(ctx, v.Coin, sellPriceMaxAge)
+		if IsTemporary(err) {
+			// one coin's price being unavailable shouldn't hold up the rest.
+			logging.Warn(ctx, "failed to get price, trying again next time", zap.String("coin", v.Coin), zap.Error(err))
+			continue
+		}
 		if err != nil {
-			return fmt.Errorf("failed to GetLastPrice: %w", err)
+			return fmt.Errorf("failed to GetPrice: %w", err)
 		}
+		lastPrice := price.SellPrice()
+
+		feeRate, err := s.exchange.SellFeeRate(ctx, v.Coin)
+		if err != nil {
+			return fmt.Errorf("failed to get sell fee rate: %w", err)
+		}
+		breakEven := breakEvenPrice(v, feeRate)
 
 		logging.Info(
 			ctx,
 			"purchased coin",
 			zap.String("current_price", v.PurchasePrice.String()),
+			zap.String("break_even_price", breakEven.String()),
 			zap.String("last_price", lastPrice.String()),
 		)
 
-		if s.isGreaterThanSellThreshold(ctx, v.PurchasePrice, lastPrice) {
-			sold, err := s.exchange.Sell(ctx, v.Coin, v.AmountPurchased, lastPrice)
+		if s.isGreaterThanSellThreshold(ctx, breakEven, lastPrice) {
+			if !v.Bracket.IsZero() {
+				if err := s.exchange.CancelBracket(ctx, v.Coin, v.Bracket); err != nil {
+					return fmt.Errorf("failed to cancel bracket before selling: %w", err)
+				}
+			}
+
+			fill, err := s.exchange.Sell(ctx, v.Coin, v.AmountPurchased, lastPrice, v.ClientOrderID)
+			if err != nil && !errors.Is(err, ErrOrderTooSmall) {
+				switch {
+				case fill.Amount.IsPositive():
+					// what sold before the sell failed is still sold. Recording it puts the bracket back over the
+					// rest, and the next pass only sells what is left.
+					if rerr := s.recordSale(ctx, v, fill, true); rerr != nil {
+						return rerr
+					}
+					v.AmountPurchased = v.AmountPurchased.Sub(fill.Amount)
+				case !v.Bracket.IsZero():
+					// the bracket was cancelled to sell, so the position is left unprotected unless it's placed again.
+					if _, rerr := s.replaceBracket(ctx, v); rerr != nil {
+						return rerr
+					}
+				}
+			}
+			if errors.Is(err, ErrOrderTooSmall) {
+				// whatever is left is dust the exchange won't take an order for, stop trying to sell it.
+				logging.Warn(ctx, "position too small to sell, giving up on it", zap.String("coin", v.Coin), zap.Error(err))
+				if err := s.db.MarkCoinAsCompleted(ctx, v.Coin); err != nil {
+					return fmt.Errorf("coin too small to sell but couldn't mark it as completed in DB: %w", err)
+				}
+				continue
+			}
+			if errors.Is(err, ErrInsufficientBalance) {
+				// it's tried again every pass, but once is enough to tell someone.
+				if !s.mismatched[v.Coin] {
+					s.notifier.NotifyError(ctx, fmt.Errorf("%w: failed to sell %s %s: %v", ErrInventoryMismatch, v.AmountPurchased, v.Coin, err))
+					s.mismatched[v.Coin] = true
+				}
+				continue
+			}
+			if IsTemporary(err) {
+				logging.Warn(ctx, "failed to sell coin, trying again next time", zap.String("coin", v.Coin), zap.Error(err))
+				continue
+			}
 			if err != nil {
 				return fmt.Errorf("failed to sell coin: %w", err)
 			}
-			s.notifier.NotifySold(ctx, v.Coin, sold, lastPrice)
-			if err := s.db.MarkCoinAsCompleted(ctx, v.Coin); err != nil {
-				return fmt.Errorf("coin sold but couldn't mark it as so in DB: %w", err)
+			if err := s.recordSale(ctx, v, fill, false); err != nil {
+				return err
 			}
 		}
 	}
 	return nil
 }
 
+// renewBracket cancels v's bracket and places a fresh one, for when the exchange is about to drop it.
+func (s *Seller) renewBracket(ctx context.Context, v SellingDetails) (Bracket, error) {
+	if err := s.exchange.CancelBracket(ctx, v.Coin, v.Bracket); err != nil {
+		return v.Bracket, fmt.Errorf("failed to cancel bracket before renewing it: %w", err)
+	}
+	return s.replaceBracket(ctx, v)
+}
+
+// replaceBracket places a bracket for v, priced from what it was bought at, and stores it in place of v.Bracket. If it
+// can't be placed the bracket is cleared from the DB instead, so no stale IDs are left, and someone is told the
+// position is unprotected.
+func (s *Seller) replaceBracket(ctx context.Context, v SellingDetails) (Bracket, error) {
+	bracket, err := s.exchange.PlaceBracket(ctx, v.Coin, Fill{Price: v.PurchasePrice, Amount: v.AmountPurchased, Pair: v.Pair})
+	if err != nil {
+		logging.Error(ctx, "failed to place bracket again", zap.String("coin", v.Coin), zap.Error(err))
+		s.notifier.NotifyError(ctx, fmt.Errorf("%s is no longer protected by a bracket: %w", v.Coin, err))
+		bracket = Bracket{}
+	}
+
+	if err := s.db.StoreBracket(ctx, v.Coin, bracket); err != nil {
+		return bracket, fmt.Errorf("couldn't store the bracket placed again for %s in DB: %w", v.Coin, err)
+	}
+	return bracket, nil
+}
+
+// forgetMismatches stops remembering mismatches for coins that are no longer held.
+func (s *Seller) forgetMismatches(coins []SellingDetails) {
+	held := make(map[string]bool, len(coins))
+	for _, v := range coins {
+		held[v.Coin] = true
+	}
+	for coin := range s.mismatched {
+		if !held[coin] {
+			delete(s.mismatched, coin)
+		}
+	}
+}
+
+// recordSale notifies about a sale and updates the position, whether it was sold by the seller or by its bracket.
+// failed is whether the sell went wrong part way, in which case whatever it didn't sell is kept open like the rest of
+// a partly filled order. Whatever is kept open gets a new bracket, given it had one.
+func (s *Seller) recordSale(ctx context.Context, v SellingDetails, fill Fill, failed bool) error {
+	if fill.Shortfall.IsPositive() {
+		// something other than the bot has sold or moved some of this coin, someone should look at why.
+		s.notifier.NotifyError(ctx, fmt.Errorf(
+			"%w: bot owns %s %s but only %s was available to sell",
+			ErrInventoryMismatch, v.AmountPurchased, v.Coin, v.AmountPurchased.Sub(fill.Shortfall),
+		))
+	}
+	if fill.Amount.IsZero() {
+		return s.markCompleted(ctx, v.Coin)
+	}
+
+	profit := fill.NetProceeds().Sub(fill.Amount.Mul(costPrice(v)))
+	logging.Info(
+		ctx,
+		"sold coin",
+		zap.String("coin", v.Coin),
+		zap.String("amount", fill.Amount.String()),
+		zap.String("average_price", fill.Price.String()),
+		zap.String("proceeds", fill.FilledTotal.String()),
+		zap.String("net_proceeds", fill.NetProceeds().String()),
+		zap.String("profit", profit.String()),
+	)
+	s.notifier.NotifySold(ctx, v.Coin, fill.Amount, fill.Price)
+
+	remaining := v.AmountPurchased.Sub(fill.Amount)
+	if fill.Shortfall.IsPositive() {
+		remaining = remaining.Sub(fill.Shortfall)
+	}
+
+	// only part of the order filled, keep the rest of the position open for the next pass.
+	partial := (fill.Left.IsPositive() || failed) && remaining.IsPositive()
+	if partial {
+		if err := s.db.UpdateCoinAmount(ctx, v.Coin, remaining); err != nil {
+			return fmt.Errorf("coin partially sold but couldn't update amount in DB: %w", err)
+		}
+	} else if err := s.markCompleted(ctx, v.Coin); err != nil {
+		return err
+	}
+
+	if s.sweeper != nil {
+		// the sale is recorded before its profit moves, so a sweep that fails or hangs can't leave it to be sold twice.
+		if err := s.sweeper.Sweep(ctx, v.Coin, fill, profit); err != nil {
+			logging.Error(ctx, "failed to sweep profit", zap.String("coin", v.Coin), zap.Error(err))
+			s.notifier.NotifyError(ctx, fmt.Errorf("failed to sweep profit from selling %s: %w", v.Coin, err))
+		}
+	}
+
+	if partial && !v.Bracket.IsZero() {
+		// whatever bracket there was is spent or cancelled by now, so the rest is protected by a new one.
+		v.AmountPurchased = remaining
+		if _, err := s.replaceBracket(ctx, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Seller) markCompleted(ctx context.Context, coin string) error {
+	if err := s.db.MarkCoinAsCompleted(ctx, coin); err != nil {
+		return fmt.Errorf("coin sold but couldn't mark it as so in DB: %w", err)
+	}
+	return nil
+}
+
+func costPrice(v SellingDetails) decimal.Decimal {
+	if v.CostPrice.IsPositive() {
+		return v.CostPrice
+	}
+	return v.PurchasePrice
+}
+
+// breakEvenPrice is the price a position has to sell at to get back what it cost, once the sell's fee is paid too.
+func breakEvenPrice(v SellingDetails, sellFeeRate decimal.Decimal) decimal.Decimal {
+	keep := decimal.NewFromInt(1).Sub(sellFeeRate)
+	if !keep.IsPositive() {
+		return costPrice(v)
+	}
+	return costPrice(v).Div(keep)
+}
+
 func (s *Seller) isGreaterThanSellThreshold(ctx context.Context, purchasePrice decimal.Decimal, lastPrice decimal.Decimal) bool {
 	if purchasePrice.Equal(decimal.NewFromInt(0)) {
 		logging.Warn(ctx, "purchase price was 0 for some reason")
@@ -3,6 +3,7 @@ package trader_test
 import (
 	"context"
 	"errors"
+	"fmt"
 
 	"testing"
 
@@ -51,12 +52,12 @@ func TestSeller_MonitorAndSell(t *testing.T) {
 		db.EXPECT().GetCoinsToConsider(ctx).Return([]trader.SellingDetails{{
 			Coin: coinToCheck,
 		}}, nil)
-		exchange.EXPECT().GetLastPrice(ctx, coinToCheck).Return(decimal.NewFromFloat(0), errors.New("some-price-error"))
+		exchange.EXPECT().GetPrice(ctx, coinToCheck, gomock.Any()).Return(trader.PriceSnapshot{}, errors.New("some-price-error"))
 
 		err := s.MonitorAndSell(ctx)
 		require.Error(t, err)
 
-		assert.Contains(t, err.Error(), "failed to GetLastPrice")
+		assert.Contains(t, err.Error(), "failed to GetPrice")
 	})
 	t.Run("does not sell given less than threshold", func(t *testing.T) {
 		var (
@@ -79,7 +80,8 @@ func TestSeller_MonitorAndSell(t *testing.T) {
 			Coin:          coinToCheck,
 			PurchasePrice: purchasePrice,
 		}}, nil)
-		exchange.EXPECT().GetLastPrice(ctx, coinToCheck).Return(lastPrice, nil)
+		exchange.EXPECT().GetPrice(ctx, coinToCheck, gomock.Any()).Return(trader.PriceSnapshot{Bid: lastPrice}, nil)
+		exchange.EXPECT().SellFeeRate(ctx, coinToCheck).Return(decimal.Zero, nil)
 
 		err := s.MonitorAndSell(ctx)
 		require.NoError(t, err)
@@ -110,8 +112,9 @@ func TestSeller_MonitorAndSell(t *testing.T) {
 				PurchasePrice:   purchasePrice,
 				AmountPurchased: amountToSell,
 			}}, nil),
-			exchange.EXPECT().GetLastPrice(ctx, coinToCheck).Return(lastPrice, nil),
-			exchange.EXPECT().Sell(ctx, coinToCheck, amountToSell, lastPrice).Return(amountToSell, nil),
+			exchange.EXPECT().GetPrice(ctx, coinToCheck, gomock.Any()).Return(trader.PriceSnapshot{Bid: lastPrice}, nil),
+			exchange.EXPECT().SellFeeRate(ctx, coinToCheck).Return(decimal.Zero, nil),
+			exchange.EXPECT().Sell(ctx, coinToCheck, amountToSell, lastPrice, "").Return(trader.Fill{Amount: amountToSell, Price: lastPrice}, nil),
 			notifier.EXPECT().NotifySold(ctx, coinToCheck, amountToSell, lastPrice),
 			db.EXPECT().MarkCoinAsCompleted(ctx, coinToCheck),
 		)
@@ -119,5 +122,479 @@ func TestSeller_MonitorAndSell(t *testing.T) {
 		err := s.MonitorAndSell(ctx)
 		require.NoError(t, err)
 	})
+	t.Run("keeps the rest of the position given sell only partially fills", func(t *testing.T) {
+		var (
+			ctrl = gomock.NewController(t)
+			ctx  = context.Background()
+
+			db       = mocks.NewMockSellingDB(ctrl)
+			exchange = mocks.NewMockSellingExchange(ctrl)
+			notifier = mocks.NewMockNotifier(ctrl)
+
+			coinToCheck = "mattcoin"
+
+			amountToSell             = decimal.NewFromFloat(30)
+			amountSold               = decimal.NewFromFloat(20)
+			purchasePrice            = decimal.NewFromFloat(100)
+			lastPrice                = decimal.NewFromFloat(300)
+			purchaseThresholdPercent = int64(200)
+		)
+		defer ctrl.Finish()
+
+		s := trader.NewSeller(notifier, db, exchange, purchaseThresholdPercent)
+
+		gomock.InOrder(
+			db.EXPECT().GetCoinsToConsider(ctx).Return([]trader.SellingDetails{{
+				Coin:            coinToCheck,
+				PurchasePrice:   purchasePrice,
+				AmountPurchased: amountToSell,
+			}}, nil),
+			exchange.EXPECT().GetPrice(ctx, coinToCheck, gomock.Any()).Return(trader.PriceSnapshot{Bid: lastPrice}, nil),
+			exchange.EXPECT().SellFeeRate(ctx, coinToCheck).Return(decimal.Zero, nil),
+			exchange.EXPECT().Sell(ctx, coinToCheck, amountToSell, lastPrice, "").Return(trader.Fill{
+				Amount: amountSold,
+				Price:  lastPrice,
+				Left:   amountToSell.Sub(amountSold),
+			}, nil),
+			notifier.EXPECT().NotifySold(ctx, coinToCheck, amountSold, lastPrice),
+			db.EXPECT().UpdateCoinAmount(ctx, coinToCheck, amountToSell.Sub(amountSold)),
+		)
+
+		err := s.MonitorAndSell(ctx)
+		require.NoError(t, err)
+	})
+	t.Run("records the sale given a bracket leg executed", func(t *testing.T) {
+		var (
+			ctrl = gomock.NewController(t)
+			ctx  = context.Background()
+
+			db       = mocks.NewMockSellingDB(ctrl)
+			exchange = mocks.NewMockSellingExchange(ctrl)
+			notifier = mocks.NewMockNotifier(ctrl)
+
+			coinToCheck = "mattcoin"
+			amount      = decimal.NewFromFloat(30)
+			stopPrice   = decimal.NewFromFloat(90)
+			bracket     = trader.Bracket{TakeProfitOrderID: "1", StopOrderID: "2"}
+		)
+		defer ctrl.Finish()
+
+		s := trader.NewSeller(notifier, db, exchange, 200)
+
+		gomock.InOrder(
+			db.EXPECT().GetCoinsToConsider(ctx).Return([]trader.SellingDetails{{
+				Coin:            coinToCheck,
+				PurchasePrice:   decimal.NewFromFloat(100),
+				AmountPurchased: amount,
+				Bracket:         bracket,
+			}}, nil),
+			exchange.EXPECT().CheckBracket(ctx, coinToCheck, bracket).Return(trader.Fill{Amount: amount, Price: stopPrice}, true, nil),
+			notifier.EXPECT().NotifySold(ctx, coinToCheck, amount, stopPrice),
+			db.EXPECT().MarkCoinAsCompleted(ctx, coinToCheck),
+		)
+
+		err := s.MonitorAndSell(ctx)
+		require.NoError(t, err)
+	})
+	t.Run("cancels the bracket before selling given higher than threshold", func(t *testing.T) {
+		var (
+			ctrl = gomock.NewController(t)
+			ctx  = context.Background()
+
+			db       = mocks.NewMockSellingDB(ctrl)
+			exchange = mocks.NewMockSellingExchange(ctrl)
+			notifier = mocks.NewMockNotifier(ctrl)
+
+			coinToCheck = "mattcoin"
+			amount      = decimal.NewFromFloat(30)
+			lastPrice   = decimal.NewFromFloat(300)
+			bracket     = trader.Bracket{TakeProfitOrderID: "1", StopOrderID: "2"}
+		)
+		defer ctrl.Finish()
+
+		s := trader.NewSeller(notifier, db, exchange, 200)
+
+		gomock.InOrder(
+			db.EXPECT().GetCoinsToConsider(ctx).Return([]trader.SellingDetails{{
+				Coin:            coinToCheck,
+				PurchasePrice:   decimal.NewFromFloat(100),
+				AmountPurchased: amount,
+				Bracket:         bracket,
+			}}, nil),
+			exchange.EXPECT().CheckBracket(ctx, coinToCheck, bracket).Return(trader.Fill{}, false, nil),
+			exchange.EXPECT().GetPrice(ctx, coinToCheck, gomock.Any()).Return(trader.PriceSnapshot{Bid: lastPrice}, nil),
+			exchange.EXPECT().SellFeeRate(ctx, coinToCheck).Return(decimal.Zero, nil),
+			exchange.EXPECT().CancelBracket(ctx, coinToCheck, bracket).Return(nil),
+			exchange.EXPECT().Sell(ctx, coinToCheck, amount, lastPrice, "").Return(trader.Fill{Amount: amount, Price: lastPrice}, nil),
+			notifier.EXPECT().NotifySold(ctx, coinToCheck, amount, lastPrice),
+			db.EXPECT().MarkCoinAsCompleted(ctx, coinToCheck),
+		)
+
+		err := s.MonitorAndSell(ctx)
+		require.NoError(t, err)
+	})
+	t.Run("marks coin completed given what is left is too small to sell", func(t *testing.T) {
+		var (
+			ctrl = gomock.NewController(t)
+			ctx  = context.Background()
+
+			db       = mocks.NewMockSellingDB(ctrl)
+			exchange = mocks.NewMockSellingExchange(ctrl)
+			notifier = mocks.NewMockNotifier(ctrl)
+
+			coinToCheck = "mattcoin"
+			dust        = decimal.NewFromFloat(0.0001)
+			lastPrice   = decimal.NewFromFloat(300)
+		)
+		defer ctrl.Finish()
+
+		s := trader.NewSeller(notifier, db, exchange, 200)
+
+		gomock.InOrder(
+			db.EXPECT().GetCoinsToConsider(ctx).Return([]trader.SellingDetails{{
+				Coin:            coinToCheck,
+				PurchasePrice:   decimal.NewFromFloat(100),
+				AmountPurchased: dust,
+			}}, nil),
+			exchange.EXPECT().GetPrice(ctx, coinToCheck, gomock.Any()).Return(trader.PriceSnapshot{Bid: lastPrice}, nil),
+			exchange.EXPECT().SellFeeRate(ctx, coinToCheck).Return(decimal.Zero, nil),
+			exchange.EXPECT().Sell(ctx, coinToCheck, dust, lastPrice, "").Return(trader.Fill{}, fmt.Errorf("wrapped: %w", trader.ErrOrderTooSmall)),
+			db.EXPECT().MarkCoinAsCompleted(ctx, coinToCheck),
+		)
+
+		err := s.MonitorAndSell(ctx)
+		require.NoError(t, err)
+	})
+	t.Run("flags a mismatch given the exchange held less than the bot owns", func(t *testing.T) {
+		var (
+			ctrl = gomock.NewController(t)
+			ctx  = context.Background()
+
+			db       = mocks.NewMockSellingDB(ctrl)
+			exchange = mocks.NewMockSellingExchange(ctrl)
+			notifier = mocks.NewMockNotifier(ctrl)
+
+			coinToCheck = "mattcoin"
+			owned       = decimal.NewFromFloat(30)
+			sold        = decimal.NewFromFloat(25)
+			lastPrice   = decimal.NewFromFloat(300)
+		)
+		defer ctrl.Finish()
+
+		s := trader.NewSeller(notifier, db, exchange, 200)
+
+		gomock.InOrder(
+			db.EXPECT().GetCoinsToConsider(ctx).Return([]trader.SellingDetails{{
+				Coin:            coinToCheck,
+				PurchasePrice:   decimal.NewFromFloat(100),
+				AmountPurchased: owned,
+			}}, nil),
+			exchange.EXPECT().GetPrice(ctx, coinToCheck, gomock.Any()).Return(trader.PriceSnapshot{Bid: lastPrice}, nil),
+			exchange.EXPECT().SellFeeRate(ctx, coinToCheck).Return(decimal.Zero, nil),
+			exchange.EXPECT().Sell(ctx, coinToCheck, owned, lastPrice, "").Return(trader.Fill{
+				Amount:    sold,
+				Price:     lastPrice,
+				Shortfall: owned.Sub(sold),
+			}, nil),
+			notifier.EXPECT().NotifyError(ctx, gomock.Any()).Do(func(_ context.Context, err error) {
+				assert.True(t, errors.Is(err, trader.ErrInventoryMismatch))
+			}),
+			notifier.EXPECT().NotifySold(ctx, coinToCheck, sold, lastPrice),
+			db.EXPECT().MarkCoinAsCompleted(ctx, coinToCheck),
+		)
+
+		err := s.MonitorAndSell(ctx)
+		require.NoError(t, err)
+	})
+	t.Run("carries on with the next coin given a temporary exchange error", func(t *testing.T) {
+		var (
+			ctrl = gomock.NewController(t)
+			ctx  = context.Background()
+
+			db       = mocks.NewMockSellingDB(ctrl)
+			exchange = mocks.NewMockSellingExchange(ctrl)
+			notifier = mocks.NewMockNotifier(ctrl)
+
+			lastPrice = decimal.NewFromFloat(300)
+			amount    = decimal.NewFromFloat(30)
+		)
+		defer ctrl.Finish()
+
+		s := trader.NewSeller(notifier, db, exchange, 200)
+
+		gomock.InOrder(
+			db.EXPECT().GetCoinsToConsider(ctx).Return([]trader.SellingDetails{
+				{Coin: "limited", PurchasePrice: decimal.NewFromFloat(100), AmountPurchased: amount},
+				{Coin: "mattcoin", PurchasePrice: decimal.NewFromFloat(100), AmountPurchased: amount},
+			}, nil),
+			exchange.EXPECT().GetPrice(ctx, "limited", gomock.Any()).Return(trader.PriceSnapshot{}, fmt.Errorf("wrapped: %w", trader.ErrRateLimited)),
+			exchange.EXPECT().GetPrice(ctx, "mattcoin", gomock.Any()).Return(trader.PriceSnapshot{Bid: lastPrice}, nil),
+			exchange.EXPECT().SellFeeRate(ctx, "mattcoin").Return(decimal.Zero, nil),
+			exchange.EXPECT().Sell(ctx, "mattcoin", amount, lastPrice, "").Return(trader.Fill{Amount: amount, Price: lastPrice}, nil),
+			notifier.EXPECT().NotifySold(ctx, "mattcoin", amount, lastPrice),
+			db.EXPECT().MarkCoinAsCompleted(ctx, "mattcoin"),
+		)
+
+		err := s.MonitorAndSell(ctx)
+		require.NoError(t, err)
+	})
+	t.Run("flags a mismatch once given the exchange says there isn't enough balance to sell", func(t *testing.T) {
+		var (
+			ctrl = gomock.NewController(t)
+			ctx  = context.Background()
+
+			db       = mocks.NewMockSellingDB(ctrl)
+			exchange = mocks.NewMockSellingExchange(ctrl)
+			notifier = mocks.NewMockNotifier(ctrl)
+
+			coinToCheck = "mattcoin"
+			owned       = decimal.NewFromFloat(30)
+			lastPrice   = decimal.NewFromFloat(300)
+			coins       = []trader.SellingDetails{{
+				Coin:            coinToCheck,
+				PurchasePrice:   decimal.NewFromFloat(100),
+				AmountPurchased: owned,
+			}}
+		)
+		defer ctrl.Finish()
+
+		s := trader.NewSeller(notifier, db, exchange, 200)
+
+		gomock.InOrder(
+			db.EXPECT().GetCoinsToConsider(ctx).Return(coins, nil),
+			exchange.EXPECT().GetPrice(ctx, coinToCheck, gomock.Any()).Return(trader.PriceSnapshot{Bid: lastPrice}, nil),
+			exchange.EXPECT().SellFeeRate(ctx, coinToCheck).Return(decimal.Zero, nil),
+			exchange.EXPECT().Sell(ctx, coinToCheck, owned, lastPrice, "").Return(trader.Fill{}, fmt.Errorf("wrapped: %w", trader.ErrInsufficientBalance)),
+			notifier.EXPECT().NotifyError(ctx, gomock.Any()).Do(func(_ context.Context, err error) {
+				assert.True(t, errors.Is(err, trader.ErrInventoryMismatch))
+			}),
+			db.EXPECT().GetCoinsToConsider(ctx).Return(coins, nil),
+			exchange.EXPECT().GetPrice(ctx, coinToCheck, gomock.Any()).Return(trader.PriceSnapshot{Bid: lastPrice}, nil),
+			exchange.EXPECT().SellFeeRate(ctx, coinToCheck).Return(decimal.Zero, nil),
+			exchange.EXPECT().Sell(ctx, coinToCheck, owned, lastPrice, "").Return(trader.Fill{}, fmt.Errorf("wrapped: %w", trader.ErrInsufficientBalance)),
+		)
+
+		require.NoError(t, s.MonitorAndSell(ctx))
+		require.NoError(t, s.MonitorAndSell(ctx))
+	})
+	t.Run("places the bracket again given the sell didn't go through", func(t *testing.T) {
+		var (
+			ctrl = gomock.NewController(t)
+			ctx  = context.Background()
+
+			db       = mocks.NewMockSellingDB(ctrl)
+			exchange = mocks.NewMockSellingExchange(ctrl)
+			notifier = mocks.NewMockNotifier(ctrl)
+
+			coinToCheck   = "mattcoin"
+			amount        = decimal.NewFromFloat(30)
+			purchasePrice = decimal.NewFromFloat(100)
+			lastPrice     = decimal.NewFromFloat(300)
+			bracket       = trader.Bracket{TakeProfitOrderID: "1", StopOrderID: "2"}
+			replaced      = trader.Bracket{TakeProfitOrderID: "3", StopOrderID: "4"}
+		)
+		defer ctrl.Finish()
+
+		s := trader.NewSeller(notifier, db, exchange, 200)
+
+		gomock.InOrder(
+			db.EXPECT().GetCoinsToConsider(ctx).Return([]trader.SellingDetails{{
+				Coin:            coinToCheck,
+				PurchasePrice:   purchasePrice,
+				AmountPurchased: amount,
+				Bracket:         bracket,
+			}}, nil),
+			exchange.EXPECT().CheckBracket(ctx, coinToCheck, bracket).Return(trader.Fill{}, false, nil),
+			exchange.EXPECT().GetPrice(ctx, coinToCheck, gomock.Any()).Return(trader.PriceSnapshot{Bid: lastPrice}, nil),
+			exchange.EXPECT().SellFeeRate(ctx, coinToCheck).Return(decimal.Zero, nil),
+			exchange.EXPECT().CancelBracket(ctx, coinToCheck, bracket).Return(nil),
+			exchange.EXPECT().Sell(ctx, coinToCheck, amount, lastPrice, "").Return(trader.Fill{}, fmt.Errorf("wrapped: %w", trader.ErrRateLimited)),
+			exchange.EXPECT().PlaceBracket(ctx, coinToCheck, trader.Fill{Price: purchasePrice, Amount: amount}).Return(replaced, nil),
+			db.EXPECT().StoreBracket(ctx, coinToCheck, replaced),
+		)
+
+		require.NoError(t, s.MonitorAndSell(ctx))
+	})
+	t.Run("places a bracket over the rest given sell only partially fills", func(t *testing.T) {
+		var (
+			ctrl = gomock.NewController(t)
+			ctx  = context.Background()
+
+			db       = mocks.NewMockSellingDB(ctrl)
+			exchange = mocks.NewMockSellingExchange(ctrl)
+			notifier = mocks.NewMockNotifier(ctrl)
+
+			coinToCheck   = "mattcoin"
+			amount        = decimal.NewFromFloat(30)
+			amountSold    = decimal.NewFromFloat(20)
+			rest          = amount.Sub(amountSold)
+			purchasePrice = decimal.NewFromFloat(100)
+			lastPrice     = decimal.NewFromFloat(300)
+			bracket       = trader.Bracket{TakeProfitOrderID: "1", StopOrderID: "2"}
+			replaced      = trader.Bracket{TakeProfitOrderID: "3", StopOrderID: "4"}
+		)
+		defer ctrl.Finish()
+
+		s := trader.NewSeller(notifier, db, exchange, 200)
+
+		gomock.InOrder(
+			db.EXPECT().GetCoinsToConsider(ctx).Return([]trader.SellingDetails{{
+				Coin:            coinToCheck,
+				PurchasePrice:   purchasePrice,
+				AmountPurchased: amount,
+				Bracket:         bracket,
+			}}, nil),
+			exchange.EXPECT().CheckBracket(ctx, coinToCheck, bracket).Return(trader.Fill{}, false, nil),
+			exchange.EXPECT().GetPrice(ctx, coinToCheck, gomock.Any()).Return(trader.PriceSnapshot{Bid: lastPrice}, nil),
+			exchange.EXPECT().SellFeeRate(ctx, coinToCheck).Return(decimal.Zero, nil),
+			exchange.EXPECT().CancelBracket(ctx, coinToCheck, bracket).Return(nil),
+			exchange.EXPECT().Sell(ctx, coinToCheck, amount, lastPrice, "").Return(trader.Fill{Amount: amountSold, Price: lastPrice, Left: rest}, nil),
+			notifier.EXPECT().NotifySold(ctx, coinToCheck, amountSold, lastPrice),
+			db.EXPECT().UpdateCoinAmount(ctx, coinToCheck, rest),
+			exchange.EXPECT().PlaceBracket(ctx, coinToCheck, trader.Fill{Price: purchasePrice, Amount: rest}).Return(replaced, nil),
+			db.EXPECT().StoreBracket(ctx, coinToCheck, replaced),
+		)
+
+		require.NoError(t, s.MonitorAndSell(ctx))
+	})
+	t.Run("records what sold given the sell failed part way", func(t *testing.T) {
+		var (
+			ctrl = gomock.NewController(t)
+			ctx  = context.Background()
+
+			db       = mocks.NewMockSellingDB(ctrl)
+			exchange = mocks.NewMockSellingExchange(ctrl)
+			notifier = mocks.NewMockNotifier(ctrl)
+
+			coinToCheck   = "mattcoin"
+			amount        = decimal.NewFromFloat(30)
+			amountSold    = decimal.NewFromFloat(20)
+			purchasePrice = decimal.NewFromFloat(100)
+			lastPrice     = decimal.NewFromFloat(300)
+		)
+		defer ctrl.Finish()
+
+		s := trader.NewSeller(notifier, db, exchange, 200)
+
+		gomock.InOrder(
+			db.EXPECT().GetCoinsToConsider(ctx).Return([]trader.SellingDetails{{
+				Coin:            coinToCheck,
+				PurchasePrice:   purchasePrice,
+				AmountPurchased: amount,
+			}}, nil),
+			exchange.EXPECT().GetPrice(ctx, coinToCheck, gomock.Any()).Return(trader.PriceSnapshot{Bid: lastPrice}, nil),
+			exchange.EXPECT().SellFeeRate(ctx, coinToCheck).Return(decimal.Zero, nil),
+			exchange.EXPECT().Sell(ctx, coinToCheck, amount, lastPrice, "").Return(
+				trader.Fill{Amount: amountSold, Price: lastPrice},
+				fmt.Errorf("wrapped: %w", trader.ErrExchangeUnavailable),
+			),
+			notifier.EXPECT().NotifySold(ctx, coinToCheck, amountSold, lastPrice),
+			db.EXPECT().UpdateCoinAmount(ctx, coinToCheck, amount.Sub(amountSold)),
+		)
+
+		require.NoError(t, s.MonitorAndSell(ctx))
+	})
+	t.Run("clears the bracket given it can't be placed again after the sell didn't go through", func(t *testing.T) {
+		var (
+			ctrl = gomock.NewController(t)
+			ctx  = context.Background()
+
+			db       = mocks.NewMockSellingDB(ctrl)
+			exchange = mocks.NewMockSellingExchange(ctrl)
+			notifier = mocks.NewMockNotifier(ctrl)
+
+			coinToCheck = "mattcoin"
+			amount      = decimal.NewFromFloat(30)
+			lastPrice   = decimal.NewFromFloat(300)
+			bracket     = trader.Bracket{TakeProfitOrderID: "1", StopOrderID: "2"}
+		)
+		defer ctrl.Finish()
+
+		s := trader.NewSeller(notifier, db, exchange, 200)
+
+		gomock.InOrder(
+			db.EXPECT().GetCoinsToConsider(ctx).Return([]trader.SellingDetails{{
+				Coin:            coinToCheck,
+				PurchasePrice:   decimal.NewFromFloat(100),
+				AmountPurchased: amount,
+				Bracket:         bracket,
+			}}, nil),
+			exchange.EXPECT().CheckBracket(ctx, coinToCheck, bracket).Return(trader.Fill{}, false, nil),
+			exchange.EXPECT().GetPrice(ctx, coinToCheck, gomock.Any()).Return(trader.PriceSnapshot{Bid: lastPrice}, nil),
+			exchange.EXPECT().SellFeeRate(ctx, coinToCheck).Return(decimal.Zero, nil),
+			exchange.EXPECT().CancelBracket(ctx, coinToCheck, bracket).Return(nil),
+			exchange.EXPECT().Sell(ctx, coinToCheck, amount, lastPrice, "").Return(trader.Fill{}, fmt.Errorf("wrapped: %w", trader.ErrExchangeUnavailable)),
+			exchange.EXPECT().PlaceBracket(ctx, coinToCheck, gomock.Any()).Return(trader.Bracket{}, fmt.Errorf("wrapped: %w", trader.ErrExchangeUnavailable)),
+			notifier.EXPECT().NotifyError(ctx, gomock.Any()),
+			db.EXPECT().StoreBracket(ctx, coinToCheck, trader.Bracket{}),
+		)
+
+		require.NoError(t, s.MonitorAndSell(ctx))
+	})
+	t.Run("renews the bracket given it is about to expire", func(t *testing.T) {
+		var (
+			ctrl = gomock.NewController(t)
+			ctx  = context.Background()
+
+			db       = mocks.NewMockSellingDB(ctrl)
+			exchange = mocks.NewMockSellingExchange(ctrl)
+			notifier = mocks.NewMockNotifier(ctrl)
+
+			coinToCheck   = "mattcoin"
+			amount        = decimal.NewFromFloat(30)
+			purchasePrice = decimal.NewFromFloat(100)
+			bracket       = trader.Bracket{TakeProfitOrderID: "1", StopOrderID: "2"}
+			renewed       = trader.Bracket{TakeProfitOrderID: "3", StopOrderID: "4"}
+		)
+		defer ctrl.Finish()
+
+		s := trader.NewSeller(notifier, db, exchange, 200)
+
+		gomock.InOrder(
+			db.EXPECT().GetCoinsToConsider(ctx).Return([]trader.SellingDetails{{
+				Coin:            coinToCheck,
+				PurchasePrice:   purchasePrice,
+				AmountPurchased: amount,
+				Bracket:         bracket,
+			}}, nil),
+			exchange.EXPECT().CheckBracket(ctx, coinToCheck, bracket).Return(trader.Fill{}, false, fmt.Errorf("wrapped: %w", trader.ErrBracketExpiring)),
+			exchange.EXPECT().CancelBracket(ctx, coinToCheck, bracket).Return(nil),
+			exchange.EXPECT().PlaceBracket(ctx, coinToCheck, trader.Fill{Price: purchasePrice, Amount: amount}).Return(renewed, nil),
+			db.EXPECT().StoreBracket(ctx, coinToCheck, renewed),
+			exchange.EXPECT().GetPrice(ctx, coinToCheck, gomock.Any()).Return(trader.PriceSnapshot{Bid: purchasePrice}, nil),
+			exchange.EXPECT().SellFeeRate(ctx, coinToCheck).Return(decimal.Zero, nil),
+		)
+
+		require.NoError(t, s.MonitorAndSell(ctx))
+	})
+	t.Run("does not sell given the price is over threshold before fees but not after", func(t *testing.T) {
+		var (
+			ctrl = gomock.NewController(t)
+			ctx  = context.Background()
+
+			db       = mocks.NewMockSellingDB(ctrl)
+			exchange = mocks.NewMockSellingExchange(ctrl)
+			notifier = mocks.NewMockNotifier(ctrl)
+
+			coinToCheck = "mattcoin"
+		)
+		defer ctrl.Finish()
+
+		s := trader.NewSeller(notifier, db, exchange, 20)
+
+		gomock.InOrder(
+			db.EXPECT().GetCoinsToConsider(ctx).Return([]trader.SellingDetails{{
+				Coin:            coinToCheck,
+				PurchasePrice:   decimal.NewFromFloat(100),
+				CostPrice:       decimal.NewFromFloat(100.2),
+				AmountPurchased: decimal.NewFromFloat(30),
+			}}, nil),
+			exchange.EXPECT().GetPrice(ctx, coinToCheck, gomock.Any()).Return(trader.PriceSnapshot{Bid: decimal.NewFromFloat(120)}, nil),
+			exchange.EXPECT().SellFeeRate(ctx, coinToCheck).Return(decimal.NewFromFloat(0.002), nil),
+		)
+
+		err := s.MonitorAndSell(ctx)
+		require.NoError(t, err)
+	})
 	t.Run("notifies given timeout waiting to sell", func(t *testing.T) {})
 }
@@ -0,0 +1,234 @@
+package trader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+
+	"github.com/moonr-app/crypto-signal-trading-bot/internal/scraper"
+	"github.com/moonr-app/crypto-signal-trading-bot/internal/shared/logging"
+)
+
+// ErrShortBudgetSpent is returned when opening another short would put more margin at risk than the short budget allows.
+var ErrShortBudgetSpent = errors.New("short budget is spent")
+
+// ShortPosition is a short opened on a perpetual futures contract, with the stop and take-profit left on the exchange
+// to close it.
+type ShortPosition struct {
+	Coin     string
+	Contract string
+	// Contracts is how many contracts were sold, and Amount how much of the coin they are for.
+	Contracts  int64
+	Amount     decimal.Decimal
+	EntryPrice decimal.Decimal
+	// Margin is the USDT put up for the position, and counts against the short budget until it's closed.
+	Margin   decimal.Decimal
+	Leverage int64
+	// StopPrice and TakeProfitPrice are the prices the bracket closes the position at.
+	StopPrice       decimal.Decimal
+	TakeProfitPrice decimal.Decimal
+	Bracket         Bracket
+	OpenedAt        time.Time
+}
+
+// ShortBudget is how much margin the shorter may risk, kept apart from the USDT spent buying coins.
+type ShortBudget struct {
+	// PerShort is the margin put up for each short.
+	PerShort decimal.Decimal
+	// Total is the most margin open shorts can have between them.
+	Total decimal.Decimal
+}
+
+type ShortExchange interface {
+	// ShortContract is the USDT perpetual coin can be shorted through, and false if there isn't one taking new positions.
+	ShortContract(ctx context.Context, coin string) (contract string, ok bool, err error)
+	// OpenShort sells contract short with margin USDT at the configured leverage, and leaves a stop and a
+	// take-profit on the exchange to close it.
+	OpenShort(ctx context.Context, coin string, contract string, margin decimal.Decimal) (ShortPosition, error)
+	// CheckShort reports whether position has been closed, by its stop, its take-profit or by hand, and if so what
+	// it made in USDT once fees are paid.
+	CheckShort(ctx context.Context, position ShortPosition) (pnl decimal.Decimal, closed bool, err error)
+}
+
+type ShortDB interface {
+	// CheckUniqueShort reports whether contract has never been shorted, so each delisting is only shorted once.
+	CheckUniqueShort(ctx context.Context, contract string) bool
+	GetOpenShorts(ctx context.Context) ([]ShortPosition, error)
+	StoreShortOpened(ctx context.Context, position ShortPosition) error
+	MarkShortClosed(ctx context.Context, contract string, pnl decimal.Decimal) error
+}
+
+// Shorter opens a small short on the perpetual of every coin its scrapers find, e.g. coins Binance announces it will
+// delist, and records each one until the exchange closes it.
+type Shorter struct {
+	db       ShortDB
+	notifier Notifier
+	exchange ShortExchange
+	budget   ShortBudget
+	interval time.Duration
+	scrapers []Scraper
+
+	lock sync.Mutex
+	// unstored are shorts that were opened but couldn't be recorded. Until they are, each pass stores them again and
+	// they count as shorted and against the budget, so nothing is shorted twice.
+	unstored []ShortPosition
+}
+
+func NewShorter(db ShortDB, notifier Notifier, exchange ShortExchange, budget ShortBudget, interval time.Duration, scrapers ...Scraper) *Shorter {
+	return &Shorter{db: db, notifier: notifier, exchange: exchange, budget: budget, interval: interval, scrapers: scrapers}
+}
+
+// Run scrapes for coins to short and checks on open shorts every interval until ctx is done.
+func (s *Shorter) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.scrape(ctx)
+			if err := s.Monitor(ctx); err != nil {
+				logging.Error(ctx, "failed to check open shorts", zap.Error(err))
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (s *Shorter) scrape(ctx context.Context) {
+	for _, sc := range s.scrapers {
+		coin, err := sc.Scrape(ctx)
+		if err != nil {
+			if !errors.Is(err, scraper.ErrNoCoin) {
+				logging.Error(ctx, "scrape error", zap.String("scraper", sc.Name()), zap.Error(err))
+			}
+			continue
+		}
+
+		err = s.Short(ctx, coin)
+		switch {
+		case err == nil:
+		case errors.Is(err, ErrNoNewCoin), errors.Is(err, ErrCoinUnsupported):
+			logging.Debug(ctx, "not shorting", zap.String("coin", coin), zap.Error(err))
+		default:
+			logging.Error(ctx, "failed to short", zap.String("coin", coin), zap.Error(err))
+			s.notifier.NotifyError(ctx, err)
+		}
+	}
+}
+
+// Short opens a short on coin's perpetual, as long as it's never been shorted before and there's enough left of the
+// budget. It returns ErrCoinUnsupported if the coin has no perpetual, ErrNoNewCoin if it has already been shorted and
+// ErrShortBudgetSpent if the budget is used up.
+func (s *Shorter) Short(ctx context.Context, coin string) error {
+	contract, ok, err := s.exchange.ShortContract(ctx, coin)
+	if err != nil {
+		return fmt.Errorf("failed to look up perpetual for %s: %w", coin, err)
+	}
+	if !ok {
+		return fmt.Errorf("%w: %s has no perpetual to short", ErrCoinUnsupported, coin)
+	}
+
+	s.lock.Lock()
+	unstored := append([]ShortPosition(nil), s.unstored...)
+	s.lock.Unlock()
+
+	for _, p := range unstored {
+		if p.Contract == contract {
+			return fmt.Errorf("%w: %s has already been shorted", ErrNoNewCoin, contract)
+		}
+	}
+	if !s.db.CheckUniqueShort(ctx, contract) {
+		return fmt.Errorf("%w: %s has already been shorted", ErrNoNewCoin, contract)
+	}
+
+	open, err := s.db.GetOpenShorts(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get open shorts: %w", err)
+	}
+	open = append(open, unstored...)
+
+	committed := decimal.Zero
+	for _, p := range open {
+		committed = committed.Add(p.Margin)
+	}
+	if committed.Add(s.budget.PerShort).GreaterThan(s.budget.Total) {
+		return fmt.Errorf("%w: not shorting %s, %s of %s USDT is already in open shorts", ErrShortBudgetSpent, contract, committed, s.budget.Total)
+	}
+
+	position, err := s.exchange.OpenShort(ctx, coin, contract, s.budget.PerShort)
+	if err != nil {
+		return fmt.Errorf("failed to short %s: %w", contract, err)
+	}
+
+	if err := s.db.StoreShortOpened(ctx, position); err != nil {
+		s.lock.Lock()
+		s.unstored = append(s.unstored, position)
+		s.lock.Unlock()
+		return fmt.Errorf("shorted %s but couldn't record it in DB, it will be stored again next pass: %w", contract, err)
+	}
+
+	logging.Info(
+		ctx,
+		"opened short",
+		zap.String("contract", contract),
+		zap.String("amount", position.Amount.String()),
+		zap.String("entry_price", position.EntryPrice.String()),
+	)
+	s.notifier.NotifyShorted(ctx, contract, position.Amount, position.EntryPrice)
+	return nil
+}
+
+// Monitor marks every open short the exchange has closed as closed, freeing its margin for the next one. Shorts that
+// couldn't be recorded when they were opened are stored first.
+func (s *Shorter) Monitor(ctx context.Context) error {
+	s.storeUnstored(ctx)
+
+	open, err := s.db.GetOpenShorts(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get open shorts: %w", err)
+	}
+
+	for _, p := range open {
+		pnl, closed, err := s.exchange.CheckShort(ctx, p)
+		if err != nil {
+			// the next pass is the retry.
+			logging.Error(ctx, "failed to check short", zap.String("contract", p.Contract), zap.Error(err))
+			continue
+		}
+		if !closed {
+			continue
+		}
+
+		if err := s.db.MarkShortClosed(ctx, p.Contract, pnl); err != nil {
+			return fmt.Errorf("failed to mark short on %s as closed: %w", p.Contract, err)
+		}
+
+		logging.Info(ctx, "short closed", zap.String("contract", p.Contract), zap.String("pnl", pnl.String()))
+		s.notifier.NotifyShortClosed(ctx, p.Contract, pnl)
+	}
+	return nil
+}
+
+// storeUnstored tries again to record each short that couldn't be when it was opened, keeping those that still fail.
+func (s *Shorter) storeUnstored(ctx context.Context) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	var failed []ShortPosition
+	for _, p := range s.unstored {
+		if err := s.db.StoreShortOpened(ctx, p); err != nil {
+			logging.Error(ctx, "failed to record short again", zap.String("contract", p.Contract), zap.Error(err))
+			failed = append(failed, p)
+			continue
+		}
+		logging.Info(ctx, "recorded short", zap.String("contract", p.Contract))
+	}
+	s.unstored = failed
+}
@@ -0,0 +1,156 @@
+package trader_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/moonr-app/crypto-signal-trading-bot/internal/mocks"
+	"github.com/moonr-app/crypto-signal-trading-bot/internal/trader"
+)
+
+func TestShorter_Short(t *testing.T) {
+	var (
+		ctx    = context.Background()
+		budget = trader.ShortBudget{PerShort: decimal.NewFromInt(20), Total: decimal.NewFromInt(50)}
+	)
+
+	t.Run("opens, stores and notifies given budget is left", func(t *testing.T) {
+		var (
+			ctrl     = gomock.NewController(t)
+			db       = mocks.NewMockShortDB(ctrl)
+			notifier = mocks.NewMockNotifier(ctrl)
+			exchange = mocks.NewMockShortExchange(ctrl)
+			position = trader.ShortPosition{Coin: "ant", Contract: "ANT_USDT", Amount: decimal.NewFromInt(30), EntryPrice: decimal.NewFromInt(4)}
+		)
+		defer ctrl.Finish()
+
+		gomock.InOrder(
+			exchange.EXPECT().ShortContract(ctx, "ant").Return("ANT_USDT", true, nil),
+			db.EXPECT().CheckUniqueShort(ctx, "ANT_USDT").Return(true),
+			db.EXPECT().GetOpenShorts(ctx).Return([]trader.ShortPosition{{Contract: "VAI_USDT", Margin: decimal.NewFromInt(20)}}, nil),
+			exchange.EXPECT().OpenShort(ctx, "ant", "ANT_USDT", budget.PerShort).Return(position, nil),
+			db.EXPECT().StoreShortOpened(ctx, position),
+			notifier.EXPECT().NotifyShorted(ctx, "ANT_USDT", position.Amount, position.EntryPrice),
+		)
+
+		s := trader.NewShorter(db, notifier, exchange, budget, 0)
+		require.NoError(t, s.Short(ctx, "ant"))
+	})
+
+	t.Run("ErrShortBudgetSpent given another short would go over the budget", func(t *testing.T) {
+		var (
+			ctrl     = gomock.NewController(t)
+			db       = mocks.NewMockShortDB(ctrl)
+			exchange = mocks.NewMockShortExchange(ctrl)
+		)
+		defer ctrl.Finish()
+
+		gomock.InOrder(
+			exchange.EXPECT().ShortContract(ctx, "ant").Return("ANT_USDT", true, nil),
+			db.EXPECT().CheckUniqueShort(ctx, "ANT_USDT").Return(true),
+			db.EXPECT().GetOpenShorts(ctx).Return([]trader.ShortPosition{
+				{Contract: "VAI_USDT", Margin: decimal.NewFromInt(20)},
+				{Contract: "XMR_USDT", Margin: decimal.NewFromInt(20)},
+			}, nil),
+		)
+
+		err := trader.NewShorter(db, nil, exchange, budget, 0).Short(ctx, "ant")
+		assert.True(t, errors.Is(err, trader.ErrShortBudgetSpent))
+	})
+
+	t.Run("ErrNoNewCoin given the contract has been shorted before", func(t *testing.T) {
+		var (
+			ctrl     = gomock.NewController(t)
+			db       = mocks.NewMockShortDB(ctrl)
+			exchange = mocks.NewMockShortExchange(ctrl)
+		)
+		defer ctrl.Finish()
+
+		gomock.InOrder(
+			exchange.EXPECT().ShortContract(ctx, "ant").Return("ANT_USDT", true, nil),
+			db.EXPECT().CheckUniqueShort(ctx, "ANT_USDT").Return(false),
+		)
+
+		err := trader.NewShorter(db, nil, exchange, budget, 0).Short(ctx, "ant")
+		assert.True(t, errors.Is(err, trader.ErrNoNewCoin))
+	})
+
+	t.Run("ErrCoinUnsupported given there is no perpetual", func(t *testing.T) {
+		var (
+			ctrl     = gomock.NewController(t)
+			exchange = mocks.NewMockShortExchange(ctrl)
+		)
+		defer ctrl.Finish()
+
+		exchange.EXPECT().ShortContract(ctx, "ant").Return("", false, nil)
+
+		err := trader.NewShorter(nil, nil, exchange, budget, 0).Short(ctx, "ant")
+		assert.True(t, errors.Is(err, trader.ErrCoinUnsupported))
+	})
+
+	t.Run("stores a short again next pass given it couldn't be stored, and doesn't short it twice", func(t *testing.T) {
+		var (
+			ctrl     = gomock.NewController(t)
+			db       = mocks.NewMockShortDB(ctrl)
+			exchange = mocks.NewMockShortExchange(ctrl)
+			position = trader.ShortPosition{Coin: "ant", Contract: "ANT_USDT", Margin: budget.PerShort}
+		)
+		defer ctrl.Finish()
+
+		gomock.InOrder(
+			exchange.EXPECT().ShortContract(ctx, "ant").Return("ANT_USDT", true, nil),
+			db.EXPECT().CheckUniqueShort(ctx, "ANT_USDT").Return(true),
+			db.EXPECT().GetOpenShorts(ctx).Return(nil, nil),
+			exchange.EXPECT().OpenShort(ctx, "ant", "ANT_USDT", budget.PerShort).Return(position, nil),
+			db.EXPECT().StoreShortOpened(ctx, position).Return(errors.New("dynamo unavailable")),
+
+			exchange.EXPECT().ShortContract(ctx, "ant").Return("ANT_USDT", true, nil),
+
+			db.EXPECT().StoreShortOpened(ctx, position),
+			db.EXPECT().GetOpenShorts(ctx).Return([]trader.ShortPosition{position}, nil),
+			exchange.EXPECT().CheckShort(ctx, position).Return(decimal.Zero, false, nil),
+		)
+
+		s := trader.NewShorter(db, nil, exchange, budget, 0)
+		require.Error(t, s.Short(ctx, "ant"))
+
+		err := s.Short(ctx, "ant")
+		assert.True(t, errors.Is(err, trader.ErrNoNewCoin))
+
+		require.NoError(t, s.Monitor(ctx))
+	})
+}
+
+func TestShorter_Monitor(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("marks closed shorts and leaves open ones", func(t *testing.T) {
+		var (
+			ctrl     = gomock.NewController(t)
+			db       = mocks.NewMockShortDB(ctrl)
+			notifier = mocks.NewMockNotifier(ctrl)
+			exchange = mocks.NewMockShortExchange(ctrl)
+			open     = trader.ShortPosition{Contract: "VAI_USDT"}
+			closed   = trader.ShortPosition{Contract: "ANT_USDT"}
+			pnl      = decimal.NewFromFloat(3.5)
+		)
+		defer ctrl.Finish()
+
+		gomock.InOrder(
+			db.EXPECT().GetOpenShorts(ctx).Return([]trader.ShortPosition{open, closed}, nil),
+			exchange.EXPECT().CheckShort(ctx, open).Return(decimal.Zero, false, nil),
+			exchange.EXPECT().CheckShort(ctx, closed).Return(pnl, true, nil),
+			db.EXPECT().MarkShortClosed(ctx, "ANT_USDT", pnl),
+			notifier.EXPECT().NotifyShortClosed(ctx, "ANT_USDT", pnl),
+		)
+
+		s := trader.NewShorter(db, notifier, exchange, trader.ShortBudget{}, 0)
+		require.NoError(t, s.Monitor(ctx))
+	})
+}
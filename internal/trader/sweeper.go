@@ -0,0 +1,96 @@
+package trader
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+
+	"github.com/moonr-app/crypto-signal-trading-bot/internal/shared/logging"
+)
+
+const (
+	// quoteCurrency is what the bot spends, and what profit is counted and moved in.
+	quoteCurrency = "USDT"
+	// sweepPrecision is how many decimal places are swept, so nothing smaller than a cent is ever moved.
+	sweepPrecision = 2
+)
+
+// ProfitVault is the exchange account profit is swept out of.
+type ProfitVault interface {
+	GetBalanceForCoin(ctx context.Context, coin string) (decimal.Decimal, error)
+	// TransferProfit moves amount of USDT out of the trading account and returns where it went.
+	TransferProfit(ctx context.Context, amount decimal.Decimal) (destination string, err error)
+}
+
+type SweepDB interface {
+	StoreProfitSweep(ctx context.Context, sweep ProfitSweep) error
+}
+
+// ProfitSweep is profit moved out of the trading account after a sale.
+type ProfitSweep struct {
+	Coin string
+	// Profit is what the sale made once fees are paid.
+	Profit decimal.Decimal
+	// Amount is what was moved. It's less than Profit if moving all of it would have taken the balance under the floor.
+	Amount      decimal.Decimal
+	Destination string
+	At          time.Time
+}
+
+// ProfitSweeper moves realised profit out of the trading account, so the bot can't risk it again. The account always
+// keeps at least floor USDT, and only profit above it is moved.
+type ProfitSweeper struct {
+	vault    ProfitVault
+	db       SweepDB
+	notifier Notifier
+	floor    decimal.Decimal
+	// venue is the venue whose sales are swept. Sales recorded without a venue are swept too.
+	venue string
+}
+
+func NewProfitSweeper(vault ProfitVault, db SweepDB, notifier Notifier, floor decimal.Decimal, venue string) *ProfitSweeper {
+	return &ProfitSweeper{vault: vault, db: db, notifier: notifier, floor: floor, venue: venue}
+}
+
+// Sweep moves profit made selling coin by fill out of the trading account, records it and tells the team. Profit from
+// a sale through a pair not quoted in USDT stays in that quote currency, so only as much USDT as the account has
+// above the floor can be moved for it.
+func (p *ProfitSweeper) Sweep(ctx context.Context, coin string, fill Fill, profit decimal.Decimal) error {
+	if !profit.IsPositive() || (fill.Venue != "" && fill.Venue != p.venue) {
+		return nil
+	}
+
+	balance, err := p.vault.GetBalanceForCoin(ctx, quoteCurrency)
+	if err != nil {
+		return fmt.Errorf("failed to get balance to sweep from: %w", err)
+	}
+
+	amount := decimal.Min(profit, balance.Sub(p.floor)).Truncate(sweepPrecision)
+	if !amount.IsPositive() {
+		logging.Info(
+			ctx,
+			"balance is at the floor, not sweeping profit",
+			zap.String("coin", coin),
+			zap.String("profit", profit.String()),
+			zap.String("balance", balance.String()),
+		)
+		return nil
+	}
+
+	destination, err := p.vault.TransferProfit(ctx, amount)
+	if err != nil {
+		return err
+	}
+
+	sweep := ProfitSweep{Coin: coin, Profit: profit, Amount: amount, Destination: destination, At: time.Now()}
+	if err := p.db.StoreProfitSweep(ctx, sweep); err != nil {
+		return fmt.Errorf("swept %s %s to %s but couldn't record it in DB: %w", amount, quoteCurrency, destination, err)
+	}
+
+	logging.Info(ctx, "swept profit", zap.String("coin", coin), zap.String("amount", amount.String()), zap.String("to", destination))
+	p.notifier.NotifySwept(ctx, amount, destination)
+	return nil
+}
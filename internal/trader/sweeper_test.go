@@ -0,0 +1,190 @@
+package trader_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/moonr-app/crypto-signal-trading-bot/internal/mocks"
+	"github.com/moonr-app/crypto-signal-trading-bot/internal/trader"
+)
+
+func TestProfitSweeper_Sweep(t *testing.T) {
+	var (
+		ctx   = context.Background()
+		floor = decimal.NewFromInt(100)
+	)
+
+	t.Run("sweeps all the profit given the balance stays over the floor", func(t *testing.T) {
+		var (
+			ctrl     = gomock.NewController(t)
+			vault    = mocks.NewMockProfitVault(ctrl)
+			db       = mocks.NewMockSweepDB(ctrl)
+			notifier = mocks.NewMockNotifier(ctrl)
+			profit   = decimal.NewFromFloat(12.345)
+			swept    = decimal.NewFromFloat(12.34)
+		)
+		defer ctrl.Finish()
+
+		var stored trader.ProfitSweep
+		gomock.InOrder(
+			vault.EXPECT().GetBalanceForCoin(ctx, "USDT").Return(decimal.NewFromInt(500), nil),
+			vault.EXPECT().TransferProfit(ctx, swept).Return("sub-account 1", nil),
+			db.EXPECT().StoreProfitSweep(ctx, gomock.Any()).DoAndReturn(func(ctx context.Context, sweep trader.ProfitSweep) error {
+				stored = sweep
+				return nil
+			}),
+			notifier.EXPECT().NotifySwept(ctx, swept, "sub-account 1"),
+		)
+
+		s := trader.NewProfitSweeper(vault, db, notifier, floor, "gateio")
+		require.NoError(t, s.Sweep(ctx, "RARE", trader.Fill{Venue: "gateio"}, profit))
+
+		assert.Equal(t, "RARE", stored.Coin)
+		assert.Equal(t, profit.String(), stored.Profit.String())
+		assert.Equal(t, swept.String(), stored.Amount.String())
+		assert.Equal(t, "sub-account 1", stored.Destination)
+		assert.False(t, stored.At.IsZero())
+	})
+
+	t.Run("sweeps only what is over the floor", func(t *testing.T) {
+		var (
+			ctrl     = gomock.NewController(t)
+			vault    = mocks.NewMockProfitVault(ctrl)
+			db       = mocks.NewMockSweepDB(ctrl)
+			notifier = mocks.NewMockNotifier(ctrl)
+			swept    = decimal.NewFromInt(5)
+		)
+		defer ctrl.Finish()
+
+		gomock.InOrder(
+			vault.EXPECT().GetBalanceForCoin(ctx, "USDT").Return(decimal.NewFromInt(105), nil),
+			vault.EXPECT().TransferProfit(ctx, swept).Return("futures account", nil),
+			db.EXPECT().StoreProfitSweep(ctx, gomock.Any()),
+			notifier.EXPECT().NotifySwept(ctx, swept, "futures account"),
+		)
+
+		s := trader.NewProfitSweeper(vault, db, notifier, floor, "gateio")
+		require.NoError(t, s.Sweep(ctx, "RARE", trader.Fill{}, decimal.NewFromInt(20)))
+	})
+
+	t.Run("nothing swept given the balance is under the floor", func(t *testing.T) {
+		var (
+			ctrl  = gomock.NewController(t)
+			vault = mocks.NewMockProfitVault(ctrl)
+		)
+		defer ctrl.Finish()
+
+		vault.EXPECT().GetBalanceForCoin(ctx, "USDT").Return(decimal.NewFromInt(90), nil)
+
+		s := trader.NewProfitSweeper(vault, nil, nil, floor, "gateio")
+		require.NoError(t, s.Sweep(ctx, "RARE", trader.Fill{}, decimal.NewFromInt(20)))
+	})
+
+	t.Run("nothing swept given a loss or a sale on another venue", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		s := trader.NewProfitSweeper(mocks.NewMockProfitVault(ctrl), nil, nil, floor, "gateio")
+		require.NoError(t, s.Sweep(ctx, "RARE", trader.Fill{}, decimal.NewFromInt(-3)))
+		require.NoError(t, s.Sweep(ctx, "RARE", trader.Fill{Venue: "kucoin"}, decimal.NewFromInt(20)))
+	})
+
+	t.Run("err given the transfer fails", func(t *testing.T) {
+		var (
+			ctrl  = gomock.NewController(t)
+			vault = mocks.NewMockProfitVault(ctrl)
+		)
+		defer ctrl.Finish()
+
+		vault.EXPECT().GetBalanceForCoin(ctx, "USDT").Return(decimal.NewFromInt(500), nil)
+		vault.EXPECT().TransferProfit(ctx, gomock.Any()).Return("", trader.ErrExchangeUnavailable)
+
+		s := trader.NewProfitSweeper(vault, nil, nil, floor, "gateio")
+		err := s.Sweep(ctx, "RARE", trader.Fill{}, decimal.NewFromInt(20))
+		assert.True(t, errors.Is(err, trader.ErrExchangeUnavailable))
+	})
+}
+
+func TestSeller_SweepsProfit(t *testing.T) {
+	t.Run("sale is recorded given the sweep fails", func(t *testing.T) {
+		var (
+			ctrl = gomock.NewController(t)
+			ctx  = context.Background()
+
+			db       = mocks.NewMockSellingDB(ctrl)
+			exchange = mocks.NewMockSellingExchange(ctrl)
+			notifier = mocks.NewMockNotifier(ctrl)
+			vault    = mocks.NewMockProfitVault(ctrl)
+
+			coin      = "mattcoin"
+			amount    = decimal.NewFromInt(10)
+			lastPrice = decimal.NewFromInt(300)
+		)
+		defer ctrl.Finish()
+
+		s := trader.NewSeller(notifier, db, exchange, 200)
+		s.SetProfitSweeper(trader.NewProfitSweeper(vault, nil, notifier, decimal.Zero, "gateio"))
+
+		gomock.InOrder(
+			db.EXPECT().GetCoinsToConsider(ctx).Return([]trader.SellingDetails{{
+				Coin:            coin,
+				PurchasePrice:   decimal.NewFromInt(100),
+				AmountPurchased: amount,
+			}}, nil),
+			exchange.EXPECT().GetPrice(ctx, coin, gomock.Any()).Return(trader.PriceSnapshot{Bid: lastPrice}, nil),
+			exchange.EXPECT().SellFeeRate(ctx, coin).Return(decimal.Zero, nil),
+			exchange.EXPECT().Sell(ctx, coin, amount, lastPrice, "").Return(trader.Fill{
+				Amount: amount, Price: lastPrice, FilledTotal: decimal.NewFromInt(3000),
+			}, nil),
+			notifier.EXPECT().NotifySold(ctx, coin, amount, lastPrice),
+			db.EXPECT().MarkCoinAsCompleted(ctx, coin),
+			vault.EXPECT().GetBalanceForCoin(ctx, "USDT").Return(decimal.Zero, trader.ErrExchangeUnavailable),
+			notifier.EXPECT().NotifyError(ctx, gomock.Any()),
+		)
+
+		require.NoError(t, s.MonitorAndSell(ctx))
+	})
+
+	t.Run("profit isn't swept given the sale can't be recorded", func(t *testing.T) {
+		var (
+			ctrl = gomock.NewController(t)
+			ctx  = context.Background()
+
+			db       = mocks.NewMockSellingDB(ctrl)
+			exchange = mocks.NewMockSellingExchange(ctrl)
+			notifier = mocks.NewMockNotifier(ctrl)
+			vault    = mocks.NewMockProfitVault(ctrl)
+
+			coin      = "mattcoin"
+			amount    = decimal.NewFromInt(10)
+			lastPrice = decimal.NewFromInt(300)
+		)
+		defer ctrl.Finish()
+
+		s := trader.NewSeller(notifier, db, exchange, 200)
+		s.SetProfitSweeper(trader.NewProfitSweeper(vault, nil, notifier, decimal.Zero, "gateio"))
+
+		gomock.InOrder(
+			db.EXPECT().GetCoinsToConsider(ctx).Return([]trader.SellingDetails{{
+				Coin:            coin,
+				PurchasePrice:   decimal.NewFromInt(100),
+				AmountPurchased: amount,
+			}}, nil),
+			exchange.EXPECT().GetPrice(ctx, coin, gomock.Any()).Return(trader.PriceSnapshot{Bid: lastPrice}, nil),
+			exchange.EXPECT().SellFeeRate(ctx, coin).Return(decimal.Zero, nil),
+			exchange.EXPECT().Sell(ctx, coin, amount, lastPrice, "").Return(trader.Fill{
+				Amount: amount, Price: lastPrice, FilledTotal: decimal.NewFromInt(3000),
+			}, nil),
+			notifier.EXPECT().NotifySold(ctx, coin, amount, lastPrice),
+			db.EXPECT().MarkCoinAsCompleted(ctx, coin).Return(errors.New("dynamo unavailable")),
+		)
+
+		require.Error(t, s.MonitorAndSell(ctx))
+	})
+}
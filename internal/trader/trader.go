@@ -9,7 +9,9 @@ import (
 	"go.uber.org/zap"
 	"golang.org/x/sync/errgroup"
 
+	"github.com/moonr-app/crypto-signal-trading-bot/internal/scraper"
 	"github.com/moonr-app/crypto-signal-trading-bot/internal/shared/logging"
+	"github.com/moonr-app/crypto-signal-trading-bot/internal/signal"
 )
 
 type Notifier interface {
@@ -17,12 +19,19 @@ type Notifier interface {
 	NotifyUnsupported(ctx context.Context, coin string)
 	NotifyPurchased(ctx context.Context, coin string, price decimal.Decimal, amount decimal.Decimal)
 	NotifySold(ctx context.Context, coin string, amount decimal.Decimal, pricePerCoin decimal.Decimal)
+	// NotifySwept tells the team amount of USDT profit was moved out of the trading account to destination.
+	NotifySwept(ctx context.Context, amount decimal.Decimal, destination string)
+	// NotifyShorted tells the team amount of coin was sold short on contract at price.
+	NotifyShorted(ctx context.Context, contract string, amount decimal.Decimal, price decimal.Decimal)
+	// NotifyShortClosed tells the team the short on contract was closed, having made pnl USDT.
+	NotifyShortClosed(ctx context.Context, contract string, pnl decimal.Decimal)
 }
 
 type Trader struct {
 	buyConsiderInterval  time.Duration
 	sellConsiderInterval time.Duration
-	buyer                *Buyer
+	coordinator          *Coordinator
+	bus                  *signal.Bus
 	Seller               *Seller
 	scrapers             []Scraper
 }
@@ -30,47 +39,66 @@ type Trader struct {
 func NewTrader(
 	buyConsiderInterval time.Duration,
 	sellConsiderInterval time.Duration,
-	buyer *Buyer,
+	coordinator *Coordinator,
+	bus *signal.Bus,
 	seller *Seller,
 	scrapers ...Scraper,
 ) *Trader {
 	return &Trader{
 		buyConsiderInterval:  buyConsiderInterval,
 		sellConsiderInterval: sellConsiderInterval,
-		buyer:                buyer,
+		coordinator:          coordinator,
+		bus:                  bus,
 		Seller:               seller,
 		scrapers:             scrapers,
 	}
 }
 
+// Trade runs every scraper on its own ticker, publishing matches onto the bus for the coordinator to act on,
+// and monitors open positions on the sell interval.
 func (t *Trader) Trade(ctx context.Context) error {
-	buyTicker := time.NewTicker(t.buyConsiderInterval)
-	sellTicker := time.NewTicker(t.sellConsiderInterval)
-
 	g, ctx := errgroup.WithContext(ctx)
 
-	for _, s := range t.scrapers {
-		scraper := s
+	g.Go(func() error {
+		return t.coordinator.Run(ctx)
+	})
+
+	for _, sc := range t.scrapers {
+		s := sc
 
 		g.Go(func() error {
-			logger := logging.FromContext(ctx).With(zap.String("scraper", scraper.Name()))
+			logger := logging.FromContext(ctx).With(zap.String("scraper", s.Name()))
+			buyTicker := time.NewTicker(t.buyConsiderInterval)
+			defer buyTicker.Stop()
 
 			for {
 				select {
 				case <-buyTicker.C:
-					err := t.buyer.Buy(ctx, scraper)
+					coin, err := s.Scrape(ctx)
 					if err != nil {
 						switch {
-						case errors.Is(err, ErrNoNewCoin):
+						case errors.Is(err, scraper.ErrNoCoin):
 							// do nothing
 						default:
-							logger.Error("buy error, should notify", zap.Error(err))
+							logger.Error("scrape error", zap.Error(err))
 						}
+						continue
 					}
-				case <-sellTicker.C:
-					err := t.Seller.MonitorAndSell(ctx)
-					if err != nil {
-						logger.Error("sell error, should notify", zap.Error(err))
+
+					sig := signal.Signal{
+						Coin:       coin,
+						Source:     s.Name(),
+						ReceivedAt: time.Now(),
+					}
+					if c, ok := s.(signal.Contracted); ok {
+						sig.Chain, sig.Contract, _ = c.Contract(coin)
+					}
+					if ti, ok := s.(signal.Titled); ok {
+						sig.Title, _ = ti.Title(coin)
+					}
+
+					if err := t.bus.Publish(ctx, sig); err != nil {
+						return err
 					}
 				case <-ctx.Done():
 					return errors.New("we are done")
@@ -79,5 +107,22 @@ func (t *Trader) Trade(ctx context.Context) error {
 		})
 	}
 
+	g.Go(func() error {
+		sellTicker := time.NewTicker(t.sellConsiderInterval)
+		defer sellTicker.Stop()
+
+		for {
+			select {
+			case <-sellTicker.C:
+				err := t.Seller.MonitorAndSell(ctx)
+				if err != nil {
+					logging.Error(ctx, "sell error, should notify", zap.Error(err))
+				}
+			case <-ctx.Done():
+				return errors.New("we are done")
+			}
+		}
+	})
+
 	return g.Wait()
 }